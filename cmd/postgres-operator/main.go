@@ -30,6 +30,7 @@ import (
 
 	"github.com/crunchydata/postgres-operator/internal/bridge"
 	"github.com/crunchydata/postgres-operator/internal/bridge/crunchybridgecluster"
+	"github.com/crunchydata/postgres-operator/internal/config"
 	"github.com/crunchydata/postgres-operator/internal/controller/pgupgrade"
 	"github.com/crunchydata/postgres-operator/internal/controller/postgrescluster"
 	"github.com/crunchydata/postgres-operator/internal/controller/runtime"
@@ -137,13 +138,21 @@ func main() {
 // addControllersToManager adds all PostgreSQL Operator controllers to the provided controller
 // runtime manager.
 func addControllersToManager(mgr manager.Manager, openshift bool, log logr.Logger, reg registration.Registration) {
+	rootPolicy, leafPolicy, err := config.CertificatePolicies()
+	if err != nil {
+		log.Error(err, "unable to read certificate settings")
+		os.Exit(1)
+	}
+
 	pgReconciler := &postgrescluster.Reconciler{
-		Client:       mgr.GetClient(),
-		IsOpenShift:  openshift,
-		Owner:        postgrescluster.ControllerName,
-		Recorder:     mgr.GetEventRecorderFor(postgrescluster.ControllerName),
-		Registration: reg,
-		Tracer:       otel.Tracer(postgrescluster.ControllerName),
+		Client:           mgr.GetClient(),
+		IsOpenShift:      openshift,
+		LeafCertificates: leafPolicy,
+		Owner:            postgrescluster.ControllerName,
+		Recorder:         mgr.GetEventRecorderFor(postgrescluster.ControllerName),
+		Registration:     reg,
+		RootCertificates: rootPolicy,
+		Tracer:           otel.Tracer(postgrescluster.ControllerName),
 	}
 
 	if err := pgReconciler.SetupWithManager(mgr); err != nil {
@@ -18,7 +18,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/crunchydata/postgres-operator/internal/pki"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -129,6 +131,41 @@ func PGONamespace() string {
 	return os.Getenv("PGO_NAMESPACE")
 }
 
+// CertificatePolicies returns the policies for root and leaf certificates
+// that the operator generates. These come from environment variables:
+//   - PGO_CERTIFICATE_KEY_ALGORITHM is "ECDSA" or "RSA" for both
+//   - PGO_ROOT_CERTIFICATE_LIFETIME is a duration, such as "87600h"
+//   - PGO_LEAF_CERTIFICATE_LIFETIME is a duration, such as "2160h"
+func CertificatePolicies() (root, leaf pki.Policy, err error) {
+	switch algorithm := pki.KeyAlgorithm(os.Getenv("PGO_CERTIFICATE_KEY_ALGORITHM")); algorithm {
+	case "", pki.ECDSA, pki.RSA:
+		root.KeyAlgorithm, leaf.KeyAlgorithm = algorithm, algorithm
+	default:
+		err = fmt.Errorf("PGO_CERTIFICATE_KEY_ALGORITHM: unknown algorithm %q", algorithm)
+	}
+
+	lifetime := func(key string) time.Duration {
+		var d time.Duration
+		if value := os.Getenv(key); err == nil && value != "" {
+			d, err = time.ParseDuration(value)
+			if err != nil {
+				err = fmt.Errorf("%s: %w", key, err)
+			} else if d < time.Hour {
+				err = fmt.Errorf("%s: must be at least one hour", key)
+			}
+		}
+		return d
+	}
+	root.Lifetime = lifetime("PGO_ROOT_CERTIFICATE_LIFETIME")
+	leaf.Lifetime = lifetime("PGO_LEAF_CERTIFICATE_LIFETIME")
+
+	if err == nil && root.Lifetime > 0 && leaf.Lifetime > root.Lifetime {
+		err = fmt.Errorf("PGO_LEAF_CERTIFICATE_LIFETIME: must not exceed the root lifetime")
+	}
+
+	return
+}
+
 // VerifyImageValues checks that all container images required by the
 // spec are defined. If any are undefined, a list is returned in an error.
 func VerifyImageValues(cluster *v1beta1.PostgresCluster) error {
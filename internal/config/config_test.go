@@ -18,10 +18,12 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"gotest.tools/v3/assert"
 	"sigs.k8s.io/yaml"
 
+	"github.com/crunchydata/postgres-operator/internal/pki"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -281,3 +283,42 @@ func TestVerifyImageValues(t *testing.T) {
 	})
 
 }
+
+func TestCertificatePolicies(t *testing.T) {
+	unsetEnv(t, "PGO_CERTIFICATE_KEY_ALGORITHM")
+	unsetEnv(t, "PGO_LEAF_CERTIFICATE_LIFETIME")
+	unsetEnv(t, "PGO_ROOT_CERTIFICATE_LIFETIME")
+
+	root, leaf, err := CertificatePolicies()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, root, pki.Policy{})
+	assert.DeepEqual(t, leaf, pki.Policy{})
+
+	setEnv(t, "PGO_CERTIFICATE_KEY_ALGORITHM", "RSA")
+	setEnv(t, "PGO_LEAF_CERTIFICATE_LIFETIME", "720h")
+	setEnv(t, "PGO_ROOT_CERTIFICATE_LIFETIME", "8760h")
+
+	root, leaf, err = CertificatePolicies()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, root, pki.Policy{KeyAlgorithm: pki.RSA, Lifetime: 8760 * time.Hour})
+	assert.DeepEqual(t, leaf, pki.Policy{KeyAlgorithm: pki.RSA, Lifetime: 720 * time.Hour})
+
+	t.Run("Invalid", func(t *testing.T) {
+		setEnv(t, "PGO_CERTIFICATE_KEY_ALGORITHM", "DSA")
+		_, _, err := CertificatePolicies()
+		assert.ErrorContains(t, err, "PGO_CERTIFICATE_KEY_ALGORITHM")
+
+		setEnv(t, "PGO_CERTIFICATE_KEY_ALGORITHM", "ECDSA")
+		setEnv(t, "PGO_LEAF_CERTIFICATE_LIFETIME", "5m")
+		_, _, err = CertificatePolicies()
+		assert.ErrorContains(t, err, "at least one hour")
+
+		setEnv(t, "PGO_LEAF_CERTIFICATE_LIFETIME", "1 year")
+		_, _, err = CertificatePolicies()
+		assert.ErrorContains(t, err, "PGO_LEAF_CERTIFICATE_LIFETIME")
+
+		setEnv(t, "PGO_LEAF_CERTIFICATE_LIFETIME", "9000h")
+		_, _, err = CertificatePolicies()
+		assert.ErrorContains(t, err, "exceed the root")
+	})
+}
@@ -0,0 +1,336 @@
+// Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgupgrade
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// backupID returns the value of the "pgbackrest-backup" and "pgbackrest-restore"
+// annotations that PGO uses to take and restore the backup of upgrade.
+func backupID(upgrade *v1beta1.PGUpgrade) string {
+	return "pgupgrade-" + string(upgrade.UID)
+}
+
+// backupOptions returns the pgBackRest options of the full backup annotated
+// with id.
+func backupOptions(id string) []string {
+	return []string{"--type=full", "--annotation=" + pgUpgrade + "=" + id}
+}
+
+// backupLabel returns the label of the most recent full backup in the output
+// of "pgbackrest info --output=json" that is annotated with id.
+// - https://pgbackrest.org/command.html#command-info
+func backupLabel(info []byte, id string) (string, error) {
+	var stanzas []struct {
+		Backup []struct {
+			Annotation map[string]string `json:"annotation"`
+			Label      string            `json:"label"`
+			Type       string            `json:"type"`
+		} `json:"backup"`
+	}
+
+	var label string
+	err := errors.WithStack(json.Unmarshal(info, &stanzas))
+	for _, stanza := range stanzas {
+		for _, backup := range stanza.Backup {
+			if backup.Type == "full" && backup.Annotation[pgUpgrade] == id {
+				label = backup.Label
+			}
+		}
+	}
+	return label, err
+}
+
+// rollbackRequested returns true when upgrade should restore its backup.
+func rollbackRequested(upgrade *v1beta1.PGUpgrade, world *World) bool {
+	if upgrade.GetAnnotations()[AnnotationRollback] != "" {
+		return true
+	}
+
+	job := world.Jobs[pgUpgradeJob(upgrade).Name]
+	return upgrade.Spec.Backup != nil && upgrade.Spec.Backup.RollbackOnFailure &&
+		job != nil && jobFailed(job)
+}
+
+// rollbackBlockedBy returns the names of the upgrade and remove-data Jobs of
+// upgrade that are still running. The restore waits for them so that it does
+// not replace data directories those Jobs are writing.
+func rollbackBlockedBy(upgrade *v1beta1.PGUpgrade, world *World) []string {
+	var names []string
+	for name, job := range world.Jobs {
+		if name != pgUpgradeJob(upgrade).Name && job.GetLabels()[LabelRole] != removeData {
+			continue
+		}
+		if !jobCompleted(job) && !jobFailed(job) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+//+kubebuilder:rbac:groups="postgres-operator.crunchydata.com",resources="postgresclusters",verbs={patch}
+//+kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+
+// reconcileBackup asks PGO to take a full backup of the cluster of upgrade and
+// records its label once it succeeds. It returns true when the backup is done.
+func (r *PGUpgradeReconciler) reconcileBackup(
+	ctx context.Context, upgrade *v1beta1.PGUpgrade, world *World,
+) (bool, error) {
+	if upgrade.Status.Backup != nil && upgrade.Status.Backup.Label != "" {
+		return true, nil
+	}
+
+	id := backupID(upgrade)
+	repoName := upgrade.Spec.Backup.RepoName
+
+	progressing := func(reason, message string) {
+		meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+			ObservedGeneration: upgrade.Generation,
+			Type:               ConditionPGUpgradeBackedUp,
+			Status:             metav1.ConditionFalse,
+			Reason:             reason,
+			Message:            message,
+		})
+	}
+
+	// Ask PGO for a backup the same way a person would: set the manual backup
+	// options and annotate the cluster. Remember the settings that change
+	// along the way so they can be set again.
+	// - https://access.crunchydata.com/documentation/postgres-operator/latest/tutorials/backups-disaster-recovery/backup-management
+	if world.Cluster.GetAnnotations()[AnnotationPGBackRestBackup] != id {
+		upgrade.Status.Backup = &v1beta1.PGUpgradeBackupStatus{
+			RepoName: repoName,
+			Image:    world.Cluster.Spec.Image,
+			Manual:   world.Cluster.Spec.Backups.PGBackRest.Manual.DeepCopy(),
+			Restore:  world.Cluster.Spec.Backups.PGBackRest.Restore.DeepCopy(),
+		}
+
+		patch := world.Cluster.DeepCopy()
+		patch.Annotations = Merge(patch.Annotations, map[string]string{
+			AnnotationPGBackRestBackup: id,
+		})
+		patch.Spec.Backups.PGBackRest.Manual = &v1beta1.PGBackRestManualBackup{
+			RepoName: repoName,
+			Options:  backupOptions(id),
+		}
+
+		err := errors.WithStack(r.Client.Patch(ctx, patch, client.MergeFrom(world.Cluster), r.Owner))
+		if err == nil {
+			progressing("PGUpgradeBackupInProgress",
+				fmt.Sprintf("Taking a full backup in %s; the cluster must be running", repoName))
+		}
+		return false, err
+	}
+
+	var status *v1beta1.PGBackRestJobStatus
+	if world.Cluster.Status.PGBackRest != nil {
+		status = world.Cluster.Status.PGBackRest.ManualBackup
+	}
+	if status == nil || status.ID != id || !status.Finished {
+		progressing("PGUpgradeBackupInProgress",
+			fmt.Sprintf("Taking a full backup in %s; the cluster must be running", repoName))
+		return false, nil
+	}
+	if status.Succeeded == 0 {
+		progressing("PGUpgradeBackupFailed",
+			"Backup did not complete successfully, please check the pgBackRest backup Job")
+		return false, nil
+	}
+
+	if upgrade.Status.Backup == nil {
+		upgrade.Status.Backup = &v1beta1.PGUpgradeBackupStatus{RepoName: repoName}
+	}
+
+	// Set the manual backup options of the cluster back to what they were.
+	if manual := world.Cluster.Spec.Backups.PGBackRest.Manual; manual != nil &&
+		manual.RepoName == repoName && equality.Semantic.DeepEqual(manual.Options, backupOptions(id)) {
+		patch := world.Cluster.DeepCopy()
+		patch.Spec.Backups.PGBackRest.Manual = upgrade.Status.Backup.Manual.DeepCopy()
+
+		err := errors.WithStack(r.Client.Patch(ctx, patch, client.MergeFrom(world.Cluster), r.Owner))
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// Read the label of the backup from the running primary.
+	primary := readyPrimary(world.Pods)
+	if primary == nil {
+		progressing("PGClusterPrimaryNotReady",
+			"Backup complete, waiting for the primary to read its label")
+		return false, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := errors.WithStack(r.PodExec(primary.Namespace, primary.Name, ContainerDatabase,
+		nil, &stdout, &stderr,
+		"pgbackrest", "info", "--stanza=db", "--repo="+repoName[len("repo"):], "--output=json"))
+	if err != nil {
+		err = errors.WithMessage(err, stderr.String())
+	}
+
+	if err == nil {
+		upgrade.Status.Backup.Label, err = backupLabel(stdout.Bytes(), id)
+	}
+	if err == nil && upgrade.Status.Backup.Label == "" {
+		err = errors.Errorf("no backup annotated %q in %s", id, repoName)
+	}
+	if err != nil {
+		progressing("PGUpgradeBackupFailed", err.Error())
+		return false, err
+	}
+
+	meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+		ObservedGeneration: upgrade.Generation,
+		Type:               ConditionPGUpgradeBackedUp,
+		Status:             metav1.ConditionTrue,
+		Reason:             "PGUpgradeBackupComplete",
+		Message: fmt.Sprintf("Backup %s is in %s",
+			upgrade.Status.Backup.Label, repoName),
+	})
+	return true, nil
+}
+
+// reconcileRollback asks PGO to restore the cluster of upgrade in place from
+// the backup taken before the upgrade, at the old version and image of
+// PostgreSQL. It reports the progress of the restore in the "RolledBack"
+// condition and sets the restore settings of the cluster back to what they
+// were once it finishes.
+func (r *PGUpgradeReconciler) reconcileRollback(
+	ctx context.Context, upgrade *v1beta1.PGUpgrade, world *World,
+) (ctrl.Result, error) {
+	rolledBack := func(condition metav1.ConditionStatus, reason, message string) {
+		meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+			ObservedGeneration: upgrade.Generation,
+			Type:               ConditionPGUpgradeRolledBack,
+			Status:             condition,
+			Reason:             reason,
+			Message:            message,
+		})
+	}
+
+	if upgrade.Status.Backup == nil || upgrade.Status.Backup.Label == "" {
+		rolledBack(metav1.ConditionFalse, "PGUpgradeBackupMissing", "There is no backup to restore")
+		return ctrl.Result{}, nil
+	}
+
+	id := backupID(upgrade)
+	backup := upgrade.Status.Backup
+
+	// A restore that has not started waits for running Jobs. Those Jobs wake
+	// this controller when they finish.
+	if world.Cluster.GetAnnotations()[AnnotationPGBackRestRestore] != id {
+		if names := rollbackBlockedBy(upgrade, world); len(names) > 0 {
+			rolledBack(metav1.ConditionFalse, "PGUpgradeRollbackWaiting",
+				fmt.Sprintf("Waiting for Jobs to finish: %s", strings.Join(names, ", ")))
+			return ctrl.Result{}, nil
+		}
+	}
+
+	inProgress := fmt.Sprintf("PostgresCluster %s is restoring backup %s at version %d",
+		upgrade.Spec.PostgresClusterName, backup.Label, upgrade.Spec.FromPostgresVersion)
+
+	meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+		ObservedGeneration: upgrade.Generation,
+		Type:               ConditionPGUpgradeProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             "PGUpgradeRollbackInProgress",
+		Message:            "Upgrade rolling back",
+	})
+
+	// Restore the backup and replay WAL archived since then. PGO stops the
+	// cluster, restores the data directory, then starts any instances that
+	// are not shut down.
+	// - https://access.crunchydata.com/documentation/postgres-operator/latest/tutorials/backups-disaster-recovery/disaster-recovery
+	if world.Cluster.GetAnnotations()[AnnotationPGBackRestRestore] != id {
+		patch := world.Cluster.DeepCopy()
+		patch.Annotations = Merge(patch.Annotations, map[string]string{
+			AnnotationPGBackRestRestore: id,
+		})
+		patch.Spec.Image = backup.Image
+		patch.Spec.PostgresVersion = upgrade.Spec.FromPostgresVersion
+		patch.Spec.Backups.PGBackRest.Restore = &v1beta1.PGBackRestRestore{
+			Enabled: initialize.Bool(true),
+			PostgresClusterDataSource: &v1beta1.PostgresClusterDataSource{
+				RepoName: backup.RepoName,
+				Options:  []string{"--set=" + backup.Label},
+			},
+		}
+
+		err := errors.WithStack(r.Client.Patch(ctx, patch, client.MergeFrom(world.Cluster), r.Owner))
+
+		// The cluster no longer runs the new version.
+		if err == nil && world.Cluster.Status.PostgresVersion == upgrade.Spec.ToPostgresVersion {
+			status := patch.DeepCopy()
+			status.Status.PostgresVersion = upgrade.Spec.FromPostgresVersion
+			err = errors.WithStack(r.Client.Status().Patch(ctx, status, client.MergeFrom(patch), r.Owner))
+		}
+		if err == nil {
+			rolledBack(metav1.ConditionFalse, "PGUpgradeRollbackInProgress", inProgress)
+		}
+		return ctrl.Result{}, err
+	}
+
+	// Wait for PGO to finish the restore.
+	var status *v1beta1.PGBackRestJobStatus
+	if world.Cluster.Status.PGBackRest != nil {
+		status = world.Cluster.Status.PGBackRest.Restore
+	}
+	if status == nil || status.ID != id || !status.Finished {
+		rolledBack(metav1.ConditionFalse, "PGUpgradeRollbackInProgress", inProgress)
+		return ctrl.Result{}, nil
+	}
+	if status.Succeeded == 0 {
+		rolledBack(metav1.ConditionFalse, "PGUpgradeRollbackFailed",
+			"Restore did not complete successfully, please check the pgBackRest restore Job")
+		return ctrl.Result{}, nil
+	}
+
+	// Set the restore settings of the cluster back to what they were.
+	patch := world.Cluster.DeepCopy()
+	patch.Spec.Backups.PGBackRest.Restore = backup.Restore.DeepCopy()
+
+	err := errors.WithStack(r.Client.Patch(ctx, patch, client.MergeFrom(world.Cluster), r.Owner))
+	if err == nil {
+		rolledBack(metav1.ConditionTrue, "PGUpgradeRollbackComplete", fmt.Sprintf(
+			"PostgresCluster %s restored backup %s at version %d",
+			upgrade.Spec.PostgresClusterName, backup.Label, upgrade.Spec.FromPostgresVersion))
+		meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+			ObservedGeneration: upgrade.Generation,
+			Type:               ConditionPGUpgradeProgressing,
+			Status:             metav1.ConditionFalse,
+			Reason:             "PGUpgradeRolledBack",
+			Message:            "Upgrade rolled back",
+		})
+	}
+	return ctrl.Result{}, err
+}
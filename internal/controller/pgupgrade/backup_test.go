@@ -0,0 +1,299 @@
+// Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgupgrade
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/controller/runtime"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestBackupLabel(t *testing.T) {
+	info := []byte(`[{"name":"db","backup":[
+		{"label":"20240101-010101F","type":"full","annotation":{"pgupgrade":"pgupgrade-uid"}},
+		{"label":"20240101-010101F_20240102-010101I","type":"incr"},
+		{"label":"20240103-010101F","type":"full","annotation":{"pgupgrade":"other"}},
+		{"label":"20240104-010101F","type":"full"}
+	]}]`)
+
+	label, err := backupLabel(info, "pgupgrade-uid")
+	assert.NilError(t, err)
+	assert.Equal(t, label, "20240101-010101F")
+
+	label, err = backupLabel(info, "missing")
+	assert.NilError(t, err)
+	assert.Equal(t, label, "")
+
+	_, err = backupLabel([]byte(`nope`), "pgupgrade-uid")
+	assert.ErrorContains(t, err, "invalid")
+}
+
+func TestRollbackRequested(t *testing.T) {
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Name = "pgu2"
+
+	world := NewWorld()
+	assert.Assert(t, !rollbackRequested(upgrade, world))
+
+	failed := &batchv1.Job{}
+	failed.Status.Conditions = []batchv1.JobCondition{
+		{Type: batchv1.JobFailed, Status: corev1.ConditionTrue},
+	}
+	world.Jobs[pgUpgradeJob(upgrade).Name] = failed
+	assert.Assert(t, !rollbackRequested(upgrade, world), "expected no rollback by default")
+
+	upgrade.Spec.Backup = &v1beta1.PGUpgradeBackupSpec{RepoName: "repo1", RollbackOnFailure: true}
+	assert.Assert(t, rollbackRequested(upgrade, world))
+
+	upgrade.Annotations = map[string]string{AnnotationRollback: "yes"}
+	assert.Assert(t, rollbackRequested(upgrade, NewWorld()))
+}
+
+func TestReconcileBackup(t *testing.T) {
+	ctx := context.Background()
+
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Name = "pgu2"
+	upgrade.UID = "uid3"
+	upgrade.Spec.Backup = &v1beta1.PGUpgradeBackupSpec{RepoName: "repo2"}
+
+	cluster := v1beta1.NewPostgresCluster()
+	cluster.Namespace = "ns1"
+	cluster.Name = "pg5"
+	cluster.Spec.Image = "postgres:15"
+	cluster.Spec.Backups.PGBackRest.Manual = &v1beta1.PGBackRestManualBackup{
+		RepoName: "repo1", Options: []string{"--type=incr"},
+	}
+
+	primary := &corev1.Pod{}
+	primary.Namespace = "ns1"
+	primary.Name = "pg5-abc-0"
+	primary.Labels = map[string]string{LabelRole: RolePatroniLeader}
+	primary.Status.Conditions = []corev1.PodCondition{
+		{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+	}
+
+	reconciler := &PGUpgradeReconciler{
+		Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).WithObjects(cluster).Build(),
+		PodExec: func(
+			namespace, pod, container string,
+			_ io.Reader, stdout, _ io.Writer, command ...string,
+		) error {
+			assert.Equal(t, pod, "pg5-abc-0")
+			assert.DeepEqual(t, command, []string{
+				"pgbackrest", "info", "--stanza=db", "--repo=2", "--output=json",
+			})
+			_, _ = stdout.Write([]byte(`[{"backup":[` +
+				`{"label":"L1F","type":"full","annotation":{"pgupgrade":"pgupgrade-uid3"}}]}]`))
+			return nil
+		},
+	}
+
+	world := NewWorld()
+	world.Cluster = cluster.DeepCopy()
+	world.Pods = []*corev1.Pod{primary}
+
+	// The first pass asks for a backup.
+	done, err := reconciler.reconcileBackup(ctx, upgrade, world)
+	assert.NilError(t, err)
+	assert.Assert(t, !done)
+
+	assert.NilError(t, reconciler.Client.Get(ctx, client.ObjectKeyFromObject(cluster), world.Cluster))
+	assert.Equal(t, world.Cluster.Annotations[AnnotationPGBackRestBackup], "pgupgrade-uid3")
+	assert.DeepEqual(t, world.Cluster.Spec.Backups.PGBackRest.Manual, &v1beta1.PGBackRestManualBackup{
+		RepoName: "repo2",
+		Options:  []string{"--type=full", "--annotation=pgupgrade=pgupgrade-uid3"},
+	})
+
+	backedUp := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeBackedUp)
+	assert.Assert(t, backedUp != nil)
+	assert.Equal(t, backedUp.Reason, "PGUpgradeBackupInProgress")
+
+	// It remembers the settings it changed.
+	assert.DeepEqual(t, upgrade.Status.Backup, &v1beta1.PGUpgradeBackupStatus{
+		RepoName: "repo2", Image: "postgres:15",
+		Manual: &v1beta1.PGBackRestManualBackup{
+			RepoName: "repo1", Options: []string{"--type=incr"},
+		},
+	})
+
+	// It waits for the backup.
+	done, err = reconciler.reconcileBackup(ctx, upgrade, world)
+	assert.NilError(t, err)
+	assert.Assert(t, !done)
+
+	// It records the label of a finished backup.
+	world.Cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+		ManualBackup: &v1beta1.PGBackRestJobStatus{
+			ID: "pgupgrade-uid3", Finished: true, Succeeded: 1,
+		},
+	}
+	done, err = reconciler.reconcileBackup(ctx, upgrade, world)
+	assert.NilError(t, err)
+	assert.Assert(t, done)
+	assert.Equal(t, upgrade.Status.Backup.RepoName, "repo2")
+	assert.Equal(t, upgrade.Status.Backup.Label, "L1F")
+	assert.Assert(t, meta.IsStatusConditionTrue(upgrade.Status.Conditions, ConditionPGUpgradeBackedUp))
+
+	// The manual backup settings are set back to what they were.
+	restored := v1beta1.NewPostgresCluster()
+	assert.NilError(t, reconciler.Client.Get(ctx, client.ObjectKeyFromObject(cluster), restored))
+	assert.DeepEqual(t, restored.Spec.Backups.PGBackRest.Manual, &v1beta1.PGBackRestManualBackup{
+		RepoName: "repo1", Options: []string{"--type=incr"},
+	})
+}
+
+func TestReconcileRollback(t *testing.T) {
+	ctx := context.Background()
+
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Name = "pgu2"
+	upgrade.UID = "uid3"
+	upgrade.Spec.PostgresClusterName = "pg5"
+	upgrade.Spec.FromPostgresVersion = 15
+	upgrade.Spec.ToPostgresVersion = 16
+
+	cluster := v1beta1.NewPostgresCluster()
+	cluster.Namespace = "ns1"
+	cluster.Name = "pg5"
+	cluster.Spec.Image = "postgres:16"
+	cluster.Spec.PostgresVersion = 16
+	cluster.Status.PostgresVersion = 16
+
+	t.Run("NoBackup", func(t *testing.T) {
+		reconciler := &PGUpgradeReconciler{}
+		upgrade := upgrade.DeepCopy()
+		world := NewWorld()
+		world.Cluster = cluster.DeepCopy()
+
+		_, err := reconciler.reconcileRollback(ctx, upgrade, world)
+		assert.NilError(t, err)
+
+		rolledBack := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeRolledBack)
+		assert.Assert(t, rolledBack != nil)
+		assert.Equal(t, rolledBack.Status, metav1.ConditionFalse)
+		assert.Equal(t, rolledBack.Reason, "PGUpgradeBackupMissing")
+	})
+
+	t.Run("RunningJobs", func(t *testing.T) {
+		reconciler := &PGUpgradeReconciler{}
+		upgrade := upgrade.DeepCopy()
+		upgrade.Status.Backup = &v1beta1.PGUpgradeBackupStatus{
+			RepoName: "repo2", Label: "L1F", Image: "postgres:15",
+		}
+
+		remove := &batchv1.Job{}
+		remove.Labels = map[string]string{LabelRole: removeData}
+		failed := &batchv1.Job{}
+		failed.Status.Conditions = []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue},
+		}
+
+		world := NewWorld()
+		world.Cluster = cluster.DeepCopy()
+		world.Jobs[pgUpgradeJob(upgrade).Name] = failed
+		world.Jobs["pg5-instance-removedata"] = remove
+		world.Jobs["unrelated"] = &batchv1.Job{}
+
+		// Nothing is patched while a remove-data Job runs.
+		_, err := reconciler.reconcileRollback(ctx, upgrade, world)
+		assert.NilError(t, err)
+
+		rolledBack := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeRolledBack)
+		assert.Assert(t, rolledBack != nil)
+		assert.Equal(t, rolledBack.Status, metav1.ConditionFalse)
+		assert.Equal(t, rolledBack.Reason, "PGUpgradeRollbackWaiting")
+		assert.Assert(t, strings.Contains(rolledBack.Message, "pg5-instance-removedata"))
+		assert.Assert(t, !strings.Contains(rolledBack.Message, "unrelated"))
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		reconciler := &PGUpgradeReconciler{
+			Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).WithObjects(cluster.DeepCopy()).Build(),
+		}
+		upgrade := upgrade.DeepCopy()
+		upgrade.Status.Backup = &v1beta1.PGUpgradeBackupStatus{
+			RepoName: "repo2", Label: "L1F", Image: "postgres:15",
+		}
+
+		world := NewWorld()
+		assert.NilError(t, reconciler.Client.Get(ctx, client.ObjectKeyFromObject(cluster), cluster))
+		world.Cluster = cluster.DeepCopy()
+
+		// The first pass starts the restore.
+		_, err := reconciler.reconcileRollback(ctx, upgrade, world)
+		assert.NilError(t, err)
+
+		rolledBack := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeRolledBack)
+		assert.Assert(t, rolledBack != nil)
+		assert.Equal(t, rolledBack.Status, metav1.ConditionFalse)
+		assert.Equal(t, rolledBack.Reason, "PGUpgradeRollbackInProgress")
+
+		restored := v1beta1.NewPostgresCluster()
+		assert.NilError(t, reconciler.Client.Get(ctx, client.ObjectKeyFromObject(cluster), restored))
+		assert.Equal(t, restored.Annotations[AnnotationPGBackRestRestore], "pgupgrade-uid3")
+		assert.Equal(t, restored.Spec.Image, "postgres:15")
+		assert.Equal(t, restored.Spec.PostgresVersion, 15)
+		assert.Equal(t, restored.Status.PostgresVersion, 15)
+		assert.Assert(t, *restored.Spec.Backups.PGBackRest.Restore.Enabled)
+		assert.Equal(t, restored.Spec.Backups.PGBackRest.Restore.RepoName, "repo2")
+		assert.DeepEqual(t, restored.Spec.Backups.PGBackRest.Restore.Options, []string{"--set=L1F"})
+
+		// It waits for the restore.
+		world.Cluster = restored.DeepCopy()
+		_, err = reconciler.reconcileRollback(ctx, upgrade, world)
+		assert.NilError(t, err)
+		assert.Assert(t, !meta.IsStatusConditionTrue(upgrade.Status.Conditions, ConditionPGUpgradeRolledBack))
+
+		t.Run("Failed", func(t *testing.T) {
+			upgrade := upgrade.DeepCopy()
+			world := NewWorld()
+			world.Cluster = restored.DeepCopy()
+			world.Cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+				Restore: &v1beta1.PGBackRestJobStatus{ID: "pgupgrade-uid3", Finished: true},
+			}
+
+			_, err := reconciler.reconcileRollback(ctx, upgrade, world)
+			assert.NilError(t, err)
+
+			rolledBack := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeRolledBack)
+			assert.Equal(t, rolledBack.Status, metav1.ConditionFalse)
+			assert.Equal(t, rolledBack.Reason, "PGUpgradeRollbackFailed")
+		})
+
+		// It finishes when the restore succeeds and removes its restore settings.
+		world.Cluster.Status.PGBackRest = &v1beta1.PGBackRestStatus{
+			Restore: &v1beta1.PGBackRestJobStatus{ID: "pgupgrade-uid3", Finished: true, Succeeded: 1},
+		}
+		_, err = reconciler.reconcileRollback(ctx, upgrade, world)
+		assert.NilError(t, err)
+		assert.Assert(t, meta.IsStatusConditionTrue(upgrade.Status.Conditions, ConditionPGUpgradeRolledBack))
+
+		assert.NilError(t, reconciler.Client.Get(ctx, client.ObjectKeyFromObject(cluster), restored))
+		assert.Assert(t, restored.Spec.Backups.PGBackRest.Restore == nil)
+	})
+}
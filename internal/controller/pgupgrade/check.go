@@ -0,0 +1,325 @@
+// Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgupgrade
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/config"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// checkFindingDescriptions describe the files that pg_upgrade --check writes
+// when it finds something that prevents an upgrade.
+// - https://git.postgresql.org/gitweb/?p=postgresql.git;a=blob;f=src/bin/pg_upgrade/check.c
+var checkFindingDescriptions = map[string]string{
+	"contrib_isn_and_int8_pass_by_value.txt": "contrib/isn functions that depend on bigint passing",
+	"incompatible_polymorphics.txt":          "objects using incompatible polymorphic functions",
+	"loadable_libraries.txt":                 "libraries or extensions missing from the new version",
+	"postfix_ops.txt":                        "user-defined postfix operators",
+	"tables_using_aclitem.txt":               "tables using the aclitem data type",
+	"tables_using_composite.txt":             "tables using system-defined composite types",
+	"tables_using_jsonb.txt":                 "tables using the 9.4 beta jsonb data type",
+	"tables_using_line.txt":                  "tables using the line data type",
+	"tables_using_reg.txt":                   "tables using reg* data types",
+	"tables_using_sql_identifier.txt":        "tables using the sql_identifier data type",
+	"tables_using_unknown.txt":               "tables using the unknown data type",
+	"tables_with_oids.txt":                   "tables declared WITH OIDS",
+}
+
+// checkFindings returns the names of the files listed in the report of
+// a check Job. See [checkCommand].
+func checkFindings(report string) []string {
+	for _, line := range strings.Split(report, "\n") {
+		if strings.HasPrefix(line, "findings:") {
+			findings := strings.Fields(strings.TrimPrefix(line, "findings:"))
+			sort.Strings(findings)
+			return findings
+		}
+	}
+	return nil
+}
+
+// checkReport returns the termination message of the check Job of upgrade.
+func checkReport(upgrade *v1beta1.PGUpgrade, pods []*corev1.Pod) string {
+	for _, pod := range pods {
+		if pod.Labels[LabelPGUpgrade] != upgrade.Name ||
+			pod.Labels[LabelRole] != pgUpgradeCheck {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated != nil && status.State.Terminated.Message != "" {
+				return status.State.Terminated.Message
+			}
+		}
+	}
+	return ""
+}
+
+//+kubebuilder:rbac:groups="",resources="configmaps",verbs={create,patch}
+//+kubebuilder:rbac:groups="",resources="persistentvolumeclaims",verbs={create,patch}
+//+kubebuilder:rbac:groups="",resources="persistentvolumeclaims",verbs={list,delete}
+
+// reconcileCheck checks that the cluster of upgrade can be upgraded without
+// stopping it. It clones the volumes of the running primary and runs
+// pg_upgrade --check against those clones. When that finishes, it writes
+// the findings to a ConfigMap and the "Compatible" condition, then deletes
+// the clones.
+//
+// The instance that is cloned is recorded in status and kept until the check
+// finishes. When that instance goes away before the check Job starts, its
+// clones are deleted and the check starts over from the running primary.
+//
+// The volumes are cloned one at a time, so a cluster with a separate WAL
+// volume may be cloned at slightly different points in time.
+func (r *PGUpgradeReconciler) reconcileCheck(
+	ctx context.Context, upgrade *v1beta1.PGUpgrade, world *World,
+) (ctrl.Result, error) {
+	var err error
+	job := world.Jobs[pgUpgradeCheckJob(upgrade).Name]
+
+	if job != nil && (jobCompleted(job) || jobFailed(job)) {
+		report := checkReport(upgrade, world.Pods)
+		err = r.applyCheckReport(ctx, upgrade, report)
+
+		if err == nil {
+			err = r.deleteCheckVolumes(ctx, upgrade)
+		}
+		if err == nil {
+			upgrade.Status.Check = nil
+			setCheckConditions(upgrade, job, report)
+		}
+		return ctrl.Result{}, err
+	}
+
+	var instance *appsv1.StatefulSet
+	if upgrade.Status.Check != nil {
+		instance = world.ClusterInstances[upgrade.Status.Check.Instance]
+	}
+
+	// The Job keeps using its clones when their instance goes away.
+	if instance == nil && job != nil {
+		return r.reportCheckVolumes(ctx, upgrade, world)
+	}
+
+	// Start over from the running primary when the recorded instance is gone.
+	if instance == nil && upgrade.Status.Check != nil {
+		if err := r.deleteCheckVolumes(ctx, upgrade); err != nil {
+			return ctrl.Result{}, err
+		}
+		upgrade.Status.Check = nil
+	}
+
+	// The check copies the data of the running primary. Wait until Patroni
+	// has identified it.
+	if instance == nil && world.ClusterLeader == nil {
+		meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+			ObservedGeneration: upgrade.Generation,
+			Type:               ConditionPGUpgradeProgressing,
+			Status:             metav1.ConditionFalse,
+			Reason:             "PGClusterPrimaryNotIdentified",
+			Message:            "PostgresCluster primary instance not identified",
+		})
+
+		return ctrl.Result{}, nil
+	}
+
+	setStatusToProgressingIfReasonWas("PGClusterPrimaryNotIdentified", upgrade)
+
+	if instance == nil {
+		instance = world.ClusterLeader
+		upgrade.Status.Check = &v1beta1.PGUpgradeCheckStatus{Instance: instance.Name}
+	}
+
+	// Clone every volume of the instance.
+	clones := make(map[string]string)
+	for _, volume := range instance.Spec.Template.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		source := world.ClusterVolumes[volume.PersistentVolumeClaim.ClaimName]
+		if source == nil {
+			continue
+		}
+
+		clone := r.generateCheckVolume(upgrade, source)
+		if err == nil {
+			err = errors.WithStack(r.apply(ctx, clone))
+		}
+		clones[source.Name] = clone.Name
+	}
+
+	if err == nil && job == nil {
+		err = errors.WithStack(r.apply(ctx,
+			r.generateCheckJob(ctx, upgrade, instance, clones,
+				config.FetchKeyCommand(&world.Cluster.Spec))))
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return r.reportCheckVolumes(ctx, upgrade, world)
+}
+
+// reportCheckVolumes sets the "Progressing" condition of upgrade while the
+// volumes cloned for its check Job are not yet bound. It includes the reason
+// that the Pod of that Job cannot be scheduled, such as a clone that could not be
+// provisioned. It returns a result that checks again later; nothing else
+// signals when a volume binds.
+func (r *PGUpgradeReconciler) reportCheckVolumes(
+	ctx context.Context, upgrade *v1beta1.PGUpgrade, world *World,
+) (ctrl.Result, error) {
+	var volumes corev1.PersistentVolumeClaimList
+	err := errors.WithStack(r.Client.List(ctx, &volumes,
+		client.InNamespace(upgrade.Namespace),
+		client.MatchingLabels{
+			LabelPGUpgrade: upgrade.Name,
+			LabelRole:      pgUpgradeCheck,
+		}))
+
+	var pending []string
+	for i := range volumes.Items {
+		if volumes.Items[i].Status.Phase != corev1.ClaimBound {
+			pending = append(pending, volumes.Items[i].Name)
+		}
+	}
+	if err != nil || len(pending) == 0 {
+		setStatusToProgressingIfReasonWas("PGUpgradeCheckVolumesPending", upgrade)
+		return ctrl.Result{}, err
+	}
+
+	sort.Strings(pending)
+	message := fmt.Sprintf("Waiting for volumes of the check to be provisioned: %s",
+		strings.Join(pending, ", "))
+
+	for _, pod := range world.Pods {
+		if pod.Labels[LabelPGUpgrade] != upgrade.Name || pod.Labels[LabelRole] != pgUpgradeCheck {
+			continue
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodScheduled &&
+				condition.Status == corev1.ConditionFalse && condition.Message != "" {
+				message += "; " + condition.Message
+			}
+		}
+	}
+
+	meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+		ObservedGeneration: upgrade.Generation,
+		Type:               ConditionPGUpgradeProgressing,
+		Status:             metav1.ConditionFalse,
+		Reason:             "PGUpgradeCheckVolumesPending",
+		Message:            message,
+	})
+
+	return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+}
+
+// applyCheckReport writes report to the ConfigMap of upgrade.
+func (r *PGUpgradeReconciler) applyCheckReport(
+	ctx context.Context, upgrade *v1beta1.PGUpgrade, report string,
+) error {
+	configmap := &corev1.ConfigMap{ObjectMeta: pgUpgradeCheckJob(upgrade)}
+	configmap.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+
+	configmap.Annotations = upgrade.Spec.Metadata.GetAnnotationsOrNil()
+	configmap.Labels = Merge(upgrade.Spec.Metadata.GetLabelsOrNil(),
+		commonLabels(pgUpgradeCheck, upgrade))
+	configmap.Data = map[string]string{"report.txt": report}
+
+	r.setControllerReference(upgrade, configmap)
+	return errors.WithStack(r.apply(ctx, configmap))
+}
+
+// deleteCheckVolumes deletes the volumes cloned for the check of upgrade.
+func (r *PGUpgradeReconciler) deleteCheckVolumes(
+	ctx context.Context, upgrade *v1beta1.PGUpgrade,
+) error {
+	var volumes corev1.PersistentVolumeClaimList
+	err := errors.WithStack(r.Client.List(ctx, &volumes,
+		client.InNamespace(upgrade.Namespace),
+		client.MatchingLabels{
+			LabelPGUpgrade: upgrade.Name,
+			LabelRole:      pgUpgradeCheck,
+		}))
+
+	for i := range volumes.Items {
+		if err == nil && metav1.IsControlledBy(&volumes.Items[i], upgrade) {
+			err = errors.WithStack(client.IgnoreNotFound(
+				r.Client.Delete(ctx, &volumes.Items[i])))
+		}
+	}
+	return err
+}
+
+// setCheckConditions sets the conditions of upgrade according to its finished
+// check job and report.
+func setCheckConditions(upgrade *v1beta1.PGUpgrade, job *batchv1.Job, report string) {
+	compatible := metav1.Condition{
+		ObservedGeneration: upgrade.Generation,
+		Type:               ConditionPGUpgradeCompatible,
+		Status:             metav1.ConditionTrue,
+		Reason:             "PGUpgradeCheckPassed",
+		Message: fmt.Sprintf(
+			"PostgresCluster %s can be upgraded to version %d",
+			upgrade.Spec.PostgresClusterName, upgrade.Spec.ToPostgresVersion),
+	}
+
+	if !jobCompleted(job) {
+		var findings []string
+		for _, file := range checkFindings(report) {
+			if description, ok := checkFindingDescriptions[file]; ok {
+				findings = append(findings, description)
+			} else {
+				findings = append(findings, file)
+			}
+		}
+
+		compatible.Status = metav1.ConditionFalse
+		compatible.Reason = "PGUpgradeCheckFailed"
+		compatible.Message = fmt.Sprintf(
+			"pg_upgrade check failed, see ConfigMap %s", pgUpgradeCheckJob(upgrade).Name)
+
+		if len(findings) > 0 {
+			compatible.Message = fmt.Sprintf(
+				"Found %s; see ConfigMap %s",
+				strings.Join(findings, ", "), pgUpgradeCheckJob(upgrade).Name)
+		}
+	}
+
+	meta.SetStatusCondition(&upgrade.Status.Conditions, compatible)
+	meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+		ObservedGeneration: upgrade.Generation,
+		Type:               ConditionPGUpgradeProgressing,
+		Status:             metav1.ConditionFalse,
+		Reason:             "PGUpgradeCheckCompleted",
+		Message: fmt.Sprintf(
+			"Check of PostgresCluster %s completed",
+			upgrade.Spec.PostgresClusterName),
+	})
+}
@@ -0,0 +1,245 @@
+// Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgupgrade
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/controller/runtime"
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestCheckFindings(t *testing.T) {
+	assert.Assert(t, checkFindings("") == nil)
+	assert.DeepEqual(t, checkFindings("status: 0\nfindings: \n== output\n"), []string{})
+	assert.DeepEqual(t, checkFindings(`status: 1
+findings: tables_using_reg.txt loadable_libraries.txt 
+== loadable_libraries.txt
+could not load library "$libdir/postgis-3"
+== output
+`), []string{"loadable_libraries.txt", "tables_using_reg.txt"})
+}
+
+func TestCheckReport(t *testing.T) {
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Name = "pgu2"
+
+	terminated := func(message string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{State: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{Message: message},
+		}}
+	}
+
+	other := &corev1.Pod{}
+	other.Labels = map[string]string{LabelPGUpgrade: "pgu2", LabelRole: pgUpgrade}
+	other.Status.ContainerStatuses = []corev1.ContainerStatus{terminated("nope")}
+
+	check := &corev1.Pod{}
+	check.Labels = map[string]string{LabelPGUpgrade: "pgu2", LabelRole: pgUpgradeCheck}
+	check.Status.ContainerStatuses = []corev1.ContainerStatus{terminated("status: 0")}
+
+	assert.Equal(t, checkReport(upgrade, nil), "")
+	assert.Equal(t, checkReport(upgrade, []*corev1.Pod{other, check}), "status: 0")
+}
+
+func TestSetCheckConditions(t *testing.T) {
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Name = "pgu2"
+	upgrade.Spec.PostgresClusterName = "pg5"
+	upgrade.Spec.ToPostgresVersion = 16
+
+	finished := func(condition batchv1.JobConditionType) *batchv1.Job {
+		job := &batchv1.Job{}
+		job.Status.Conditions = []batchv1.JobCondition{
+			{Type: condition, Status: corev1.ConditionTrue},
+		}
+		return job
+	}
+
+	t.Run("Passed", func(t *testing.T) {
+		upgrade := upgrade.DeepCopy()
+		setCheckConditions(upgrade, finished(batchv1.JobComplete), "status: 0")
+
+		compatible := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeCompatible)
+		assert.Assert(t, compatible != nil)
+		assert.Equal(t, compatible.Status, metav1.ConditionTrue)
+		assert.Equal(t, compatible.Reason, "PGUpgradeCheckPassed")
+
+		progressing := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeProgressing)
+		assert.Assert(t, progressing != nil)
+		assert.Equal(t, progressing.Status, metav1.ConditionFalse)
+		assert.Equal(t, progressing.Reason, "PGUpgradeCheckCompleted")
+	})
+
+	t.Run("Findings", func(t *testing.T) {
+		upgrade := upgrade.DeepCopy()
+		setCheckConditions(upgrade, finished(batchv1.JobFailed),
+			"status: 1\nfindings: loadable_libraries.txt something_new.txt\n")
+
+		compatible := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeCompatible)
+		assert.Assert(t, compatible != nil)
+		assert.Equal(t, compatible.Status, metav1.ConditionFalse)
+		assert.Equal(t, compatible.Reason, "PGUpgradeCheckFailed")
+		assert.Equal(t, compatible.Message, "Found libraries or extensions missing from the new version,"+
+			" something_new.txt; see ConfigMap pgu2-check")
+	})
+
+	t.Run("NoFindings", func(t *testing.T) {
+		upgrade := upgrade.DeepCopy()
+		setCheckConditions(upgrade, finished(batchv1.JobFailed), "")
+
+		compatible := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeCompatible)
+		assert.Assert(t, compatible != nil)
+		assert.Equal(t, compatible.Status, metav1.ConditionFalse)
+		assert.Equal(t, compatible.Message, "pg_upgrade check failed, see ConfigMap pgu2-check")
+	})
+}
+
+func TestReconcileCheck(t *testing.T) {
+	ctx := context.Background()
+
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Namespace = "ns1"
+	upgrade.Name = "pgu1"
+	upgrade.UID = "uid1"
+	upgrade.Spec.PostgresClusterName = "pg5"
+	upgrade.Spec.FromPostgresVersion = 15
+	upgrade.Spec.ToPostgresVersion = 16
+
+	cluster := v1beta1.NewPostgresCluster()
+	cluster.Namespace = "ns1"
+	cluster.Name = "pg5"
+
+	instance := func(name string) *appsv1.StatefulSet {
+		sts := &appsv1.StatefulSet{}
+		sts.Namespace, sts.Name = "ns1", name
+		sts.Spec.Template.Spec.Containers = []corev1.Container{{Name: ContainerDatabase}}
+		sts.Spec.Template.Spec.Volumes = []corev1.Volume{{
+			Name: "postgres-data",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: name + "-pgdata",
+				},
+			},
+		}}
+		return sts
+	}
+	volume := func(name string) *corev1.PersistentVolumeClaim {
+		pvc := &corev1.PersistentVolumeClaim{}
+		pvc.Namespace, pvc.Name = "ns1", name
+		return pvc
+	}
+
+	abc, xyz := instance("pg5-abc"), instance("pg5-xyz")
+	world := NewWorld()
+	world.Cluster = cluster
+	world.ClusterLeader = abc
+	world.ClusterInstances = map[string]*appsv1.StatefulSet{abc.Name: abc, xyz.Name: xyz}
+	world.ClusterVolumes = map[string]*corev1.PersistentVolumeClaim{
+		"pg5-abc-pgdata": volume("pg5-abc-pgdata"),
+		"pg5-xyz-pgdata": volume("pg5-xyz-pgdata"),
+	}
+
+	// A clone that is waiting for storage.
+	pending := volume("pgu1-check-pg5-abc-pgdata")
+	pending.Labels = map[string]string{LabelPGUpgrade: "pgu1", LabelRole: pgUpgradeCheck}
+	pending.Status.Phase = corev1.ClaimPending
+	pending.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: v1beta1.GroupVersion.String(), Kind: "PGUpgrade",
+		Name: "pgu1", UID: "uid1", Controller: initialize.Bool(true),
+	}}
+
+	var patched []client.Object
+	reconciler := &PGUpgradeReconciler{
+		Client: clientRecordingPatches{
+			Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).
+				WithObjects(pending).Build(),
+			patched: &patched,
+		},
+	}
+
+	upgrade = upgrade.DeepCopy()
+	result, err := reconciler.reconcileCheck(ctx, upgrade, world)
+	assert.NilError(t, err)
+	assert.Assert(t, result.RequeueAfter > 0)
+	assert.DeepEqual(t, upgrade.Status.Check, &v1beta1.PGUpgradeCheckStatus{Instance: "pg5-abc"})
+	assert.Equal(t, len(patched), 2)
+	assert.Equal(t, patched[0].GetName(), "pgu1-check-pg5-abc-pgdata")
+	assert.Equal(t, patched[1].GetName(), pgUpgradeCheckJob(upgrade).Name)
+
+	progressing := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeProgressing)
+	assert.Assert(t, progressing != nil)
+	assert.Equal(t, progressing.Reason, "PGUpgradeCheckVolumesPending")
+	assert.Assert(t, strings.Contains(progressing.Message, "pgu1-check-pg5-abc-pgdata"))
+
+	t.Run("NewPrimary", func(t *testing.T) {
+		patched = nil
+		upgrade := upgrade.DeepCopy()
+		world := *world
+		world.ClusterLeader = xyz
+
+		// The recorded instance is cloned again, not the new primary.
+		_, err := reconciler.reconcileCheck(ctx, upgrade, &world)
+		assert.NilError(t, err)
+		assert.Equal(t, upgrade.Status.Check.Instance, "pg5-abc")
+		assert.Equal(t, patched[0].GetName(), "pgu1-check-pg5-abc-pgdata")
+	})
+
+	t.Run("InstanceRemoved", func(t *testing.T) {
+		patched = nil
+		upgrade := upgrade.DeepCopy()
+		world := *world
+		world.ClusterLeader = xyz
+		world.ClusterInstances = map[string]*appsv1.StatefulSet{xyz.Name: xyz}
+
+		// Without a Job, the check starts over from the new primary.
+		result, err := reconciler.reconcileCheck(ctx, upgrade, &world)
+		assert.NilError(t, err)
+		assert.Assert(t, result.IsZero())
+		assert.Equal(t, upgrade.Status.Check.Instance, "pg5-xyz")
+		assert.Equal(t, patched[0].GetName(), "pgu1-check-pg5-xyz-pgdata")
+
+		var volumes corev1.PersistentVolumeClaimList
+		assert.NilError(t, reconciler.Client.List(ctx, &volumes))
+		assert.Equal(t, len(volumes.Items), 0, "expected the old clone to be deleted")
+	})
+
+	t.Run("Finished", func(t *testing.T) {
+		upgrade := upgrade.DeepCopy()
+		world := *world
+		world.Jobs = map[string]*batchv1.Job{pgUpgradeCheckJob(upgrade).Name: {
+			Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+			}},
+		}}
+
+		_, err := reconciler.reconcileCheck(ctx, upgrade, &world)
+		assert.NilError(t, err)
+		assert.Assert(t, upgrade.Status.Check == nil)
+		assert.Assert(t, meta.IsStatusConditionTrue(upgrade.Status.Conditions, ConditionPGUpgradeCompatible))
+	})
+}
@@ -29,8 +29,87 @@ import (
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
+// nssWrapperScript is a bash script that makes the current UID and GID
+// resolve to "postgres". It expects the home directory in ${data_volume}.
+//
+// Note: Rather than import the nss_wrapper init container, as we do in
+// the main postgres-operator, these jobs do the required nss_wrapper
+// settings themselves.
+var nssWrapperScript = strings.Join([]string{
+	// Create a copy of the system group definitions, but remove the "postgres"
+	// group or any group with the current GID. Replace them with our own that
+	// has the current GID.
+	`gid=$(id -G); NSS_WRAPPER_GROUP=$(mktemp)`,
+	`(sed "/^postgres:x:/ d; /^[^:]*:x:${gid%% *}:/ d" /etc/group`,
+	`echo "postgres:x:${gid%% *}:") > "${NSS_WRAPPER_GROUP}"`,
+
+	// Create a copy of the system user definitions, but remove the "postgres"
+	// user or any user with the current UID. Replace them with our own that
+	// has the current UID and GID.
+	`uid=$(id -u); NSS_WRAPPER_PASSWD=$(mktemp)`,
+	`(sed "/^postgres:x:/ d; /^[^:]*:x:${uid}:/ d" /etc/passwd`,
+	`echo "postgres:x:${uid}:${gid%% *}::${data_volume}:") > "${NSS_WRAPPER_PASSWD}"`,
+
+	// Enable nss_wrapper so the current UID and GID resolve to "postgres".
+	// - https://cwrap.org/nss_wrapper.html
+	`export LD_PRELOAD='libnss_wrapper.so' NSS_WRAPPER_GROUP NSS_WRAPPER_PASSWD`,
+}, "\n")
+
 // Upgrade job
 
+// upgradeProgressFile is where the upgrade Job records the start of each step.
+const upgradeProgressFile = "/tmp/pgupgrade-progress"
+
+// upgradeProgressScript is a bash script that defines a "step" function to
+// record the start of each step. When the script exits, it writes those steps
+// and its exit status to the termination message of the container. When that
+// status is not zero, it also writes the end of the pg_upgrade log and of the
+// output of commands that append to ${output_file}. See [upgradeSteps].
+// - https://docs.k8s.io/tasks/debug/debug-application/determine-reason-pod-failure/
+var upgradeProgressScript = strings.Join([]string{
+	`declare -r progress_file='` + upgradeProgressFile + `' output_file='/tmp/pgupgrade-output'`,
+	`: > "${progress_file}"; : > "${output_file}"; set -o pipefail`,
+	`step() { echo "step: $1 $(date -u +%Y-%m-%dT%H:%M:%SZ)" >> "${progress_file}"; }`,
+	`report() {`,
+	`status=$?`,
+	`{ cat "${progress_file}"; echo "status: ${status}"`,
+	`if [[ "${status}" -ne 0 ]]; then`,
+	`find /pgdata -maxdepth 6 -name pg_upgrade_internal.log 2> /dev/null |`,
+	`while read -r file; do echo "== ${file##*/}"; tail -n 20 "${file}"; done || true`,
+	`echo "== output"; tail -n 20 "${output_file}"`,
+	`fi; } | head -c 4000 > /dev/termination-log || true`,
+	`}`,
+	`trap report EXIT`,
+}, "\n")
+
+// transferOptions returns the pg_upgrade options for the transfer mode and
+// parallelism of upgrade. Copy is the default of pg_upgrade, and its option
+// first appears in PostgreSQL 16, so it is omitted.
+// - https://www.postgresql.org/docs/current/pgupgrade.html
+func transferOptions(upgrade *v1beta1.PGUpgrade) string {
+	var options string
+	switch upgrade.Spec.TransferMode {
+	case TransferModeClone:
+		options = ` --clone`
+	case TransferModeCopy:
+		// pg_upgrade copies by default.
+	default:
+		options = ` --link`
+	}
+	if upgrade.Spec.Jobs != nil {
+		options += fmt.Sprintf(" --jobs=%d", *upgrade.Spec.Jobs)
+	}
+	return options
+}
+
+// keepsOldData returns true when the transfer mode of upgrade leaves the old
+// data directories intact. The cluster can then be started at the old version
+// again, so the old data directories of replicas are kept as well.
+func keepsOldData(upgrade *v1beta1.PGUpgrade) bool {
+	return upgrade.Spec.TransferMode == TransferModeClone ||
+		upgrade.Spec.TransferMode == TransferModeCopy
+}
+
 // pgUpgradeJob returns the ObjectMeta for the pg_upgrade Job utilized to
 // upgrade from one major PostgreSQL version to another
 func pgUpgradeJob(upgrade *v1beta1.PGUpgrade) metav1.ObjectMeta {
@@ -45,6 +124,7 @@ func pgUpgradeJob(upgrade *v1beta1.PGUpgrade) metav1.ObjectMeta {
 func upgradeCommand(upgrade *v1beta1.PGUpgrade, fetchKeyCommand string) []string {
 	oldVersion := fmt.Sprint(upgrade.Spec.FromPostgresVersion)
 	newVersion := fmt.Sprint(upgrade.Spec.ToPostgresVersion)
+	transfer := transferOptions(upgrade)
 
 	// if the fetch key command is set for TDE, provide the value during initialization
 	initdb := `/usr/pgsql-"${new_version}"/bin/initdb -k -D /pgdata/pg"${new_version}"`
@@ -56,28 +136,8 @@ func upgradeCommand(upgrade *v1beta1.PGUpgrade, fetchKeyCommand string) []string
 	script := strings.Join([]string{
 		`declare -r data_volume='/pgdata' old_version="$1" new_version="$2"`,
 		`printf 'Performing PostgreSQL upgrade from version "%s" to "%s" ...\n\n' "$@"`,
-
-		// Note: Rather than import the nss_wrapper init container, as we do in
-		// the main postgres-operator, this job does the required nss_wrapper
-		// settings here.
-
-		// Create a copy of the system group definitions, but remove the "postgres"
-		// group or any group with the current GID. Replace them with our own that
-		// has the current GID.
-		`gid=$(id -G); NSS_WRAPPER_GROUP=$(mktemp)`,
-		`(sed "/^postgres:x:/ d; /^[^:]*:x:${gid%% *}:/ d" /etc/group`,
-		`echo "postgres:x:${gid%% *}:") > "${NSS_WRAPPER_GROUP}"`,
-
-		// Create a copy of the system user definitions, but remove the "postgres"
-		// user or any user with the current UID. Replace them with our own that
-		// has the current UID and GID.
-		`uid=$(id -u); NSS_WRAPPER_PASSWD=$(mktemp)`,
-		`(sed "/^postgres:x:/ d; /^[^:]*:x:${uid}:/ d" /etc/passwd`,
-		`echo "postgres:x:${uid}:${gid%% *}::${data_volume}:") > "${NSS_WRAPPER_PASSWD}"`,
-
-		// Enable nss_wrapper so the current UID and GID resolve to "postgres".
-		// - https://cwrap.org/nss_wrapper.html
-		`export LD_PRELOAD='libnss_wrapper.so' NSS_WRAPPER_GROUP NSS_WRAPPER_PASSWD`,
+		nssWrapperScript,
+		upgradeProgressScript,
 
 		// Below is the pg_upgrade script used to upgrade a PostgresCluster from
 		// one major version to another. Additional information concerning the
@@ -87,10 +147,11 @@ func upgradeCommand(upgrade *v1beta1.PGUpgrade, fetchKeyCommand string) []string
 		// To begin, we first move to the mounted /pgdata directory and create a
 		// new version directory which is then initialized with the initdb command.
 		`cd /pgdata || exit`,
+		`step initdb`,
 		`echo -e "Step 1: Making new pgdata directory...\n"`,
 		`mkdir /pgdata/pg"${new_version}"`,
 		`echo -e "Step 2: Initializing new pgdata directory...\n"`,
-		initdb,
+		initdb + ` 2>&1 | tee -a "${output_file}"`,
 
 		// Before running the upgrade check, which ensures the clusters are compatible,
 		// proper permissions have to be set on the old pgdata directory and the
@@ -103,17 +164,19 @@ func upgradeCommand(upgrade *v1beta1.PGUpgrade, fetchKeyCommand string) []string
 
 		// Before the actual upgrade is run, we will run the upgrade --check to
 		// verify everything before actually changing any data.
+		`step check`,
 		`echo -e "Step 5: Running pg_upgrade check...\n"`,
 		`time /usr/pgsql-"${new_version}"/bin/pg_upgrade --old-bindir /usr/pgsql-"${old_version}"/bin \`,
 		`--new-bindir /usr/pgsql-"${new_version}"/bin --old-datadir /pgdata/pg"${old_version}"\`,
-		` --new-datadir /pgdata/pg"${new_version}" --link --check`,
+		` --new-datadir /pgdata/pg"${new_version}"` + transfer + ` --check 2>&1 | tee -a "${output_file}"`,
 
 		// Assuming the check completes successfully, the pg_upgrade command will
 		// be run that actually prepares the upgraded pgdata directory.
+		`step upgrade`,
 		`echo -e "\nStep 6: Running pg_upgrade...\n"`,
 		`time /usr/pgsql-"${new_version}"/bin/pg_upgrade --old-bindir /usr/pgsql-"${old_version}"/bin \`,
 		`--new-bindir /usr/pgsql-"${new_version}"/bin --old-datadir /pgdata/pg"${old_version}" \`,
-		`--new-datadir /pgdata/pg"${new_version}" --link`,
+		`--new-datadir /pgdata/pg"${new_version}"` + transfer + ` 2>&1 | tee -a "${output_file}"`,
 
 		// Since we have cleared the Patroni cluster step by removing the EndPoints, we copy patroni.dynamic.json
 		// from the old data dir to help retain PostgreSQL parameters you had set before.
@@ -184,10 +247,12 @@ func (r *PGUpgradeReconciler) generateUpgradeJob(
 		VolumeMounts:    database.VolumeMounts,
 
 		// Use our upgrade command and the specified image and resources.
-		Command:         upgradeCommand(upgrade, fetchKeyCommand),
-		Image:           pgUpgradeContainerImage(upgrade),
-		ImagePullPolicy: upgrade.Spec.ImagePullPolicy,
-		Resources:       upgrade.Spec.Resources,
+		// Keep the report of the command or the end of its output.
+		Command:                  upgradeCommand(upgrade, fetchKeyCommand),
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		Image:                    pgUpgradeContainerImage(upgrade),
+		ImagePullPolicy:          upgrade.Spec.ImagePullPolicy,
+		Resources:                upgrade.Spec.Resources,
 	}}
 
 	// The following will set these fields to null if not set in the spec
@@ -200,6 +265,173 @@ func (r *PGUpgradeReconciler) generateUpgradeJob(
 	return job
 }
 
+// Check job
+
+// pgUpgradeCheckJob returns the ObjectMeta for the Job that checks whether or
+// not a cluster can be upgraded from one major PostgreSQL version to another.
+func pgUpgradeCheckJob(upgrade *v1beta1.PGUpgrade) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: upgrade.Namespace,
+		Name:      upgrade.Name + "-check",
+	}
+}
+
+// pgUpgradeCheckVolume returns the ObjectMeta for the clone of the volume
+// named source that is checked by the Job of upgrade.
+func pgUpgradeCheckVolume(upgrade *v1beta1.PGUpgrade, source string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: upgrade.Namespace,
+		Name:      upgrade.Name + "-check-" + source,
+	}
+}
+
+// checkCommand returns an entrypoint that runs pg_upgrade --check against a
+// copy of the PostgreSQL data directory. That copy was taken while PostgreSQL
+// was running, so it is first recovered by starting and stopping PostgreSQL.
+//
+// The result is written to the termination message of the container:
+// the exit status of pg_upgrade, the names of the files it wrote about
+// incompatibilities, the start of each file, and the end of its output.
+// - https://docs.k8s.io/tasks/debug/debug-application/determine-reason-pod-failure/
+func checkCommand(upgrade *v1beta1.PGUpgrade, fetchKeyCommand string) []string {
+	oldVersion := fmt.Sprint(upgrade.Spec.FromPostgresVersion)
+	newVersion := fmt.Sprint(upgrade.Spec.ToPostgresVersion)
+
+	// if the fetch key command is set for TDE, provide the value during initialization
+	initdb := `/usr/pgsql-"${new_version}"/bin/initdb -k -D /pgdata/pg"${new_version}"`
+	if fetchKeyCommand != "" {
+		initdb += ` --encryption-key-command "` + fetchKeyCommand + `"`
+	}
+
+	args := []string{oldVersion, newVersion}
+	script := strings.Join([]string{
+		`declare -r data_volume='/pgdata' old_version="$1" new_version="$2"`,
+		`printf 'Checking PostgreSQL upgrade from version "%s" to "%s" ...\n\n' "$@"`,
+		nssWrapperScript,
+
+		// The copy looks like PostgreSQL crashed. Start it without networking
+		// or archiving so that it replays its WAL, then stop it cleanly as
+		// pg_upgrade requires.
+		`cd /pgdata || exit`,
+		`echo -e "Step 1: Recovering the copy of the old pgdata directory...\n"`,
+		`rm -f /pgdata/pg"${old_version}"/postmaster.pid`,
+		`chmod 700 /pgdata/pg"${old_version}"`,
+		`/usr/pgsql-"${old_version}"/bin/pg_ctl start --wait --silent --pgdata /pgdata/pg"${old_version}" \`,
+		`--options "-c listen_addresses='' -c unix_socket_directories=/tmp -c archive_mode=off"`,
+		`/usr/pgsql-"${old_version}"/bin/pg_ctl stop --wait --silent --pgdata /pgdata/pg"${old_version}" --mode fast`,
+
+		// These steps are the same as the upgrade.
+		`echo -e "Step 2: Making new pgdata directory...\n"`,
+		`mkdir /pgdata/pg"${new_version}"`,
+		`echo -e "Step 3: Initializing new pgdata directory...\n"`,
+		initdb,
+		`echo -e "\nStep 4: Copying shared_preload_libraries setting to new postgresql.conf file...\n"`,
+		`echo "shared_preload_libraries = '$(/usr/pgsql-"""${old_version}"""/bin/postgres -D \`,
+		`/pgdata/pg"""${old_version}""" -C shared_preload_libraries)'" >> /pgdata/pg"${new_version}"/postgresql.conf`,
+
+		// pg_upgrade writes its findings to files in the current directory
+		// or in a subdirectory of the new pgdata directory, depending on
+		// its version.
+		`echo -e "Step 5: Running pg_upgrade check...\n"`,
+		`mkdir /pgdata/check && cd /pgdata/check`,
+		`status=0`,
+		`/usr/pgsql-"${new_version}"/bin/pg_upgrade --old-bindir /usr/pgsql-"${old_version}"/bin \`,
+		`--new-bindir /usr/pgsql-"${new_version}"/bin --old-datadir /pgdata/pg"${old_version}" \`,
+		`--new-datadir /pgdata/pg"${new_version}"` + transferOptions(upgrade) + ` --check > output.log 2>&1 || status=$?`,
+		`cat output.log`,
+
+		`findings=$(find . /pgdata/pg"${new_version}"/pg_upgrade_output.d -name '*.txt' -printf '%f ' 2> /dev/null || true)`,
+		`{`,
+		`echo "status: ${status}"`,
+		`echo "findings: ${findings}"`,
+		`find . /pgdata/pg"${new_version}"/pg_upgrade_output.d -name '*.txt' 2> /dev/null |`,
+		`while read -r file; do echo "== ${file##*/}"; head -n 20 "${file}"; done || true`,
+		`echo "== output"`,
+		`tail -n 20 output.log`,
+		`} | head -c 4000 > /dev/termination-log`,
+
+		`echo -e "\npg_upgrade check Job Complete!"`,
+		`exit "${status}"`,
+	}, "\n")
+
+	return append([]string{"bash", "-ceu", "--", script, "check"}, args...)
+}
+
+// generateCheckJob returns a Job that can check the PostgreSQL data directory
+// of the leader instance. The Job mounts the clones in volumes rather than
+// the volumes of leader.
+func (r *PGUpgradeReconciler) generateCheckJob(
+	ctx context.Context, upgrade *v1beta1.PGUpgrade,
+	leader *appsv1.StatefulSet, volumes map[string]string, fetchKeyCommand string,
+) *batchv1.Job {
+	job := r.generateUpgradeJob(ctx, upgrade, leader, fetchKeyCommand)
+	job.Name = pgUpgradeCheckJob(upgrade).Name
+
+	job.Labels = Merge(upgrade.Spec.Metadata.GetLabelsOrNil(),
+		commonLabels(pgUpgradeCheck, upgrade),
+		map[string]string{
+			LabelVersion: fmt.Sprint(upgrade.Spec.ToPostgresVersion),
+		})
+	job.Spec.Template.Labels = job.Labels
+
+	// Use our check command and keep its report even when it fails.
+	job.Spec.Template.Spec.Containers[0].Command = checkCommand(upgrade, fetchKeyCommand)
+	job.Spec.Template.Spec.Containers[0].TerminationMessagePolicy =
+		corev1.TerminationMessageReadFile
+
+	for i := range job.Spec.Template.Spec.Volumes {
+		if source := job.Spec.Template.Spec.Volumes[i].PersistentVolumeClaim; source != nil {
+			if clone, ok := volumes[source.ClaimName]; ok {
+				source.ClaimName = clone
+			}
+		}
+	}
+
+	return job
+}
+
+// generateCheckVolume returns a PersistentVolumeClaim that clones source. It
+// has the same storage class and at least the same size as source.
+// - https://docs.k8s.io/concepts/storage/volume-pvc-datasource/
+func (r *PGUpgradeReconciler) generateCheckVolume(
+	upgrade *v1beta1.PGUpgrade, source *corev1.PersistentVolumeClaim,
+) *corev1.PersistentVolumeClaim {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: pgUpgradeCheckVolume(upgrade, source.Name),
+	}
+	pvc.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"))
+
+	// These are not labeled with the cluster so that the PostgresCluster
+	// controller does not consider them.
+	pvc.Annotations = upgrade.Spec.Metadata.GetAnnotationsOrNil()
+	pvc.Labels = Merge(upgrade.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			LabelPGUpgrade: upgrade.Name,
+			LabelRole:      pgUpgradeCheck,
+		})
+
+	pvc.Spec.AccessModes = source.Spec.AccessModes
+	pvc.Spec.StorageClassName = source.Spec.StorageClassName
+	pvc.Spec.VolumeMode = source.Spec.VolumeMode
+	pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{
+		Kind: "PersistentVolumeClaim",
+		Name: source.Name,
+	}
+
+	// A clone must be at least as large as its source.
+	request := source.Spec.Resources.Requests[corev1.ResourceStorage]
+	if capacity, ok := source.Status.Capacity[corev1.ResourceStorage]; ok &&
+		capacity.Cmp(request) > 0 {
+		request = capacity
+	}
+	pvc.Spec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceStorage: request,
+	}
+
+	r.setControllerReference(upgrade, pvc)
+	return pvc
+}
+
 // Remove data job
 
 // removeDataCommand returns an entrypoint that removes certain directories.
@@ -294,10 +526,12 @@ func (r *PGUpgradeReconciler) generateRemoveDataJob(
 		VolumeMounts:    database.VolumeMounts,
 
 		// Use our remove command and the specified resources.
-		Command:         removeDataCommand(upgrade),
-		Image:           pgUpgradeContainerImage(upgrade),
-		ImagePullPolicy: upgrade.Spec.ImagePullPolicy,
-		Resources:       upgrade.Spec.Resources,
+		// Keep the end of its output when it fails.
+		Command:                  removeDataCommand(upgrade),
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		Image:                    pgUpgradeContainerImage(upgrade),
+		ImagePullPolicy:          upgrade.Spec.ImagePullPolicy,
+		Resources:                upgrade.Spec.Resources,
 	}}
 
 	// The following will set these fields to null if not set in the spec
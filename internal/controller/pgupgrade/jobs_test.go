@@ -122,24 +122,40 @@ spec:
           (sed "/^postgres:x:/ d; /^[^:]*:x:${uid}:/ d" /etc/passwd
           echo "postgres:x:${uid}:${gid%% *}::${data_volume}:") > "${NSS_WRAPPER_PASSWD}"
           export LD_PRELOAD='libnss_wrapper.so' NSS_WRAPPER_GROUP NSS_WRAPPER_PASSWD
+          declare -r progress_file='/tmp/pgupgrade-progress' output_file='/tmp/pgupgrade-output'
+          : > "${progress_file}"; : > "${output_file}"; set -o pipefail
+          step() { echo "step: $1 $(date -u +%Y-%m-%dT%H:%M:%SZ)" >> "${progress_file}"; }
+          report() {
+          status=$?
+          { cat "${progress_file}"; echo "status: ${status}"
+          if [[ "${status}" -ne 0 ]]; then
+          find /pgdata -maxdepth 6 -name pg_upgrade_internal.log 2> /dev/null |
+          while read -r file; do echo "== ${file##*/}"; tail -n 20 "${file}"; done || true
+          echo "== output"; tail -n 20 "${output_file}"
+          fi; } | head -c 4000 > /dev/termination-log || true
+          }
+          trap report EXIT
           cd /pgdata || exit
+          step initdb
           echo -e "Step 1: Making new pgdata directory...\n"
           mkdir /pgdata/pg"${new_version}"
           echo -e "Step 2: Initializing new pgdata directory...\n"
-          /usr/pgsql-"${new_version}"/bin/initdb -k -D /pgdata/pg"${new_version}"
+          /usr/pgsql-"${new_version}"/bin/initdb -k -D /pgdata/pg"${new_version}" 2>&1 | tee -a "${output_file}"
           echo -e "\nStep 3: Setting the expected permissions on the old pgdata directory...\n"
           chmod 700 /pgdata/pg"${old_version}"
           echo -e "Step 4: Copying shared_preload_libraries setting to new postgresql.conf file...\n"
           echo "shared_preload_libraries = '$(/usr/pgsql-"""${old_version}"""/bin/postgres -D \
           /pgdata/pg"""${old_version}""" -C shared_preload_libraries)'" >> /pgdata/pg"${new_version}"/postgresql.conf
+          step check
           echo -e "Step 5: Running pg_upgrade check...\n"
           time /usr/pgsql-"${new_version}"/bin/pg_upgrade --old-bindir /usr/pgsql-"${old_version}"/bin \
           --new-bindir /usr/pgsql-"${new_version}"/bin --old-datadir /pgdata/pg"${old_version}"\
-           --new-datadir /pgdata/pg"${new_version}" --link --check
+           --new-datadir /pgdata/pg"${new_version}" --link --check 2>&1 | tee -a "${output_file}"
+          step upgrade
           echo -e "\nStep 6: Running pg_upgrade...\n"
           time /usr/pgsql-"${new_version}"/bin/pg_upgrade --old-bindir /usr/pgsql-"${old_version}"/bin \
           --new-bindir /usr/pgsql-"${new_version}"/bin --old-datadir /pgdata/pg"${old_version}" \
-          --new-datadir /pgdata/pg"${new_version}" --link
+          --new-datadir /pgdata/pg"${new_version}" --link 2>&1 | tee -a "${output_file}"
           echo -e "\nStep 7: Copying patroni.dynamic.json...\n"
           cp /pgdata/pg"${old_version}"/patroni.dynamic.json /pgdata/pg"${new_version}"
           echo -e "\npg_upgrade Job Complete!"
@@ -153,6 +169,7 @@ spec:
             cpu: 3140m
         securityContext:
           privileged: false
+        terminationMessagePolicy: FallbackToLogsOnError
         volumeMounts:
         - mountPath: /mnt/some/such
           name: vm1
@@ -170,6 +187,175 @@ status: {}
 		`/usr/pgsql-"${new_version}"/bin/initdb -k -D /pgdata/pg"${new_version}" --encryption-key-command "echo testKey"`))
 }
 
+func TestGenerateCheckJob(t *testing.T) {
+	ctx := context.Background()
+	reconciler := &PGUpgradeReconciler{}
+
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Namespace = "ns1"
+	upgrade.Name = "pgu2"
+	upgrade.UID = "uid3"
+	upgrade.Spec.Image = initialize.Pointer("img4")
+	upgrade.Spec.PostgresClusterName = "pg5"
+	upgrade.Spec.FromPostgresVersion = 19
+	upgrade.Spec.ToPostgresVersion = 25
+
+	leader := &appsv1.StatefulSet{}
+	leader.Spec.Template.Spec = corev1.PodSpec{
+		Containers: []corev1.Container{{Name: ContainerDatabase}},
+		Volumes: []corev1.Volume{
+			{
+				Name: "postgres-data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: "pg5-abc-pgdata",
+					},
+				},
+			},
+			{
+				Name: "other",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: "elsewhere",
+					},
+				},
+			},
+		},
+	}
+
+	job := reconciler.generateCheckJob(ctx, upgrade, leader,
+		map[string]string{"pg5-abc-pgdata": "pgu2-check-pg5-abc-pgdata"}, "")
+
+	assert.Equal(t, job.Name, "pgu2-check")
+	assert.DeepEqual(t, job.Labels, job.Spec.Template.Labels)
+	assert.Equal(t, job.Labels[LabelRole], "pgupgrade-check")
+	assert.Equal(t, job.Labels[LabelCluster], "pg5")
+
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.DeepEqual(t, container.Command[4:], []string{"check", "19", "25"})
+	assert.Equal(t, container.TerminationMessagePolicy, corev1.TerminationMessageReadFile)
+
+	assert.Assert(t, marshalMatches(job.Spec.Template.Spec.Volumes, `
+- name: postgres-data
+  persistentVolumeClaim:
+    claimName: pgu2-check-pg5-abc-pgdata
+- name: other
+  persistentVolumeClaim:
+    claimName: elsewhere
+	`))
+
+	// The leader is unchanged.
+	assert.Equal(t, leader.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName,
+		"pg5-abc-pgdata")
+}
+
+func TestCheckCommand(t *testing.T) {
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Spec.FromPostgresVersion = 14
+	upgrade.Spec.ToPostgresVersion = 16
+
+	script := checkCommand(upgrade, "")[3]
+	assert.Assert(t, strings.Contains(script, `--link --check > output.log`))
+	assert.Assert(t, strings.Contains(script, `archive_mode=off`),
+		"expected the copy to recover without archiving")
+	assert.Assert(t, strings.Contains(script, `> /dev/termination-log`))
+	assert.Assert(t, strings.HasSuffix(script, `exit "${status}"`))
+
+	script = checkCommand(upgrade, "echo testKey")[3]
+	assert.Assert(t, strings.Contains(script, `--encryption-key-command "echo testKey"`))
+}
+
+func TestTransferOptions(t *testing.T) {
+	upgrade := &v1beta1.PGUpgrade{}
+	assert.Equal(t, transferOptions(upgrade), " --link")
+	assert.Assert(t, !keepsOldData(upgrade))
+
+	upgrade.Spec.TransferMode = "Link"
+	assert.Equal(t, transferOptions(upgrade), " --link")
+	assert.Assert(t, !keepsOldData(upgrade))
+
+	upgrade.Spec.TransferMode = "Clone"
+	assert.Equal(t, transferOptions(upgrade), " --clone")
+	assert.Assert(t, keepsOldData(upgrade))
+
+	upgrade.Spec.TransferMode = "Copy"
+	assert.Equal(t, transferOptions(upgrade), "")
+	assert.Assert(t, keepsOldData(upgrade))
+
+	upgrade.Spec.Jobs = initialize.Int32(4)
+	assert.Equal(t, transferOptions(upgrade), " --jobs=4")
+
+	upgrade.Spec.FromPostgresVersion = 14
+	upgrade.Spec.ToPostgresVersion = 16
+	upgrade.Spec.TransferMode = "Clone"
+
+	script := upgradeCommand(upgrade, "")[3]
+	assert.Assert(t, strings.Contains(script, `--clone --jobs=4 --check 2>&1`))
+	assert.Assert(t, strings.Contains(script, `--clone --jobs=4 2>&1`))
+	assert.Assert(t, !strings.Contains(script, `--link`))
+
+	script = checkCommand(upgrade, "")[3]
+	assert.Assert(t, strings.Contains(script, `--clone --jobs=4 --check > output.log`))
+}
+
+func TestGenerateCheckVolume(t *testing.T) {
+	reconciler := &PGUpgradeReconciler{}
+
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Namespace = "ns1"
+	upgrade.Name = "pgu2"
+	upgrade.UID = "uid3"
+
+	source := &corev1.PersistentVolumeClaim{}
+	source.Name = "pg5-abc-pgdata"
+	source.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	source.Spec.StorageClassName = initialize.String("fast")
+	source.Spec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceStorage: resource.MustParse("1Gi"),
+	}
+
+	assert.Assert(t, marshalMatches(reconciler.generateCheckVolume(upgrade, source), `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  creationTimestamp: null
+  labels:
+    postgres-operator.crunchydata.com/pgupgrade: pgu2
+    postgres-operator.crunchydata.com/role: pgupgrade-check
+  name: pgu2-check-pg5-abc-pgdata
+  namespace: ns1
+  ownerReferences:
+  - apiVersion: postgres-operator.crunchydata.com/v1beta1
+    blockOwnerDeletion: true
+    controller: true
+    kind: PGUpgrade
+    name: pgu2
+    uid: uid3
+spec:
+  accessModes:
+  - ReadWriteOnce
+  dataSource:
+    apiGroup: null
+    kind: PersistentVolumeClaim
+    name: pg5-abc-pgdata
+  resources:
+    requests:
+      storage: 1Gi
+  storageClassName: fast
+status: {}
+	`))
+
+	t.Run("LargerCapacity", func(t *testing.T) {
+		source := source.DeepCopy()
+		source.Status.Capacity = corev1.ResourceList{
+			corev1.ResourceStorage: resource.MustParse("2Gi"),
+		}
+
+		pvc := reconciler.generateCheckVolume(upgrade, source)
+		assert.Equal(t, pvc.Spec.Resources.Requests.Storage().String(), "2Gi")
+	})
+}
+
 func TestGenerateRemoveDataJob(t *testing.T) {
 	ctx := context.Background()
 	reconciler := &PGUpgradeReconciler{}
@@ -259,6 +445,7 @@ spec:
             cpu: 3140m
         securityContext:
           privileged: false
+        terminationMessagePolicy: FallbackToLogsOnError
         volumeMounts:
         - mountPath: /mnt/some/such
           name: vm1
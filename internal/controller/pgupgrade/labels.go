@@ -27,6 +27,30 @@ const (
 	// status of a Postgres major upgrade.
 	ConditionPGUpgradeSucceeded = "Succeeded"
 
+	// ConditionPGUpgradeCompatible is the type used in a condition to indicate
+	// whether or not a check found that a cluster can be upgraded.
+	ConditionPGUpgradeCompatible = "Compatible"
+
+	// ConditionPGUpgradeExtensionsUpdated is the type used in a condition to
+	// indicate whether or not extensions were updated after an upgrade.
+	ConditionPGUpgradeExtensionsUpdated = "ExtensionsUpdated"
+
+	// ConditionPGUpgradeAnalyzed is the type used in a condition to indicate
+	// whether or not optimizer statistics were generated after an upgrade.
+	ConditionPGUpgradeAnalyzed = "Analyzed"
+
+	// ConditionPGUpgradeBackedUp is the type used in a condition to indicate
+	// whether or not a backup was taken before an upgrade.
+	ConditionPGUpgradeBackedUp = "BackedUp"
+
+	// ConditionPGUpgradeRolledBack is the type used in a condition to indicate
+	// whether or not a cluster is being restored from its pre-upgrade backup.
+	ConditionPGUpgradeRolledBack = "RolledBack"
+
+	// ConditionPGUpgradeReplicating is the type used in a condition to indicate
+	// whether or not a new cluster is subscribed to every database of the old.
+	ConditionPGUpgradeReplicating = "Replicating"
+
 	labelPrefix           = "postgres-operator.crunchydata.com/"
 	LabelPGUpgrade        = labelPrefix + "pgupgrade"
 	LabelCluster          = labelPrefix + "cluster"
@@ -35,12 +59,51 @@ const (
 	LabelPatroni          = labelPrefix + "patroni"
 	LabelPGBackRestBackup = labelPrefix + "pgbackrest-backup"
 	LabelInstance         = labelPrefix + "instance"
+	LabelPostgresUser     = labelPrefix + "pguser"
+
+	AnnotationPGBackRestBackup  = labelPrefix + "pgbackrest-backup"
+	AnnotationPGBackRestRestore = labelPrefix + "pgbackrest-restore"
+
+	// AnnotationPrimaryServiceRedirect is the annotation that is added to
+	// a PostgresCluster to resolve its primary Service to another cluster.
+	AnnotationPrimaryServiceRedirect = labelPrefix + "redirect-primary-service"
 
 	ReplicaCreate     = "replica-create"
 	ContainerDatabase = "database"
+	RolePostgresUser  = "pguser"
+
+	// RolePatroniLeader is the LabelRole that Patroni sets on the Pod that is
+	// currently the leader.
+	RolePatroniLeader = "master"
+
+	// ModeCheck is the mode of a PGUpgrade that only checks a cluster.
+	ModeCheck = "Check"
+
+	// ModeLogical is the mode of a PGUpgrade that copies a cluster to a new
+	// one through logical replication.
+	ModeLogical = "Logical"
+
+	// TransferModeClone and TransferModeCopy are the transfer modes of
+	// a PGUpgrade that leave the old data directory intact.
+	TransferModeClone = "Clone"
+	TransferModeCopy  = "Copy"
+
+	pgUpgrade      = "pgupgrade"
+	pgUpgradeCheck = "pgupgrade-check"
+	removeData     = "removedata"
+
+	// postUpgradeExtensions and postUpgradeAnalyze are the roles of the Jobs
+	// that run after an upgrade succeeds.
+	postUpgradeExtensions = "pgupgrade-extensions"
+	postUpgradeAnalyze    = "pgupgrade-analyze"
+
+	// logicalSubscribe is the role of the Jobs that subscribe the new cluster
+	// of a logical upgrade to each database of the old cluster.
+	logicalSubscribe = "pgupgrade-subscribe"
 
-	pgUpgrade  = "pgupgrade"
-	removeData = "removedata"
+	// logicalFinalizer keeps a PGUpgrade in Logical mode until it removes its
+	// replication objects from the old cluster.
+	logicalFinalizer = labelPrefix + "pgupgrade-logical"
 )
 
 func commonLabels(role string, upgrade *v1beta1.PGUpgrade) map[string]string {
@@ -0,0 +1,1138 @@
+// Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgupgrade
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/kubeapi"
+	"github.com/crunchydata/postgres-operator/internal/pgbouncer"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	pgpassword "github.com/crunchydata/postgres-operator/internal/postgres/password"
+	"github.com/crunchydata/postgres-operator/internal/util"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+const (
+	// logicalPublication is the name of the publication in every database of
+	// the old cluster.
+	logicalPublication = "pgupgrade"
+
+	// upgradeRole is the PostgreSQL role that the new cluster of a logical
+	// upgrade uses to connect to the old cluster and that Jobs use to connect
+	// to a cluster after an upgrade. It exists only while it is needed.
+	upgradeRole = "_crunchyupgrade"
+
+	// pgbouncerSchema is the schema that PGO creates for PgBouncer in every
+	// database. pgmonitorSchema is the schema that PGO creates for the
+	// exporter in pgmonitorDatabase.
+	pgbouncerSchema   = "pgbouncer"
+	pgmonitorSchema   = "monitor"
+	pgmonitorDatabase = "postgres"
+)
+
+// logicalDatabase is a database of the old cluster.
+type logicalDatabase struct {
+	OID  int64  `json:"oid"`
+	Name string `json:"name"`
+}
+
+// subscription returns the name of the subscription to database. Replication
+// slots are named after their subscription and must be unique in the old
+// cluster, so this uses the OID of the database.
+func (database logicalDatabase) subscription() string {
+	return fmt.Sprintf("pgupgrade_%d", database.OID)
+}
+
+// logicalTarget returns the ObjectMeta of the PostgresCluster that upgrade
+// creates at the new version.
+func logicalTarget(upgrade *v1beta1.PGUpgrade) metav1.ObjectMeta {
+	name := fmt.Sprintf("%s-pg%d",
+		upgrade.Spec.PostgresClusterName, upgrade.Spec.ToPostgresVersion)
+
+	if upgrade.Spec.Logical != nil && upgrade.Spec.Logical.TargetClusterName != "" {
+		name = upgrade.Spec.Logical.TargetClusterName
+	}
+	return metav1.ObjectMeta{
+		Namespace: upgrade.Namespace,
+		Name:      name,
+	}
+}
+
+// upgradeRoleSecret returns the ObjectMeta of the Secret with the password
+// of [upgradeRole].
+func upgradeRoleSecret(upgrade *v1beta1.PGUpgrade) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: upgrade.Namespace,
+		Name:      upgrade.Name + "-pguser",
+	}
+}
+
+// primaryService returns the hostname and port of the primary Service of
+// cluster.
+func primaryService(cluster *v1beta1.PostgresCluster) (string, int32) {
+	port := int32(5432)
+	if cluster.Spec.Port != nil {
+		port = *cluster.Spec.Port
+	}
+	return cluster.Name + "-primary." + cluster.Namespace + ".svc", port
+}
+
+// logicalConnection returns a libpq connection string for database through
+// the primary Service of cluster. It has no password when password is empty.
+// - https://www.postgresql.org/docs/current/libpq-connect.html#LIBPQ-CONNSTRING
+func logicalConnection(cluster *v1beta1.PostgresCluster, password, database string) string {
+	quote := func(value string) string {
+		return `'` + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + `'`
+	}
+
+	host, port := primaryService(cluster)
+	parameters := []string{
+		"host=" + quote(host),
+		"port=" + fmt.Sprint(port),
+		"dbname=" + quote(database),
+		"user=" + quote(upgradeRole),
+	}
+	if password != "" {
+		parameters = append(parameters, "password="+quote(password))
+	}
+	return strings.Join(append(parameters, "sslmode=require"), " ")
+}
+
+// upgradeRoleEnvironment returns the environment of a Job that connects to
+// the primary of cluster as [upgradeRole].
+// - https://www.postgresql.org/docs/current/libpq-envars.html
+func upgradeRoleEnvironment(
+	upgrade *v1beta1.PGUpgrade, cluster *v1beta1.PostgresCluster,
+) []corev1.EnvVar {
+	host, port := primaryService(cluster)
+	password := &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: upgradeRoleSecret(upgrade).Name},
+		Key:                  "password",
+	}}
+	return []corev1.EnvVar{
+		{Name: "PGHOST", Value: host},
+		{Name: "PGPORT", Value: fmt.Sprint(port)},
+		{Name: "PGDATABASE", Value: "postgres"},
+		{Name: "PGUSER", Value: upgradeRole},
+		{Name: "PGPASSWORD", ValueFrom: password},
+		{Name: "PGSSLMODE", Value: "require"},
+		{Name: "PGCONNECT_TIMEOUT", Value: "10"},
+	}
+}
+
+// generateLogicalCluster returns a copy of source at the new version of
+// upgrade. It has no data source; its schema and data arrive through logical
+// replication. It has no proxy; at cutover, the proxy of source is pointed at
+// the Services of the new cluster. Its cloud repositories get their own paths
+// so that its backups do not mix with those of source.
+func generateLogicalCluster(
+	upgrade *v1beta1.PGUpgrade, source *v1beta1.PostgresCluster,
+) *v1beta1.PostgresCluster {
+	cluster := &v1beta1.PostgresCluster{ObjectMeta: logicalTarget(upgrade)}
+	cluster.SetGroupVersionKind(v1beta1.GroupVersion.WithKind("PostgresCluster"))
+
+	source.Spec.DeepCopyInto(&cluster.Spec)
+	cluster.Annotations = upgrade.Spec.Metadata.GetAnnotationsOrNil()
+	cluster.Labels = Merge(upgrade.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{LabelPGUpgrade: upgrade.Name})
+
+	cluster.Spec.PostgresVersion = upgrade.Spec.ToPostgresVersion
+	cluster.Spec.Image = upgrade.Spec.ToPostgresImage
+	cluster.Spec.DataSource = nil
+	cluster.Spec.DatabaseInitSQL = nil
+	cluster.Spec.Proxy = nil
+	cluster.Spec.Shutdown = nil
+	cluster.Spec.Standby = nil
+	cluster.Spec.Backups.PGBackRest.Manual = nil
+	cluster.Spec.Backups.PGBackRest.Restore = nil
+
+	// PGO creates a user named after a cluster without users. Keep that user.
+	if cluster.Spec.Users == nil {
+		cluster.Spec.Users = []v1beta1.PostgresUserSpec{{
+			Name:      v1beta1.PostgresIdentifier(source.Name),
+			Databases: []v1beta1.PostgresIdentifier{v1beta1.PostgresIdentifier(source.Name)},
+		}}
+	}
+
+	// Volume repositories are claims of each cluster, but cloud repositories
+	// are shared storage. Suffix their paths with the name of upgrade.
+	// - https://pgbackrest.org/configuration.html#section-repository/option-repo-path
+	for _, repo := range cluster.Spec.Backups.PGBackRest.Repos {
+		if repo.Volume != nil {
+			continue
+		}
+		if cluster.Spec.Backups.PGBackRest.Global == nil {
+			cluster.Spec.Backups.PGBackRest.Global = map[string]string{}
+		}
+		path := cluster.Spec.Backups.PGBackRest.Global[repo.Name+"-path"]
+		if path == "" {
+			path = "/pgbackrest/" + repo.Name
+		}
+		cluster.Spec.Backups.PGBackRest.Global[repo.Name+"-path"] = path + "-" + upgrade.Name
+	}
+
+	// Node ports cannot be shared.
+	if cluster.Spec.Service != nil {
+		cluster.Spec.Service.NodePort = nil
+	}
+	if cluster.Spec.ReplicaService != nil {
+		cluster.Spec.ReplicaService.NodePort = nil
+	}
+
+	return cluster
+}
+
+// logicalDatabases returns the databases of the old cluster that accept
+// connections.
+func logicalDatabases(ctx context.Context, exec postgres.Executor) ([]logicalDatabase, error) {
+	const sql = `SELECT COALESCE(json_agg(json_build_object('oid', oid, 'name', datname) ORDER BY datname), '[]')` +
+		` FROM pg_catalog.pg_database WHERE datallowconn AND NOT datistemplate`
+
+	var stdout, stderr bytes.Buffer
+	err := exec(ctx, nil, &stdout, &stderr, "psql", "-Xw", "-At", "--command="+sql)
+	if err != nil {
+		return nil, errors.WithMessage(err, stderr.String())
+	}
+
+	var databases []logicalDatabase
+	return databases, errors.WithStack(json.Unmarshal(stdout.Bytes(), &databases))
+}
+
+// createUpgradeRole creates or updates [upgradeRole] in a cluster. The role
+// is a superuser so that it can publish, read, and analyze every table.
+func createUpgradeRole(ctx context.Context, exec postgres.Executor, verifier string) error {
+	const sql = `
+SELECT pg_catalog.format('CREATE ROLE %I', :'role')
+ WHERE NOT EXISTS (SELECT 1 FROM pg_catalog.pg_roles WHERE rolname = :'role')
+\gexec
+ALTER ROLE :"role" WITH LOGIN REPLICATION SUPERUSER PASSWORD :'verifier';
+`
+	// Send the verifier through stdin so it stays out of the exec request.
+	stdin := strings.NewReader(`\set verifier '` + verifier + "'\n" + sql)
+
+	var stderr bytes.Buffer
+	err := exec(ctx, stdin, io.Discard, &stderr,
+		"psql", "-Xw", "--quiet", "--set=ON_ERROR_STOP=1", "--set=role="+upgradeRole, "--file=-")
+	if err != nil {
+		err = errors.WithMessage(err, stderr.String())
+	}
+	return err
+}
+
+// pgUpgradeSubscribeJob returns the ObjectMeta of the Job that subscribes the
+// new cluster of upgrade to database.
+func pgUpgradeSubscribeJob(upgrade *v1beta1.PGUpgrade, database logicalDatabase) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: upgrade.Namespace,
+		Name:      fmt.Sprintf("%s-subscribe-%d", upgrade.Name, database.OID),
+	}
+}
+
+// subscribeCommand returns a command that creates database in the new
+// cluster, copies its schema from the old cluster, publishes every table
+// there, and subscribes to that publication. Once the subscription exists,
+// it does nothing. It connects to both clusters as [upgradeRole] with the
+// password in its environment. Schemas that PGO creates in the new cluster
+// are not copied.
+func subscribeCommand(
+	source *v1beta1.PostgresCluster, database logicalDatabase,
+) []string {
+	const script = `
+declare -r source="$1" database="$2" subscription="$3" publication="$4"
+shift 4
+set -o pipefail
+
+psql -Xw --quiet --set=ON_ERROR_STOP=1 --set=database="${database}" --dbname=postgres <<'SQL'
+SELECT pg_catalog.format('CREATE DATABASE %I', :'database')
+ WHERE NOT EXISTS (SELECT 1 FROM pg_catalog.pg_database WHERE datname = :'database')
+\gexec
+SQL
+
+exists=$(psql -Xw -At --set=ON_ERROR_STOP=1 --set=subscription="${subscription}" --dbname="${database}" <<'SQL'
+SELECT 1 FROM pg_catalog.pg_subscription WHERE subname = :'subscription'
+SQL
+)
+[[ -z "${exists}" ]] || exit 0
+
+# Roles that already exist in the new cluster report errors here.
+pg_dumpall --roles-only --no-role-passwords --dbname="${source}" | psql -Xw --quiet --dbname=postgres
+
+# Copy the schema in one transaction that stops at the first error.
+pg_dump --schema-only "$@" --dbname="${source}" |
+  psql -Xw --quiet --set=ON_ERROR_STOP=1 --single-transaction --dbname="${database}"
+
+psql -Xw --quiet --set=ON_ERROR_STOP=1 --set=publication="${publication}" --dbname="${source}" <<'SQL'
+SELECT pg_catalog.format('CREATE PUBLICATION %I FOR ALL TABLES', :'publication')
+ WHERE NOT EXISTS (SELECT 1 FROM pg_catalog.pg_publication WHERE pubname = :'publication')
+\gexec
+SQL
+
+psql -Xw --quiet --set=ON_ERROR_STOP=1 --dbname="${database}" \
+  --set=subscription="${subscription}" --set=publication="${publication}" \
+  --set=source="${source} password='${PGPASSWORD}'" <<'SQL'
+CREATE SUBSCRIPTION :"subscription" CONNECTION :'source' PUBLICATION :"publication";
+SQL
+`
+	command := []string{"bash", "-ceu", "--", script, "subscribe",
+		logicalConnection(source, "", database.Name),
+		database.Name, database.subscription(), logicalPublication,
+		"--exclude-schema=" + pgbouncerSchema,
+	}
+	if database.Name == pgmonitorDatabase {
+		command = append(command, "--exclude-schema="+pgmonitorSchema)
+	}
+	return command
+}
+
+// generateSubscribeJob returns a Job that runs [subscribeCommand] for
+// database using the image of primary, the primary of target. Its password
+// comes from the Secret of [upgradeRole].
+func (r *PGUpgradeReconciler) generateSubscribeJob(
+	upgrade *v1beta1.PGUpgrade, source, target *v1beta1.PostgresCluster,
+	primary *corev1.Pod, database logicalDatabase,
+) *batchv1.Job {
+	job := &batchv1.Job{ObjectMeta: pgUpgradeSubscribeJob(upgrade, database)}
+	job.SetGroupVersionKind(batchv1.SchemeGroupVersion.WithKind("Job"))
+
+	job.Annotations = upgrade.Spec.Metadata.GetAnnotationsOrNil()
+	job.Labels = Merge(upgrade.Spec.Metadata.GetLabelsOrNil(),
+		commonLabels(logicalSubscribe, upgrade),
+		map[string]string{
+			LabelVersion: fmt.Sprint(upgrade.Spec.ToPostgresVersion),
+		})
+	job.Spec.Template.ObjectMeta = metav1.ObjectMeta{
+		Annotations: job.Annotations,
+		Labels:      job.Labels,
+	}
+
+	// Find the database container.
+	var container corev1.Container
+	for i := range primary.Spec.Containers {
+		if primary.Spec.Containers[i].Name == ContainerDatabase {
+			container = primary.Spec.Containers[i]
+		}
+	}
+
+	// Retry a few times before reporting the subscription failed.
+	job.Spec.BackoffLimit = initialize.Int32(2)
+	job.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	job.Spec.Template.Spec.SecurityContext = primary.Spec.SecurityContext
+	job.Spec.Template.Spec.ImagePullSecrets = primary.Spec.ImagePullSecrets
+
+	job.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name:            container.Name,
+		SecurityContext: container.SecurityContext,
+
+		Command:                  subscribeCommand(source, database),
+		Env:                      upgradeRoleEnvironment(upgrade, target),
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		Image:                    container.Image,
+		ImagePullPolicy:          container.ImagePullPolicy,
+		Resources:                upgrade.Spec.Resources,
+	}}
+
+	// The following will set these fields to null if not set in the spec
+	job.Spec.Template.Spec.Affinity = upgrade.Spec.Affinity
+	job.Spec.Template.Spec.PriorityClassName = initialize.FromPointer(
+		upgrade.Spec.PriorityClassName)
+	job.Spec.Template.Spec.Tolerations = upgrade.Spec.Tolerations
+
+	r.setControllerReference(upgrade, job)
+	return job
+}
+
+// replicationLag returns the amount of WAL in the old cluster that the new
+// cluster has yet to confirm, in bytes.
+func replicationLag(ctx context.Context, exec postgres.Executor) (int64, error) {
+	const sql = `SELECT COALESCE(SUM(pg_catalog.pg_wal_lsn_diff(pg_catalog.pg_current_wal_lsn(), confirmed_flush_lsn)), 0)::bigint` +
+		` FROM pg_catalog.pg_replication_slots WHERE slot_name LIKE 'pgupgrade\_%'`
+
+	var stdout, stderr bytes.Buffer
+	err := exec(ctx, nil, &stdout, &stderr, "psql", "-Xw", "-At", "--command="+sql)
+	if err != nil {
+		return 0, errors.WithMessage(err, stderr.String())
+	}
+
+	lag, err := strconv.ParseInt(strings.TrimSpace(stdout.String()), 10, 64)
+	return lag, errors.WithStack(err)
+}
+
+// unsynchronizedTables returns the number of tables in the new cluster that
+// have not finished their initial copy.
+// - https://www.postgresql.org/docs/current/catalog-pg-subscription-rel.html
+func unsynchronizedTables(
+	ctx context.Context, exec postgres.Executor, databases []logicalDatabase,
+) (int, error) {
+	const script = `
+for database in "$@"; do
+  psql -Xw -At --dbname="${database}" \
+    --command="SELECT count(*) FROM pg_catalog.pg_subscription_rel WHERE srsubstate <> 'r'"
+done
+`
+	args := []string{"bash", "-ceu", "--", script, "tables"}
+	for _, database := range databases {
+		args = append(args, database.Name)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := exec(ctx, nil, &stdout, &stderr, args...); err != nil {
+		return 0, errors.WithMessage(err, stderr.String())
+	}
+
+	var total int
+	for _, line := range strings.Fields(stdout.String()) {
+		count, err := strconv.Atoi(line)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// connectGrants returns the CONNECT privileges on databases of the old
+// cluster that [stopWrites] revokes.
+func connectGrants(ctx context.Context, exec postgres.Executor) ([]v1beta1.PGUpgradeConnectGrant, error) {
+	const sql = `SELECT COALESCE(json_agg(json_build_object('database', d.datname, 'role', COALESCE(r.rolname, ''))` +
+		` ORDER BY d.datname, r.rolname), '[]')` +
+		` FROM pg_catalog.pg_database d,` +
+		` pg_catalog.aclexplode(COALESCE(d.datacl, pg_catalog.acldefault('d', d.datdba))) acl` +
+		` LEFT JOIN pg_catalog.pg_roles r ON r.oid = acl.grantee` +
+		` WHERE d.datallowconn AND acl.privilege_type = 'CONNECT' AND (acl.grantee = 0 OR NOT r.rolsuper)`
+
+	var stdout, stderr bytes.Buffer
+	err := exec(ctx, nil, &stdout, &stderr, "psql", "-Xw", "-At", "--command="+sql)
+	if err != nil {
+		return nil, errors.WithMessage(err, stderr.String())
+	}
+
+	var grants []v1beta1.PGUpgradeConnectGrant
+	return grants, errors.WithStack(json.Unmarshal(stdout.Bytes(), &grants))
+}
+
+// grantConnect grants the CONNECT privileges that [stopWrites] revoked. It
+// skips those of databases and roles that no longer exist.
+func grantConnect(
+	ctx context.Context, exec postgres.Executor, grants []v1beta1.PGUpgradeConnectGrant,
+) error {
+	const sql = `
+SELECT CASE WHEN COALESCE(g.role, '') = ''
+       THEN pg_catalog.format('GRANT CONNECT ON DATABASE %I TO PUBLIC', g.database)
+       ELSE pg_catalog.format('GRANT CONNECT ON DATABASE %I TO %I', g.database, g.role) END
+  FROM pg_catalog.json_to_recordset(:'grants') AS g(database text, role text)
+ WHERE EXISTS (SELECT 1 FROM pg_catalog.pg_database WHERE datname = g.database)
+   AND (COALESCE(g.role, '') = '' OR EXISTS (SELECT 1 FROM pg_catalog.pg_roles WHERE rolname = g.role))
+\gexec
+`
+	data, err := json.Marshal(grants)
+	if err != nil || len(grants) == 0 {
+		return errors.WithStack(err)
+	}
+
+	var stderr bytes.Buffer
+	err = exec(ctx, strings.NewReader(sql), io.Discard, &stderr,
+		"psql", "-Xw", "--quiet", "--set=ON_ERROR_STOP=1", "--set=grants="+string(data), "--file=-")
+	if err != nil {
+		err = errors.WithMessage(err, stderr.String())
+	}
+	return err
+}
+
+// stopWrites keeps clients from connecting to the old cluster and
+// disconnects those that are connected, except those of [upgradeRole] and
+// replication. It revokes the CONNECT privilege that roles other than
+// superusers have on every database, so superusers can still connect. Those
+// privileges stay revoked unless [grantConnect] grants them again; call
+// [connectGrants] first to know what they were.
+func stopWrites(ctx context.Context, exec postgres.Executor) error {
+	const sql = `
+SELECT pg_catalog.format('REVOKE CONNECT ON DATABASE %I FROM PUBLIC', datname)
+  FROM pg_catalog.pg_database WHERE datallowconn
+\gexec
+SELECT pg_catalog.format('REVOKE CONNECT ON DATABASE %I FROM %I', d.datname, r.rolname)
+  FROM pg_catalog.pg_database d, pg_catalog.aclexplode(d.datacl) acl
+  JOIN pg_catalog.pg_roles r ON r.oid = acl.grantee
+ WHERE d.datallowconn AND acl.privilege_type = 'CONNECT' AND NOT r.rolsuper
+\gexec
+SELECT pg_catalog.pg_terminate_backend(pid) FROM pg_catalog.pg_stat_activity
+ WHERE pid <> pg_catalog.pg_backend_pid() AND backend_type = 'client backend'
+   AND usename IS DISTINCT FROM :'role';
+`
+	var stderr bytes.Buffer
+	err := exec(ctx, strings.NewReader(sql), io.Discard, &stderr,
+		"psql", "-Xw", "--quiet", "--set=ON_ERROR_STOP=1", "--set=role="+upgradeRole, "--file=-")
+	if err != nil {
+		err = errors.WithMessage(err, stderr.String())
+	}
+	return err
+}
+
+// finishDatabase runs in the new cluster after writes to the old cluster have
+// stopped and replication has caught up. Logical replication does not copy
+// sequences, so it copies their values, then drops the subscription to
+// database along with its replication slot.
+func finishDatabase(
+	ctx context.Context, exec postgres.Executor, database logicalDatabase, connection string,
+) error {
+	const script = `
+IFS= read -r source
+declare -r database="$1" subscription="$2"
+
+psql -Xw -At --set=ON_ERROR_STOP=1 --dbname="${source}" <<'SQL' |
+SELECT pg_catalog.format('SELECT pg_catalog.setval(%L, %s, true);',
+       pg_catalog.format('%I.%I', schemaname, sequencename), last_value)
+  FROM pg_catalog.pg_sequences WHERE last_value IS NOT NULL;
+SQL
+psql -Xw --quiet --set=ON_ERROR_STOP=1 --dbname="${database}" > /dev/null
+
+psql -Xw --quiet --set=ON_ERROR_STOP=1 --set=subscription="${subscription}" --dbname="${database}" <<'SQL'
+SELECT pg_catalog.format('DROP SUBSCRIPTION %I', :'subscription')
+ WHERE EXISTS (SELECT 1 FROM pg_catalog.pg_subscription WHERE subname = :'subscription')
+\gexec
+SQL
+`
+	var stderr bytes.Buffer
+	err := exec(ctx, strings.NewReader(connection+"\n"), io.Discard, &stderr,
+		"bash", "-ceu", "--", script, "finish", database.Name, database.subscription())
+	if err != nil {
+		err = errors.WithMessage(err, stderr.String())
+	}
+	return err
+}
+
+// dropLogicalRole drops the publications and [upgradeRole] of a cluster.
+func dropLogicalRole(ctx context.Context, exec postgres.Executor) error {
+	const script = `
+declare -r role="$1" publication="$2"
+
+psql -Xw -At --command="SELECT datname FROM pg_catalog.pg_database WHERE datallowconn AND NOT datistemplate" |
+while IFS= read -r database; do
+  psql -Xw --quiet --set=ON_ERROR_STOP=1 --set=publication="${publication}" --dbname="${database}" <<'SQL'
+DROP PUBLICATION IF EXISTS :"publication";
+SQL
+done
+
+psql -Xw --quiet --set=ON_ERROR_STOP=1 --set=role="${role}" --dbname=postgres <<'SQL'
+DROP ROLE IF EXISTS :"role";
+SQL
+`
+	var stderr bytes.Buffer
+	err := exec(ctx, nil, io.Discard, &stderr,
+		"bash", "-ceu", "--", script, "drop", upgradeRole, logicalPublication)
+	if err != nil {
+		err = errors.WithMessage(err, stderr.String())
+	}
+	return err
+}
+
+// dropLogicalSubscriptions drops the subscriptions of the new cluster without
+// contacting the old cluster. Their replication slots remain there; see
+// [dropLogicalSlots].
+func dropLogicalSubscriptions(ctx context.Context, exec postgres.Executor) error {
+	const script = `
+psql -Xw -At --command="SELECT datname FROM pg_catalog.pg_database WHERE datallowconn AND NOT datistemplate" |
+while IFS= read -r database; do
+  psql -Xw --quiet --set=ON_ERROR_STOP=1 --dbname="${database}" <<'SQL'
+SELECT pg_catalog.format('ALTER SUBSCRIPTION %I DISABLE', subname),
+       pg_catalog.format('ALTER SUBSCRIPTION %I SET (slot_name = NONE)', subname),
+       pg_catalog.format('DROP SUBSCRIPTION %I', subname)
+  FROM pg_catalog.pg_subscription
+ WHERE subname LIKE 'pgupgrade\_%'
+   AND subdbid = (SELECT oid FROM pg_catalog.pg_database WHERE datname = pg_catalog.current_database())
+\gexec
+SQL
+done
+`
+	var stderr bytes.Buffer
+	err := exec(ctx, nil, io.Discard, &stderr, "bash", "-ceu", "--", script)
+	if err != nil {
+		err = errors.WithMessage(err, stderr.String())
+	}
+	return err
+}
+
+// dropLogicalSlots drops the replication slots of subscriptions in the old
+// cluster. It keeps [upgradeRole] from connecting and disconnects it so that
+// no slot stays active, then returns an error when any slot remains.
+func dropLogicalSlots(ctx context.Context, exec postgres.Executor) error {
+	const sql = `
+SELECT pg_catalog.format('ALTER ROLE %I NOLOGIN', :'role')
+ WHERE EXISTS (SELECT 1 FROM pg_catalog.pg_roles WHERE rolname = :'role')
+\gexec
+\o /dev/null
+SELECT pg_catalog.pg_terminate_backend(pid) FROM pg_catalog.pg_stat_activity
+ WHERE pid <> pg_catalog.pg_backend_pid() AND usename = :'role';
+SELECT pg_catalog.pg_drop_replication_slot(slot_name) FROM pg_catalog.pg_replication_slots
+ WHERE slot_name LIKE 'pgupgrade\_%' AND NOT active;
+\o
+SELECT pg_catalog.count(*) FROM pg_catalog.pg_replication_slots WHERE slot_name LIKE 'pgupgrade\_%';
+`
+	var stdout, stderr bytes.Buffer
+	err := exec(ctx, strings.NewReader(sql), &stdout, &stderr,
+		"psql", "-Xw", "-At", "--quiet", "--set=ON_ERROR_STOP=1", "--set=role="+upgradeRole, "--file=-")
+	if err != nil {
+		return errors.WithMessage(err, stderr.String())
+	}
+
+	if remaining := strings.TrimSpace(stdout.String()); remaining != "0" {
+		err = errors.Errorf("%s replication slots are still active", remaining)
+	}
+	return err
+}
+
+// readyPrimary returns the Pod in pods that Patroni has labeled as the leader
+// when it is ready.
+func readyPrimary(pods []*corev1.Pod) *corev1.Pod {
+	for _, pod := range pods {
+		if ready, _ := kubeapi.PodIsReady(pod); ready && pod.Labels[LabelRole] == RolePatroniLeader {
+			return pod
+		}
+	}
+	return nil
+}
+
+// clusterPrimary returns the ready primary of cluster, if any.
+func (r *PGUpgradeReconciler) clusterPrimary(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (*corev1.Pod, error) {
+	var pods corev1.PodList
+	err := errors.WithStack(r.Client.List(ctx, &pods,
+		client.InNamespace(cluster.Namespace),
+		client.MatchingLabels{
+			LabelCluster: cluster.Name,
+			LabelRole:    RolePatroniLeader,
+		}))
+
+	var primaries []*corev1.Pod
+	for i := range pods.Items {
+		primaries = append(primaries, &pods.Items[i])
+	}
+	return readyPrimary(primaries), err
+}
+
+// podExecutor returns a [postgres.Executor] that runs in the database
+// container of pod.
+func (r *PGUpgradeReconciler) podExecutor(pod *corev1.Pod) postgres.Executor {
+	return func(
+		_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error {
+		return r.PodExec(pod.Namespace, pod.Name, ContainerDatabase,
+			stdin, stdout, stderr, command...)
+	}
+}
+
+//+kubebuilder:rbac:groups="",resources="secrets",verbs={get,list}
+//+kubebuilder:rbac:groups="",resources="secrets",verbs={create,patch}
+//+kubebuilder:rbac:groups="",resources="pods",verbs={list}
+//+kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+//+kubebuilder:rbac:groups="batch",resources="jobs",verbs={create,patch}
+//+kubebuilder:rbac:groups="postgres-operator.crunchydata.com",resources="postgresclusters",verbs={get,create,patch}
+
+// reconcileLogical upgrades the cluster of upgrade through logical
+// replication. It creates a new cluster at the new version with the same
+// users and passwords, then runs a Job that subscribes the new cluster to
+// each database of the old one. When upgrade is annotated for cutover, it
+// stops clients of the old cluster, waits for replication to catch up, copies
+// sequences, and points the primary Service and proxy of the old cluster at
+// the new cluster. Deleting upgrade or the new cluster before cutover removes
+// replication from the old cluster; see [r.abandonLogical].
+//
+// Clients that verify the hostname in the certificate of the server cannot
+// connect through the old primary Service after cutover.
+func (r *PGUpgradeReconciler) reconcileLogical(
+	ctx context.Context, upgrade *v1beta1.PGUpgrade, world *World,
+) (ctrl.Result, error) {
+	target := &v1beta1.PostgresCluster{ObjectMeta: logicalTarget(upgrade)}
+	wait := ctrl.Result{RequeueAfter: 10 * time.Second}
+
+	if upgrade.Status.Logical == nil {
+		upgrade.Status.Logical = &v1beta1.PGUpgradeLogicalStatus{}
+	}
+	status := upgrade.Status.Logical
+	status.TargetClusterName = target.Name
+
+	replicating := func(condition metav1.ConditionStatus, reason, message string) {
+		meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+			ObservedGeneration: upgrade.Generation,
+			Type:               ConditionPGUpgradeReplicating,
+			Status:             condition,
+			Reason:             reason,
+			Message:            message,
+		})
+	}
+
+	source := readyPrimary(world.Pods)
+	if source == nil {
+		replicating(metav1.ConditionFalse, "PGClusterPrimaryNotReady", fmt.Sprintf(
+			"Waiting for the primary of PostgresCluster %s", upgrade.Spec.PostgresClusterName))
+		return ctrl.Result{}, nil
+	}
+
+	password, err := r.reconcileUpgradeRoleSecret(ctx, upgrade)
+
+	if err == nil {
+		err = errors.WithStack(r.Client.Get(ctx, client.ObjectKeyFromObject(target), target))
+
+		// Copy the user Secrets before creating the cluster so that its users
+		// have the same passwords.
+		// Replication to a previous target cannot resume. Remove what it
+		// left in the old cluster and subscribe the new target from scratch.
+		if apierrors.IsNotFound(errors.Cause(err)) {
+			if _, err = r.abandonLogical(ctx, upgrade, world); err == nil {
+				status.Databases = nil
+				status.ReplicationLagBytes = nil
+				status.RevokedConnect = nil
+			}
+			if err == nil {
+				err = r.copyUserSecrets(ctx, world.Cluster, target.Name)
+			}
+			if err == nil {
+				err = errors.WithStack(r.Client.Create(ctx,
+					generateLogicalCluster(upgrade, world.Cluster), r.Owner))
+			}
+			if err == nil {
+				replicating(metav1.ConditionFalse, "PGUpgradeTargetCreated", fmt.Sprintf(
+					"Created PostgresCluster %s at version %d",
+					target.Name, upgrade.Spec.ToPostgresVersion))
+			}
+			return wait, err
+		}
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	primary, err := r.clusterPrimary(ctx, target)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if primary == nil {
+		replicating(metav1.ConditionFalse, "PGClusterPrimaryNotReady", fmt.Sprintf(
+			"Waiting for the primary of PostgresCluster %s", target.Name))
+		return wait, nil
+	}
+
+	sourceExec, targetExec := r.podExecutor(source), r.podExecutor(primary)
+	connection := func(database string) string {
+		return logicalConnection(world.Cluster, password, database)
+	}
+
+	databases, err := logicalDatabases(ctx, sourceExec)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var names []string
+	for _, database := range databases {
+		names = append(names, database.Name)
+	}
+
+	// Subscribe until every database is replicating.
+	if !meta.IsStatusConditionTrue(upgrade.Status.Conditions, ConditionPGUpgradeReplicating) ||
+		strings.Join(status.Databases, "\n") != strings.Join(names, "\n") {
+		var verifier string
+		verifier, err = pgpassword.NewSCRAMPassword(password).Build()
+		err = errors.WithStack(err)
+
+		if err == nil {
+			err = createUpgradeRole(ctx, sourceExec, verifier)
+		}
+		if err == nil {
+			err = createUpgradeRole(ctx, targetExec, verifier)
+		}
+
+		// Each database is subscribed by its own Job.
+		var running, failed []string
+		for _, database := range databases {
+			job := world.Jobs[pgUpgradeSubscribeJob(upgrade, database).Name]
+
+			switch {
+			case err != nil:
+			case job == nil:
+				job = r.generateSubscribeJob(upgrade, world.Cluster, target, primary, database)
+				err = errors.WithStack(r.apply(ctx, job))
+				running = append(running, job.Name)
+			case jobFailed(job):
+				failed = append(failed, job.Name)
+			case !jobCompleted(job):
+				running = append(running, job.Name)
+			}
+		}
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if len(failed) > 0 {
+			replicating(metav1.ConditionFalse, "PGUpgradeSubscribeFailed", fmt.Sprintf(
+				"Jobs %s failed; check their pod logs and delete them to try again",
+				strings.Join(failed, ", ")))
+			return ctrl.Result{}, nil
+		}
+		if len(running) > 0 {
+			// The Jobs wake us when they finish.
+			replicating(metav1.ConditionFalse, "PGUpgradeSubscribing", fmt.Sprintf(
+				"Jobs %s are running", strings.Join(running, ", ")))
+			return ctrl.Result{}, nil
+		}
+	}
+	status.Databases = names
+
+	lag, err := replicationLag(ctx, sourceExec)
+	var tables int
+	if err == nil {
+		tables, err = unsynchronizedTables(ctx, targetExec, databases)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status.ReplicationLagBytes = &lag
+	replicating(metav1.ConditionTrue, "PGUpgradeReplicating", fmt.Sprintf(
+		"Replicating %d databases to PostgresCluster %s; %d tables are still copying",
+		len(databases), target.Name, tables))
+
+	if upgrade.GetAnnotations()[AnnotationCutover] == "" {
+		// Cutover was abandoned; let clients of the old cluster connect again.
+		if status.RevokedConnect != nil {
+			if err = grantConnect(ctx, sourceExec, status.RevokedConnect.Grants); err != nil {
+				return ctrl.Result{}, err
+			}
+			status.RevokedConnect = nil
+		}
+		return wait, nil
+	}
+
+	// Cutover: record the privileges that stopping writes revokes so they can
+	// be granted again when cutover is abandoned. Store them before revoking.
+	if status.RevokedConnect == nil {
+		grants, err := connectGrants(ctx, sourceExec)
+		if err == nil {
+			status.RevokedConnect = &v1beta1.PGUpgradeRevokedConnect{Grants: grants}
+		}
+		return ctrl.Result{RequeueAfter: 2 * time.Second}, err
+	}
+
+	// Stop writes then wait for the new cluster to catch up.
+	if err = stopWrites(ctx, sourceExec); err != nil {
+		return ctrl.Result{}, err
+	}
+	if lag > 0 || tables > 0 {
+		meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+			ObservedGeneration: upgrade.Generation,
+			Type:               ConditionPGUpgradeProgressing,
+			Status:             metav1.ConditionTrue,
+			Reason:             "PGUpgradeCutoverInProgress",
+			Message: fmt.Sprintf(
+				"Writes to PostgresCluster %s stopped; waiting for %s to catch up",
+				upgrade.Spec.PostgresClusterName, target.Name),
+		})
+		return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+	}
+
+	for _, database := range databases {
+		if err == nil {
+			err = finishDatabase(ctx, targetExec, database, connection(database.Name))
+		}
+	}
+	if err == nil {
+		err = dropLogicalRole(ctx, targetExec)
+	}
+	if err == nil {
+		err = dropLogicalRole(ctx, sourceExec)
+	}
+
+	// The proxy of the old cluster moves to the new cluster. Create its role
+	// and authentication function there with the password it already has.
+	// The default HBA rules of the new cluster let it connect.
+	if err == nil && world.Cluster.Spec.Proxy != nil && world.Cluster.Spec.Proxy.PGBouncer != nil {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Namespace: world.Cluster.Namespace,
+			Name:      world.Cluster.Name + "-pgbouncer",
+		}}
+		err = errors.WithStack(r.Client.Get(ctx, client.ObjectKeyFromObject(secret), secret))
+
+		if err == nil {
+			err = pgbouncer.EnableInPostgreSQL(ctx, targetExec, secret)
+		}
+	}
+
+	// Point the primary Service of the old cluster at the new cluster. The
+	// proxy of the old cluster connects to the Services of the new cluster.
+	if err == nil {
+		patch := world.Cluster.DeepCopy()
+		patch.Annotations = Merge(patch.Annotations, map[string]string{
+			AnnotationPrimaryServiceRedirect: target.Name,
+		})
+		err = errors.WithStack(r.Client.Patch(ctx, patch, client.MergeFrom(world.Cluster), r.Owner))
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	now := metav1.Now()
+	status.CutoverTime = &now
+	status.ReplicationLagBytes = nil
+
+	replicating(metav1.ConditionFalse, "PGUpgradeCutoverComplete", fmt.Sprintf(
+		"PostgresCluster %s no longer replicates from %s",
+		target.Name, upgrade.Spec.PostgresClusterName))
+	meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+		ObservedGeneration: upgrade.Generation,
+		Type:               ConditionPGUpgradeProgressing,
+		Status:             metav1.ConditionFalse,
+		Reason:             "PGUpgradeCutoverComplete",
+		Message:            "Upgrade through logical replication complete",
+	})
+	meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+		ObservedGeneration: upgrade.Generation,
+		Type:               ConditionPGUpgradeSucceeded,
+		Status:             metav1.ConditionTrue,
+		Reason:             "PGUpgradeCutoverComplete",
+		Message: fmt.Sprintf(
+			"The primary Service of PostgresCluster %s resolves to PostgresCluster %s at version %d",
+			upgrade.Spec.PostgresClusterName, target.Name, upgrade.Spec.ToPostgresVersion),
+	})
+	return ctrl.Result{}, nil
+}
+
+// abandonLogical removes what upgrade created for replication before cutover.
+// It drops the subscriptions and [upgradeRole] of the new cluster, grants the
+// CONNECT privileges that cutover revoked, and drops the replication slots,
+// publications, and [upgradeRole] of the old cluster. It also deletes the Jobs
+// that subscribed. Clusters that no longer exist are skipped. It returns the
+// name of a cluster when it is waiting for the primary of that cluster.
+func (r *PGUpgradeReconciler) abandonLogical(
+	ctx context.Context, upgrade *v1beta1.PGUpgrade, world *World,
+) (string, error) {
+	target := &v1beta1.PostgresCluster{ObjectMeta: logicalTarget(upgrade)}
+	err := errors.WithStack(r.Client.Get(ctx, client.ObjectKeyFromObject(target), target))
+
+	if err == nil && target.DeletionTimestamp.IsZero() {
+		var primary *corev1.Pod
+		primary, err = r.clusterPrimary(ctx, target)
+
+		if err == nil && primary == nil {
+			return target.Name, nil
+		}
+		if err == nil {
+			err = dropLogicalSubscriptions(ctx, r.podExecutor(primary))
+		}
+		if err == nil {
+			err = dropLogicalRole(ctx, r.podExecutor(primary))
+		}
+	}
+	if err = client.IgnoreNotFound(err); err != nil {
+		return "", err
+	}
+
+	if world.Cluster != nil {
+		primary := readyPrimary(world.Pods)
+		if primary == nil {
+			return world.Cluster.Name, nil
+		}
+
+		exec := r.podExecutor(primary)
+		if upgrade.Status.Logical != nil && upgrade.Status.Logical.RevokedConnect != nil {
+			err = grantConnect(ctx, exec, upgrade.Status.Logical.RevokedConnect.Grants)
+		}
+		if err == nil {
+			err = dropLogicalSlots(ctx, exec)
+		}
+		if err == nil {
+			err = dropLogicalRole(ctx, exec)
+		}
+	}
+
+	for _, object := range world.Jobs {
+		if err == nil &&
+			object.Labels[LabelRole] == logicalSubscribe &&
+			object.Labels[LabelPGUpgrade] == upgrade.Name {
+
+			// Jobs default to an `orphanDependents` policy; delete their Pods, too.
+			propagate := client.PropagationPolicy(metav1.DeletePropagationBackground)
+			err = errors.WithStack(client.IgnoreNotFound(r.Client.Delete(ctx, object, propagate)))
+		}
+	}
+	return "", err
+}
+
+//+kubebuilder:rbac:groups="postgres-operator.crunchydata.com",resources="pgupgrades",verbs={patch}
+
+// handleLogicalDelete sets a finalizer on an upgrade in Logical mode and
+// runs [abandonLogical] when upgrade is deleted before cutover. It returns
+// (nil, nil) when upgrade is not being deleted. The caller is responsible for
+// returning other values to controller-runtime.
+func (r *PGUpgradeReconciler) handleLogicalDelete(
+	ctx context.Context, upgrade *v1beta1.PGUpgrade,
+) (*ctrl.Result, error) {
+	finalizers := sets.NewString(upgrade.Finalizers...)
+	finished := upgrade.Status.Logical != nil && upgrade.Status.Logical.CutoverTime != nil
+
+	if upgrade.DeletionTimestamp.IsZero() {
+		if finalizers.Has(logicalFinalizer) || upgrade.Spec.Mode != ModeLogical || finished {
+			return nil, nil
+		}
+
+		// The Finalizers field is shared by multiple controllers. Build a
+		// merge-patch that includes the full list of Finalizers plus
+		// ResourceVersion to detect conflicts with other potential writers.
+		before := upgrade.DeepCopy()
+		// Make another copy so that Patch doesn't write back to upgrade.
+		intent := before.DeepCopy()
+		intent.Finalizers = append(intent.Finalizers, logicalFinalizer)
+		err := errors.WithStack(r.patch(ctx, intent,
+			client.MergeFromWithOptions(before, client.MergeFromWithOptimisticLock{})))
+
+		// The caller can do what they like or requeue upon error.
+		return nil, err
+	}
+
+	if !finalizers.Has(logicalFinalizer) {
+		// The upgrade is being deleted and there is no finalizer.
+		// The caller should listen for another event.
+		return &ctrl.Result{}, nil
+	}
+
+	// Nothing was created in the old cluster before status was recorded, and
+	// nothing remains there after cutover.
+	if upgrade.Status.Logical != nil && !finished {
+		world, err := r.observeWorld(ctx, upgrade)
+		if err != nil {
+			return nil, err
+		}
+
+		waiting, err := r.abandonLogical(ctx, upgrade, world)
+		if err != nil {
+			return nil, err
+		}
+		if waiting != "" {
+			meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+				ObservedGeneration: upgrade.Generation,
+				Type:               ConditionPGUpgradeProgressing,
+				Status:             metav1.ConditionFalse,
+				Reason:             "PGClusterPrimaryNotReady",
+				Message: fmt.Sprintf(
+					"Waiting for the primary of PostgresCluster %s to remove replication", waiting),
+			})
+			return &ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+		}
+	}
+
+	// Our finalizer logic is finished; remove our finalizer.
+	before := upgrade.DeepCopy()
+	// Make another copy so that Patch doesn't write back to upgrade.
+	intent := before.DeepCopy()
+	intent.Finalizers = finalizers.Delete(logicalFinalizer).List()
+	err := errors.WithStack(r.patch(ctx, intent,
+		client.MergeFromWithOptions(before, client.MergeFromWithOptimisticLock{})))
+
+	// The caller should wait for further events or requeue upon error.
+	return &ctrl.Result{}, err
+}
+
+// reconcileUpgradeRoleSecret returns the password of [upgradeRole],
+// generating one when necessary.
+func (r *PGUpgradeReconciler) reconcileUpgradeRoleSecret(
+	ctx context.Context, upgrade *v1beta1.PGUpgrade,
+) (string, error) {
+	existing := &corev1.Secret{ObjectMeta: upgradeRoleSecret(upgrade)}
+	err := errors.WithStack(client.IgnoreNotFound(
+		r.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing)))
+
+	if err == nil && len(existing.Data["password"]) > 0 {
+		return string(existing.Data["password"]), nil
+	}
+
+	// The password goes into connection strings, so avoid characters that
+	// need quoting.
+	var password string
+	if err == nil {
+		password, err = util.GenerateAlphaNumericPassword(util.DefaultGeneratedPasswordLength)
+		err = errors.WithStack(err)
+	}
+	if err == nil {
+		secret := &corev1.Secret{ObjectMeta: upgradeRoleSecret(upgrade)}
+		secret.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+
+		secret.Annotations = upgrade.Spec.Metadata.GetAnnotationsOrNil()
+		secret.Labels = Merge(upgrade.Spec.Metadata.GetLabelsOrNil(),
+			commonLabels(pgUpgrade, upgrade))
+		secret.Data = map[string][]byte{"password": []byte(password)}
+
+		r.setControllerReference(upgrade, secret)
+		err = errors.WithStack(r.apply(ctx, secret))
+	}
+	return password, err
+}
+
+// copyUserSecrets creates Secrets for the users of cluster named after target.
+// PGO uses the passwords in these Secrets when it creates target.
+func (r *PGUpgradeReconciler) copyUserSecrets(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, target string,
+) error {
+	var secrets corev1.SecretList
+	err := errors.WithStack(r.Client.List(ctx, &secrets,
+		client.InNamespace(cluster.Namespace),
+		client.MatchingLabels{
+			LabelCluster: cluster.Name,
+			LabelRole:    RolePostgresUser,
+		}))
+
+	for i := range secrets.Items {
+		existing := &secrets.Items[i]
+		user := existing.Labels[LabelPostgresUser]
+		if err != nil || user == "" || len(existing.Data["password"]) == 0 {
+			continue
+		}
+
+		// The Secret has no owner. PGO adopts it when it creates target.
+		secret := &corev1.Secret{}
+		secret.Namespace = cluster.Namespace
+		secret.Name = target + "-pguser-" + user
+		secret.Labels = map[string]string{
+			LabelCluster:      target,
+			LabelRole:         RolePostgresUser,
+			LabelPostgresUser: user,
+		}
+		secret.Data = map[string][]byte{
+			"password": existing.Data["password"],
+			"verifier": existing.Data["verifier"],
+		}
+
+		err = errors.WithStack(r.Client.Create(ctx, secret, r.Owner))
+		if apierrors.IsAlreadyExists(errors.Cause(err)) {
+			err = nil
+		}
+	}
+	return err
+}
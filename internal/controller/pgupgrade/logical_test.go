@@ -0,0 +1,567 @@
+// Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgupgrade
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/controller/runtime"
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/testing/cmp"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestLogicalTarget(t *testing.T) {
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Namespace = "ns1"
+	upgrade.Spec.PostgresClusterName = "hippo"
+	upgrade.Spec.ToPostgresVersion = 16
+
+	assert.DeepEqual(t, logicalTarget(upgrade), metav1.ObjectMeta{Namespace: "ns1", Name: "hippo-pg16"})
+
+	upgrade.Spec.Logical = &v1beta1.PGUpgradeLogicalSpec{TargetClusterName: "rhino"}
+	assert.DeepEqual(t, logicalTarget(upgrade), metav1.ObjectMeta{Namespace: "ns1", Name: "rhino"})
+}
+
+func TestLogicalConnection(t *testing.T) {
+	cluster := v1beta1.NewPostgresCluster()
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.Spec.Port = initialize.Int32(5454)
+
+	assert.Equal(t, logicalConnection(cluster, "secret", `it's a \ db`), ""+
+		`host='hippo-primary.ns1.svc' port=5454 dbname='it\'s a \\ db'`+
+		` user='_crunchyupgrade' password='secret' sslmode=require`)
+
+	assert.Equal(t, logicalConnection(cluster, "", "app"), ""+
+		`host='hippo-primary.ns1.svc' port=5454 dbname='app'`+
+		` user='_crunchyupgrade' sslmode=require`)
+}
+
+func TestGenerateLogicalCluster(t *testing.T) {
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Namespace = "ns1"
+	upgrade.Name = "pgu2"
+	upgrade.Spec.PostgresClusterName = "hippo"
+	upgrade.Spec.ToPostgresVersion = 16
+	upgrade.Spec.ToPostgresImage = "img:16"
+
+	source := v1beta1.NewPostgresCluster()
+	source.Namespace = "ns1"
+	source.Name = "hippo"
+	source.Spec.PostgresVersion = 15
+	source.Spec.Image = "img:15"
+	source.Spec.Shutdown = initialize.Bool(false)
+	source.Spec.DataSource = &v1beta1.DataSource{}
+	source.Spec.Proxy = &v1beta1.PostgresProxySpec{}
+	source.Spec.Service = &v1beta1.ServiceSpec{Type: "NodePort", NodePort: initialize.Int32(30000)}
+	source.Spec.Backups.PGBackRest.Manual = &v1beta1.PGBackRestManualBackup{RepoName: "repo1"}
+	source.Spec.Backups.PGBackRest.Global = map[string]string{"repo3-path": "/custom"}
+	source.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{
+		{Name: "repo1", Volume: &v1beta1.RepoPVC{}},
+		{Name: "repo2", S3: &v1beta1.RepoS3{}},
+		{Name: "repo3", GCS: &v1beta1.RepoGCS{}},
+	}
+
+	cluster := generateLogicalCluster(upgrade, source)
+	assert.Equal(t, cluster.Name, "hippo-pg16")
+	assert.Equal(t, cluster.Labels[LabelPGUpgrade], "pgu2")
+	assert.Equal(t, cluster.Spec.PostgresVersion, 16)
+	assert.Equal(t, cluster.Spec.Image, "img:16")
+	assert.Assert(t, cluster.Spec.Shutdown == nil)
+	assert.Assert(t, cluster.Spec.DataSource == nil)
+	assert.Assert(t, cluster.Spec.Proxy == nil)
+	assert.Assert(t, cluster.Spec.Backups.PGBackRest.Manual == nil)
+	assert.Equal(t, cluster.Spec.Service.Type, "NodePort")
+	assert.Assert(t, cluster.Spec.Service.NodePort == nil)
+
+	assert.Equal(t, len(cluster.Spec.Users), 1)
+	assert.Equal(t, cluster.Spec.Users[0].Name, v1beta1.PostgresIdentifier("hippo"))
+
+	// Cloud repositories are stored apart from those of the source.
+	assert.DeepEqual(t, cluster.Spec.Backups.PGBackRest.Global, map[string]string{
+		"repo2-path": "/pgbackrest/repo2-pgu2",
+		"repo3-path": "/custom-pgu2",
+	})
+
+	// The source is unchanged.
+	assert.Equal(t, *source.Spec.Service.NodePort, int32(30000))
+	assert.Assert(t, source.Spec.Users == nil)
+	assert.DeepEqual(t, source.Spec.Backups.PGBackRest.Global, map[string]string{"repo3-path": "/custom"})
+}
+
+func TestLogicalQueries(t *testing.T) {
+	ctx := context.Background()
+
+	exec := func(output string, commands *[][]string) func(
+		context.Context, io.Reader, io.Writer, io.Writer, ...string,
+	) error {
+		return func(
+			_ context.Context, _ io.Reader, stdout, _ io.Writer, command ...string,
+		) error {
+			*commands = append(*commands, command)
+			_, _ = stdout.Write([]byte(output))
+			return nil
+		}
+	}
+
+	t.Run("Databases", func(t *testing.T) {
+		var commands [][]string
+		databases, err := logicalDatabases(ctx,
+			exec(`[{"oid":5,"name":"postgres"},{"oid":16384,"name":"app"}]`, &commands))
+
+		assert.NilError(t, err)
+		assert.DeepEqual(t, databases, []logicalDatabase{
+			{OID: 5, Name: "postgres"}, {OID: 16384, Name: "app"},
+		})
+		assert.Equal(t, databases[1].subscription(), "pgupgrade_16384")
+		assert.Assert(t, strings.Contains(commands[0][3], "datallowconn"))
+	})
+
+	t.Run("Lag", func(t *testing.T) {
+		var commands [][]string
+		lag, err := replicationLag(ctx, exec("1234\n", &commands))
+
+		assert.NilError(t, err)
+		assert.Equal(t, lag, int64(1234))
+		assert.Assert(t, strings.Contains(commands[0][3], `'pgupgrade\_%'`))
+	})
+
+	t.Run("ConnectGrants", func(t *testing.T) {
+		var commands [][]string
+		grants, err := connectGrants(ctx,
+			exec(`[{"database":"app","role":""},{"database":"app","role":"app"}]`, &commands))
+
+		assert.NilError(t, err)
+		assert.DeepEqual(t, grants, []v1beta1.PGUpgradeConnectGrant{
+			{Database: "app"}, {Database: "app", Role: "app"},
+		})
+		assert.Assert(t, strings.Contains(commands[0][3], "NOT r.rolsuper"))
+
+		commands = nil
+		assert.NilError(t, grantConnect(ctx, exec("", &commands), grants))
+		assert.Assert(t, cmp.Contains(commands[0],
+			`--set=grants=[{"database":"app"},{"database":"app","role":"app"}]`))
+
+		commands = nil
+		assert.NilError(t, grantConnect(ctx, exec("", &commands), nil))
+		assert.Equal(t, len(commands), 0)
+	})
+
+	t.Run("Slots", func(t *testing.T) {
+		var commands [][]string
+		assert.NilError(t, dropLogicalSlots(ctx, exec("0\n", &commands)))
+		assert.Assert(t, cmp.Contains(commands[0], "--set=role=_crunchyupgrade"))
+
+		assert.ErrorContains(t, dropLogicalSlots(ctx, exec("2\n", &commands)), "2 replication slots")
+	})
+
+	t.Run("Tables", func(t *testing.T) {
+		var commands [][]string
+		tables, err := unsynchronizedTables(ctx, exec("2\n0\n1\n", &commands),
+			[]logicalDatabase{{Name: "a"}, {Name: "b"}, {Name: "c"}})
+
+		assert.NilError(t, err)
+		assert.Equal(t, tables, 3)
+		assert.DeepEqual(t, commands[0][4:], []string{"tables", "a", "b", "c"})
+	})
+}
+
+func TestSubscribeCommand(t *testing.T) {
+	source := v1beta1.NewPostgresCluster()
+	source.Namespace = "ns1"
+	source.Name = "hippo"
+
+	command := subscribeCommand(source, logicalDatabase{OID: 7, Name: "app"})
+	assert.DeepEqual(t, command[4:], []string{"subscribe",
+		`host='hippo-primary.ns1.svc' port=5432 dbname='app' user='_crunchyupgrade' sslmode=require`,
+		"app", "pgupgrade_7", "pgupgrade", "--exclude-schema=pgbouncer",
+	})
+	assert.Assert(t, strings.Contains(command[3], "set -o pipefail"))
+	assert.Assert(t, strings.Contains(command[3], "--set=ON_ERROR_STOP=1 --single-transaction"))
+
+	command = subscribeCommand(source, logicalDatabase{OID: 5, Name: "postgres"})
+	assert.DeepEqual(t, command[9:], []string{
+		"--exclude-schema=pgbouncer", "--exclude-schema=monitor",
+	})
+}
+
+func TestGenerateSubscribeJob(t *testing.T) {
+	reconciler := &PGUpgradeReconciler{
+		Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).Build(),
+	}
+
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Namespace = "ns1"
+	upgrade.Name = "pgu2"
+	upgrade.Spec.PostgresClusterName = "hippo"
+	upgrade.Spec.ToPostgresVersion = 16
+
+	source := v1beta1.NewPostgresCluster()
+	source.Namespace = "ns1"
+	source.Name = "hippo"
+
+	target := v1beta1.NewPostgresCluster()
+	target.Namespace = "ns1"
+	target.Name = "hippo-pg16"
+
+	primary := &corev1.Pod{}
+	primary.Spec.Containers = []corev1.Container{
+		{Name: "sidecar", Image: "other"},
+		{Name: ContainerDatabase, Image: "postgres:16"},
+	}
+
+	database := logicalDatabase{OID: 7, Name: "app"}
+	job := reconciler.generateSubscribeJob(upgrade, source, target, primary, database)
+
+	assert.Equal(t, job.Name, "pgu2-subscribe-7")
+	assert.Equal(t, job.Labels[LabelRole], logicalSubscribe)
+	assert.Equal(t, job.Labels[LabelCluster], "hippo")
+	assert.Equal(t, len(job.OwnerReferences), 1)
+
+	assert.Equal(t, len(job.Spec.Template.Spec.Containers), 1)
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, container.Image, "postgres:16")
+	assert.DeepEqual(t, container.Command, subscribeCommand(source, database))
+
+	env := map[string]corev1.EnvVar{}
+	for _, e := range container.Env {
+		env[e.Name] = e
+	}
+	assert.Equal(t, env["PGHOST"].Value, "hippo-pg16-primary.ns1.svc")
+	assert.Equal(t, env["PGUSER"].Value, upgradeRole)
+	assert.Equal(t, env["PGPASSWORD"].ValueFrom.SecretKeyRef.Name, "pgu2-pguser")
+}
+
+func TestCopyUserSecrets(t *testing.T) {
+	ctx := context.Background()
+
+	cluster := v1beta1.NewPostgresCluster()
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+
+	user := &corev1.Secret{}
+	user.Namespace = "ns1"
+	user.Name = "hippo-pguser-app"
+	user.Labels = map[string]string{
+		LabelCluster: "hippo", LabelRole: RolePostgresUser, LabelPostgresUser: "app",
+	}
+	user.Data = map[string][]byte{"password": []byte("pw"), "verifier": []byte("v"), "uri": []byte("u")}
+
+	reconciler := &PGUpgradeReconciler{
+		Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).WithObjects(user).Build(),
+	}
+
+	assert.NilError(t, reconciler.copyUserSecrets(ctx, cluster, "hippo-pg16"))
+	assert.NilError(t, reconciler.copyUserSecrets(ctx, cluster, "hippo-pg16"),
+		"expected no error when the Secret exists")
+
+	copied := &corev1.Secret{}
+	assert.NilError(t, reconciler.Client.Get(ctx,
+		client.ObjectKey{Namespace: "ns1", Name: "hippo-pg16-pguser-app"}, copied))
+	assert.DeepEqual(t, copied.Labels, map[string]string{
+		LabelCluster: "hippo-pg16", LabelRole: RolePostgresUser, LabelPostgresUser: "app",
+	})
+	assert.DeepEqual(t, copied.Data, map[string][]byte{"password": []byte("pw"), "verifier": []byte("v")})
+	assert.Equal(t, len(copied.OwnerReferences), 0)
+}
+
+func TestReconcileLogical(t *testing.T) {
+	ctx := context.Background()
+
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Namespace = "ns1"
+	upgrade.Name = "pgu2"
+	upgrade.Spec.PostgresClusterName = "hippo"
+	upgrade.Spec.FromPostgresVersion = 15
+	upgrade.Spec.ToPostgresVersion = 16
+
+	cluster := v1beta1.NewPostgresCluster()
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.Spec.PostgresVersion = 15
+
+	t.Run("NoPrimary", func(t *testing.T) {
+		reconciler := &PGUpgradeReconciler{}
+		upgrade := upgrade.DeepCopy()
+		world := NewWorld()
+		world.Cluster = cluster.DeepCopy()
+
+		_, err := reconciler.reconcileLogical(ctx, upgrade, world)
+		assert.NilError(t, err)
+
+		replicating := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeReplicating)
+		assert.Assert(t, replicating != nil)
+		assert.Equal(t, replicating.Reason, "PGClusterPrimaryNotReady")
+		assert.Equal(t, upgrade.Status.Logical.TargetClusterName, "hippo-pg16")
+	})
+
+	t.Run("CreateTarget", func(t *testing.T) {
+		secret := &corev1.Secret{ObjectMeta: upgradeRoleSecret(upgrade)}
+		secret.Data = map[string][]byte{"password": []byte("pw")}
+
+		// A Job that subscribed a previous target.
+		job := &batchv1.Job{}
+		job.Namespace = "ns1"
+		job.Name = "pgu2-subscribe-7"
+		job.Labels = commonLabels(logicalSubscribe, upgrade)
+
+		var commands []string
+		reconciler := &PGUpgradeReconciler{
+			Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).WithObjects(secret, job).Build(),
+			PodExec: func(
+				_, _, _ string, stdin io.Reader, stdout, _ io.Writer, command ...string,
+			) error {
+				var script []byte
+				if stdin != nil {
+					script, _ = io.ReadAll(stdin)
+				}
+				commands = append(commands, strings.Join(command, " ")+string(script))
+				_, _ = stdout.Write([]byte("0\n"))
+				return nil
+			},
+		}
+		upgrade := upgrade.DeepCopy()
+		upgrade.Status.Logical = &v1beta1.PGUpgradeLogicalStatus{
+			Databases: []string{"app"},
+		}
+
+		primary := &corev1.Pod{}
+		primary.Labels = map[string]string{LabelRole: RolePatroniLeader}
+		primary.Status.Conditions = []corev1.PodCondition{
+			{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+		}
+
+		world := NewWorld()
+		world.Cluster = cluster.DeepCopy()
+		world.Pods = []*corev1.Pod{primary}
+		world.Jobs[job.Name] = job
+
+		result, err := reconciler.reconcileLogical(ctx, upgrade, world)
+		assert.NilError(t, err)
+		assert.Assert(t, result.RequeueAfter > 0)
+
+		// Replication of the previous target is removed from the old cluster.
+		assert.Equal(t, len(commands), 2)
+		assert.Assert(t, strings.Contains(commands[0], "pg_drop_replication_slot"))
+		assert.Assert(t, strings.Contains(commands[1], "DROP ROLE"))
+		assert.Assert(t, upgrade.Status.Logical.Databases == nil)
+		assert.Assert(t, apierrors.IsNotFound(
+			reconciler.Client.Get(ctx, client.ObjectKeyFromObject(job), &batchv1.Job{})))
+
+		target := v1beta1.NewPostgresCluster()
+		assert.NilError(t, reconciler.Client.Get(ctx,
+			client.ObjectKey{Namespace: "ns1", Name: "hippo-pg16"}, target))
+		assert.Equal(t, target.Spec.PostgresVersion, 16)
+
+		replicating := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeReplicating)
+		assert.Assert(t, replicating != nil)
+		assert.Equal(t, replicating.Reason, "PGUpgradeTargetCreated")
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		secret := &corev1.Secret{ObjectMeta: upgradeRoleSecret(upgrade)}
+		secret.Data = map[string][]byte{"password": []byte("pw")}
+
+		target := v1beta1.NewPostgresCluster()
+		target.Namespace = "ns1"
+		target.Name = "hippo-pg16"
+
+		ready := []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		primary := &corev1.Pod{}
+		primary.Namespace = "ns1"
+		primary.Name = "hippo-pg16-abc-0"
+		primary.Labels = map[string]string{LabelCluster: "hippo-pg16", LabelRole: RolePatroniLeader}
+		primary.Spec.Containers = []corev1.Container{{Name: ContainerDatabase, Image: "postgres:16"}}
+		primary.Status.Conditions = ready
+
+		source := &corev1.Pod{}
+		source.Name = "hippo-abc-0"
+		source.Labels = map[string]string{LabelRole: RolePatroniLeader}
+		source.Status.Conditions = ready
+
+		var execs []string
+		var patched []client.Object
+		reconciler := &PGUpgradeReconciler{
+			Client: clientRecordingPatches{
+				Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).
+					WithObjects(secret, target, primary).Build(),
+				patched: &patched,
+			},
+			PodExec: func(
+				_, _, _ string, _ io.Reader, stdout, _ io.Writer, command ...string,
+			) error {
+				execs = append(execs, strings.Join(command, " "))
+				if strings.Contains(strings.Join(command, " "), "aclexplode") {
+					_, _ = stdout.Write([]byte(`[{"database":"app","role":""}]`))
+				} else if strings.Contains(strings.Join(command, " "), "datallowconn") {
+					_, _ = stdout.Write([]byte(`[{"oid":7,"name":"app"}]`))
+				} else {
+					_, _ = stdout.Write([]byte("0\n"))
+				}
+				return nil
+			},
+		}
+
+		upgrade := upgrade.DeepCopy()
+		world := NewWorld()
+		world.Cluster = cluster.DeepCopy()
+		world.Pods = []*corev1.Pod{source}
+
+		result, err := reconciler.reconcileLogical(ctx, upgrade, world)
+		assert.NilError(t, err)
+		assert.Assert(t, result.RequeueAfter == 0, "expected the Job to wake us")
+
+		assert.Equal(t, len(patched), 1)
+		assert.Equal(t, patched[0].GetName(), "pgu2-subscribe-7")
+
+		replicating := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeReplicating)
+		assert.Assert(t, replicating != nil)
+		assert.Equal(t, replicating.Reason, "PGUpgradeSubscribing")
+
+		job := patched[0].(*batchv1.Job).DeepCopy()
+
+		// A failed Job is reported and not replaced.
+		job.Status.Conditions = []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue},
+		}
+		world.Jobs[job.Name] = job
+		patched = nil
+
+		_, err = reconciler.reconcileLogical(ctx, upgrade, world)
+		assert.NilError(t, err)
+		assert.Equal(t, len(patched), 0)
+
+		replicating = meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeReplicating)
+		assert.Equal(t, replicating.Reason, "PGUpgradeSubscribeFailed")
+		assert.Assert(t, strings.Contains(replicating.Message, "pgu2-subscribe-7"))
+
+		// A completed Job means the database is replicating.
+		job.Status.Conditions = []batchv1.JobCondition{
+			{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+		}
+
+		_, err = reconciler.reconcileLogical(ctx, upgrade, world)
+		assert.NilError(t, err)
+		assert.Equal(t, len(patched), 0)
+
+		assert.Assert(t, meta.IsStatusConditionTrue(upgrade.Status.Conditions, ConditionPGUpgradeReplicating))
+		assert.DeepEqual(t, upgrade.Status.Logical.Databases, []string{"app"})
+
+		// Cutover records the CONNECT privileges before revoking them.
+		upgrade.Annotations = map[string]string{AnnotationCutover: "true"}
+		execs = nil
+
+		result, err = reconciler.reconcileLogical(ctx, upgrade, world)
+		assert.NilError(t, err)
+		assert.Assert(t, result.RequeueAfter > 0)
+		assert.DeepEqual(t, upgrade.Status.Logical.RevokedConnect,
+			&v1beta1.PGUpgradeRevokedConnect{Grants: []v1beta1.PGUpgradeConnectGrant{{Database: "app"}}})
+		for _, command := range execs {
+			assert.Assert(t, !strings.Contains(command, "REVOKE"))
+		}
+
+		// Abandoning cutover grants them again.
+		upgrade.Annotations = nil
+		execs = nil
+
+		_, err = reconciler.reconcileLogical(ctx, upgrade, world)
+		assert.NilError(t, err)
+		assert.Assert(t, upgrade.Status.Logical.RevokedConnect == nil)
+		assert.Assert(t, cmp.Contains(execs[len(execs)-1], `--set=grants=[{"database":"app"}]`))
+	})
+}
+
+func TestHandleLogicalDelete(t *testing.T) {
+	ctx := context.Background()
+
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Namespace = "ns1"
+	upgrade.Name = "pgu2"
+	upgrade.Spec.Mode = ModeLogical
+	upgrade.Spec.PostgresClusterName = "hippo"
+	upgrade.Spec.FromPostgresVersion = 15
+	upgrade.Spec.ToPostgresVersion = 16
+
+	t.Run("AddsFinalizer", func(t *testing.T) {
+		upgrade := upgrade.DeepCopy()
+		reconciler := &PGUpgradeReconciler{
+			Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).WithObjects(upgrade).Build(),
+		}
+
+		result, err := reconciler.handleLogicalDelete(ctx, upgrade)
+		assert.NilError(t, err)
+		assert.Assert(t, result == nil)
+
+		assert.NilError(t, reconciler.Client.Get(ctx, client.ObjectKeyFromObject(upgrade), upgrade))
+		assert.DeepEqual(t, upgrade.Finalizers, []string{logicalFinalizer})
+	})
+
+	t.Run("WaitsForPrimary", func(t *testing.T) {
+		cluster := v1beta1.NewPostgresCluster()
+		cluster.Namespace = "ns1"
+		cluster.Name = "hippo"
+
+		upgrade := upgrade.DeepCopy()
+		upgrade.Finalizers = []string{logicalFinalizer}
+		upgrade.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		upgrade.Status.Logical = &v1beta1.PGUpgradeLogicalStatus{}
+
+		reconciler := &PGUpgradeReconciler{
+			Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).WithObjects(upgrade, cluster).Build(),
+		}
+
+		result, err := reconciler.handleLogicalDelete(ctx, upgrade)
+		assert.NilError(t, err)
+		assert.Assert(t, result != nil && result.RequeueAfter > 0)
+
+		progressing := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeProgressing)
+		assert.Assert(t, progressing != nil)
+		assert.Equal(t, progressing.Reason, "PGClusterPrimaryNotReady")
+		assert.DeepEqual(t, upgrade.Finalizers, []string{logicalFinalizer})
+	})
+
+	t.Run("RemovesFinalizer", func(t *testing.T) {
+		upgrade := upgrade.DeepCopy()
+		upgrade.Finalizers = []string{logicalFinalizer}
+		upgrade.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		upgrade.Status.Logical = &v1beta1.PGUpgradeLogicalStatus{}
+
+		reconciler := &PGUpgradeReconciler{
+			Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).WithObjects(upgrade).Build(),
+		}
+
+		// Neither cluster exists, so there is nothing to remove.
+		result, err := reconciler.handleLogicalDelete(ctx, upgrade)
+		assert.NilError(t, err)
+		assert.Assert(t, result != nil && result.RequeueAfter == 0)
+
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(upgrade), upgrade)
+		assert.Assert(t, apierrors.IsNotFound(err) || len(upgrade.Finalizers) == 0)
+	})
+}
@@ -17,6 +17,8 @@ package pgupgrade
 import (
 	"context"
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/pkg/errors"
 	batchv1 "k8s.io/api/batch/v1"
@@ -32,12 +34,21 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/crunchydata/postgres-operator/internal/config"
+	controllerruntime "github.com/crunchydata/postgres-operator/internal/controller/runtime"
 	"github.com/crunchydata/postgres-operator/internal/registration"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 const (
 	AnnotationAllowUpgrade = "postgres-operator.crunchydata.com/allow-upgrade"
+
+	// AnnotationRollback is the annotation that is added to a PGUpgrade to
+	// restore its cluster from the backup taken before the upgrade.
+	AnnotationRollback = "postgres-operator.crunchydata.com/rollback"
+
+	// AnnotationCutover is the annotation that is added to a PGUpgrade in
+	// Logical mode to move connections to the new cluster.
+	AnnotationCutover = "postgres-operator.crunchydata.com/cutover"
 )
 
 // PGUpgradeReconciler reconciles a PGUpgrade object
@@ -45,6 +56,11 @@ type PGUpgradeReconciler struct {
 	Client client.Client
 	Owner  client.FieldOwner
 
+	PodExec func(
+		namespace, pod, container string,
+		stdin io.Reader, stdout, stderr io.Writer, command ...string,
+	) error
+
 	Recorder     record.EventRecorder
 	Registration registration.Registration
 }
@@ -55,6 +71,14 @@ type PGUpgradeReconciler struct {
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *PGUpgradeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.PodExec == nil {
+		var err error
+		r.PodExec, err = controllerruntime.NewPodExecutor(mgr.GetConfig())
+		if err != nil {
+			return err
+		}
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1beta1.PGUpgrade{}).
 		Owns(&batchv1.Job{}).
@@ -67,7 +91,8 @@ func (r *PGUpgradeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 //+kubebuilder:rbac:groups="postgres-operator.crunchydata.com",resources="pgupgrades",verbs={list}
 
-// findUpgradesForPostgresCluster returns PGUpgrades that target cluster.
+// findUpgradesForPostgresCluster returns PGUpgrades that target cluster or
+// that created cluster in Logical mode.
 func (r *PGUpgradeReconciler) findUpgradesForPostgresCluster(
 	ctx context.Context, cluster client.ObjectKey,
 ) []*v1beta1.PGUpgrade {
@@ -84,6 +109,9 @@ func (r *PGUpgradeReconciler) findUpgradesForPostgresCluster(
 		for i := range upgrades.Items {
 			if upgrades.Items[i].Spec.PostgresClusterName == cluster.Name {
 				matching = append(matching, &upgrades.Items[i])
+			} else if logical := upgrades.Items[i].Status.Logical; logical != nil &&
+				logical.TargetClusterName == cluster.Name {
+				matching = append(matching, &upgrades.Items[i])
 			}
 		}
 	}
@@ -161,6 +189,14 @@ func (r *PGUpgradeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// Handle deletion of an upgrade in Logical mode. Return early if it is
+	// being deleted or there was an error.
+	if result, err := r.handleLogicalDelete(ctx, upgrade); err != nil {
+		return ctrl.Result{}, err
+	} else if result != nil {
+		return *result, nil
+	}
+
 	// Validate the remainder of the upgrade specification. These can likely
 	// move to CEL rules or a webhook when supported.
 
@@ -172,7 +208,28 @@ func (r *PGUpgradeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// the succeeded condition and remove upgrade and removedata jobs.
 	succeeded := meta.FindStatusCondition(upgrade.Status.Conditions,
 		ConditionPGUpgradeSucceeded)
-	if succeeded != nil && succeeded.Reason == "PGUpgradeSucceeded" {
+	upgraded := succeeded != nil && succeeded.Reason == "PGUpgradeSucceeded"
+	if upgraded && postUpgradeFinished(upgrade) &&
+		upgrade.GetAnnotations()[AnnotationRollback] == "" {
+		return
+	}
+
+	// Exit once a rollback has finished.
+	if meta.IsStatusConditionTrue(upgrade.Status.Conditions, ConditionPGUpgradeRolledBack) {
+		return
+	}
+
+	// Likewise, exit if a check has already finished. Create a new PGUpgrade
+	// to check again.
+	checking := upgrade.Spec.Mode == ModeCheck
+	if checking && meta.FindStatusCondition(upgrade.Status.Conditions,
+		ConditionPGUpgradeCompatible) != nil {
+		return
+	}
+
+	// Likewise, exit after a logical upgrade has moved connections.
+	logical := upgrade.Spec.Mode == ModeLogical
+	if logical && upgrade.Status.Logical != nil && upgrade.Status.Logical.CutoverTime != nil {
 		return
 	}
 
@@ -254,6 +311,18 @@ func (r *PGUpgradeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	setStatusToProgressingIfReasonWas("PGClusterNotFound", upgrade)
 
+	// Restore the cluster from its backup when the upgrade failed or when
+	// asked. This also replaces any remaining post-upgrade steps.
+	if !checking && !logical && rollbackRequested(upgrade, world) {
+		return r.reconcileRollback(ctx, upgrade, world)
+	}
+
+	// Once the upgrade has succeeded, the remaining steps happen while the
+	// cluster is running the new version.
+	if upgraded {
+		return r.reconcilePostUpgrade(ctx, upgrade, world)
+	}
+
 	// Get the spec version to check if this cluster is at the requested version
 	version := int64(world.Cluster.Spec.PostgresVersion)
 
@@ -277,7 +346,8 @@ func (r *PGUpgradeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			}
 		}
 	}
-	removeDataJobsComplete := len(removeDataJobsCompleted) == world.ReplicasExpected
+	removeDataJobsComplete := keepsOldData(upgrade) ||
+		len(removeDataJobsCompleted) == world.ReplicasExpected
 
 	// If the PostgresCluster is already set to the desired version, but the upgradejob has
 	// not completed successfully, the operator assumes that the cluster is already
@@ -322,18 +392,52 @@ func (r *PGUpgradeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 					"PostgresCluster %s is ready to complete upgrade to version %d",
 					upgrade.Spec.PostgresClusterName, upgrade.Spec.ToPostgresVersion),
 			})
+
+			// Mark the post-upgrade steps as pending so that they run.
+			for _, step := range postUpgradeSteps() {
+				meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+					ObservedGeneration: upgrade.Generation,
+					Type:               step.condition,
+					Status:             metav1.ConditionFalse,
+					Reason:             "PGUpgradePostUpgradePending",
+					Message:            "Waiting for the upgraded primary",
+				})
+			}
 		}
 
 		return ctrl.Result{}, nil
 	}
 
+	// Take a backup before the cluster is shut down, when asked. The backup
+	// patches the cluster, so it also requires the annotation checked below.
+	if upgrade.Spec.Backup != nil && !checking && !logical && !upgradeJobComplete {
+		if allowed := world.Cluster.GetAnnotations()[AnnotationAllowUpgrade] == upgrade.Name; !allowed {
+			meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+				ObservedGeneration: upgrade.Generation,
+				Type:               ConditionPGUpgradeProgressing,
+				Status:             metav1.ConditionFalse,
+				Reason:             "PGClusterMissingRequiredAnnotation",
+				Message: fmt.Sprintf(
+					"PostgresCluster %s lacks annotation for upgrade %s",
+					upgrade.Spec.PostgresClusterName, upgrade.GetName()),
+			})
+
+			return ctrl.Result{}, nil
+		}
+
+		if done, err := r.reconcileBackup(ctx, upgrade, world); !done || err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	// The upgrade needs to manipulate the data directory of the primary while
 	// Postgres is stopped. Wait until all instances are gone and the primary
 	// is identified.
 	//
 	// Requiring the cluster be shutdown also provides some assurance that the
-	// user understands downtime requirement of upgrading
-	if !world.ClusterShutdown {
+	// user understands downtime requirement of upgrading. A check does not
+	// touch the cluster, and a logical upgrade copies it while it is running.
+	if !world.ClusterShutdown && !checking && !logical {
 		meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
 			ObservedGeneration: upgrade.Generation,
 			Type:               ConditionPGUpgradeProgressing,
@@ -349,7 +453,7 @@ func (r *PGUpgradeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	// A separate check for primary identification allows for cases where the
 	// PostgresCluster may not have been initialized properly.
-	if world.ClusterPrimary == nil {
+	if world.ClusterPrimary == nil && !checking && !logical {
 		meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
 			ObservedGeneration: upgrade.Generation,
 			Type:               ConditionPGUpgradeProgressing,
@@ -404,6 +508,22 @@ func (r *PGUpgradeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	setStatusToProgressingIfReasonWas("PGClusterMissingRequiredAnnotation", upgrade)
 
+	// A check runs against copies of the cluster volumes and ends there.
+	if checking {
+		return r.reconcileCheck(ctx, upgrade, world)
+	}
+
+	// A logical upgrade creates and replicates to a new cluster.
+	if logical {
+		return r.reconcileLogical(ctx, upgrade, world)
+	}
+
+	// Record the progress of each step and keep the output of finished jobs.
+	var upgradeJobRunning bool
+	if upgradeJobRunning, err = r.reconcileSteps(ctx, upgrade, world); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Currently our jobs are set to only run once, so if any job has failed, the
 	// upgrade has failed.
 	if upgradeJobFailed || removeDataJobsFailed {
@@ -412,7 +532,9 @@ func (r *PGUpgradeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			Type:               ConditionPGUpgradeSucceeded,
 			Status:             metav1.ConditionFalse,
 			Reason:             "PGUpgradeFailed",
-			Message:            "Upgrade jobs failed, please check individual pod logs",
+			Message: fmt.Sprintf(
+				"Upgrade jobs failed, please check ConfigMap %s or individual pod logs",
+				pgUpgradeLogs(upgrade).Name),
 		})
 
 		return ctrl.Result{}, nil
@@ -467,7 +589,7 @@ func (r *PGUpgradeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	}
 
 	// Create the jobs to remove the data from the replicas, as long as
-	// the upgrade job has completed.
+	// the upgrade job has completed and the old data is not being kept.
 	// (When the cluster is not shutdown, the `world.ClusterReplicas` will be [],
 	// so there should be no danger of accidentally targeting the primary.)
 	if err == nil && upgradeJobComplete && !removeDataJobsComplete {
@@ -503,6 +625,11 @@ func (r *PGUpgradeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// TODO: consider what it means to "re-use" the same PGUpgrade for more than
 	// one postgres version. Should the job name include the version number?
 
+	// Check on the steps of a running upgrade job.
+	if err == nil && upgradeJobRunning {
+		result.RequeueAfter = 10 * time.Second
+	}
+
 	log.Info("Reconciled", "requeue", err != nil ||
 		result.Requeue ||
 		result.RequeueAfter > 0)
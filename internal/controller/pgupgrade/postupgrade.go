@@ -0,0 +1,294 @@
+// Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgupgrade
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	pgpassword "github.com/crunchydata/postgres-operator/internal/postgres/password"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// postUpgradeStep is one of the steps that follow a successful upgrade. Each
+// runs in its own Job and reports in its own condition.
+type postUpgradeStep struct {
+	role, condition, done string
+	command               []string
+}
+
+// postUpgradeSteps returns the steps that follow a successful upgrade in the
+// order they run. The first applies the extension updates that pg_upgrade
+// writes to the directory where it ran; the script is renamed after it
+// succeeds so that it runs only once. The second generates optimizer
+// statistics in every database; its first stages are quick and coarse so
+// that queries can be planned sooner.
+// - https://www.postgresql.org/docs/current/pgupgrade.html
+// - https://www.postgresql.org/docs/current/app-vacuumdb.html
+func postUpgradeSteps() []postUpgradeStep {
+	const extensions = `
+declare -r file=/pgdata/update_extensions.sql
+if [[ -f "${file}" ]]; then
+	psql -Xw --set=ON_ERROR_STOP=1 --file="${file}"
+	mv "${file}" "${file}.done"
+fi
+`
+	return []postUpgradeStep{{
+		role:      postUpgradeExtensions,
+		condition: ConditionPGUpgradeExtensionsUpdated,
+		done:      "Extensions updated",
+		command:   []string{"bash", "-ceu", "--", extensions, "extensions"},
+	}, {
+		role:      postUpgradeAnalyze,
+		condition: ConditionPGUpgradeAnalyzed,
+		done:      "Optimizer statistics generated",
+		command:   []string{"vacuumdb", "--all", "--analyze-in-stages"},
+	}}
+}
+
+// postUpgradeFinished returns true when every post-upgrade step of upgrade
+// has succeeded. An upgrade that succeeded before these steps existed has
+// none of their conditions; it is finished as well.
+func postUpgradeFinished(upgrade *v1beta1.PGUpgrade) bool {
+	finished, started := true, false
+	for _, step := range postUpgradeSteps() {
+		condition := meta.FindStatusCondition(upgrade.Status.Conditions, step.condition)
+		finished = finished && condition != nil && condition.Status == metav1.ConditionTrue
+		started = started || condition != nil
+	}
+	return finished || !started
+}
+
+// pgUpgradePostJob returns the ObjectMeta of the Job that runs step after
+// upgrade succeeds.
+func pgUpgradePostJob(upgrade *v1beta1.PGUpgrade, step postUpgradeStep) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: upgrade.Namespace,
+		Name:      upgrade.Name + "-" + strings.TrimPrefix(step.role, pgUpgrade+"-"),
+	}
+}
+
+// generatePostUpgradeJob returns a Job that runs step against the primary of
+// cluster. The Job connects through the primary Service as [upgradeRole]. It
+// mounts the volumes of leader, the StatefulSet of the primary, and runs on
+// the same node so that it can read what pg_upgrade left in the data
+// directory.
+func (r *PGUpgradeReconciler) generatePostUpgradeJob(
+	upgrade *v1beta1.PGUpgrade, cluster *v1beta1.PostgresCluster,
+	leader *appsv1.StatefulSet, step postUpgradeStep,
+) *batchv1.Job {
+	job := &batchv1.Job{ObjectMeta: pgUpgradePostJob(upgrade, step)}
+	job.SetGroupVersionKind(batchv1.SchemeGroupVersion.WithKind("Job"))
+
+	job.Annotations = upgrade.Spec.Metadata.GetAnnotationsOrNil()
+	job.Labels = Merge(upgrade.Spec.Metadata.GetLabelsOrNil(),
+		commonLabels(step.role, upgrade),
+		map[string]string{
+			LabelVersion: fmt.Sprint(upgrade.Spec.ToPostgresVersion),
+		})
+
+	// Find the database container.
+	var database *corev1.Container
+	for i := range leader.Spec.Template.Spec.Containers {
+		container := leader.Spec.Template.Spec.Containers[i]
+		if container.Name == ContainerDatabase {
+			database = &container
+		}
+	}
+
+	// Copy the pod template from the leader StatefulSet. This includes the
+	// service account, volumes, image pull secrets, and tolerations.
+	leader.Spec.Template.DeepCopyInto(&job.Spec.Template)
+
+	// Use the same labels and annotations as the job.
+	job.Spec.Template.ObjectMeta = metav1.ObjectMeta{
+		Annotations: job.Annotations,
+		Labels:      job.Labels,
+	}
+
+	// Retry a few times before reporting the step failed.
+	job.Spec.BackoffLimit = initialize.Int32(2)
+	job.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyNever
+
+	// Replace all containers with one that runs the step using the binaries
+	// of the database container.
+	job.Spec.Template.Spec.EphemeralContainers = nil
+	job.Spec.Template.Spec.InitContainers = nil
+	job.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name:            database.Name,
+		SecurityContext: database.SecurityContext,
+		VolumeMounts:    database.VolumeMounts,
+
+		Command:                  step.command,
+		Env:                      upgradeRoleEnvironment(upgrade, cluster),
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		Image:                    database.Image,
+		ImagePullPolicy:          database.ImagePullPolicy,
+		Resources:                upgrade.Spec.Resources,
+	}}
+
+	// Run next to the primary so that a volume that can be attached to only
+	// one node is available.
+	job.Spec.Template.Spec.Affinity = &corev1.Affinity{
+		PodAffinity: &corev1.PodAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						LabelCluster:  cluster.Name,
+						LabelInstance: leader.Name,
+					},
+				},
+				TopologyKey: corev1.LabelHostname,
+			}},
+		},
+	}
+	job.Spec.Template.Spec.PriorityClassName = initialize.FromPointer(
+		upgrade.Spec.PriorityClassName)
+
+	r.setControllerReference(upgrade, job)
+	return job
+}
+
+// dropUpgradeRole drops [upgradeRole] from a cluster.
+func dropUpgradeRole(ctx context.Context, exec postgres.Executor) error {
+	var stderr bytes.Buffer
+	err := exec(ctx, strings.NewReader(`DROP ROLE IF EXISTS :"role";`), io.Discard, &stderr,
+		"psql", "-Xw", "--quiet", "--set=ON_ERROR_STOP=1", "--set=role="+upgradeRole, "--file=-")
+	if err != nil {
+		err = errors.WithMessage(err, stderr.String())
+	}
+	return err
+}
+
+//+kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+//+kubebuilder:rbac:groups="",resources="secrets",verbs={get,create,patch}
+//+kubebuilder:rbac:groups="batch",resources="jobs",verbs={create,patch}
+
+// reconcilePostUpgrade runs the steps that follow a successful upgrade once
+// the upgraded primary is ready: it applies the extension updates prepared by
+// pg_upgrade and then generates optimizer statistics. Each step runs in a Job
+// and has its own condition. [upgradeRole] exists only while a Job runs.
+func (r *PGUpgradeReconciler) reconcilePostUpgrade(
+	ctx context.Context, upgrade *v1beta1.PGUpgrade, world *World,
+) (ctrl.Result, error) {
+	// Find the primary running the new version.
+	var primary *corev1.Pod
+	if world.Cluster.Spec.PostgresVersion == upgrade.Spec.ToPostgresVersion &&
+		world.ClusterLeader != nil {
+		primary = readyPrimary(world.Pods)
+	}
+
+	for _, step := range postUpgradeSteps() {
+		if meta.IsStatusConditionTrue(upgrade.Status.Conditions, step.condition) {
+			continue
+		}
+
+		if primary == nil {
+			meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+				ObservedGeneration: upgrade.Generation,
+				Type:               step.condition,
+				Status:             metav1.ConditionFalse,
+				Reason:             "PGClusterPrimaryNotReady",
+				Message: fmt.Sprintf(
+					"Waiting for PostgresCluster %s to run version %d",
+					upgrade.Spec.PostgresClusterName, upgrade.Spec.ToPostgresVersion),
+			})
+			continue
+		}
+
+		ctx := logging.NewContext(ctx, logging.FromContext(ctx).WithValues("pod", primary.Name))
+		exec := r.podExecutor(primary)
+		job := world.Jobs[pgUpgradePostJob(upgrade, step).Name]
+
+		switch {
+		case job != nil && jobCompleted(job):
+			if err := dropUpgradeRole(ctx, exec); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+				ObservedGeneration: upgrade.Generation,
+				Type:               step.condition,
+				Status:             metav1.ConditionTrue,
+				Reason:             "PGUpgradePostUpgradeSucceeded",
+				Message:            step.done,
+			})
+			continue
+
+		case job != nil && jobFailed(job):
+			// Remove the role until someone deletes the Job to try again.
+			if err := dropUpgradeRole(ctx, exec); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+				ObservedGeneration: upgrade.Generation,
+				Type:               step.condition,
+				Status:             metav1.ConditionFalse,
+				Reason:             "PGUpgradePostUpgradeFailed",
+				Message: fmt.Sprintf(
+					"Job %s failed; check its pod logs and delete it to try again", job.Name),
+			})
+
+		case job == nil:
+			password, err := r.reconcileUpgradeRoleSecret(ctx, upgrade)
+
+			var verifier string
+			if err == nil {
+				verifier, err = pgpassword.NewSCRAMPassword(password).Build()
+				err = errors.WithStack(err)
+			}
+			if err == nil {
+				err = createUpgradeRole(ctx, exec, verifier)
+			}
+			if err == nil {
+				err = errors.WithStack(r.apply(ctx,
+					r.generatePostUpgradeJob(upgrade, world.Cluster, world.ClusterLeader, step)))
+			}
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			fallthrough
+
+		default:
+			meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+				ObservedGeneration: upgrade.Generation,
+				Type:               step.condition,
+				Status:             metav1.ConditionFalse,
+				Reason:             "PGUpgradePostUpgradeRunning",
+				Message:            fmt.Sprintf("Job %s is running", pgUpgradePostJob(upgrade, step).Name),
+			})
+		}
+
+		// Steps run one at a time; the Job wakes us when it finishes.
+		return ctrl.Result{}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,328 @@
+// Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgupgrade
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/controller/runtime"
+	"github.com/crunchydata/postgres-operator/internal/testing/require"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestPostUpgradeSteps(t *testing.T) {
+	shellcheck := require.ShellCheck(t)
+	steps := postUpgradeSteps()
+
+	// Extensions are updated before statistics are generated.
+	assert.Equal(t, len(steps), 2)
+	assert.Equal(t, steps[0].condition, ConditionPGUpgradeExtensionsUpdated)
+	assert.Equal(t, steps[1].condition, ConditionPGUpgradeAnalyzed)
+	assert.DeepEqual(t, steps[1].command, []string{"vacuumdb", "--all", "--analyze-in-stages"})
+
+	// Expect shellcheck to be happy with the script.
+	command := steps[0].command
+	assert.DeepEqual(t, command[:3], []string{"bash", "-ceu", "--"})
+
+	file := filepath.Join(t.TempDir(), "script.bash")
+	assert.NilError(t, os.WriteFile(file, []byte(command[3]), 0o600))
+
+	cmd := exec.Command(shellcheck, "--enable=all", "--shell=bash", file)
+	output, err := cmd.CombinedOutput()
+	assert.NilError(t, err, "%q\n%s", cmd.Args, output)
+}
+
+func TestPostUpgradeFinished(t *testing.T) {
+	upgrade := &v1beta1.PGUpgrade{}
+
+	// An upgrade without post-upgrade conditions succeeded before they existed.
+	assert.Assert(t, postUpgradeFinished(upgrade))
+
+	for _, step := range postUpgradeSteps() {
+		meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+			Type: step.condition, Status: metav1.ConditionFalse, Reason: "PGUpgradePostUpgradePending",
+		})
+	}
+	assert.Assert(t, !postUpgradeFinished(upgrade))
+
+	meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+		Type: ConditionPGUpgradeExtensionsUpdated, Status: metav1.ConditionTrue, Reason: "x",
+	})
+	assert.Assert(t, !postUpgradeFinished(upgrade))
+
+	meta.SetStatusCondition(&upgrade.Status.Conditions, metav1.Condition{
+		Type: ConditionPGUpgradeAnalyzed, Status: metav1.ConditionTrue, Reason: "x",
+	})
+	assert.Assert(t, postUpgradeFinished(upgrade))
+}
+
+func TestGeneratePostUpgradeJob(t *testing.T) {
+	reconciler := &PGUpgradeReconciler{
+		Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).Build(),
+	}
+
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Namespace = "ns1"
+	upgrade.Name = "pgu1"
+	upgrade.Spec.PostgresClusterName = "pg5"
+	upgrade.Spec.ToPostgresVersion = 16
+
+	cluster := v1beta1.NewPostgresCluster()
+	cluster.Namespace = "ns1"
+	cluster.Name = "pg5"
+
+	leader := &appsv1.StatefulSet{}
+	leader.Name = "pg5-abc"
+	leader.Spec.Template.Spec.ServiceAccountName = "pg5-instance"
+	leader.Spec.Template.Spec.Containers = []corev1.Container{
+		{Name: "sidecar", Image: "other"},
+		{
+			Name: ContainerDatabase, Image: "postgres:16",
+			VolumeMounts: []corev1.VolumeMount{{Name: "postgres-data", MountPath: "/pgdata"}},
+		},
+	}
+
+	step := postUpgradeSteps()[1]
+	job := reconciler.generatePostUpgradeJob(upgrade, cluster, leader, step)
+
+	assert.Equal(t, job.Name, "pgu1-analyze")
+	assert.Equal(t, job.Labels[LabelRole], postUpgradeAnalyze)
+	assert.Equal(t, job.Labels[LabelCluster], "pg5")
+	assert.Equal(t, len(job.OwnerReferences), 1)
+	assert.Equal(t, job.Spec.Template.Spec.ServiceAccountName, "pg5-instance")
+
+	assert.Equal(t, len(job.Spec.Template.Spec.Containers), 1)
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, container.Image, "postgres:16")
+	assert.DeepEqual(t, container.Command, step.command)
+	assert.Equal(t, container.VolumeMounts[0].MountPath, "/pgdata")
+
+	env := map[string]corev1.EnvVar{}
+	for _, e := range container.Env {
+		env[e.Name] = e
+	}
+	assert.Equal(t, env["PGHOST"].Value, "pg5-primary.ns1.svc")
+	assert.Equal(t, env["PGPORT"].Value, "5432")
+	assert.Equal(t, env["PGUSER"].Value, upgradeRole)
+	assert.Equal(t, env["PGSSLMODE"].Value, "require")
+	assert.Equal(t, env["PGPASSWORD"].ValueFrom.SecretKeyRef.Name, "pgu1-pguser")
+
+	// The Job runs on the node of the primary.
+	terms := job.Spec.Template.Spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	assert.Equal(t, len(terms), 1)
+	assert.Equal(t, terms[0].TopologyKey, corev1.LabelHostname)
+	assert.DeepEqual(t, terms[0].LabelSelector.MatchLabels, map[string]string{
+		LabelCluster: "pg5", LabelInstance: "pg5-abc",
+	})
+}
+
+// clientRecordingPatches records the objects that are patched rather than
+// sending them. The fake client cannot apply.
+type clientRecordingPatches struct {
+	client.Client
+	patched *[]client.Object
+}
+
+func (c clientRecordingPatches) Patch(
+	_ context.Context, object client.Object, _ client.Patch, _ ...client.PatchOption,
+) error {
+	*c.patched = append(*c.patched, object)
+	return nil
+}
+
+func TestReconcilePostUpgrade(t *testing.T) {
+	ctx := context.Background()
+
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Namespace = "ns1"
+	upgrade.Name = "pgu1"
+	upgrade.Spec.PostgresClusterName = "pg5"
+	upgrade.Spec.ToPostgresVersion = 16
+
+	cluster := v1beta1.NewPostgresCluster()
+	cluster.Namespace = "ns1"
+	cluster.Name = "pg5"
+	cluster.Spec.PostgresVersion = 16
+
+	leader := &appsv1.StatefulSet{}
+	leader.Name = "pg5-abc"
+	leader.Spec.Template.Spec.Containers = []corev1.Container{{Name: ContainerDatabase}}
+
+	primary := &corev1.Pod{}
+	primary.Namespace = "ns1"
+	primary.Name = "pg5-abc-0"
+	primary.Labels = map[string]string{LabelRole: RolePatroniLeader}
+	primary.Status.Conditions = []corev1.PodCondition{
+		{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+	}
+
+	secret := &corev1.Secret{ObjectMeta: upgradeRoleSecret(upgrade)}
+	secret.Data = map[string][]byte{"password": []byte("pw")}
+
+	finished := func(job *batchv1.Job, condition batchv1.JobConditionType) *batchv1.Job {
+		job = job.DeepCopy()
+		job.Status.Conditions = []batchv1.JobCondition{
+			{Type: condition, Status: corev1.ConditionTrue},
+		}
+		return job
+	}
+
+	newReconciler := func(commands *[]string, patched *[]client.Object) *PGUpgradeReconciler {
+		return &PGUpgradeReconciler{
+			Client: clientRecordingPatches{
+				Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).
+					WithObjects(secret.DeepCopy()).Build(),
+				patched: patched,
+			},
+			PodExec: func(
+				namespace, pod, container string,
+				stdin io.Reader, _, _ io.Writer, command ...string,
+			) error {
+				assert.Equal(t, namespace, "ns1")
+				assert.Equal(t, pod, "pg5-abc-0")
+				assert.Equal(t, container, "database")
+
+				b, _ := io.ReadAll(stdin)
+				*commands = append(*commands, string(b))
+				return nil
+			},
+		}
+	}
+
+	t.Run("NotReady", func(t *testing.T) {
+		reconciler := &PGUpgradeReconciler{PodExec: func(
+			string, string, string, io.Reader, io.Writer, io.Writer, ...string,
+		) error {
+			t.Fatal("expected no exec")
+			return nil
+		}}
+
+		upgrade := upgrade.DeepCopy()
+		world := NewWorld()
+		world.Cluster = cluster.DeepCopy()
+		world.Cluster.Spec.PostgresVersion = 15
+		world.ClusterLeader = leader
+		world.Pods = []*corev1.Pod{primary}
+
+		_, err := reconciler.reconcilePostUpgrade(ctx, upgrade, world)
+		assert.NilError(t, err)
+		assert.Assert(t, !postUpgradeFinished(upgrade))
+
+		analyzed := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeAnalyzed)
+		assert.Assert(t, analyzed != nil)
+		assert.Equal(t, analyzed.Status, metav1.ConditionFalse)
+		assert.Equal(t, analyzed.Reason, "PGClusterPrimaryNotReady")
+	})
+
+	t.Run("Steps", func(t *testing.T) {
+		var commands []string
+		var patched []client.Object
+		reconciler := newReconciler(&commands, &patched)
+
+		upgrade := upgrade.DeepCopy()
+		world := NewWorld()
+		world.Cluster = cluster
+		world.ClusterLeader = leader
+		world.Pods = []*corev1.Pod{primary}
+
+		// The first step creates the role and its Job.
+		_, err := reconciler.reconcilePostUpgrade(ctx, upgrade, world)
+		assert.NilError(t, err)
+		assert.Equal(t, len(commands), 1)
+		assert.Assert(t, strings.Contains(commands[0], "ALTER ROLE"))
+		assert.Equal(t, len(patched), 1)
+		assert.Equal(t, patched[0].GetName(), "pgu1-extensions")
+
+		extensions := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeExtensionsUpdated)
+		assert.Assert(t, extensions != nil)
+		assert.Equal(t, extensions.Reason, "PGUpgradePostUpgradeRunning")
+		assert.Assert(t, meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeAnalyzed) == nil)
+
+		// Nothing more happens while the Job runs.
+		world.Jobs["pgu1-extensions"] = patched[0].(*batchv1.Job)
+		commands, patched = nil, nil
+
+		_, err = reconciler.reconcilePostUpgrade(ctx, upgrade, world)
+		assert.NilError(t, err)
+		assert.Equal(t, len(commands), 0)
+		assert.Equal(t, len(patched), 0)
+
+		// When the Job completes, the role is dropped and the next step starts.
+		world.Jobs["pgu1-extensions"] = finished(world.Jobs["pgu1-extensions"], batchv1.JobComplete)
+
+		_, err = reconciler.reconcilePostUpgrade(ctx, upgrade, world)
+		assert.NilError(t, err)
+		assert.Equal(t, len(commands), 2)
+		assert.Assert(t, strings.Contains(commands[0], "DROP ROLE"))
+		assert.Assert(t, strings.Contains(commands[1], "ALTER ROLE"))
+		assert.Equal(t, len(patched), 1)
+		assert.Equal(t, patched[0].GetName(), "pgu1-analyze")
+		assert.Assert(t, meta.IsStatusConditionTrue(upgrade.Status.Conditions,
+			ConditionPGUpgradeExtensionsUpdated))
+
+		world.Jobs["pgu1-analyze"] = finished(patched[0].(*batchv1.Job), batchv1.JobComplete)
+		commands, patched = nil, nil
+
+		_, err = reconciler.reconcilePostUpgrade(ctx, upgrade, world)
+		assert.NilError(t, err)
+		assert.Assert(t, postUpgradeFinished(upgrade))
+		assert.Equal(t, len(commands), 1)
+		assert.Assert(t, strings.Contains(commands[0], "DROP ROLE"))
+		assert.Equal(t, len(patched), 0)
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		var commands []string
+		var patched []client.Object
+		reconciler := newReconciler(&commands, &patched)
+
+		upgrade := upgrade.DeepCopy()
+		world := NewWorld()
+		world.Cluster = cluster
+		world.ClusterLeader = leader
+		world.Pods = []*corev1.Pod{primary}
+		world.Jobs["pgu1-extensions"] = finished(&batchv1.Job{}, batchv1.JobFailed)
+
+		// The role is dropped while the failed Job waits to be deleted.
+		_, err := reconciler.reconcilePostUpgrade(ctx, upgrade, world)
+		assert.NilError(t, err)
+		assert.Equal(t, len(commands), 1)
+		assert.Assert(t, strings.Contains(commands[0], "DROP ROLE"))
+		assert.Equal(t, len(patched), 0)
+
+		extensions := meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeExtensionsUpdated)
+		assert.Assert(t, extensions != nil)
+		assert.Equal(t, extensions.Status, metav1.ConditionFalse)
+		assert.Equal(t, extensions.Reason, "PGUpgradePostUpgradeFailed")
+
+		// Later steps wait.
+		assert.Assert(t, meta.FindStatusCondition(upgrade.Status.Conditions, ConditionPGUpgradeAnalyzed) == nil)
+	})
+}
@@ -0,0 +1,219 @@
+// Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgupgrade
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+const (
+	stepRunning   = "Running"
+	stepSucceeded = "Succeeded"
+	stepFailed    = "Failed"
+)
+
+// pgUpgradeLogs returns the ObjectMeta for the ConfigMap that keeps the output
+// of the finished Jobs of upgrade.
+func pgUpgradeLogs(upgrade *v1beta1.PGUpgrade) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: upgrade.Namespace,
+		Name:      upgrade.Name + "-logs",
+	}
+}
+
+// jobTermination returns the terminated state of the container of job, if any.
+func jobTermination(job *batchv1.Job, pods []*corev1.Pod) *corev1.ContainerStateTerminated {
+	for _, pod := range pods {
+		if !metav1.IsControlledBy(pod, job) {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated != nil {
+				return status.State.Terminated
+			}
+		}
+	}
+	return nil
+}
+
+// upgradeSteps parses the steps in report. See [upgradeProgressScript]. When
+// report has an exit status, the last step finished at finished. Otherwise,
+// the last step is still running.
+func upgradeSteps(report string, finished *metav1.Time) []v1beta1.PGUpgradeStepStatus {
+	var steps []v1beta1.PGUpgradeStepStatus
+	var exited bool
+	var status string
+
+	for _, line := range strings.Split(report, "\n") {
+		if fields := strings.Fields(line); len(fields) == 3 && fields[0] == "step:" {
+			var started *metav1.Time
+			if t, err := time.Parse(time.RFC3339, fields[2]); err == nil {
+				started = &metav1.Time{Time: t}
+			}
+			if n := len(steps); n > 0 {
+				steps[n-1].State = stepSucceeded
+				steps[n-1].CompletionTime = started
+			}
+			steps = append(steps, v1beta1.PGUpgradeStepStatus{
+				Name: fields[1], State: stepRunning, StartTime: started,
+			})
+		}
+		if strings.HasPrefix(line, "status: ") {
+			exited, status = true, strings.TrimPrefix(line, "status: ")
+		}
+	}
+
+	if n := len(steps); n > 0 && exited {
+		steps[n-1].CompletionTime = finished
+		steps[n-1].State = stepSucceeded
+		if status != "0" {
+			steps[n-1].State = stepFailed
+		}
+	}
+	return steps
+}
+
+// mergeSteps returns recorded with each of steps in place of the recorded
+// step of the same name. Steps that are not yet recorded go at the end.
+func mergeSteps(recorded, steps []v1beta1.PGUpgradeStepStatus) []v1beta1.PGUpgradeStepStatus {
+	merged := append([]v1beta1.PGUpgradeStepStatus(nil), recorded...)
+
+	for _, step := range steps {
+		found := false
+		for i := range merged {
+			if merged[i].Name == step.Name {
+				merged[i], found = step, true
+			}
+		}
+		if !found {
+			merged = append(merged, step)
+		}
+	}
+	return merged
+}
+
+// removeDataStep returns the step of a Job that removes data from a replica.
+func removeDataStep(upgrade *v1beta1.PGUpgrade, job *batchv1.Job) v1beta1.PGUpgradeStepStatus {
+	step := v1beta1.PGUpgradeStepStatus{
+		Name:      "remove-data-" + strings.TrimPrefix(job.Name, upgrade.Name+"-"),
+		State:     stepRunning,
+		StartTime: job.Status.StartTime,
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Status == corev1.ConditionTrue &&
+			(condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) {
+			step.CompletionTime = condition.LastTransitionTime.DeepCopy()
+		}
+	}
+	if jobCompleted(job) {
+		step.State = stepSucceeded
+	}
+	if jobFailed(job) {
+		step.State = stepFailed
+	}
+	return step
+}
+
+//+kubebuilder:rbac:groups="",resources="configmaps",verbs={create,patch}
+//+kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+
+// reconcileSteps records the progress of each step of upgrade in its status.
+// The output of finished Jobs is kept in a ConfigMap that outlives them.
+// It returns true while the upgrade Job is running.
+func (r *PGUpgradeReconciler) reconcileSteps(
+	ctx context.Context, upgrade *v1beta1.PGUpgrade, world *World,
+) (bool, error) {
+	var running bool
+	var steps []v1beta1.PGUpgradeStepStatus
+	reports := make(map[string]string)
+
+	if job := world.Jobs[pgUpgradeJob(upgrade).Name]; job != nil {
+		terminated := jobTermination(job, world.Pods)
+		finished := jobCompleted(job) || jobFailed(job)
+
+		switch {
+		case finished && terminated != nil:
+			reports[job.Name] = terminated.Message
+			steps = append(steps, upgradeSteps(terminated.Message, &terminated.FinishedAt)...)
+
+		case finished:
+			// The Pod of the Job is gone; keep the steps already recorded.
+
+		default:
+			running = true
+
+			// Read the steps so far from the running container.
+			for _, pod := range world.Pods {
+				if metav1.IsControlledBy(pod, job) && pod.Status.Phase == corev1.PodRunning {
+					var stdout, stderr bytes.Buffer
+					err := r.PodExec(pod.Namespace, pod.Name, ContainerDatabase,
+						nil, &stdout, &stderr, "cat", upgradeProgressFile)
+					if err != nil {
+						logging.FromContext(ctx).V(1).Info("Unable to read upgrade progress",
+							"pod", pod.Name, "error", err.Error(), "stderr", stderr.String())
+					}
+					steps = append(steps, upgradeSteps(stdout.String(), nil)...)
+				}
+			}
+		}
+	}
+
+	var removals []*batchv1.Job
+	for _, job := range world.Jobs {
+		if job.GetLabels()[LabelRole] == removeData {
+			removals = append(removals, job)
+		}
+	}
+	sort.Slice(removals, func(i, j int) bool { return removals[i].Name < removals[j].Name })
+
+	for _, job := range removals {
+		steps = append(steps, removeDataStep(upgrade, job))
+
+		if terminated := jobTermination(job, world.Pods); terminated != nil && jobFailed(job) {
+			reports[job.Name] = terminated.Message
+		}
+	}
+
+	// Keep steps that have been recorded when their Job is gone.
+	upgrade.Status.Steps = mergeSteps(upgrade.Status.Steps, steps)
+
+	var err error
+	if len(reports) > 0 {
+		configmap := &corev1.ConfigMap{ObjectMeta: pgUpgradeLogs(upgrade)}
+		configmap.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+
+		configmap.Annotations = upgrade.Spec.Metadata.GetAnnotationsOrNil()
+		configmap.Labels = Merge(upgrade.Spec.Metadata.GetLabelsOrNil(),
+			commonLabels(pgUpgrade, upgrade))
+		configmap.Data = reports
+
+		r.setControllerReference(upgrade, configmap)
+		err = errors.WithStack(r.apply(ctx, configmap))
+	}
+
+	return running, err
+}
@@ -0,0 +1,198 @@
+// Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgupgrade
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestUpgradeSteps(t *testing.T) {
+	t1 := metav1.NewTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	t2 := metav1.NewTime(t1.Add(time.Minute))
+	t3 := metav1.NewTime(t1.Add(time.Hour))
+
+	assert.Assert(t, upgradeSteps("", nil) == nil)
+
+	t.Run("Running", func(t *testing.T) {
+		steps := upgradeSteps(""+
+			"step: initdb 2024-01-02T03:04:05Z\n"+
+			"step: check 2024-01-02T03:05:05Z\n", nil)
+
+		assert.DeepEqual(t, steps, []v1beta1.PGUpgradeStepStatus{
+			{Name: "initdb", State: "Succeeded", StartTime: &t1, CompletionTime: &t2},
+			{Name: "check", State: "Running", StartTime: &t2},
+		})
+	})
+
+	t.Run("Succeeded", func(t *testing.T) {
+		steps := upgradeSteps(""+
+			"step: initdb 2024-01-02T03:04:05Z\n"+
+			"step: check 2024-01-02T03:05:05Z\n"+
+			"status: 0\n", &t3)
+
+		assert.DeepEqual(t, steps, []v1beta1.PGUpgradeStepStatus{
+			{Name: "initdb", State: "Succeeded", StartTime: &t1, CompletionTime: &t2},
+			{Name: "check", State: "Succeeded", StartTime: &t2, CompletionTime: &t3},
+		})
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		steps := upgradeSteps(""+
+			"step: initdb 2024-01-02T03:04:05Z\n"+
+			"status: 1\n"+
+			"== output\n"+
+			"step: not a step\n", &t3)
+
+		assert.DeepEqual(t, steps, []v1beta1.PGUpgradeStepStatus{
+			{Name: "initdb", State: "Failed", StartTime: &t1, CompletionTime: &t3},
+		})
+	})
+}
+
+func TestMergeSteps(t *testing.T) {
+	assert.Assert(t, mergeSteps(nil, nil) == nil)
+
+	recorded := []v1beta1.PGUpgradeStepStatus{
+		{Name: "initdb", State: "Succeeded"},
+		{Name: "upgrade", State: "Running"},
+	}
+	merged := mergeSteps(recorded, []v1beta1.PGUpgradeStepStatus{
+		{Name: "remove-data-pg5-abc", State: "Running"},
+		{Name: "upgrade", State: "Succeeded"},
+	})
+
+	assert.DeepEqual(t, merged, []v1beta1.PGUpgradeStepStatus{
+		{Name: "initdb", State: "Succeeded"},
+		{Name: "upgrade", State: "Succeeded"},
+		{Name: "remove-data-pg5-abc", State: "Running"},
+	})
+
+	// The recorded steps are not modified.
+	assert.Equal(t, recorded[1].State, "Running")
+}
+
+func TestRemoveDataStep(t *testing.T) {
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Name = "pgu2"
+
+	started := metav1.NewTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	finished := metav1.NewTime(started.Add(time.Minute))
+
+	job := &batchv1.Job{}
+	job.Name = "pgu2-pg5-abc"
+	job.Status.StartTime = &started
+
+	assert.DeepEqual(t, removeDataStep(upgrade, job), v1beta1.PGUpgradeStepStatus{
+		Name: "remove-data-pg5-abc", State: "Running", StartTime: &started,
+	})
+
+	job.Status.Conditions = []batchv1.JobCondition{{
+		Type: batchv1.JobFailed, Status: corev1.ConditionTrue, LastTransitionTime: finished,
+	}}
+	assert.DeepEqual(t, removeDataStep(upgrade, job), v1beta1.PGUpgradeStepStatus{
+		Name: "remove-data-pg5-abc", State: "Failed", StartTime: &started, CompletionTime: &finished,
+	})
+}
+
+func TestReconcileStepsRunning(t *testing.T) {
+	ctx := context.Background()
+
+	upgrade := &v1beta1.PGUpgrade{}
+	upgrade.Namespace = "ns1"
+	upgrade.Name = "pgu2"
+
+	job := &batchv1.Job{}
+	job.Namespace = "ns1"
+	job.Name = pgUpgradeJob(upgrade).Name
+	job.UID = "job-uid"
+
+	pod := &corev1.Pod{}
+	pod.Namespace = "ns1"
+	pod.Name = "pgu2-pgdata-xyz"
+	pod.OwnerReferences = []metav1.OwnerReference{{
+		Controller: initialize.Bool(true), UID: job.UID,
+	}}
+	pod.Status.Phase = corev1.PodRunning
+
+	var calls int
+	reconciler := &PGUpgradeReconciler{
+		PodExec: func(
+			namespace, name, container string,
+			_ io.Reader, stdout, _ io.Writer, command ...string,
+		) error {
+			calls++
+			assert.Equal(t, name, "pgu2-pgdata-xyz")
+			assert.Equal(t, container, ContainerDatabase)
+			assert.DeepEqual(t, command, []string{"cat", "/tmp/pgupgrade-progress"})
+			_, _ = stdout.Write([]byte("step: initdb 2024-01-02T03:04:05Z\n"))
+			return nil
+		},
+	}
+
+	world := NewWorld()
+	world.Jobs[job.Name] = job
+	world.Pods = []*corev1.Pod{pod}
+
+	running, err := reconciler.reconcileSteps(ctx, upgrade, world)
+	assert.NilError(t, err)
+	assert.Assert(t, running)
+	assert.Equal(t, calls, 1)
+
+	assert.Equal(t, len(upgrade.Status.Steps), 1)
+	assert.Equal(t, upgrade.Status.Steps[0].Name, "initdb")
+	assert.Equal(t, upgrade.Status.Steps[0].State, "Running")
+
+	// Steps are kept when the Job is gone.
+	running, err = reconciler.reconcileSteps(ctx, upgrade, NewWorld())
+	assert.NilError(t, err)
+	assert.Assert(t, !running)
+	assert.Equal(t, len(upgrade.Status.Steps), 1)
+
+	// A finished Job is not running, even when its Pod is gone.
+	world = NewWorld()
+	world.Jobs[job.Name] = job.DeepCopy()
+	world.Jobs[job.Name].Status.Conditions = []batchv1.JobCondition{
+		{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+	}
+
+	running, err = reconciler.reconcileSteps(ctx, upgrade, world)
+	assert.NilError(t, err)
+	assert.Assert(t, !running)
+	assert.Equal(t, calls, 1)
+	assert.Equal(t, len(upgrade.Status.Steps), 1)
+
+	// Steps of other Jobs are added to those recorded.
+	removal := &batchv1.Job{}
+	removal.Name = "pgu2-pg5-abc"
+	removal.Labels = map[string]string{LabelRole: removeData}
+	world.Jobs[removal.Name] = removal
+
+	_, err = reconciler.reconcileSteps(ctx, upgrade, world)
+	assert.NilError(t, err)
+	assert.Equal(t, len(upgrade.Status.Steps), 2)
+	assert.Equal(t, upgrade.Status.Steps[0].Name, "initdb")
+	assert.Equal(t, upgrade.Status.Steps[1].Name, "remove-data-pg5-abc")
+}
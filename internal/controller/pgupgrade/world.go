@@ -36,6 +36,8 @@ import (
 //+kubebuilder:rbac:groups="",resources="endpoints",verbs={list,watch}
 //+kubebuilder:rbac:groups="batch",resources="jobs",verbs={list,watch}
 //+kubebuilder:rbac:groups="apps",resources="statefulsets",verbs={list,watch}
+//+kubebuilder:rbac:groups="",resources="persistentvolumeclaims",verbs={list,watch}
+//+kubebuilder:rbac:groups="",resources="pods",verbs={list,watch}
 
 func (r *PGUpgradeReconciler) observeWorld(
 	ctx context.Context, upgrade *v1beta1.PGUpgrade,
@@ -77,6 +79,28 @@ func (r *PGUpgradeReconciler) observeWorld(
 		}
 	}
 
+	if err == nil {
+		var pods corev1.PodList
+		err = errors.WithStack(
+			r.Client.List(ctx, &pods,
+				client.InNamespace(upgrade.Namespace),
+				client.MatchingLabelsSelector{Selector: selectCluster},
+			))
+		world.populatePods(pods.Items)
+	}
+
+	if err == nil {
+		var volumes corev1.PersistentVolumeClaimList
+		err = errors.WithStack(
+			r.Client.List(ctx, &volumes,
+				client.InNamespace(upgrade.Namespace),
+				client.MatchingLabelsSelector{Selector: selectCluster},
+			))
+		for i := range volumes.Items {
+			world.ClusterVolumes[volumes.Items[i].Name] = &volumes.Items[i]
+		}
+	}
+
 	if err == nil {
 		var statefulsets appsv1.StatefulSetList
 		err = errors.WithStack(
@@ -115,18 +139,37 @@ func (w *World) populatePatroniEndpoints(endpoints []corev1.Endpoints) {
 	}
 }
 
+func (w *World) populatePods(pods []corev1.Pod) {
+	for index := range pods {
+		w.Pods = append(w.Pods, &pods[index])
+	}
+}
+
 // populateStatefulSets assigns
 // a) the expected number of replicas -- the number of StatefulSets that have the expected
 // LabelInstance label, minus 1 (for the primary)
 // b) the primary StatefulSet and replica StatefulSets if the cluster is shutdown.
 // When the cluster is not shutdown, we cannot verify which StatefulSet is the primary.
+// c) the StatefulSet of the Pod that Patroni labels as leader, if any.
+// d) every instance StatefulSet by name.
 func (w *World) populateStatefulSets(statefulSets []appsv1.StatefulSet) {
+	var leader string
+	for _, pod := range w.Pods {
+		if pod.Labels[LabelRole] == RolePatroniLeader {
+			leader = pod.Labels[LabelInstance]
+		}
+	}
+
 	w.ReplicasExpected = -1
 	if w.Cluster != nil {
 		startup := w.Cluster.Status.StartupInstance
 		for index, sts := range statefulSets {
 			if sts.Labels[LabelInstance] != "" {
+				w.ClusterInstances[sts.Name] = &statefulSets[index]
 				w.ReplicasExpected++
+				if leader != "" && sts.Name == leader {
+					w.ClusterLeader = &statefulSets[index]
+				}
 				if startup != "" {
 					switch sts.Name {
 					case startup:
@@ -174,12 +217,22 @@ type World struct {
 	ClusterShutdown  bool
 	ReplicasExpected int
 
+	// ClusterLeader is the StatefulSet of the running primary, if any.
+	ClusterLeader *appsv1.StatefulSet
+
+	// ClusterInstances are the instance StatefulSets of the cluster by name.
+	ClusterInstances map[string]*appsv1.StatefulSet
+	ClusterVolumes   map[string]*corev1.PersistentVolumeClaim
+
 	PatroniEndpoints []*corev1.Endpoints
 	Jobs             map[string]*batchv1.Job
+	Pods             []*corev1.Pod
 }
 
 func NewWorld() *World {
 	return &World{
-		Jobs: make(map[string]*batchv1.Job),
+		ClusterInstances: make(map[string]*appsv1.StatefulSet),
+		ClusterVolumes:   make(map[string]*corev1.PersistentVolumeClaim),
+		Jobs:             make(map[string]*batchv1.Job),
 	}
 }
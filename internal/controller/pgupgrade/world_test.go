@@ -237,4 +237,39 @@ func TestPopulateStatefulSets(t *testing.T) {
 		assert.DeepEqual(t, world.ClusterReplicas, []*appsv1.StatefulSet{&replica})
 		assert.Assert(t, world.ReplicasExpected == 1)
 	})
+
+	t.Run("Leader", func(t *testing.T) {
+		world := NewWorld()
+		world.Cluster = v1beta1.NewPostgresCluster()
+
+		first := appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "first",
+				Labels: map[string]string{LabelInstance: "first"},
+			},
+		}
+		second := appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "second",
+				Labels: map[string]string{LabelInstance: "second"},
+			},
+		}
+
+		world.populateStatefulSets([]appsv1.StatefulSet{first, second})
+		assert.Assert(t, world.ClusterLeader == nil, "expected none without pods")
+
+		world = NewWorld()
+		world.Cluster = v1beta1.NewPostgresCluster()
+		world.populatePods([]corev1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+				LabelInstance: "first", LabelRole: "replica",
+			}}},
+			{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+				LabelInstance: "second", LabelRole: RolePatroniLeader,
+			}}},
+		})
+
+		world.populateStatefulSets([]appsv1.StatefulSet{first, second})
+		assert.DeepEqual(t, world.ClusterLeader, &second)
+	})
 }
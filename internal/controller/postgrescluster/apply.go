@@ -27,6 +27,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -72,6 +73,14 @@ func (r *Reconciler) apply(ctx context.Context, object client.Object) error {
 	return err
 }
 
+// applyUnstructured sends an apply patch of object as-is to its endpoint in the
+// Kubernetes API and updates object with any returned content. The apply patch
+// of [Reconciler.apply] cannot be computed for unstructured objects, which is
+// how this package writes the objects of APIs it does not depend on.
+func (r *Reconciler) applyUnstructured(ctx context.Context, object *unstructured.Unstructured) error {
+	return r.patch(ctx, object, client.Apply, client.ForceOwnership)
+}
+
 // handleServiceError inspects err for expected Kubernetes API responses to
 // writing a Service. It returns err when it cannot resolve the issue, otherwise
 // it returns nil.
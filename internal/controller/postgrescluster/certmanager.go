@@ -0,0 +1,431 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/patroni"
+	"github.com/crunchydata/postgres-operator/internal/pgbackrest"
+	"github.com/crunchydata/postgres-operator/internal/pgbouncer"
+	"github.com/crunchydata/postgres-operator/internal/pki"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// certManagerCertificateGVK is the kind of the cert-manager objects this
+// package writes. The operator does not depend on cert-manager, so these are
+// handled as unstructured objects.
+// - https://cert-manager.io/docs/reference/api-docs/#cert-manager.io/v1.Certificate
+var certManagerCertificateGVK = schema.GroupVersionKind{
+	Group: "cert-manager.io", Version: "v1", Kind: "Certificate",
+}
+
+// certManagerCertificate returns a cert-manager Certificate for purpose that
+// is issued by the issuer in cluster and has the key and lifetime in policy.
+// Usages are the key usages cert-manager writes into the certificate.
+func certManagerCertificate(
+	cluster *v1beta1.PostgresCluster, policy pki.Policy, meta metav1.ObjectMeta,
+	purpose string, commonName string, dnsNames []string, usages ...string,
+) *unstructured.Unstructured {
+	issuer := cluster.Spec.CertManager.IssuerRef
+	labels := naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster:            cluster.Name,
+			naming.LabelClusterCertificate: purpose,
+		})
+	annotations := naming.Merge(
+		cluster.Spec.Metadata.GetAnnotationsOrNil())
+
+	spec := map[string]any{
+		"secretName": meta.Name,
+		"issuerRef": map[string]any{
+			"name":  issuer.Name,
+			"kind":  issuer.Kind,
+			"group": issuer.Group,
+		},
+		// Match the algorithm of certificates generated by the operator and
+		// replace the private key whenever the certificate is renewed.
+		"privateKey": map[string]any{
+			"algorithm":      "ECDSA",
+			"size":           int64(256),
+			"rotationPolicy": "Always",
+		},
+		"usages": stringsToAny(append([]string{"digital signature", "key encipherment"}, usages...)),
+	}
+	if policy.KeyAlgorithm == pki.RSA {
+		spec["privateKey"] = map[string]any{
+			"algorithm":      "RSA",
+			"size":           int64(2048),
+			"rotationPolicy": "Always",
+		}
+	}
+	// cert-manager also renews when a third of the duration remains.
+	if policy.Lifetime > 0 {
+		spec["duration"] = policy.Lifetime.String()
+	}
+	if len(commonName) > 0 {
+		spec["commonName"] = commonName
+	}
+	if len(dnsNames) > 0 {
+		spec["dnsNames"] = stringsToAny(dnsNames)
+	}
+
+	// Label the Secret so that changes to it trigger a reconcile of cluster.
+	template := map[string]any{"labels": stringMapToAny(labels)}
+	if len(annotations) > 0 {
+		template["annotations"] = stringMapToAny(annotations)
+	}
+	spec["secretTemplate"] = template
+
+	certificate := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	certificate.SetGroupVersionKind(certManagerCertificateGVK)
+	certificate.SetNamespace(meta.Namespace)
+	certificate.SetName(meta.Name)
+	certificate.SetLabels(labels)
+	if len(annotations) > 0 {
+		certificate.SetAnnotations(annotations)
+	}
+
+	return certificate
+}
+
+// certManagerCertificateIssued returns true when cert-manager has written a
+// certificate into the Secret of certificate. The Secret remains usable while
+// cert-manager renews it.
+func certManagerCertificateIssued(certificate *unstructured.Unstructured) bool {
+	if revision, _, _ := unstructured.NestedInt64(certificate.Object, "status", "revision"); revision > 0 {
+		return true
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(certificate.Object, "status", "conditions")
+	for i := range conditions {
+		if condition, ok := conditions[i].(map[string]any); ok && condition["type"] == "Ready" {
+			return condition["status"] == string(metav1.ConditionTrue)
+		}
+	}
+	return false
+}
+
+// generateCertManagerCertificates returns the cert-manager Certificates that
+// cluster needs. Certificates that have a custom secret in the spec are skipped.
+func (r *Reconciler) generateCertManagerCertificates(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) []*unstructured.Unstructured {
+	var certificates []*unstructured.Unstructured
+	policy := r.leafCertificatePolicy(cluster)
+
+	serviceDNSNames := func(meta metav1.ObjectMeta) []string {
+		return naming.ServiceDNSNames(ctx, &corev1.Service{ObjectMeta: meta})
+	}
+
+	if cluster.Spec.CustomTLSSecret == nil {
+		dnsNames := append(
+			serviceDNSNames(naming.ClusterPrimaryService(cluster)),
+			serviceDNSNames(naming.ClusterReplicaService(cluster))...)
+
+		certificates = append(certificates, certManagerCertificate(cluster, policy,
+			naming.CertManagerCertificate(cluster, naming.CertManagerServer),
+			naming.CertManagerServer, dnsNames[0], dnsNames, "server auth"))
+	}
+
+	if cluster.Spec.CustomReplicationClientTLSSecret == nil {
+		certificates = append(certificates, certManagerCertificate(cluster, policy,
+			naming.CertManagerCertificate(cluster, naming.CertManagerReplication),
+			naming.CertManagerReplication, postgres.ReplicationUser, nil, "client auth"))
+	}
+
+	if cluster.Spec.Proxy != nil && cluster.Spec.Proxy.PGBouncer != nil &&
+		cluster.Spec.Proxy.PGBouncer.CustomTLSSecret == nil {
+		dnsNames := serviceDNSNames(naming.ClusterPGBouncer(cluster))
+
+		// Additional poolers share this certificate, so it includes their
+		// Service names as well. See [pgbouncer.Secret].
+		for _, name := range pgbouncer.PoolerNames(cluster) {
+			dnsNames = append(dnsNames,
+				serviceDNSNames(naming.ClusterPGBouncerPooler(cluster, name))...)
+		}
+
+		certificates = append(certificates, certManagerCertificate(cluster, policy,
+			naming.CertManagerCertificate(cluster, naming.CertManagerPGBouncer),
+			naming.CertManagerPGBouncer, dnsNames[0], dnsNames, "server auth"))
+	}
+
+	// pgBackRest verifies the common name of clients and the DNS names of
+	// servers. Every instance and repository host Pod is in the same subdomain,
+	// so a single wildcard certificate serves the entire cluster.
+	if pgbackrest.DedicatedRepoHostEnabled(cluster) {
+		subdomain := serviceDNSNames(naming.ClusterPodService(cluster))
+		dnsNames := make([]string, len(subdomain))
+		for i := range subdomain {
+			dnsNames[i] = "*." + subdomain[i]
+		}
+
+		certificates = append(certificates, certManagerCertificate(cluster, policy,
+			naming.CertManagerCertificate(cluster, naming.CertManagerPGBackRest),
+			naming.CertManagerPGBackRest, pgbackrest.ClientCommonName(cluster),
+			dnsNames, "server auth", "client auth"))
+	}
+
+	// PostgreSQL compares the certificate common name to the user name.
+	// - https://www.postgresql.org/docs/current/auth-cert.html
+	for i := range cluster.Spec.Users {
+		user := cluster.Spec.Users[i]
+		if user.ClientCertificate != nil && *user.ClientCertificate {
+			certificates = append(certificates, certManagerCertificate(cluster, policy,
+				naming.CertManagerUserCertificate(cluster, string(user.Name)),
+				"pguser", string(user.Name), nil, "client auth"))
+		}
+	}
+
+	return certificates
+}
+
+// +kubebuilder:rbac:groups="cert-manager.io",resources="certificates",verbs={list}
+// +kubebuilder:rbac:groups="cert-manager.io",resources="certificates",verbs={create,patch,delete}
+
+// reconcileCertManagerCertificates writes the cert-manager Certificates that
+// cluster needs and deletes those it no longer needs. It reports whether or not
+// cert-manager has issued them, with a certificate authority, in the
+// "CertificatesIssued" condition. Changes
+// to the issued Secrets trigger a reconcile, but their Certificates do not.
+func (r *Reconciler) reconcileCertManagerCertificates(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) error {
+	if cluster.Spec.CertManager == nil {
+		apimeta.RemoveStatusCondition(&cluster.Status.Conditions, v1beta1.CertificatesIssued)
+		return nil
+	}
+
+	var err error
+	intents := r.generateCertManagerCertificates(ctx, cluster)
+	names := sets.NewString()
+	pending := sets.NewString()
+	noAuthority := sets.NewString()
+
+	for _, intent := range intents {
+		names.Insert(intent.GetName())
+
+		if err == nil {
+			err = errors.WithStack(r.setControllerReference(cluster, intent))
+		}
+		if err == nil {
+			err = errors.WithStack(r.applyUnstructured(ctx, intent))
+		}
+		if err == nil && !certManagerCertificateIssued(intent) {
+			pending.Insert(intent.GetName())
+		}
+
+		// Pods cannot start until the issued Secret has an authority.
+		if err == nil && !pending.Has(intent.GetName()) {
+			var issued *corev1.Secret
+			secretName, _, _ := unstructured.NestedString(intent.Object, "spec", "secretName")
+			issued, err = r.getCertManagerSecret(ctx, metav1.ObjectMeta{
+				Namespace: intent.GetNamespace(), Name: secretName,
+			})
+			if err == nil && certManagerSecretMissingAuthority(issued) {
+				noAuthority.Insert(intent.GetName())
+			}
+		}
+	}
+
+	existing := &unstructured.UnstructuredList{}
+	existing.SetGroupVersionKind(certManagerCertificateGVK.GroupVersion().WithKind("CertificateList"))
+
+	if err == nil {
+		err = errors.WithStack(r.Client.List(ctx, existing,
+			client.InNamespace(cluster.Namespace),
+			client.MatchingLabels{naming.LabelCluster: cluster.Name},
+		))
+	}
+	for i := range existing.Items {
+		// Certificates of instances are deleted along with their instance.
+		purpose := existing.Items[i].GetLabels()[naming.LabelClusterCertificate]
+
+		if err == nil && purpose != naming.CertManagerInstance &&
+			!names.Has(existing.Items[i].GetName()) {
+			err = errors.WithStack(client.IgnoreNotFound(
+				r.deleteControlled(ctx, cluster, &existing.Items[i])))
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               v1beta1.CertificatesIssued,
+		ObservedGeneration: cluster.GetGeneration(),
+	}
+
+	switch {
+	case apimeta.IsNoMatchError(errors.Cause(err)):
+		// Report that cert-manager is missing rather than returning an error
+		// that would be retried forever.
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "CertManagerNotInstalled"
+		condition.Message = "The cert-manager Certificate API is not available."
+		err = nil
+
+	case err != nil:
+		return err
+
+	case pending.Len() > 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Issuing"
+		condition.Message = "Waiting for cert-manager to issue: " +
+			strings.Join(pending.List(), ", ")
+
+	case noAuthority.Len() > 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "CertificateAuthorityMissing"
+		condition.Message = "The issuer did not write " + rootCertFile + " for: " +
+			strings.Join(noAuthority.List(), ", ") +
+			". Use an issuer that supplies its certificate authority."
+
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Issued"
+		condition.Message = "cert-manager has issued every certificate."
+	}
+
+	apimeta.SetStatusCondition(&cluster.Status.Conditions, condition)
+	return err
+}
+
+// instanceCertManagerCertificate writes a cert-manager Certificate for the
+// Patroni API of instance and copies what cert-manager issued into intent.
+// Nothing is copied until cert-manager issues it.
+func (r *Reconciler) instanceCertManagerCertificate(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	instance *appsv1.StatefulSet, intent *corev1.Secret,
+) error {
+	// RFC 2818 states that the certificate DNS names must be used to verify
+	// HTTPS identity.
+	dnsNames := naming.InstancePodDNSNames(ctx, instance)
+
+	certificate := certManagerCertificate(cluster, r.leafCertificatePolicy(cluster),
+		naming.CertManagerInstanceCertificate(instance), naming.CertManagerInstance,
+		dnsNames[0], dnsNames, "server auth")
+	certificate.SetLabels(naming.Merge(certificate.GetLabels(),
+		map[string]string{naming.LabelInstance: instance.Name}))
+
+	err := errors.WithStack(r.setControllerReference(cluster, certificate))
+	if err == nil {
+		err = errors.WithStack(r.applyUnstructured(ctx, certificate))
+	}
+	if apimeta.IsNoMatchError(errors.Cause(err)) {
+		// The "CertificatesIssued" condition reports that cert-manager is missing.
+		return nil
+	}
+
+	var issued *corev1.Secret
+	if err == nil {
+		issued, err = r.getCertManagerSecret(ctx, naming.CertManagerInstanceCertificate(instance))
+	}
+	if err == nil && certManagerSecretIssued(issued) {
+		var authority, leaf pki.Certificate
+		var key pki.PrivateKey
+
+		err = errors.WithStack(authority.UnmarshalText(issued.Data[rootCertFile]))
+		if err == nil {
+			err = errors.WithStack(leaf.UnmarshalText(issued.Data[clusterCertFile]))
+		}
+		if err == nil {
+			err = errors.WithStack(key.UnmarshalText(issued.Data[clusterKeyFile]))
+		}
+		if err == nil {
+			err = patroni.InstanceCertificates(ctx,
+				pki.Certificates{authority}, leaf, key, intent)
+		}
+	}
+	return err
+}
+
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={get}
+
+// getCertManagerSecret returns the Secret that cert-manager issued at meta.
+// The Secret has no data until cert-manager issues it.
+func (r *Reconciler) getCertManagerSecret(
+	ctx context.Context, meta metav1.ObjectMeta,
+) (*corev1.Secret, error) {
+	issued := &corev1.Secret{ObjectMeta: meta}
+	err := errors.WithStack(client.IgnoreNotFound(
+		r.Client.Get(ctx, client.ObjectKeyFromObject(issued), issued)))
+	return issued, err
+}
+
+// certManagerSecretIssued returns true when secret contains a certificate,
+// private key, and certificate authority issued by cert-manager. Pods mount
+// all three, so a Secret without the authority cannot be used.
+func certManagerSecretIssued(secret *corev1.Secret) bool {
+	return len(secret.Data[clusterCertFile]) > 0 && len(secret.Data[clusterKeyFile]) > 0 &&
+		len(secret.Data[rootCertFile]) > 0
+}
+
+// certManagerSecretMissingAuthority returns true when cert-manager issued a
+// certificate and private key into secret without a certificate authority.
+// Some issuers, such as ACME, do not write one.
+func certManagerSecretMissingAuthority(secret *corev1.Secret) bool {
+	return len(secret.Data[clusterCertFile]) > 0 && len(secret.Data[clusterKeyFile]) > 0 &&
+		len(secret.Data[rootCertFile]) == 0
+}
+
+// userCertManagerCertificate copies the client certificate that cert-manager
+// issued for the PostgreSQL user in spec into intent.
+func (r *Reconciler) userCertManagerCertificate(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	spec *v1beta1.PostgresUserSpec, intent *corev1.Secret,
+) error {
+	issued, err := r.getCertManagerSecret(ctx,
+		naming.CertManagerUserCertificate(cluster, string(spec.Name)))
+
+	if err == nil && certManagerSecretIssued(issued) {
+		for _, key := range []string{clusterCertFile, clusterKeyFile, rootCertFile} {
+			intent.Data[key] = issued.Data[key]
+		}
+	}
+	return err
+}
+
+// stringsToAny converts values to a type that can be stored in an
+// unstructured object.
+func stringsToAny(values []string) []any {
+	result := make([]any, len(values))
+	for i := range values {
+		result[i] = values[i]
+	}
+	return result
+}
+
+// stringMapToAny converts values to a type that can be stored in an
+// unstructured object.
+func stringMapToAny(values map[string]string) map[string]any {
+	result := make(map[string]any, len(values))
+	for k, v := range values {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,270 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/controller/runtime"
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/pki"
+	"github.com/crunchydata/postgres-operator/internal/testing/cmp"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestCertManagerCertificate(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.Spec.Metadata = &v1beta1.Metadata{
+		Labels: map[string]string{"some": "label"},
+	}
+	cluster.Spec.CertManager = &v1beta1.CertManagerSpec{
+		IssuerRef: v1beta1.CertManagerIssuerReference{
+			Name: "ca-issuer", Kind: "ClusterIssuer", Group: "cert-manager.io",
+		},
+	}
+
+	certificate := certManagerCertificate(cluster, pki.Policy{},
+		naming.CertManagerCertificate(cluster, "server"), "server",
+		"some-cn", []string{"some-cn", "other"}, "server auth")
+
+	assert.Assert(t, marshalMatches(certificate, `
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  labels:
+    postgres-operator.crunchydata.com/cluster: hippo
+    postgres-operator.crunchydata.com/cluster-certificate: server
+    some: label
+  name: hippo-tls-server
+  namespace: ns1
+spec:
+  commonName: some-cn
+  dnsNames:
+  - some-cn
+  - other
+  issuerRef:
+    group: cert-manager.io
+    kind: ClusterIssuer
+    name: ca-issuer
+  privateKey:
+    algorithm: ECDSA
+    rotationPolicy: Always
+    size: 256
+  secretName: hippo-tls-server
+  secretTemplate:
+    labels:
+      postgres-operator.crunchydata.com/cluster: hippo
+      postgres-operator.crunchydata.com/cluster-certificate: server
+      some: label
+  usages:
+  - digital signature
+  - key encipherment
+  - server auth
+	`))
+
+	t.Run("Policy", func(t *testing.T) {
+		certificate := certManagerCertificate(cluster,
+			pki.Policy{KeyAlgorithm: pki.RSA, Lifetime: 720 * time.Hour},
+			naming.CertManagerCertificate(cluster, "server"), "server", "", nil)
+
+		duration, _, _ := unstructured.NestedString(certificate.Object, "spec", "duration")
+		algorithm, _, _ := unstructured.NestedString(certificate.Object, "spec", "privateKey", "algorithm")
+		size, _, _ := unstructured.NestedInt64(certificate.Object, "spec", "privateKey", "size")
+
+		assert.Equal(t, duration, "720h0m0s")
+		assert.Equal(t, algorithm, "RSA")
+		assert.Equal(t, size, int64(2048))
+	})
+}
+
+func TestCertManagerCertificateIssued(t *testing.T) {
+	certificate := new(unstructured.Unstructured)
+	assert.Assert(t, !certManagerCertificateIssued(certificate))
+
+	certificate.Object = map[string]any{"status": map[string]any{
+		"conditions": []any{
+			map[string]any{"type": "Issuing", "status": "True"},
+			map[string]any{"type": "Ready", "status": "False"},
+		},
+	}}
+	assert.Assert(t, !certManagerCertificateIssued(certificate))
+
+	certificate.Object = map[string]any{"status": map[string]any{
+		"conditions": []any{
+			map[string]any{"type": "Ready", "status": "True"},
+		},
+	}}
+	assert.Assert(t, certManagerCertificateIssued(certificate))
+
+	// A renewal in progress does not interrupt the existing certificate.
+	certificate.Object = map[string]any{"status": map[string]any{
+		"revision": int64(3),
+		"conditions": []any{
+			map[string]any{"type": "Ready", "status": "False"},
+		},
+	}}
+	assert.Assert(t, certManagerCertificateIssued(certificate))
+}
+
+func TestCertManagerSecretIssued(t *testing.T) {
+	secret := &corev1.Secret{}
+	assert.Assert(t, !certManagerSecretIssued(secret))
+	assert.Assert(t, !certManagerSecretMissingAuthority(secret))
+
+	// Some issuers, such as ACME, write no certificate authority.
+	secret.Data = map[string][]byte{"tls.crt": []byte("c"), "tls.key": []byte("k")}
+	assert.Assert(t, !certManagerSecretIssued(secret))
+	assert.Assert(t, certManagerSecretMissingAuthority(secret))
+
+	secret.Data["ca.crt"] = []byte("a")
+	assert.Assert(t, certManagerSecretIssued(secret))
+	assert.Assert(t, !certManagerSecretMissingAuthority(secret))
+}
+
+func TestGenerateCertManagerCertificates(t *testing.T) {
+	ctx := context.Background()
+	reconciler := &Reconciler{}
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.UID = "some-uid"
+	cluster.Spec.CertManager = &v1beta1.CertManagerSpec{}
+
+	names := func(certificates []*unstructured.Unstructured) []string {
+		var result []string
+		for _, certificate := range certificates {
+			result = append(result, certificate.GetName())
+		}
+		return result
+	}
+
+	assert.DeepEqual(t, names(reconciler.generateCertManagerCertificates(ctx, cluster)),
+		[]string{"hippo-tls-server", "hippo-tls-replication"})
+
+	t.Run("Everything", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{
+			Name: "repo1", Volume: &v1beta1.RepoPVC{},
+		}}
+		cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{
+			PGBouncer: &v1beta1.PGBouncerPodSpec{},
+			Poolers:   []v1beta1.PGBouncerPoolerSpec{{Name: "batch"}},
+		}
+		cluster.Spec.Users = []v1beta1.PostgresUserSpec{
+			{Name: "app"},
+			{Name: "cert", ClientCertificate: initialize.Bool(true)},
+		}
+
+		certificates := reconciler.generateCertManagerCertificates(ctx, cluster)
+		assert.DeepEqual(t, names(certificates), []string{
+			"hippo-tls-server", "hippo-tls-replication", "hippo-tls-pgbouncer",
+			"hippo-tls-pgbackrest", "hippo-tls-pguser-cert",
+		})
+
+		// Additional poolers share the PgBouncer certificate.
+		pgbouncer := certificates[2]
+		dnsNames, _, _ := unstructured.NestedStringSlice(pgbouncer.Object, "spec", "dnsNames")
+		assert.Assert(t, cmp.Contains(dnsNames, "hippo-pgbouncer.ns1.svc"))
+		assert.Assert(t, cmp.Contains(dnsNames, "hippo-pgbouncer-batch.ns1.svc"))
+
+		pgbackrest := certificates[3]
+		commonName, _, _ := unstructured.NestedString(pgbackrest.Object, "spec", "commonName")
+		dnsNames, _, _ = unstructured.NestedStringSlice(pgbackrest.Object, "spec", "dnsNames")
+		assert.Equal(t, commonName, "pgbackrest@some-uid")
+		assert.Equal(t, dnsNames[1], "*.hippo-pods.ns1.svc")
+
+		user := certificates[4]
+		commonName, _, _ = unstructured.NestedString(user.Object, "spec", "commonName")
+		assert.Equal(t, commonName, "cert")
+	})
+
+	t.Run("CustomTakesPrecedence", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.CustomTLSSecret = &corev1.SecretProjection{}
+		cluster.Spec.CustomReplicationClientTLSSecret = &corev1.SecretProjection{}
+
+		assert.Assert(t, len(reconciler.generateCertManagerCertificates(ctx, cluster)) == 0)
+	})
+}
+
+// certManagerMissingClient behaves like a cluster without the cert-manager API.
+type certManagerMissingClient struct{ client.Client }
+
+func (certManagerMissingClient) Patch(
+	_ context.Context, object client.Object, _ client.Patch, _ ...client.PatchOption,
+) error {
+	return &apimeta.NoKindMatchError{
+		GroupKind: object.GetObjectKind().GroupVersionKind().GroupKind(),
+	}
+}
+
+func TestReconcileCertManagerCertificatesNotInstalled(t *testing.T) {
+	ctx := context.Background()
+	reconciler := &Reconciler{
+		Client: certManagerMissingClient{
+			fake.NewClientBuilder().WithScheme(runtime.Scheme).Build(),
+		},
+	}
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.Spec.CertManager = &v1beta1.CertManagerSpec{}
+
+	assert.NilError(t, reconciler.reconcileCertManagerCertificates(ctx, cluster))
+
+	condition := apimeta.FindStatusCondition(cluster.Status.Conditions, v1beta1.CertificatesIssued)
+	assert.Assert(t, condition != nil)
+	assert.Equal(t, condition.Status, metav1.ConditionFalse)
+	assert.Equal(t, condition.Reason, "CertManagerNotInstalled")
+
+	t.Run("Removed", func(t *testing.T) {
+		cluster.Spec.CertManager = nil
+		assert.NilError(t, reconciler.reconcileCertManagerCertificates(ctx, cluster))
+		assert.Assert(t, apimeta.FindStatusCondition(
+			cluster.Status.Conditions, v1beta1.CertificatesIssued) == nil)
+	})
+}
+
+func TestUserCertManagerCertificateNotIssued(t *testing.T) {
+	ctx := context.Background()
+	reconciler := &Reconciler{
+		Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).Build(),
+	}
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+
+	intent := &corev1.Secret{Data: map[string][]byte{"password": []byte("x")}}
+	assert.NilError(t, reconciler.userCertManagerCertificate(ctx, cluster,
+		&v1beta1.PostgresUserSpec{Name: "app"}, intent))
+	assert.DeepEqual(t, intent.Data, map[string][]byte{"password": []byte("x")})
+}
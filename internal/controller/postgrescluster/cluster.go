@@ -100,7 +100,8 @@ func (r *Reconciler) reconcileClusterPodService(
 }
 
 // generateClusterPrimaryService returns a v1.Service and v1.Endpoints that
-// resolve to the PostgreSQL primary instance.
+// resolve to the PostgreSQL primary instance. The Endpoints are nil when the
+// Service is redirected to another cluster.
 func (r *Reconciler) generateClusterPrimaryService(
 	cluster *v1beta1.PostgresCluster, leader *corev1.Service,
 ) (*corev1.Service, *corev1.Endpoints, error) {
@@ -132,6 +133,25 @@ func (r *Reconciler) generateClusterPrimaryService(
 	service.ObjectMeta.DeepCopyInto(&endpoints.ObjectMeta)
 	endpoints.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Endpoints"))
 
+	service.Spec.ClusterIP = corev1.ClusterIPNone
+	service.Spec.Ports = []corev1.ServicePort{{
+		Name:       naming.PortPostgreSQL,
+		Port:       *cluster.Spec.Port,
+		Protocol:   corev1.ProtocolTCP,
+		TargetPort: intstr.FromString(naming.PortPostgreSQL),
+	}}
+
+	// Select the primary of another cluster by the label Patroni maintains.
+	// Kubernetes manages the Endpoints, so they follow failovers there.
+	// - https://docs.k8s.io/concepts/services-networking/service/#headless-services
+	if other := cluster.GetAnnotations()[naming.PrimaryServiceRedirect]; other != "" {
+		service.Spec.Selector = map[string]string{
+			naming.LabelCluster: other,
+			naming.LabelRole:    naming.RolePatroniLeader,
+		}
+		return service, nil, err
+	}
+
 	if leader == nil {
 		// TODO(cbandy): We need to build a different kind of Service here.
 		return nil, nil, errors.New("Patroni DCS other than Kubernetes Endpoints is not implemented")
@@ -140,16 +160,8 @@ func (r *Reconciler) generateClusterPrimaryService(
 	// Allocate no IP address (headless) and manage the Endpoints ourselves.
 	// - https://docs.k8s.io/concepts/services-networking/service/#headless-services
 	// - https://docs.k8s.io/concepts/services-networking/service/#services-without-selectors
-	service.Spec.ClusterIP = corev1.ClusterIPNone
 	service.Spec.Selector = nil
 
-	service.Spec.Ports = []corev1.ServicePort{{
-		Name:       naming.PortPostgreSQL,
-		Port:       *cluster.Spec.Port,
-		Protocol:   corev1.ProtocolTCP,
-		TargetPort: intstr.FromString(naming.PortPostgreSQL),
-	}}
-
 	// Resolve to the ClusterIP for which Patroni has configured the Endpoints.
 	endpoints.Subsets = []corev1.EndpointSubset{{
 		Addresses: []corev1.EndpointAddress{{IP: leader.Spec.ClusterIP}},
@@ -177,7 +189,9 @@ func (r *Reconciler) generateClusterPrimaryService(
 // +kubebuilder:rbac:groups="",resources="endpoints/restricted",verbs={create}
 
 // reconcileClusterPrimaryService writes the Service and Endpoints that resolve
-// to the PostgreSQL primary instance.
+// to the PostgreSQL primary instance. When cluster is annotated with
+// [naming.PrimaryServiceRedirect], the Service selects the primary of that
+// other cluster and Kubernetes manages its Endpoints.
 func (r *Reconciler) reconcileClusterPrimaryService(
 	ctx context.Context, cluster *v1beta1.PostgresCluster, leader *corev1.Service,
 ) (*corev1.Service, error) {
@@ -186,7 +200,7 @@ func (r *Reconciler) reconcileClusterPrimaryService(
 	if err == nil {
 		err = errors.WithStack(r.apply(ctx, service))
 	}
-	if err == nil {
+	if err == nil && endpoints != nil {
 		err = errors.WithStack(r.apply(ctx, endpoints))
 	}
 	return service, err
@@ -312,7 +326,8 @@ func (r *Reconciler) reconcileDataSource(ctx context.Context,
 	// determine if the user wants to initialize the PG data directory
 	postgresDataInitRequested := cluster.Spec.DataSource != nil &&
 		(cluster.Spec.DataSource.PostgresCluster != nil ||
-			cluster.Spec.DataSource.PGBackRest != nil)
+			cluster.Spec.DataSource.PGBackRest != nil ||
+			cluster.Spec.DataSource.Streaming != nil)
 
 	// determine if the user has requested an in-place restore
 	restoreID := cluster.GetAnnotations()[naming.PGBackRestRestore]
@@ -326,6 +341,7 @@ func (r *Reconciler) reconcileDataSource(ctx context.Context,
 	// PG data initialization or an in-place restore, then simply return.
 	var dataSource *v1beta1.PostgresClusterDataSource
 	var cloudDataSource *v1beta1.PGBackRestDataSource
+	var streamingDataSource *v1beta1.StreamingDataSource
 	switch {
 	case restoreInPlaceRequested:
 		dataSource = cluster.Spec.Backups.PGBackRest.Restore.PostgresClusterDataSource
@@ -337,6 +353,9 @@ func (r *Reconciler) reconcileDataSource(ctx context.Context,
 		if dataSource == nil {
 			cloudDataSource = cluster.Spec.DataSource.PGBackRest
 		}
+		if dataSource == nil && cloudDataSource == nil {
+			streamingDataSource = cluster.Spec.DataSource.Streaming
+		}
 	default:
 		return false, nil
 	}
@@ -377,6 +396,8 @@ func (r *Reconciler) reconcileDataSource(ctx context.Context,
 	case cloudDataSource != nil:
 		configs = []string{cloudDataSource.Stanza, cloudDataSource.Repo.Name}
 		configs = append(configs, cloudDataSource.Options...)
+	case streamingDataSource != nil:
+		configs = []string{streamingDataSource.ClusterName, streamingDataSource.Role}
 	}
 	configHash, err := hashFunc(configs)
 	if err != nil {
@@ -421,6 +442,11 @@ func (r *Reconciler) reconcileDataSource(ctx context.Context,
 			configHash, clusterVolumes); err != nil {
 			return true, err
 		}
+	case streamingDataSource != nil:
+		if err := r.reconcileStreamingDataSource(ctx, cluster, streamingDataSource,
+			configHash, clusterVolumes); err != nil {
+			return true, err
+		}
 	}
 	// return early until the PG data directory is initialized
 	return true, nil
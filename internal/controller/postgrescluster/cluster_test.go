@@ -691,6 +691,28 @@ subsets:
 		assert.Equal(t, len(service.Spec.ExternalIPs), 0)
 		assert.Equal(t, service.Spec.ExternalName, "")
 	})
+
+	t.Run("Redirect", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Annotations = map[string]string{naming.PrimaryServiceRedirect: "pg6"}
+
+		service, endpoints, err := reconciler.generateClusterPrimaryService(cluster, nil)
+		assert.NilError(t, err)
+		assert.Assert(t, endpoints == nil, "expected Kubernetes to manage Endpoints")
+
+		assert.Equal(t, service.Name, "pg5-primary")
+		assert.Equal(t, service.Spec.ClusterIP, "None")
+		assert.Assert(t, marshalMatches(service.Spec.Selector, `
+postgres-operator.crunchydata.com/cluster: pg6
+postgres-operator.crunchydata.com/role: master
+		`))
+		assert.Assert(t, marshalMatches(service.Spec.Ports, `
+- name: postgres
+  port: 2600
+  protocol: TCP
+  targetPort: postgres
+		`))
+	})
 }
 
 func TestReconcileClusterPrimaryService(t *testing.T) {
@@ -25,6 +25,7 @@ import (
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel/trace"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
@@ -64,8 +65,14 @@ const (
 type Reconciler struct {
 	Client      client.Client
 	IsOpenShift bool
-	Owner       client.FieldOwner
-	PodExec     func(
+
+	// LeafCertificates and RootCertificates describe the certificates that
+	// the operator generates. Each cluster can override LeafCertificates.
+	LeafCertificates pki.Policy
+	RootCertificates pki.Policy
+
+	Owner   client.FieldOwner
+	PodExec func(
 		namespace, pod, container string,
 		stdin io.Reader, stdout, stderr io.Writer, command ...string,
 	) error
@@ -176,6 +183,12 @@ func (r *Reconciler) Reconcile(
 		return result, err
 	}
 
+	// Reject authentication rules that would prevent PostgreSQL from reloading
+	// its configuration.
+	if err := r.validatePostgresAuthentication(cluster); err != nil {
+		return result, err
+	}
+
 	var (
 		clusterConfigMap         *corev1.ConfigMap
 		clusterReplicationSecret *corev1.Secret
@@ -235,6 +248,7 @@ func (r *Reconciler) Reconcile(
 	pgHBAs := postgres.NewHBAs()
 	pgmonitor.PostgreSQLHBAs(cluster, &pgHBAs)
 	pgbouncer.PostgreSQL(cluster, &pgHBAs)
+	postgres.UserCertificateHBAs(cluster, &pgHBAs)
 
 	pgParameters := postgres.NewParameters()
 	pgaudit.PostgreSQLParameters(&pgParameters)
@@ -247,6 +261,9 @@ func (r *Reconciler) Reconcile(
 	if err == nil {
 		rootCA, err = r.reconcileRootCertificate(ctx, cluster)
 	}
+	if err == nil {
+		err = r.reconcileCertManagerCertificates(ctx, cluster)
+	}
 
 	if err == nil {
 		// Since any existing data directories must be moved prior to bootstrapping the
@@ -276,6 +293,12 @@ func (r *Reconciler) Reconcile(
 	if err == nil {
 		err = r.reconcilePatroniSwitchover(ctx, cluster, instances)
 	}
+	if err == nil {
+		err = updateResult(r.reconcileImageRollout(ctx, cluster, instances))
+	}
+	if err == nil {
+		err = updateResult(r.reconcileStandby(ctx, cluster, instances))
+	}
 	// reconcile the Pod service before reconciling any data source in case it is necessary
 	// to start Pods during data source reconciliation that require network connections (e.g.
 	// if it is necessary to start a dedicated repo host to bootstrap a new cluster using its
@@ -350,14 +373,23 @@ func (r *Reconciler) Reconcile(
 		err = r.reconcilePostgresDatabases(ctx, cluster, instances)
 	}
 	if err == nil {
-		err = r.reconcilePostgresUsers(ctx, cluster, instances)
+		err = r.reconcilePostgresUsers(ctx, cluster, instances, rootCA)
 	}
 
 	if err == nil {
 		err = updateResult(r.reconcilePGBackRest(ctx, cluster, instances, rootCA))
 	}
 	if err == nil {
-		err = r.reconcilePGBouncer(ctx, cluster, instances, primaryCertificate, rootCA)
+		err = r.reconcilePGBouncer(ctx, cluster, instances, primaryCertificate, rootCA, monitoringSecret)
+	}
+	if err == nil {
+		err = updateResult(r.reconcilePGBouncerPauses(ctx, cluster))
+	}
+	if err == nil {
+		err = r.reconcileCertificateStatus(ctx, cluster, rootCA, primaryCertificate)
+	}
+	if err == nil {
+		err = updateResult(r.reconcileRootCertificateRotation(ctx, cluster, instances, rootCA))
 	}
 	if err == nil {
 		err = r.reconcilePGMonitor(ctx, cluster, instances, monitoringSecret)
@@ -442,6 +474,7 @@ func (r *Reconciler) setOwnerReference(
 // +kubebuilder:rbac:groups="",resources="serviceaccounts",verbs={get,list,watch}
 // +kubebuilder:rbac:groups="apps",resources="deployments",verbs={get,list,watch}
 // +kubebuilder:rbac:groups="apps",resources="statefulsets",verbs={get,list,watch}
+// +kubebuilder:rbac:groups="autoscaling",resources="horizontalpodautoscalers",verbs={get,list,watch}
 // +kubebuilder:rbac:groups="batch",resources="jobs",verbs={get,list,watch}
 // +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources="roles",verbs={get,list,watch}
 // +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources="rolebindings",verbs={get,list,watch}
@@ -489,7 +522,10 @@ func (r *Reconciler) SetupWithManager(mgr manager.Manager) error {
 		Owns(&rbacv1.RoleBinding{}).
 		Owns(&batchv1.CronJob{}).
 		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
 		Watches(&source.Kind{Type: &corev1.Pod{}}, r.watchPods()).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, r.watchCertificateSecrets()).
+		Watches(&source.Kind{Type: &v1beta1.RestoreGrant{}}, r.watchRestoreGrants()).
 		Watches(&source.Kind{Type: &appsv1.StatefulSet{}},
 			r.controllerRefHandlerFuncs()). // watch all StatefulSets
 		Complete(r)
@@ -29,6 +29,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -39,6 +40,7 @@ import (
 
 	"github.com/crunchydata/postgres-operator/internal/config"
 	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/kubeapi"
 	"github.com/crunchydata/postgres-operator/internal/logging"
 	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/patroni"
@@ -82,11 +84,7 @@ func (i Instance) IsPrimary() (primary bool, known bool) {
 // connections.
 func (i Instance) IsReady() (ready bool, known bool) {
 	if len(i.Pods) == 1 {
-		for _, condition := range i.Pods[0].Status.Conditions {
-			if condition.Type == corev1.PodReady {
-				return condition.Status == corev1.ConditionTrue, true
-			}
-		}
+		return kubeapi.PodIsReady(i.Pods[0])
 	}
 
 	return false, false
@@ -463,6 +461,9 @@ func (r *Reconciler) deleteInstance(
 		Version: corev1.SchemeGroupVersion.Version,
 		Kind:    "PersistentVolumeClaimList",
 	}}
+	if cluster.Spec.CertManager != nil {
+		gvks = append(gvks, certManagerCertificateGVK.GroupVersion().WithKind("CertificateList"))
+	}
 
 	selector, err := naming.AsSelector(naming.ClusterInstance(cluster.Name, instanceName))
 	for _, gvk := range gvks {
@@ -476,6 +477,11 @@ func (r *Reconciler) deleteInstance(
 					client.MatchingLabelsSelector{Selector: selector},
 				))
 
+			// There is nothing to delete when an optional API is not installed.
+			if apimeta.IsNoMatchError(errors.Cause(err)) {
+				err = nil
+			}
+
 			for i := range uList.Items {
 				if err == nil {
 					err = errors.WithStack(client.IgnoreNotFound(
@@ -563,9 +569,13 @@ func (r *Reconciler) reconcileInstanceSets(
 		return err
 	}
 
-	// Rollout changes to instances by calling rolloutInstance.
+	// Rollout changes to instances by calling rolloutInstance. A new PostgreSQL
+	// image goes to replicas before the primary.
 	err = r.rolloutInstances(ctx, cluster, instances,
 		func(ctx context.Context, instance *Instance) error {
+			if !imageRolloutAllows(cluster, instance) {
+				return nil
+			}
 			return r.rolloutInstance(ctx, cluster, instances, instance)
 		})
 
@@ -714,7 +724,11 @@ func (r *Reconciler) rolloutInstance(
 		ctx, span = r.Tracer.Start(ctx, "patroni-change-primary")
 		defer span.End()
 
+		// Clients of PgBouncer wait rather than fail while the primary changes.
+		resume := r.pausePGBouncer(ctx, cluster)
 		success, err := patroni.Executor(exec).ChangePrimaryAndWait(ctx, pod.Name, "")
+		resume()
+
 		if err = errors.WithStack(err); err == nil && !success {
 			err = errors.New("unable to switchover")
 		}
@@ -868,6 +882,212 @@ func (r *Reconciler) rolloutInstances(
 	return err
 }
 
+// postgresContainerImage returns the image of the PostgreSQL container in pod.
+func postgresContainerImage(pod *corev1.Pod) string {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == naming.ContainerDatabase {
+			return container.Image
+		}
+	}
+	return ""
+}
+
+// postgresContainerFailing returns whether or not the PostgreSQL container in
+// pod is unable to start, either because its image cannot be pulled or because
+// the container keeps exiting. Patroni stays running when PostgreSQL cannot
+// start, so a pod that has not been ready for some time is also failing.
+func postgresContainerFailing(pod *corev1.Pod, now time.Time) bool {
+	const startTimeout = 5 * time.Minute
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != naming.ContainerDatabase || status.Ready {
+			continue
+		}
+		if waiting := status.State.Waiting; waiting != nil {
+			switch waiting.Reason {
+			case "CrashLoopBackOff", "CreateContainerConfigError",
+				"ErrImagePull", "ImagePullBackOff", "InvalidImageName":
+				return true
+			}
+		}
+		if running := status.State.Running; running != nil {
+			return now.Sub(running.StartedAt.Time) > startTimeout
+		}
+	}
+	return false
+}
+
+// reconcileImageRollout tracks the redeployment of instances onto a new
+// PostgreSQL image, such as one with a new minor version. Replicas go first;
+// each verifies its "server_version_num" once it is ready. The primary is
+// redeployed only when every replica reports a version. The "Switchover"
+// phase only labels that step; the switchover itself is done by
+// [Reconciler.rolloutInstance] as for any other change to the primary.
+// When a replica cannot start on the new image, the rollout halts until that
+// replica starts or the image changes. See [imageRolloutAllows].
+//
+// Pod events drive the rollout. It requeues only to verify a version again
+// after an error and to notice a replica that never becomes ready.
+func (r *Reconciler) reconcileImageRollout(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) (reconcile.Result, error) {
+	image := config.PostgresContainerImage(cluster)
+	rollout := cluster.Status.ImageRollout
+
+	// A rollout begins when some instance runs an image other than the one
+	// in the spec.
+	if rollout == nil || rollout.Image != image {
+		var changing bool
+		for _, instance := range instances.forCluster {
+			if instance.Spec != nil && len(instance.Pods) == 1 &&
+				postgresContainerImage(instance.Pods[0]) != image {
+				changing = true
+			}
+		}
+		if !changing {
+			return reconcile.Result{}, nil
+		}
+
+		rollout = &v1beta1.PostgresImageRolloutStatus{
+			Image: image,
+			Phase: v1beta1.PostgresImageRolloutReplicas,
+		}
+		cluster.Status.ImageRollout = rollout
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "ImageRollout",
+			"Started rollout of PostgreSQL image %q", image)
+	}
+	if rollout.Phase == v1beta1.PostgresImageRolloutComplete {
+		return reconcile.Result{}, nil
+	}
+
+	previous := make(map[string]v1beta1.PostgresMemberVersionStatus)
+	for _, member := range rollout.Members {
+		previous[member.Name] = member
+	}
+
+	var halted string
+	var requeue bool
+	members := []v1beta1.PostgresMemberVersionStatus{}
+	replicasVerified, primaryVerified := true, true
+	versions := sets.NewInt()
+
+	for _, instance := range instances.forCluster {
+		// Skip instances that have no set in cluster spec. They are being
+		// removed and do not take part in the rollout.
+		if instance.Spec == nil {
+			continue
+		}
+
+		primary, _ := instance.IsPrimary()
+		member := v1beta1.PostgresMemberVersionStatus{Name: instance.Name}
+
+		if len(instance.Pods) == 1 {
+			pod := instance.Pods[0]
+			member.Image = postgresContainerImage(pod)
+
+			// Keep the version verified earlier while the image is the same.
+			if prior, ok := previous[instance.Name]; ok && prior.Image == member.Image {
+				member.ServerVersionNum = prior.ServerVersionNum
+			}
+
+			// Leave the member unverified when its version cannot be read.
+			ready, _ := instance.IsReady()
+			if ready && member.ServerVersionNum == 0 {
+				exec := func(_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+					return r.PodExec(pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+				}
+				if version, err := postgres.ServerVersionNum(ctx, exec); err == nil {
+					member.ServerVersionNum = version
+				} else {
+					logging.FromContext(ctx).Error(err, "unable to verify PostgreSQL version",
+						"instance", instance.Name)
+					requeue = true
+				}
+			}
+
+			// A replica on the new image halts the rollout when it cannot start
+			// or when it starts with a different major version.
+			failing := !ready && postgresContainerFailing(pod, time.Now())
+			if !primary && member.Image == image && (failing ||
+				(member.ServerVersionNum > 0 && member.ServerVersionNum/10000 != cluster.Spec.PostgresVersion)) {
+				halted = instance.Name
+			}
+
+			// No event arrives when a started container stays unready.
+			if member.Image == image && !ready && !failing {
+				requeue = true
+			}
+		}
+
+		verified := member.Image == image && member.ServerVersionNum > 0
+		if verified {
+			versions.Insert(member.ServerVersionNum)
+		}
+		if primary {
+			primaryVerified = primaryVerified && verified
+		} else {
+			replicasVerified = replicasVerified && verified
+		}
+
+		members = append(members, member)
+	}
+
+	rollout.Members = members
+	phase := rollout.Phase
+
+	switch {
+	case halted != "":
+		phase = v1beta1.PostgresImageRolloutHalted
+	case replicasVerified && primaryVerified && versions.Len() == 1:
+		phase = v1beta1.PostgresImageRolloutComplete
+	case replicasVerified || phase == v1beta1.PostgresImageRolloutSwitchover:
+		// The primary changes during a switchover; stay in this phase until
+		// every instance runs the new image.
+		phase = v1beta1.PostgresImageRolloutSwitchover
+	default:
+		phase = v1beta1.PostgresImageRolloutReplicas
+	}
+
+	if phase != rollout.Phase {
+		switch phase {
+		case v1beta1.PostgresImageRolloutHalted:
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "ImageRolloutHalted",
+				"Halted rollout of PostgreSQL image %q: instance %q is unable to start", image, halted)
+		case v1beta1.PostgresImageRolloutComplete:
+			r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "ImageRollout",
+				"Completed rollout of PostgreSQL image %q", image)
+		}
+		rollout.Phase = phase
+	}
+
+	if requeue && phase != v1beta1.PostgresImageRolloutHalted &&
+		phase != v1beta1.PostgresImageRolloutComplete {
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// imageRolloutAllows returns whether or not instance can be redeployed
+// according to the image rollout of cluster. Instances already running the
+// new image can always be redeployed. Others wait while the rollout is halted,
+// and the primary waits until every replica has verified the new image.
+func imageRolloutAllows(cluster *v1beta1.PostgresCluster, instance *Instance) bool {
+	rollout := cluster.Status.ImageRollout
+	if rollout == nil || rollout.Phase == v1beta1.PostgresImageRolloutComplete ||
+		len(instance.Pods) != 1 || postgresContainerImage(instance.Pods[0]) == rollout.Image {
+		return true
+	}
+
+	switch rollout.Phase {
+	case v1beta1.PostgresImageRolloutHalted:
+		return false
+	case v1beta1.PostgresImageRolloutReplicas:
+		primary, known := instance.IsPrimary()
+		return known && !primary
+	}
+	return true
+}
+
 // scaleDownInstances removes extra instances from a cluster until it matches
 // the spec. This function can delete the primary instance and force the
 // cluster to failover under two conditions:
@@ -1361,18 +1581,31 @@ func (r *Reconciler) reconcileInstanceCertificates(
 
 	var leafCert *pki.LeafCertificate
 
-	if err == nil {
+	if err == nil && cluster.Spec.CertManager == nil {
 		leafCert, err = r.instanceCertificate(ctx, instance, existing, instanceCerts, root)
+
+		if err == nil {
+			err = patroni.InstanceCertificates(ctx,
+				root.Authorities(), leafCert.Certificate,
+				leafCert.PrivateKey, instanceCerts)
+		}
+		if err == nil {
+			err = pgbackrest.InstanceCertificates(ctx, cluster,
+				root.Certificate, leafCert.Certificate, leafCert.PrivateKey,
+				instanceCerts)
+		}
 	}
-	if err == nil {
-		err = patroni.InstanceCertificates(ctx,
-			root.Certificate, leafCert.Certificate,
-			leafCert.PrivateKey, instanceCerts)
-	}
-	if err == nil {
-		err = pgbackrest.InstanceCertificates(ctx, cluster,
-			root.Certificate, leafCert.Certificate, leafCert.PrivateKey,
-			instanceCerts)
+	if err == nil && cluster.Spec.CertManager != nil {
+		err = r.instanceCertManagerCertificate(ctx, cluster, instance, instanceCerts)
+
+		if err == nil && pgbackrest.DedicatedRepoHostEnabled(cluster) {
+			var issued *corev1.Secret
+			issued, err = r.getCertManagerSecret(ctx,
+				naming.CertManagerCertificate(cluster, naming.CertManagerPGBackRest))
+			if err == nil && certManagerSecretIssued(issued) {
+				pgbackrest.CertManagerInstanceCertificates(cluster, issued, instanceCerts)
+			}
+		}
 	}
 	if err == nil {
 		err = errors.WithStack(r.apply(ctx, instanceCerts))
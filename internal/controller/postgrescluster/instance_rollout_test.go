@@ -17,9 +17,11 @@ package postgrescluster
 
 import (
 	"context"
+	"errors"
 	"io"
 	"strings"
 	"testing"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -31,6 +33,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -171,6 +174,80 @@ func TestReconcilerRolloutInstance(t *testing.T) {
 			err := reconciler.rolloutInstance(ctx, cluster, observed, instances[0])
 			assert.ErrorContains(t, err, "switchover")
 		})
+
+		t.Run("PausePGBouncer", func(t *testing.T) {
+			cluster := new(v1beta1.PostgresCluster)
+			cluster.Namespace, cluster.Name = "ns1", "hippo"
+			cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+			cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+			cluster.Spec.Proxy.PGBouncer.Switchover = &v1beta1.PGBouncerSwitchoverSpec{Pause: true}
+			cluster.Default()
+
+			pgbouncerPod := func(name string, ready corev1.ConditionStatus) *corev1.Pod {
+				return &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "ns1", Name: name,
+						Labels: map[string]string{
+							"postgres-operator.crunchydata.com/cluster": "hippo",
+							"postgres-operator.crunchydata.com/role":    "pgbouncer",
+						},
+					},
+					Status: corev1.PodStatus{
+						Conditions: []corev1.PodCondition{{
+							Type: corev1.PodReady, Status: ready,
+						}},
+					},
+				}
+			}
+
+			var calls []string
+			var annotations []string
+			reconciler := &Reconciler{}
+			reconciler.Client = fake.NewClientBuilder().WithObjects(
+				pgbouncerPod("ready-bouncer", corev1.ConditionTrue),
+				pgbouncerPod("other-bouncer", corev1.ConditionFalse),
+			).Build()
+			reconciler.Recorder = new(record.FakeRecorder)
+			reconciler.Tracer = otel.Tracer(t.Name())
+			reconciler.PodExec = func(
+				namespace, pod, container string, _ io.Reader, stdout, _ io.Writer, command ...string,
+			) error {
+				assert.Equal(t, namespace, "ns1")
+				calls = append(calls, pod+"/"+container+": "+command[len(command)-1])
+
+				// The deadline is recorded on the paused Pod.
+				if container == "pgbouncer" {
+					bouncer := &corev1.Pod{}
+					assert.NilError(t, reconciler.Client.Get(ctx,
+						client.ObjectKey{Namespace: namespace, Name: pod}, bouncer))
+					annotations = append(annotations,
+						bouncer.Annotations["postgres-operator.crunchydata.com/pgbouncer-paused-until"])
+				}
+
+				// Indicate success through stdout.
+				_, _ = stdout.Write([]byte("switched over"))
+
+				return nil
+			}
+
+			assert.NilError(t, reconciler.rolloutInstance(ctx, cluster, observed, instances[0]))
+
+			// Only the ready PgBouncer is paused; it is resumed after the switchover.
+			assert.Equal(t, len(calls), 3, "got %q", calls)
+			assert.Assert(t, cmp.Contains(calls[0], "ready-bouncer/pgbouncer: 30"))
+			assert.Assert(t, cmp.Contains(calls[1], "the-pod/database: --candidate="))
+			assert.Assert(t, cmp.Contains(calls[2], "ready-bouncer/pgbouncer: --command=RESUME"))
+
+			// The deadline is removed after PgBouncer resumes.
+			assert.Equal(t, len(annotations), 2)
+			assert.Assert(t, annotations[0] != "")
+			assert.Equal(t, annotations[0], annotations[1])
+
+			bouncer := &corev1.Pod{}
+			assert.NilError(t, reconciler.Client.Get(ctx,
+				client.ObjectKey{Namespace: "ns1", Name: "ready-bouncer"}, bouncer))
+			assert.Assert(t, bouncer.Annotations == nil, "got %v", bouncer.Annotations)
+		})
 	})
 }
 
@@ -652,3 +729,217 @@ func TestReconcilerRolloutInstances(t *testing.T) {
 			}))
 	})
 }
+
+func TestPostgresContainerFailing(t *testing.T) {
+	now := time.Now()
+	pod := func(state corev1.ContainerState) *corev1.Pod {
+		return &corev1.Pod{Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "database", State: state}},
+		}}
+	}
+
+	assert.Assert(t, !postgresContainerFailing(&corev1.Pod{}, now))
+	assert.Assert(t, postgresContainerFailing(pod(corev1.ContainerState{
+		Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"},
+	}), now))
+	assert.Assert(t, !postgresContainerFailing(pod(corev1.ContainerState{
+		Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"},
+	}), now))
+	assert.Assert(t, !postgresContainerFailing(pod(corev1.ContainerState{
+		Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(now.Add(-time.Minute))},
+	}), now))
+	assert.Assert(t, postgresContainerFailing(pod(corev1.ContainerState{
+		Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(now.Add(-time.Hour))},
+	}), now))
+}
+
+func TestReconcileImageRollout(t *testing.T) {
+	ctx := context.Background()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.PostgresVersion = 16
+	cluster.Spec.Image = "postgres:16.2"
+
+	instance := func(name, role, image string, ready corev1.ConditionStatus) *Instance {
+		return &Instance{
+			Name: name,
+			Spec: &v1beta1.PostgresInstanceSetSpec{},
+			Pods: []*corev1.Pod{{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "ns1", Name: name + "-0",
+					Labels: map[string]string{"postgres-operator.crunchydata.com/role": role},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "database", Image: image}},
+				},
+				Status: corev1.PodStatus{
+					Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+				},
+			}},
+		}
+	}
+
+	versions := map[string]string{}
+	reconciler := &Reconciler{Recorder: new(record.FakeRecorder)}
+	reconciler.PodExec = func(
+		namespace, pod, container string, _ io.Reader, stdout, _ io.Writer, _ ...string,
+	) error {
+		assert.Equal(t, namespace, "ns1")
+		assert.Equal(t, container, "database")
+		_, err := stdout.Write([]byte(versions[pod] + "\n"))
+		return err
+	}
+
+	t.Run("Steady", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		observed := &observedInstances{forCluster: []*Instance{
+			instance("one", "master", "postgres:16.2", corev1.ConditionTrue),
+		}}
+
+		result, err := reconciler.reconcileImageRollout(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Assert(t, result.IsZero())
+		assert.Assert(t, cluster.Status.ImageRollout == nil)
+	})
+
+	t.Run("Replicas", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		primary := instance("one", "master", "postgres:16.1", corev1.ConditionTrue)
+		replica := instance("two", "replica", "postgres:16.2", corev1.ConditionTrue)
+		observed := &observedInstances{forCluster: []*Instance{primary, replica}}
+
+		versions["one-0"], versions["two-0"] = "160001", "160002"
+		replica.Pods[0].Status.Conditions[0].Status = corev1.ConditionFalse
+
+		result, err := reconciler.reconcileImageRollout(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, 10*time.Second)
+
+		rollout := cluster.Status.ImageRollout
+		assert.Assert(t, rollout != nil)
+		assert.Equal(t, rollout.Image, "postgres:16.2")
+		assert.Equal(t, rollout.Phase, "Replicas")
+		assert.DeepEqual(t, rollout.Members, []v1beta1.PostgresMemberVersionStatus{
+			{Name: "one", Image: "postgres:16.1", ServerVersionNum: 160001},
+			{Name: "two", Image: "postgres:16.2"},
+		})
+
+		// The primary waits for the replica.
+		assert.Assert(t, !imageRolloutAllows(cluster, primary))
+
+		// The replica is verified once it is ready.
+		replica.Pods[0].Status.Conditions[0].Status = corev1.ConditionTrue
+
+		_, err = reconciler.reconcileImageRollout(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Equal(t, rollout.Phase, "Switchover")
+		assert.Equal(t, rollout.Members[1].ServerVersionNum, 160002)
+		assert.Assert(t, imageRolloutAllows(cluster, primary))
+
+		// The rollout completes when the primary is on the new image.
+		primary.Pods[0].Spec.Containers[0].Image = "postgres:16.2"
+		versions["one-0"] = "160002"
+
+		result, err = reconciler.reconcileImageRollout(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Assert(t, result.IsZero())
+		assert.Equal(t, rollout.Phase, "Complete")
+		assert.DeepEqual(t, rollout.Members, []v1beta1.PostgresMemberVersionStatus{
+			{Name: "one", Image: "postgres:16.2", ServerVersionNum: 160002},
+			{Name: "two", Image: "postgres:16.2", ServerVersionNum: 160002},
+		})
+	})
+
+	t.Run("Halted", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		primary := instance("one", "master", "postgres:16.1", corev1.ConditionTrue)
+		failing := instance("two", "replica", "postgres:16.2", corev1.ConditionFalse)
+		waiting := instance("three", "replica", "postgres:16.1", corev1.ConditionTrue)
+		observed := &observedInstances{forCluster: []*Instance{primary, failing, waiting}}
+
+		versions["one-0"], versions["three-0"] = "160001", "160001"
+		failing.Pods[0].Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name: "database",
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+			},
+		}}
+
+		result, err := reconciler.reconcileImageRollout(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Equal(t, cluster.Status.ImageRollout.Phase, "Halted")
+		assert.Assert(t, result.IsZero(), "expected Pod events to continue the rollout")
+
+		// Nothing else moves onto the new image, but the failing replica can
+		// be redeployed.
+		assert.Assert(t, !imageRolloutAllows(cluster, primary))
+		assert.Assert(t, !imageRolloutAllows(cluster, waiting))
+		assert.Assert(t, imageRolloutAllows(cluster, failing))
+
+		// The rollout continues when the replica starts.
+		failing.Pods[0].Status.ContainerStatuses = nil
+		failing.Pods[0].Status.Conditions[0].Status = corev1.ConditionTrue
+		versions["two-0"] = "160002"
+
+		_, err = reconciler.reconcileImageRollout(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Equal(t, cluster.Status.ImageRollout.Phase, "Replicas")
+		assert.Assert(t, imageRolloutAllows(cluster, waiting))
+		assert.Assert(t, !imageRolloutAllows(cluster, primary))
+	})
+
+	t.Run("WrongMajorVersion", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		observed := &observedInstances{forCluster: []*Instance{
+			instance("one", "master", "postgres:16.1", corev1.ConditionTrue),
+			instance("two", "replica", "postgres:16.2", corev1.ConditionTrue),
+		}}
+
+		versions["one-0"], versions["two-0"] = "160001", "170000"
+
+		_, err := reconciler.reconcileImageRollout(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Equal(t, cluster.Status.ImageRollout.Phase, "Halted")
+	})
+
+	t.Run("ExecError", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		observed := &observedInstances{forCluster: []*Instance{
+			instance("one", "master", "postgres:16.1", corev1.ConditionTrue),
+			instance("two", "replica", "postgres:16.2", corev1.ConditionTrue),
+		}}
+
+		reconciler := &Reconciler{Recorder: new(record.FakeRecorder)}
+		reconciler.PodExec = func(
+			_, pod, _ string, _ io.Reader, stdout, _ io.Writer, _ ...string,
+		) error {
+			if pod == "one-0" {
+				return errors.New("boom")
+			}
+			_, err := stdout.Write([]byte("160002\n"))
+			return err
+		}
+
+		result, err := reconciler.reconcileImageRollout(ctx, cluster, observed)
+		assert.NilError(t, err, "expected the member to stay unverified")
+		assert.Equal(t, result.RequeueAfter, 10*time.Second)
+		assert.DeepEqual(t, cluster.Status.ImageRollout.Members, []v1beta1.PostgresMemberVersionStatus{
+			{Name: "one", Image: "postgres:16.1"},
+			{Name: "two", Image: "postgres:16.2", ServerVersionNum: 160002},
+		})
+	})
+
+	t.Run("NoPods", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Status.ImageRollout = &v1beta1.PostgresImageRolloutStatus{
+			Image: "postgres:16.2", Phase: "Replicas",
+		}
+		observed := &observedInstances{forCluster: []*Instance{
+			{Name: "one", Spec: &v1beta1.PostgresInstanceSetSpec{}},
+		}}
+
+		result, err := reconciler.reconcileImageRollout(ctx, cluster, observed)
+		assert.NilError(t, err)
+		assert.Assert(t, result.IsZero(), "expected Pod events to continue the rollout")
+	})
+}
@@ -375,6 +375,12 @@ func (r *Reconciler) reconcileReplicationSecret(
 		return custom, err
 	}
 
+	// cert-manager writes the same three files
+	if cluster.Spec.CertManager != nil {
+		return r.getCertManagerSecret(ctx,
+			naming.CertManagerCertificate(cluster, naming.CertManagerReplication))
+	}
+
 	existing := &corev1.Secret{ObjectMeta: naming.ReplicationClientCertSecret(cluster)}
 	err := errors.WithStack(client.IgnoreNotFound(
 		r.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing)))
@@ -420,7 +426,7 @@ func (r *Reconciler) reconcileReplicationSecret(
 		err = errors.WithStack(err)
 	}
 	if err == nil {
-		intent.Data[naming.ReplicationCACert], err = root.Certificate.MarshalText()
+		intent.Data[naming.ReplicationCACert], err = root.Authorities().MarshalText()
 		err = errors.WithStack(err)
 	}
 	if err == nil {
@@ -599,7 +605,11 @@ func (r *Reconciler) reconcilePatroniSwitchover(ctx context.Context,
 		nextPrimary = targetInstance.Pods[0].Name
 	}
 
+	// Clients of PgBouncer wait rather than fail while the primary changes.
+	resume := r.pausePGBouncer(ctx, cluster)
 	success, err := action(ctx, exec, nextPrimary)
+	resume()
+
 	if err = errors.WithStack(err); err == nil && !success {
 		err = errors.New("unable to switchover")
 	}
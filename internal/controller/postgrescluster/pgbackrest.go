@@ -1514,6 +1514,22 @@ func (r *Reconciler) reconcilePostgresClusterDataSource(ctx context.Context,
 				"PostgreSQL data for the cluster: %w", err)
 		}
 	} else {
+		// A PostgresCluster in another namespace must grant access to its
+		// repositories before its configuration and credentials are copied.
+		if sourceClusterNamespace != cluster.GetNamespace() {
+			granted, err := r.restoreGranted(ctx,
+				sourceClusterNamespace, sourceClusterName, cluster.GetNamespace())
+			if err != nil {
+				return err
+			}
+			if !granted {
+				r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "InvalidDataSource",
+					"No RestoreGrant in namespace %q allows restoring PostgresCluster %q into namespace %q",
+					sourceClusterNamespace, sourceClusterName, cluster.GetNamespace())
+				return nil
+			}
+		}
+
 		if err := r.Client.Get(ctx,
 			client.ObjectKey{Name: sourceClusterName, Namespace: sourceClusterNamespace},
 			sourceCluster); err != nil {
@@ -1685,6 +1701,197 @@ func (r *Reconciler) reconcileCloudBasedDataSource(ctx context.Context,
 	return nil
 }
 
+// +kubebuilder:rbac:groups="",resources="persistentvolumeclaims",verbs={create,patch}
+// +kubebuilder:rbac:groups="batch",resources="jobs",verbs={create,patch,delete}
+// +kubebuilder:rbac:groups="postgres-operator.crunchydata.com",resources="postgresclusters",verbs={get}
+
+// reconcileStreamingDataSource is responsible for reconciling a streaming PostgresCluster
+// data source. This is done by running pg_basebackup against a running PostgresCluster in the
+// same namespace to populate a PostgreSQL data volume for the PostgresCluster being reconciled.
+// The connection uses the replication client certificate of the source cluster.
+func (r *Reconciler) reconcileStreamingDataSource(ctx context.Context,
+	cluster *v1beta1.PostgresCluster, dataSource *v1beta1.StreamingDataSource,
+	configHash string, clusterVolumes []corev1.PersistentVolumeClaim) error {
+
+	// Ensure the proper instance and instance set can be identified via the status.  The
+	// StartupInstance and StartupInstanceSet values should be populated when the cluster
+	// is being prepared for a restore, and should therefore always exist at this point.
+	// Therefore, if either are not found it is treated as an error.
+	instanceName := cluster.Status.StartupInstance
+	if instanceName == "" {
+		return errors.WithStack(
+			errors.New("unable to find instance name for pg_basebackup Job"))
+	}
+	instanceSetName := cluster.Status.StartupInstanceSet
+	if instanceSetName == "" {
+		return errors.WithStack(
+			errors.New("unable to find instance set name for pg_basebackup Job"))
+	}
+
+	var instanceSet *v1beta1.PostgresInstanceSetSpec
+	for i, set := range cluster.Spec.InstanceSets {
+		if set.Name == instanceSetName {
+			instanceSet = &cluster.Spec.InstanceSets[i]
+			break
+		}
+	}
+	if instanceSet == nil {
+		return errors.WithStack(
+			errors.New("unable to determine the proper instance set for the restore"))
+	}
+
+	// If the cluster is already bootstrapped, or if the bootstrap Job is complete, then
+	// nothing to do.  However, also ensure the "data sources initialized" condition is set
+	// to true if for some reason it doesn't exist (e.g. if it was deleted since the
+	// data source for the cluster was initialized).
+	if patroni.ClusterBootstrapped(cluster) {
+		condition := meta.FindStatusCondition(cluster.Status.Conditions,
+			ConditionPostgresDataInitialized)
+		if condition == nil || (condition.Status != metav1.ConditionTrue) {
+			meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+				ObservedGeneration: cluster.GetGeneration(),
+				Type:               ConditionPostgresDataInitialized,
+				Status:             metav1.ConditionTrue,
+				Reason:             "ClusterAlreadyBootstrapped",
+				Message:            "The cluster is already bootstrapped",
+			})
+		}
+		return nil
+	}
+
+	sourceCluster := &v1beta1.PostgresCluster{}
+	if err := r.Client.Get(ctx,
+		client.ObjectKey{Name: dataSource.ClusterName, Namespace: cluster.GetNamespace()},
+		sourceCluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "InvalidDataSource",
+				"PostgresCluster %q does not exist", dataSource.ClusterName)
+			return nil
+		}
+		return errors.WithStack(err)
+	}
+	sourceCluster.Default()
+
+	// pg_basebackup copies files; the versions must match.
+	if sourceCluster.Spec.PostgresVersion != cluster.Spec.PostgresVersion {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "InvalidDataSource",
+			"PostgresCluster %q has PostgreSQL version %d, expected %d",
+			dataSource.ClusterName, sourceCluster.Spec.PostgresVersion,
+			cluster.Spec.PostgresVersion)
+		return nil
+	}
+
+	// Define a fake STS to use when calling the reconcile functions below since when
+	// bootstrapping the cluster it will not exist until after the copy is complete.
+	fakeSTS := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+		Name:      instanceName,
+		Namespace: cluster.GetNamespace(),
+	}}
+	// Reconcile the PGDATA and WAL volumes for the copy
+	pgdata, err := r.reconcilePostgresDataVolume(ctx, cluster, instanceSet, fakeSTS, clusterVolumes)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	pgwal, err := r.reconcilePostgresWALVolume(ctx, cluster, instanceSet, fakeSTS, nil, clusterVolumes)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	cmd := postgres.BaseBackupCommand(
+		postgres.DataDirectory(cluster), postgres.WALDirectory(cluster, instanceSet))
+
+	// create the volume resources required for the postgres data directory
+	dataVolumeMount := postgres.DataVolumeMount()
+	volumes := []corev1.Volume{{
+		Name: dataVolumeMount.Name,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: pgdata.GetName(),
+			},
+		},
+	}}
+	volumeMounts := []corev1.VolumeMount{dataVolumeMount}
+
+	if pgwal != nil {
+		walVolumeMount := postgres.WALVolumeMount()
+		volumes = append(volumes, corev1.Volume{
+			Name: walVolumeMount.Name,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: pgwal.GetName(),
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, walVolumeMount)
+	}
+
+	// Mount the replication client certificate of the source cluster.
+	volumes = append(volumes, corev1.Volume{
+		Name: "replication-cert",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: replicationSecretName(sourceCluster),
+				Items: []corev1.KeyToPath{
+					{Key: naming.ReplicationCert, Path: naming.ReplicationCert},
+					{Key: naming.ReplicationPrivateKey, Path: naming.ReplicationPrivateKey},
+					{Key: naming.ReplicationCACert, Path: naming.ReplicationCACert},
+				},
+			},
+		},
+	})
+	volumeMounts = append(volumeMounts, corev1.VolumeMount{
+		Name:      "replication-cert",
+		MountPath: naming.CertMountPath + naming.ReplicationDirectory,
+		ReadOnly:  true,
+	})
+
+	// The restore Job is generated from a PostgresClusterDataSource; translate the
+	// scheduling and resource fields of the StreamingDataSource.
+	tmpDataSource := &v1beta1.PostgresClusterDataSource{
+		Resources:         dataSource.Resources,
+		Affinity:          dataSource.Affinity,
+		Tolerations:       dataSource.Tolerations,
+		PriorityClassName: dataSource.PriorityClassName,
+	}
+
+	restoreJob := &batchv1.Job{}
+	if err := r.generateRestoreJobIntent(cluster, configHash, instanceName, cmd,
+		volumeMounts, volumes, tmpDataSource, restoreJob); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Connect to the primary or replica Service of the source cluster.
+	service := naming.ClusterPrimaryService(sourceCluster)
+	if dataSource.Role == "replica" {
+		service = naming.ClusterReplicaService(sourceCluster)
+	}
+	restoreJob.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{
+		{Name: "PGHOST", Value: service.Name + "." + service.Namespace + ".svc"},
+		{Name: "PGPORT", Value: fmt.Sprint(*sourceCluster.Spec.Port)},
+		{Name: "PGUSER", Value: postgres.ReplicationUser},
+		{Name: "PGSSLMODE", Value: "verify-ca"},
+		{Name: "PGSSLCERT", Value: naming.ReplicationTmp + "/" + naming.ReplicationCert},
+		{Name: "PGSSLKEY", Value: naming.ReplicationTmp + "/" + naming.ReplicationPrivateKey},
+		{Name: "PGSSLROOTCERT", Value: naming.ReplicationTmp + "/" + naming.ReplicationCACert},
+	}
+
+	addTMPEmptyDir(&restoreJob.Spec.Template)
+
+	return errors.WithStack(r.apply(ctx, restoreJob))
+}
+
+// replicationSecretName returns the name of the Secret that contains the
+// replication client certificate of cluster.
+func replicationSecretName(cluster *v1beta1.PostgresCluster) string {
+	switch {
+	case cluster.Spec.CustomReplicationClientTLSSecret != nil:
+		return cluster.Spec.CustomReplicationClientTLSSecret.Name
+	case cluster.Spec.CertManager != nil:
+		return naming.CertManagerCertificate(cluster, naming.CertManagerReplication).Name
+	}
+	return naming.ReplicationClientCertSecret(cluster).Name
+}
+
 // createRestoreConfig creates a configmap struct with pgBackRest pgbackrest.conf settings
 // in the data field, for use with restoring from cloud-based data sources
 func (r *Reconciler) createRestoreConfig(ctx context.Context, postgresCluster *v1beta1.PostgresCluster,
@@ -1701,6 +1908,25 @@ func (r *Reconciler) createRestoreConfig(ctx context.Context, postgresCluster *v
 		"", configHash, "", "", []string{})
 }
 
+// +kubebuilder:rbac:groups="postgres-operator.crunchydata.com",resources="restoregrants",verbs={list}
+
+// restoreGranted returns whether or not a RestoreGrant in sourceNamespace
+// allows the PostgresCluster named sourceName to be restored into namespace.
+func (r *Reconciler) restoreGranted(ctx context.Context,
+	sourceNamespace, sourceName, namespace string,
+) (bool, error) {
+	grants := &v1beta1.RestoreGrantList{}
+	err := errors.WithStack(
+		r.Client.List(ctx, grants, client.InNamespace(sourceNamespace)))
+
+	for i := range grants.Items {
+		if err == nil && grants.Items[i].Spec.Allows(sourceName, namespace) {
+			return true, nil
+		}
+	}
+	return false, err
+}
+
 // copyRestoreConfiguration copies pgBackRest configuration from another cluster for use by
 // the current PostgresCluster (e.g. when restoring across namespaces, and the configuration
 // for the source cluster needs to be copied into the PostgresCluster's local namespace).
@@ -1963,9 +2189,17 @@ func (r *Reconciler) reconcilePGBackRestSecret(ctx context.Context,
 	if err == nil {
 		err = r.setControllerReference(cluster, intent)
 	}
-	if err == nil {
+	if err == nil && cluster.Spec.CertManager == nil {
 		err = pgbackrest.Secret(ctx, cluster, repoHost, rootCA, existing, intent)
 	}
+	if err == nil && cluster.Spec.CertManager != nil && repoHost != nil {
+		var issued *corev1.Secret
+		issued, err = r.getCertManagerSecret(ctx,
+			naming.CertManagerCertificate(cluster, naming.CertManagerPGBackRest))
+		if err == nil && certManagerSecretIssued(issued) {
+			pgbackrest.CertManagerSecret(repoHost, issued, intent)
+		}
+	}
 
 	// Delete the Secret when it exists and there is nothing we want to keep in it.
 	if err == nil && len(existing.UID) != 0 && len(intent.Data) == 0 {
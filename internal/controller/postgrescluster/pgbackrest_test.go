@@ -52,6 +52,7 @@ import (
 	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/pgbackrest"
 	"github.com/crunchydata/postgres-operator/internal/pki"
+	"github.com/crunchydata/postgres-operator/internal/testing/cmp"
 	"github.com/crunchydata/postgres-operator/internal/testing/require"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -2237,6 +2238,110 @@ func TestReconcileCloudBasedDataSource(t *testing.T) {
 	}
 }
 
+func TestReconcileStreamingDataSource(t *testing.T) {
+	cfg, tClient := setupKubernetes(t)
+	require.ParallelCapacity(t, 2)
+
+	r := &Reconciler{}
+	ctx, cancel := setupManager(t, cfg, func(mgr manager.Manager) {
+		r = &Reconciler{
+			Client:   tClient,
+			Recorder: mgr.GetEventRecorderFor(ControllerName),
+			Tracer:   otel.Tracer(ControllerName),
+			Owner:    ControllerName,
+		}
+	})
+	t.Cleanup(func() { teardownManager(cancel, t) })
+
+	namespace := setupNamespace(t, tClient).Name
+
+	source := fakePostgresCluster("streaming-source", namespace, "sourceuid", false)
+	assert.NilError(t, tClient.Create(ctx, source))
+
+	for i, tc := range []struct {
+		desc       string
+		dataSource *v1beta1.StreamingDataSource
+		version    int
+		jobCount   int
+		host       string
+	}{{
+		desc:       "primary",
+		dataSource: &v1beta1.StreamingDataSource{ClusterName: source.Name, Role: "primary"},
+		version:    source.Spec.PostgresVersion,
+		jobCount:   1,
+		host:       "streaming-source-primary." + namespace + ".svc",
+	}, {
+		desc:       "replica",
+		dataSource: &v1beta1.StreamingDataSource{ClusterName: source.Name, Role: "replica"},
+		version:    source.Spec.PostgresVersion,
+		jobCount:   1,
+		host:       "streaming-source-replicas." + namespace + ".svc",
+	}, {
+		desc:       "missing source",
+		dataSource: &v1beta1.StreamingDataSource{ClusterName: "missing"},
+		version:    source.Spec.PostgresVersion,
+		jobCount:   0,
+	}, {
+		desc:       "different version",
+		dataSource: &v1beta1.StreamingDataSource{ClusterName: source.Name},
+		version:    source.Spec.PostgresVersion + 1,
+		jobCount:   0,
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			clusterName := "streaming-" + strconv.Itoa(i)
+			cluster := fakePostgresCluster(clusterName, namespace, "streaminguid"+strconv.Itoa(i), false)
+			cluster.Spec.PostgresVersion = tc.version
+			cluster.Spec.DataSource = &v1beta1.DataSource{Streaming: tc.dataSource}
+			assert.NilError(t, tClient.Create(ctx, cluster))
+
+			cluster.Status.StartupInstance = "testinstance"
+			cluster.Status.StartupInstanceSet = "instance1"
+			assert.NilError(t, tClient.Status().Update(ctx, cluster))
+
+			assert.NilError(t, r.reconcileStreamingDataSource(ctx,
+				cluster, tc.dataSource, "testhash", nil))
+
+			restoreJobs := &batchv1.JobList{}
+			assert.NilError(t, tClient.List(ctx, restoreJobs, &client.ListOptions{
+				LabelSelector: naming.PGBackRestRestoreJobSelector(clusterName),
+				Namespace:     cluster.Namespace,
+			}))
+			assert.Equal(t, len(restoreJobs.Items), tc.jobCount)
+
+			if tc.jobCount == 1 {
+				job := restoreJobs.Items[0]
+				assert.Equal(t, job.Labels[naming.LabelStartupInstance], "testinstance")
+
+				container := job.Spec.Template.Spec.Containers[0]
+				assert.Assert(t, cmp.Contains(container.Command[3], "pg_basebackup"))
+				assert.Assert(t, cmp.Contains(container.Env,
+					corev1.EnvVar{Name: "PGHOST", Value: tc.host}))
+				assert.Assert(t, cmp.Contains(container.Env,
+					corev1.EnvVar{Name: "PGUSER", Value: "_crunchyrepl"}))
+
+				var secretName string
+				for _, volume := range job.Spec.Template.Spec.Volumes {
+					if volume.Secret != nil {
+						secretName = volume.Secret.SecretName
+					}
+				}
+				assert.Equal(t, secretName, "streaming-source-replication-cert")
+			}
+		})
+	}
+}
+
+func TestReplicationSecretName(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Name = "hippo"
+	assert.Equal(t, replicationSecretName(cluster), "hippo-replication-cert")
+
+	cluster.Spec.CustomReplicationClientTLSSecret = &corev1.SecretProjection{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "custom"},
+	}
+	assert.Equal(t, replicationSecretName(cluster), "custom")
+}
+
 func TestCopyConfigurationResources(t *testing.T) {
 	_, tClient := setupKubernetes(t)
 	ctx := context.Background()
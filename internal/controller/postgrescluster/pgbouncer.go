@@ -17,19 +17,30 @@ package postgrescluster
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/kubeapi"
 	"github.com/crunchydata/postgres-operator/internal/logging"
 	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/pgbouncer"
@@ -42,25 +53,36 @@ import (
 func (r *Reconciler) reconcilePGBouncer(
 	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
 	primaryCertificate *corev1.SecretProjection,
-	root *pki.RootCertificateAuthority,
+	root *pki.RootCertificateAuthority, monitoringSecret *corev1.Secret,
 ) error {
 	var (
 		configmap *corev1.ConfigMap
+		console   *corev1.Secret
 		secret    *corev1.Secret
 	)
 
-	service, err := r.reconcilePGBouncerService(ctx, cluster)
+	// Console users are checked before they appear in the configuration.
+	service, err := r.reconcilePGBouncerService(ctx, cluster, "")
 	if err == nil {
-		configmap, err = r.reconcilePGBouncerConfigMap(ctx, cluster)
+		console, err = r.reconcilePGBouncerConsoleSecret(ctx, cluster, service)
 	}
 	if err == nil {
-		secret, err = r.reconcilePGBouncerSecret(ctx, cluster, root, service)
+		configmap, err = r.reconcilePGBouncerConfigMap(ctx, cluster, "")
 	}
 	if err == nil {
-		err = r.reconcilePGBouncerDeployment(ctx, cluster, primaryCertificate, configmap, secret)
+		secret, err = r.reconcilePGBouncerSecret(ctx, cluster, root, service, monitoringSecret, console)
 	}
 	if err == nil {
-		err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster)
+		err = r.reconcilePGBouncerDeployment(ctx, cluster, "", primaryCertificate, configmap, secret)
+	}
+	if err == nil {
+		err = r.reconcilePGBouncerHorizontalPodAutoscaler(ctx, cluster)
+	}
+	if err == nil {
+		err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, "")
+	}
+	if err == nil {
+		err = r.reconcilePGBouncerPoolers(ctx, cluster, primaryCertificate, secret)
 	}
 	if err == nil {
 		err = r.reconcilePGBouncerInPostgreSQL(ctx, cluster, instances, secret)
@@ -68,15 +90,91 @@ func (r *Reconciler) reconcilePGBouncer(
 	return err
 }
 
+// reconcilePGBouncerPoolers writes the objects of each additional PgBouncer
+// pooler. These share secret with the PgBouncer of spec.proxy.pgBouncer.
+func (r *Reconciler) reconcilePGBouncerPoolers(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	primaryCertificate *corev1.SecretProjection, secret *corev1.Secret,
+) error {
+	var err error
+
+	// Each Deployment reports its status below.
+	cluster.Status.Proxy.Poolers = nil
+
+	for _, pooler := range pgbouncer.PoolerNames(cluster) {
+		var configmap *corev1.ConfigMap
+
+		if err == nil {
+			_, err = r.reconcilePGBouncerService(ctx, cluster, pooler)
+		}
+		if err == nil {
+			configmap, err = r.reconcilePGBouncerConfigMap(ctx, cluster, pooler)
+		}
+		if err == nil {
+			err = r.reconcilePGBouncerDeployment(ctx, cluster, pooler,
+				primaryCertificate, configmap, secret)
+		}
+		if err == nil {
+			err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, pooler)
+		}
+	}
+
+	if err == nil {
+		err = r.deleteRemovedPGBouncerPoolers(ctx, cluster)
+	}
+	return err
+}
+
+// +kubebuilder:rbac:groups="",resources="configmaps",verbs={list}
+// +kubebuilder:rbac:groups="",resources="services",verbs={list}
+// +kubebuilder:rbac:groups="apps",resources="deployments",verbs={list}
+// +kubebuilder:rbac:groups="policy",resources="poddisruptionbudgets",verbs={list}
+
+// deleteRemovedPGBouncerPoolers deletes the objects of additional PgBouncer
+// poolers that are no longer in the spec.
+func (r *Reconciler) deleteRemovedPGBouncerPoolers(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) error {
+	selector, err := naming.AsSelector(naming.ClusterPGBouncerPoolers(cluster.Name))
+	poolers := sets.NewString(pgbouncer.PoolerNames(cluster)...)
+
+	for _, list := range []client.ObjectList{
+		&appsv1.DeploymentList{},
+		&policyv1.PodDisruptionBudgetList{},
+		&corev1.ServiceList{},
+		&corev1.ConfigMapList{},
+	} {
+		var items []runtime.Object
+		if err == nil {
+			err = errors.WithStack(r.Client.List(ctx, list,
+				client.InNamespace(cluster.Namespace),
+				client.MatchingLabelsSelector{Selector: selector},
+			))
+		}
+		if err == nil {
+			items, err = meta.ExtractList(list)
+		}
+		for i := range items {
+			object := items[i].(client.Object)
+			if err == nil && !poolers.Has(object.GetLabels()[naming.LabelPGBouncerPooler]) {
+				err = client.IgnoreNotFound(r.deleteControlled(ctx, cluster, object))
+			}
+		}
+	}
+
+	return err
+}
+
 // +kubebuilder:rbac:groups="",resources="configmaps",verbs={get}
 // +kubebuilder:rbac:groups="",resources="configmaps",verbs={create,delete,patch}
 
-// reconcilePGBouncerConfigMap writes the ConfigMap for a PgBouncer Pod.
+// reconcilePGBouncerConfigMap writes the ConfigMap for the Pods of pooler.
 func (r *Reconciler) reconcilePGBouncerConfigMap(
-	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	ctx context.Context, cluster *v1beta1.PostgresCluster, pooler string,
 ) (*corev1.ConfigMap, error) {
-	configmap := &corev1.ConfigMap{ObjectMeta: naming.ClusterPGBouncer(cluster)}
+	configmap := &corev1.ConfigMap{ObjectMeta: naming.ClusterPGBouncerPooler(cluster, pooler)}
 	configmap.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+	cluster = pgbouncer.Pooler(cluster, pooler)
 
 	if cluster.Spec.Proxy == nil || cluster.Spec.Proxy.PGBouncer == nil {
 		// PgBouncer is disabled; delete the ConfigMap if it exists. Check the
@@ -97,10 +195,7 @@ func (r *Reconciler) reconcilePGBouncerConfigMap(
 	configmap.Labels = naming.Merge(
 		cluster.Spec.Metadata.GetLabelsOrNil(),
 		cluster.Spec.Proxy.PGBouncer.Metadata.GetLabelsOrNil(),
-		map[string]string{
-			naming.LabelCluster: cluster.Name,
-			naming.LabelRole:    naming.RolePGBouncer,
-		})
+		naming.ClusterPGBouncerPoolerSelector(cluster, pooler).MatchLabels)
 
 	if err == nil {
 		pgbouncer.ConfigMap(cluster, configmap)
@@ -199,6 +294,7 @@ func (r *Reconciler) reconcilePGBouncerInPostgreSQL(
 func (r *Reconciler) reconcilePGBouncerSecret(
 	ctx context.Context, cluster *v1beta1.PostgresCluster,
 	root *pki.RootCertificateAuthority, service *corev1.Service,
+	monitoringSecret, consoleSecret *corev1.Secret,
 ) (*corev1.Secret, error) {
 	existing := &corev1.Secret{ObjectMeta: naming.ClusterPGBouncer(cluster)}
 	err := errors.WithStack(
@@ -237,7 +333,7 @@ func (r *Reconciler) reconcilePGBouncerSecret(
 		})
 
 	if err == nil {
-		err = pgbouncer.Secret(ctx, cluster, root, existing, service, intent)
+		err = pgbouncer.Secret(ctx, cluster, root, existing, service, monitoringSecret, consoleSecret, intent)
 	}
 	if err == nil {
 		err = errors.WithStack(r.apply(ctx, intent))
@@ -246,13 +342,115 @@ func (r *Reconciler) reconcilePGBouncerSecret(
 	return intent, err
 }
 
-// generatePGBouncerService returns a v1.Service that exposes PgBouncer pods.
-// The ServiceType comes from the cluster proxy spec.
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={get}
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={create,delete,patch}
+
+// reconcilePGBouncerConsoleSecret writes the Secret of PgBouncer admin console
+// users. It returns nil when there are none. It emits a warning and returns an
+// error when a console user has the name of a PostgreSQL user.
+func (r *Reconciler) reconcilePGBouncerConsoleSecret(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, service *corev1.Service,
+) (*corev1.Secret, error) {
+	if err := r.validatePGBouncerConsoleUsers(cluster); err != nil {
+		return nil, err
+	}
+
+	existing := &corev1.Secret{ObjectMeta: naming.ClusterPGBouncerConsole(cluster)}
+	err := errors.WithStack(
+		r.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing))
+	if client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+
+	if cluster.Spec.Proxy == nil || cluster.Spec.Proxy.PGBouncer == nil ||
+		cluster.Spec.Proxy.PGBouncer.Authentication == nil ||
+		len(cluster.Spec.Proxy.PGBouncer.Authentication.ConsoleUsers) == 0 {
+		// There are no console users; delete the Secret if it exists.
+		if err == nil {
+			err = errors.WithStack(r.deleteControlled(ctx, cluster, existing))
+		}
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	err = client.IgnoreNotFound(err)
+
+	intent := &corev1.Secret{ObjectMeta: naming.ClusterPGBouncerConsole(cluster)}
+	intent.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+	intent.Type = corev1.SecretTypeOpaque
+
+	if err == nil {
+		err = errors.WithStack(r.setControllerReference(cluster, intent))
+	}
+
+	intent.Annotations = naming.Merge(
+		cluster.Spec.Metadata.GetAnnotationsOrNil(),
+		cluster.Spec.Proxy.PGBouncer.Metadata.GetAnnotationsOrNil())
+	intent.Labels = naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		cluster.Spec.Proxy.PGBouncer.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster: cluster.Name,
+			naming.LabelRole:    naming.RolePGBouncer,
+		})
+
+	if err == nil {
+		err = pgbouncer.ConsoleSecret(cluster, existing, service, intent)
+	}
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, intent))
+	}
+
+	return intent, err
+}
+
+// validatePGBouncerConsoleUsers emits a warning and returns an error when an
+// admin console user of cluster has the name of a PostgreSQL user. PgBouncer
+// authenticates both kinds of user from the same auth_file, so a console user
+// would take over logins of that PostgreSQL user. NOTE(validation)
+func (r *Reconciler) validatePGBouncerConsoleUsers(cluster *v1beta1.PostgresCluster) error {
+	if cluster.Spec.Proxy == nil || cluster.Spec.Proxy.PGBouncer == nil ||
+		cluster.Spec.Proxy.PGBouncer.Authentication == nil {
+		return nil
+	}
+
+	// When users are unspecified, there is one matching the cluster name.
+	// See [Reconciler.reconcilePostgresUserSecrets].
+	users := sets.NewString("postgres")
+	if cluster.Spec.Users == nil {
+		users.Insert(cluster.Name)
+	}
+	for _, user := range cluster.Spec.Users {
+		users.Insert(string(user.Name))
+	}
+
+	path := field.NewPath("spec", "proxy", "pgBouncer", "authentication", "consoleUsers")
+	errs := field.ErrorList{}
+
+	for i, user := range cluster.Spec.Proxy.PGBouncer.Authentication.ConsoleUsers {
+		if users.Has(user.Name) {
+			errs = append(errs,
+				field.Invalid(path.Index(i).Child("name"), user.Name,
+					"cannot be the name of a PostgreSQL user"))
+		}
+	}
+
+	if len(errs) > 0 {
+		err := errs.ToAggregate()
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, "InvalidConsoleUser",
+			err.Error())
+		return err
+	}
+	return nil
+}
+
+// generatePGBouncerService returns a v1.Service that exposes the PgBouncer
+// pods of pooler. The ServiceType comes from the cluster proxy spec.
 func (r *Reconciler) generatePGBouncerService(
-	cluster *v1beta1.PostgresCluster) (*corev1.Service, bool, error,
+	cluster *v1beta1.PostgresCluster, pooler string) (*corev1.Service, bool, error,
 ) {
-	service := &corev1.Service{ObjectMeta: naming.ClusterPGBouncer(cluster)}
+	service := &corev1.Service{ObjectMeta: naming.ClusterPGBouncerPooler(cluster, pooler)}
 	service.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Service"))
+	cluster = pgbouncer.Pooler(cluster, pooler)
 
 	if cluster.Spec.Proxy == nil || cluster.Spec.Proxy.PGBouncer == nil {
 		return service, false, nil
@@ -274,18 +472,12 @@ func (r *Reconciler) generatePGBouncerService(
 
 	// add our labels last so they aren't overwritten
 	service.Labels = naming.Merge(service.Labels,
-		map[string]string{
-			naming.LabelCluster: cluster.Name,
-			naming.LabelRole:    naming.RolePGBouncer,
-		})
+		naming.ClusterPGBouncerPoolerSelector(cluster, pooler).MatchLabels)
 
 	// Allocate an IP address and/or node port and let Kubernetes manage the
 	// Endpoints by selecting Pods with the PgBouncer role.
 	// - https://docs.k8s.io/concepts/services-networking/service/#defining-a-service
-	service.Spec.Selector = map[string]string{
-		naming.LabelCluster: cluster.Name,
-		naming.LabelRole:    naming.RolePGBouncer,
-	}
+	service.Spec.Selector = naming.ClusterPGBouncerPoolerSelector(cluster, pooler).MatchLabels
 
 	// The TargetPort must be the name (not the number) of the PgBouncer
 	// ContainerPort. This name allows the port number to differ between Pods,
@@ -326,11 +518,12 @@ func (r *Reconciler) generatePGBouncerService(
 // +kubebuilder:rbac:groups="",resources="services",verbs={get}
 // +kubebuilder:rbac:groups="",resources="services",verbs={create,delete,patch}
 
-// reconcilePGBouncerService writes the Service that resolves to PgBouncer.
+// reconcilePGBouncerService writes the Service that resolves to the PgBouncer
+// of pooler.
 func (r *Reconciler) reconcilePGBouncerService(
-	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	ctx context.Context, cluster *v1beta1.PostgresCluster, pooler string,
 ) (*corev1.Service, error) {
-	service, specified, err := r.generatePGBouncerService(cluster)
+	service, specified, err := r.generatePGBouncerService(cluster, pooler)
 
 	if err == nil && !specified {
 		// PgBouncer is disabled; delete the Service if it exists. Check the client
@@ -349,14 +542,16 @@ func (r *Reconciler) reconcilePGBouncerService(
 	return service, err
 }
 
-// generatePGBouncerDeployment returns an appsv1.Deployment that runs PgBouncer pods.
+// generatePGBouncerDeployment returns an appsv1.Deployment that runs the
+// PgBouncer pods of pooler.
 func (r *Reconciler) generatePGBouncerDeployment(
-	cluster *v1beta1.PostgresCluster,
+	cluster *v1beta1.PostgresCluster, pooler string,
 	primaryCertificate *corev1.SecretProjection,
 	configmap *corev1.ConfigMap, secret *corev1.Secret,
 ) (*appsv1.Deployment, bool, error) {
-	deploy := &appsv1.Deployment{ObjectMeta: naming.ClusterPGBouncer(cluster)}
+	deploy := &appsv1.Deployment{ObjectMeta: naming.ClusterPGBouncerPooler(cluster, pooler)}
 	deploy.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	cluster = pgbouncer.Pooler(cluster, pooler)
 
 	if cluster.Spec.Proxy == nil || cluster.Spec.Proxy.PGBouncer == nil {
 		return deploy, false, nil
@@ -368,15 +563,9 @@ func (r *Reconciler) generatePGBouncerDeployment(
 	deploy.Labels = naming.Merge(
 		cluster.Spec.Metadata.GetLabelsOrNil(),
 		cluster.Spec.Proxy.PGBouncer.Metadata.GetLabelsOrNil(),
-		map[string]string{
-			naming.LabelCluster: cluster.Name,
-			naming.LabelRole:    naming.RolePGBouncer,
-		})
+		naming.ClusterPGBouncerPoolerSelector(cluster, pooler).MatchLabels)
 	deploy.Spec.Selector = &metav1.LabelSelector{
-		MatchLabels: map[string]string{
-			naming.LabelCluster: cluster.Name,
-			naming.LabelRole:    naming.RolePGBouncer,
-		},
+		MatchLabels: naming.ClusterPGBouncerPoolerSelector(cluster, pooler).MatchLabels,
 	}
 	deploy.Spec.Template.Annotations = naming.Merge(
 		cluster.Spec.Metadata.GetAnnotationsOrNil(),
@@ -384,15 +573,15 @@ func (r *Reconciler) generatePGBouncerDeployment(
 	deploy.Spec.Template.Labels = naming.Merge(
 		cluster.Spec.Metadata.GetLabelsOrNil(),
 		cluster.Spec.Proxy.PGBouncer.Metadata.GetLabelsOrNil(),
-		map[string]string{
-			naming.LabelCluster: cluster.Name,
-			naming.LabelRole:    naming.RolePGBouncer,
-		})
+		naming.ClusterPGBouncerPoolerSelector(cluster, pooler).MatchLabels)
 
-	// if the shutdown flag is set, set pgBouncer replicas to 0
+	// if the shutdown flag is set, set pgBouncer replicas to 0. A
+	// HorizontalPodAutoscaler does not act on a Deployment with zero replicas.
+	// When autoscaling, leave replicas to the HorizontalPodAutoscaler. See
+	// [Reconciler.handOffPGBouncerReplicas].
 	if cluster.Spec.Shutdown != nil && *cluster.Spec.Shutdown {
 		deploy.Spec.Replicas = initialize.Int32(0)
-	} else {
+	} else if cluster.Spec.Proxy.PGBouncer.Autoscaling == nil {
 		deploy.Spec.Replicas = cluster.Spec.Proxy.PGBouncer.Replicas
 	}
 
@@ -464,17 +653,28 @@ func (r *Reconciler) generatePGBouncerDeployment(
 // +kubebuilder:rbac:groups="apps",resources="deployments",verbs={get}
 // +kubebuilder:rbac:groups="apps",resources="deployments",verbs={create,delete,patch}
 
-// reconcilePGBouncerDeployment writes the Deployment that runs PgBouncer.
+// reconcilePGBouncerDeployment writes the Deployment that runs the PgBouncer
+// of pooler.
 func (r *Reconciler) reconcilePGBouncerDeployment(
-	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	ctx context.Context, cluster *v1beta1.PostgresCluster, pooler string,
 	primaryCertificate *corev1.SecretProjection,
 	configmap *corev1.ConfigMap, secret *corev1.Secret,
 ) error {
 	deploy, specified, err := r.generatePGBouncerDeployment(
-		cluster, primaryCertificate, configmap, secret)
+		cluster, pooler, primaryCertificate, configmap, secret)
 
 	// Set observations whether the deployment exists or not.
 	defer func() {
+		if pooler != "" {
+			cluster.Status.Proxy.Poolers = append(cluster.Status.Proxy.Poolers,
+				v1beta1.PGBouncerPoolerStatus{
+					Name:          pooler,
+					Replicas:      deploy.Status.Replicas,
+					ReadyReplicas: deploy.Status.ReadyReplicas,
+				})
+			return
+		}
+
 		cluster.Status.Proxy.PGBouncer.Replicas = deploy.Status.Replicas
 		cluster.Status.Proxy.PGBouncer.ReadyReplicas = deploy.Status.ReadyReplicas
 
@@ -514,12 +714,165 @@ func (r *Reconciler) reconcilePGBouncerDeployment(
 		return client.IgnoreNotFound(err)
 	}
 
+	if err == nil && deploy.Spec.Replicas == nil {
+		err = r.handOffPGBouncerReplicas(ctx, deploy)
+	}
 	if err == nil {
 		err = errors.WithStack(r.apply(ctx, deploy))
 	}
 	return err
 }
 
+// +kubebuilder:rbac:groups="apps",resources="deployments",verbs={get,patch}
+
+// handOffPGBouncerReplicas keeps the replicas of an existing Deployment when
+// they pass to its HorizontalPodAutoscaler. Server-side apply removes a field
+// that its manager stops sending, and the API server then defaults replicas to
+// one. A separate manager applies the current value first so that the field
+// stays until the HorizontalPodAutoscaler takes it over.
+// - https://docs.k8s.io/reference/using-api/server-side-apply/#transferring-ownership
+func (r *Reconciler) handOffPGBouncerReplicas(
+	ctx context.Context, deploy *appsv1.Deployment,
+) error {
+	existing := &appsv1.Deployment{}
+	err := errors.WithStack(r.Client.Get(ctx, client.ObjectKeyFromObject(deploy), existing))
+	if err != nil || existing.Spec.Replicas == nil ||
+		!appliedField(existing, string(r.Owner), "spec", "replicas") {
+		return client.IgnoreNotFound(err)
+	}
+
+	handoff := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Namespace: deploy.Namespace, Name: deploy.Name,
+	}}
+	handoff.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	handoff.Spec.Replicas = existing.Spec.Replicas
+
+	data, err := client.MergeFrom(&appsv1.Deployment{}).Data(handoff)
+	if err == nil {
+		err = r.patch(ctx, handoff, client.RawPatch(client.Apply.Type(), data),
+			client.ForceOwnership, client.FieldOwner(string(r.Owner)+"-handoff"))
+	}
+	return errors.WithStack(err)
+}
+
+// appliedField returns true when manager owns the field at path of object
+// through server-side apply.
+func appliedField(object metav1.Object, manager string, path ...string) bool {
+	for _, entry := range object.GetManagedFields() {
+		if entry.Manager != manager || entry.Operation != metav1.ManagedFieldsOperationApply ||
+			entry.FieldsV1 == nil {
+			continue
+		}
+
+		var fields map[string]any
+		if json.Unmarshal(entry.FieldsV1.Raw, &fields) != nil {
+			continue
+		}
+		for i, name := range path {
+			next, ok := fields["f:"+name].(map[string]any)
+			if !ok {
+				break
+			}
+			if i == len(path)-1 {
+				return true
+			}
+			fields = next
+		}
+	}
+	return false
+}
+
+// generatePGBouncerHorizontalPodAutoscaler returns an autoscaling/v2 HPA that
+// scales the PgBouncer Deployment. The second return value indicates whether
+// or not autoscaling is enabled.
+func (r *Reconciler) generatePGBouncerHorizontalPodAutoscaler(
+	cluster *v1beta1.PostgresCluster,
+) (*autoscalingv2.HorizontalPodAutoscaler, bool, error) {
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: naming.ClusterPGBouncer(cluster)}
+	hpa.SetGroupVersionKind(autoscalingv2.SchemeGroupVersion.WithKind("HorizontalPodAutoscaler"))
+
+	if cluster.Spec.Proxy == nil || cluster.Spec.Proxy.PGBouncer == nil ||
+		cluster.Spec.Proxy.PGBouncer.Autoscaling == nil {
+		return hpa, false, nil
+	}
+	spec := cluster.Spec.Proxy.PGBouncer.Autoscaling
+
+	hpa.Annotations = naming.Merge(
+		cluster.Spec.Metadata.GetAnnotationsOrNil(),
+		cluster.Spec.Proxy.PGBouncer.Metadata.GetAnnotationsOrNil())
+	hpa.Labels = naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		cluster.Spec.Proxy.PGBouncer.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster: cluster.Name,
+			naming.LabelRole:    naming.RolePGBouncer,
+		})
+
+	hpa.Spec.ScaleTargetRef = autoscalingv2.CrossVersionObjectReference{
+		APIVersion: appsv1.SchemeGroupVersion.String(),
+		Kind:       "Deployment",
+		Name:       naming.ClusterPGBouncer(cluster).Name,
+	}
+	hpa.Spec.MinReplicas = spec.MinReplicas
+	hpa.Spec.MaxReplicas = spec.MaxReplicas
+
+	if spec.TargetCPUUtilizationPercentage != nil {
+		hpa.Spec.Metrics = append(hpa.Spec.Metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: corev1.ResourceCPU,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: spec.TargetCPUUtilizationPercentage,
+				},
+			},
+		})
+	}
+	if target := spec.TargetClientConnections; target != nil {
+		hpa.Spec.Metrics = append(hpa.Spec.Metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.PodsMetricSourceType,
+			Pods: &autoscalingv2.PodsMetricSource{
+				Metric: autoscalingv2.MetricIdentifier{Name: target.Metric},
+				Target: autoscalingv2.MetricTarget{
+					Type:         autoscalingv2.AverageValueMetricType,
+					AverageValue: resource.NewQuantity(int64(target.AverageValue), resource.DecimalSI),
+				},
+			},
+		})
+	}
+
+	err := errors.WithStack(r.setControllerReference(cluster, hpa))
+
+	return hpa, true, err
+}
+
+// +kubebuilder:rbac:groups="autoscaling",resources="horizontalpodautoscalers",verbs={get}
+// +kubebuilder:rbac:groups="autoscaling",resources="horizontalpodautoscalers",verbs={create,delete,patch}
+
+// reconcilePGBouncerHorizontalPodAutoscaler writes the HorizontalPodAutoscaler
+// that scales PgBouncer, or deletes it when autoscaling is disabled.
+func (r *Reconciler) reconcilePGBouncerHorizontalPodAutoscaler(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) error {
+	hpa, specified, err := r.generatePGBouncerHorizontalPodAutoscaler(cluster)
+
+	if err == nil && !specified {
+		// Autoscaling is disabled; delete the HPA if it exists. Check the
+		// client cache first using Get.
+		key := client.ObjectKeyFromObject(hpa)
+		err := errors.WithStack(r.Client.Get(ctx, key, hpa))
+		if err == nil {
+			err = errors.WithStack(r.deleteControlled(ctx, cluster, hpa))
+		}
+		return client.IgnoreNotFound(err)
+	}
+
+	if err == nil {
+		err = errors.WithStack(r.apply(ctx, hpa))
+	}
+	return err
+}
+
 // +kubebuilder:rbac:groups="policy",resources="poddisruptionbudgets",verbs={create,patch,get,delete}
 
 // reconcilePGBouncerPodDisruptionBudget creates a PDB for the PGBouncer deployment.
@@ -528,10 +881,12 @@ func (r *Reconciler) reconcilePGBouncerDeployment(
 // or a default value will be set based on the number of replicas defined for PGBouncer.
 func (r *Reconciler) reconcilePGBouncerPodDisruptionBudget(
 	ctx context.Context,
-	cluster *v1beta1.PostgresCluster,
+	cluster *v1beta1.PostgresCluster, pooler string,
 ) error {
+	cluster = pgbouncer.Pooler(cluster, pooler)
+
 	deleteExistingPDB := func(cluster *v1beta1.PostgresCluster) error {
-		existing := &policyv1.PodDisruptionBudget{ObjectMeta: naming.ClusterPGBouncer(cluster)}
+		existing := &policyv1.PodDisruptionBudget{ObjectMeta: naming.ClusterPGBouncerPooler(cluster, pooler)}
 		err := errors.WithStack(r.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing))
 		if err == nil {
 			err = errors.WithStack(r.deleteControlled(ctx, cluster, existing))
@@ -547,28 +902,34 @@ func (r *Reconciler) reconcilePGBouncerPodDisruptionBudget(
 		// Replicas should always have a value because of defaults in the spec
 		return errors.New("Replicas should be defined")
 	}
-	minAvailable := getMinAvailable(cluster.Spec.Proxy.PGBouncer.MinAvailable,
-		*cluster.Spec.Proxy.PGBouncer.Replicas)
+
+	// When autoscaling, the fewest pods that can be running is minReplicas.
+	// Calculate the budget from that so that it never blocks every eviction.
+	replicas := *cluster.Spec.Proxy.PGBouncer.Replicas
+	if autoscaling := cluster.Spec.Proxy.PGBouncer.Autoscaling; autoscaling != nil {
+		replicas = 1
+		if autoscaling.MinReplicas != nil {
+			replicas = *autoscaling.MinReplicas
+		}
+	}
+	minAvailable := getMinAvailable(cluster.Spec.Proxy.PGBouncer.MinAvailable, replicas)
 
 	// If 'minAvailable' is set to '0', we will not reconcile the PDB. If one
 	// already exists, we will remove it.
 	scaled, err := intstr.GetScaledValueFromIntOrPercent(minAvailable,
-		int(*cluster.Spec.Proxy.PGBouncer.Replicas), true)
+		int(replicas), true)
 	if err == nil && scaled <= 0 {
 		return deleteExistingPDB(cluster)
 	}
 
-	meta := naming.ClusterPGBouncer(cluster)
+	meta := naming.ClusterPGBouncerPooler(cluster, pooler)
 	meta.Labels = naming.Merge(cluster.Spec.Metadata.GetLabelsOrNil(),
 		cluster.Spec.Proxy.PGBouncer.Metadata.GetLabelsOrNil(),
-		map[string]string{
-			naming.LabelCluster: cluster.Name,
-			naming.LabelRole:    naming.RolePGBouncer,
-		})
+		naming.ClusterPGBouncerPoolerSelector(cluster, pooler).MatchLabels)
 	meta.Annotations = naming.Merge(cluster.Spec.Metadata.GetAnnotationsOrNil(),
 		cluster.Spec.Proxy.PGBouncer.Metadata.GetAnnotationsOrNil())
 
-	selector := naming.ClusterPGBouncerSelector(cluster)
+	selector := naming.ClusterPGBouncerPoolerSelector(cluster, pooler)
 	pdb := &policyv1.PodDisruptionBudget{}
 	if err == nil {
 		pdb, err = r.generatePodDisruptionBudget(cluster, meta, minAvailable, selector)
@@ -579,3 +940,189 @@ func (r *Reconciler) reconcilePGBouncerPodDisruptionBudget(
 	}
 	return err
 }
+
+// +kubebuilder:rbac:groups="",resources="pods",verbs={list,patch}
+// +kubebuilder:rbac:groups="",resources="pods/exec",verbs={create}
+
+// pausePGBouncer pauses the connection pools of every ready PgBouncer Pod of
+// cluster when the spec asks for that during a change of primary. It returns a
+// function that resumes those pools. They are also resumed when the configured
+// timeout passes first. Each paused Pod is annotated with that deadline so
+// [Reconciler.reconcilePGBouncerPauses] can resume it should this process stop.
+func (r *Reconciler) pausePGBouncer(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (resume func()) {
+	resume = func() {}
+
+	enabled, timeout := pgbouncer.PauseDuringSwitchover(cluster)
+	if !enabled {
+		return resume
+	}
+
+	log := logging.FromContext(ctx)
+	deadline := time.Now().Add(timeout)
+
+	// Pause only those Pods that are ready to answer in the admin console. A
+	// Pod that fails to pause keeps serving clients as it would without this.
+	paused := []*corev1.Pod{}
+	for _, pooler := range append([]string{""}, pgbouncer.PoolerNames(cluster)...) {
+		pods := &corev1.PodList{}
+		selector, err := naming.AsSelector(naming.ClusterPGBouncerPoolerSelector(cluster, pooler))
+		if err == nil {
+			err = errors.WithStack(
+				r.Client.List(ctx, pods,
+					client.InNamespace(cluster.Namespace),
+					client.MatchingLabelsSelector{Selector: selector},
+				))
+		}
+		if err != nil {
+			log.Error(err, "unable to pause PgBouncer", "pooler", pooler)
+			continue
+		}
+
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if ready, _ := kubeapi.PodIsReady(pod); pod.DeletionTimestamp != nil || !ready {
+				continue
+			}
+
+			port, ok := pgbouncerPodPort(cluster, pod)
+			if !ok {
+				continue
+			}
+
+			// Record the deadline before pausing so that it is never lost.
+			err := r.setPGBouncerPausedUntil(ctx, pod, &deadline)
+			if err == nil {
+				err = r.pgbouncerExecutor(pod).Pause(ctx, port, timeout)
+			}
+			if err != nil {
+				log.Error(err, "unable to pause PgBouncer", "pod", pod.Name)
+				r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "PGBouncerPauseFailed",
+					"Unable to pause PgBouncer in %q before changing the primary", pod.Name)
+			} else {
+				paused = append(paused, pod)
+			}
+		}
+	}
+
+	var once sync.Once
+	resumeAll := func() {
+		once.Do(func() {
+			for _, pod := range paused {
+				r.resumePGBouncer(ctx, cluster, pod)
+			}
+		})
+	}
+
+	// Resume at the deadline even if the change of primary is still going.
+	timer := time.AfterFunc(timeout, resumeAll)
+
+	return func() { timer.Stop(); resumeAll() }
+}
+
+// reconcilePGBouncerPauses resumes the connection pools of PgBouncer Pods that
+// remain paused after their deadline, such as when the operator stopped while
+// the primary changed. It requeues cluster for those that are not yet due.
+func (r *Reconciler) reconcilePGBouncerPauses(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (reconcile.Result, error) {
+	if cluster.Spec.Proxy == nil || cluster.Spec.Proxy.PGBouncer == nil {
+		return reconcile.Result{}, nil
+	}
+
+	pods := &corev1.PodList{}
+	err := errors.WithStack(r.Client.List(ctx, pods,
+		client.InNamespace(cluster.Namespace),
+		client.MatchingLabels{naming.LabelCluster: cluster.Name},
+	))
+
+	var result reconcile.Result
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		value, ok := pod.Annotations[naming.PGBouncerPausedUntil]
+		if err != nil || !ok {
+			continue
+		}
+
+		// A deadline that cannot be read is treated as passed.
+		if deadline, err := time.Parse(time.RFC3339, value); err == nil && time.Now().Before(deadline) {
+			if wait := time.Until(deadline) + time.Second; result.RequeueAfter == 0 || wait < result.RequeueAfter {
+				result.RequeueAfter = wait
+			}
+			continue
+		}
+		r.resumePGBouncer(ctx, cluster, pod)
+	}
+
+	return result, err
+}
+
+// resumePGBouncer resumes the connection pools of pod and removes its deadline.
+// When the port of pod cannot be found, such as for a pooler that was removed
+// from the spec, only its deadline is removed.
+func (r *Reconciler) resumePGBouncer(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, pod *corev1.Pod,
+) {
+	log := logging.FromContext(ctx)
+
+	var err error
+	if port, ok := pgbouncerPodPort(cluster, pod); ok {
+		err = r.pgbouncerExecutor(pod).Resume(ctx, port)
+	}
+	if err == nil {
+		err = r.setPGBouncerPausedUntil(ctx, pod, nil)
+	}
+	if err != nil {
+		log.Error(err, "unable to resume PgBouncer", "pod", pod.Name)
+	}
+}
+
+// setPGBouncerPausedUntil records deadline on pod, or removes it when nil.
+func (r *Reconciler) setPGBouncerPausedUntil(
+	ctx context.Context, pod *corev1.Pod, deadline *time.Time,
+) error {
+	var value interface{}
+	if deadline != nil {
+		value = deadline.UTC().Format(time.RFC3339)
+	}
+
+	patch, err := kubeapi.NewMergePatch().
+		Add("metadata", "annotations", naming.PGBouncerPausedUntil)(value).Bytes()
+	if err == nil {
+		err = r.patch(ctx, pod, client.RawPatch(types.MergePatchType, patch))
+	}
+	return errors.WithStack(err)
+}
+
+// pgbouncerExecutor returns a [pgbouncer.Executor] that runs in the PgBouncer
+// container of pod.
+func (r *Reconciler) pgbouncerExecutor(pod *corev1.Pod) pgbouncer.Executor {
+	return func(_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+		return r.PodExec(pod.Namespace, pod.Name, naming.ContainerPGBouncer, stdin, stdout, stderr, command...)
+	}
+}
+
+// pgbouncerPodPort returns the port of the PgBouncer in pod, which may belong
+// to an additional pooler of cluster. It prefers the port declared by the
+// PgBouncer container of pod and otherwise looks for its pooler in the spec.
+// It returns false when neither has a port.
+func pgbouncerPodPort(cluster *v1beta1.PostgresCluster, pod *corev1.Pod) (int32, bool) {
+	for _, container := range pod.Spec.Containers {
+		if container.Name != naming.ContainerPGBouncer {
+			continue
+		}
+		for _, port := range container.Ports {
+			if port.Name == naming.PortPGBouncer {
+				return port.ContainerPort, true
+			}
+		}
+	}
+
+	pooler := pgbouncer.Pooler(cluster, pod.Labels[naming.LabelPGBouncerPooler])
+	if pooler.Spec.Proxy == nil || pooler.Spec.Proxy.PGBouncer == nil ||
+		pooler.Spec.Proxy.PGBouncer.Port == nil {
+		return 0, false
+	}
+	return *pooler.Spec.Proxy.PGBouncer.Port, true
+}
@@ -17,21 +17,29 @@ package postgrescluster
 
 import (
 	"context"
+	"io"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/crunchydata/postgres-operator/internal/controller/runtime"
 	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/testing/events"
 	"github.com/crunchydata/postgres-operator/internal/testing/require"
+	"github.com/crunchydata/postgres-operator/internal/util"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -55,7 +63,7 @@ func TestGeneratePGBouncerService(t *testing.T) {
 			cluster := cluster.DeepCopy()
 			cluster.Spec.Proxy = spec
 
-			service, specified, err := reconciler.generatePGBouncerService(cluster)
+			service, specified, err := reconciler.generatePGBouncerService(cluster, "")
 			assert.NilError(t, err)
 			assert.Assert(t, !specified)
 
@@ -109,7 +117,7 @@ ownerReferences:
 			Labels:      map[string]string{"b": "v2"},
 		}
 
-		service, specified, err := reconciler.generatePGBouncerService(cluster)
+		service, specified, err := reconciler.generatePGBouncerService(cluster, "")
 		assert.NilError(t, err)
 		assert.Assert(t, specified)
 
@@ -140,7 +148,7 @@ ownerReferences:
 			},
 		}
 
-		service, specified, err = reconciler.generatePGBouncerService(cluster)
+		service, specified, err = reconciler.generatePGBouncerService(cluster, "")
 		assert.NilError(t, err)
 		assert.Assert(t, specified)
 
@@ -166,7 +174,7 @@ ownerReferences:
 	})
 
 	t.Run("NoServiceSpec", func(t *testing.T) {
-		service, specified, err := reconciler.generatePGBouncerService(cluster)
+		service, specified, err := reconciler.generatePGBouncerService(cluster, "")
 		assert.NilError(t, err)
 		assert.Assert(t, specified)
 		alwaysExpect(t, service)
@@ -200,7 +208,7 @@ ownerReferences:
 			cluster := cluster.DeepCopy()
 			cluster.Spec.Proxy.PGBouncer.Service = &v1beta1.ServiceSpec{Type: test.Type}
 
-			service, specified, err := reconciler.generatePGBouncerService(cluster)
+			service, specified, err := reconciler.generatePGBouncerService(cluster, "")
 			assert.NilError(t, err)
 			assert.Assert(t, specified)
 			alwaysExpect(t, service)
@@ -258,7 +266,7 @@ ownerReferences:
 			cluster := cluster.DeepCopy()
 			cluster.Spec.Proxy.PGBouncer.Service = &v1beta1.ServiceSpec{Type: test.Type, NodePort: test.NodePort}
 
-			service, specified, err := reconciler.generatePGBouncerService(cluster)
+			service, specified, err := reconciler.generatePGBouncerService(cluster, "")
 			test.Expect(t, service, err)
 			// whether or not an error is encountered, 'specified' is true because
 			// the service *should* exist
@@ -282,7 +290,7 @@ func TestReconcilePGBouncerService(t *testing.T) {
 		cluster := cluster.DeepCopy()
 		cluster.Spec.Proxy = nil
 
-		service, err := reconciler.reconcilePGBouncerService(ctx, cluster)
+		service, err := reconciler.reconcilePGBouncerService(ctx, cluster, "")
 		assert.NilError(t, err)
 		assert.Assert(t, service == nil)
 	})
@@ -294,7 +302,7 @@ func TestReconcilePGBouncerService(t *testing.T) {
 	}
 
 	t.Run("NoServiceSpec", func(t *testing.T) {
-		service, err := reconciler.reconcilePGBouncerService(ctx, cluster)
+		service, err := reconciler.reconcilePGBouncerService(ctx, cluster, "")
 		assert.NilError(t, err)
 		assert.Assert(t, service != nil)
 		t.Cleanup(func() { assert.Check(t, cc.Delete(ctx, service)) })
@@ -311,7 +319,7 @@ func TestReconcilePGBouncerService(t *testing.T) {
 			cluster := cluster.DeepCopy()
 			cluster.Spec.Proxy.PGBouncer.Service = &v1beta1.ServiceSpec{Type: serviceType}
 
-			service, err := reconciler.reconcilePGBouncerService(ctx, cluster)
+			service, err := reconciler.reconcilePGBouncerService(ctx, cluster, "")
 			assert.NilError(t, err)
 			assert.Assert(t, service != nil)
 			t.Cleanup(func() { assert.Check(t, cc.Delete(ctx, service)) })
@@ -347,13 +355,13 @@ func TestReconcilePGBouncerService(t *testing.T) {
 				}
 				cluster.Spec.Proxy.PGBouncer.Service = &v1beta1.ServiceSpec{Type: beforeType}
 
-				before, err := reconciler.reconcilePGBouncerService(ctx, cluster)
+				before, err := reconciler.reconcilePGBouncerService(ctx, cluster, "")
 				assert.NilError(t, err)
 				t.Cleanup(func() { assert.Check(t, cc.Delete(ctx, before)) })
 
 				cluster.Spec.Proxy.PGBouncer.Service.Type = changeType
 
-				after, err := reconciler.reconcilePGBouncerService(ctx, cluster)
+				after, err := reconciler.reconcilePGBouncerService(ctx, cluster, "")
 
 				// LoadBalancers are provisioned by a separate controller that
 				// updates the Service soon after creation. The API may return
@@ -361,7 +369,7 @@ func TestReconcilePGBouncerService(t *testing.T) {
 				// don't send a resourceVersion in our payload. Retry.
 				if apierrors.IsConflict(err) {
 					t.Log("conflict:", err)
-					after, err = reconciler.reconcilePGBouncerService(ctx, cluster)
+					after, err = reconciler.reconcilePGBouncerService(ctx, cluster, "")
 				}
 
 				assert.NilError(t, err, "\n%#v", errors.Unwrap(err))
@@ -390,7 +398,7 @@ func TestGeneratePGBouncerDeployment(t *testing.T) {
 			cluster := cluster.DeepCopy()
 			cluster.Spec.Proxy = spec
 
-			deploy, specified, err := reconciler.generatePGBouncerDeployment(cluster, nil, nil, nil)
+			deploy, specified, err := reconciler.generatePGBouncerDeployment(cluster, "", nil, nil, nil)
 			assert.NilError(t, err)
 			assert.Assert(t, !specified)
 
@@ -423,7 +431,7 @@ namespace: ns3
 		}
 
 		deploy, specified, err := reconciler.generatePGBouncerDeployment(
-			cluster, primary, configmap, secret)
+			cluster, "", primary, configmap, secret)
 		assert.NilError(t, err)
 		assert.Assert(t, specified)
 
@@ -463,7 +471,7 @@ namespace: ns3
 
 	t.Run("PodSpec", func(t *testing.T) {
 		deploy, specified, err := reconciler.generatePGBouncerDeployment(
-			cluster, primary, configmap, secret)
+			cluster, "", primary, configmap, secret)
 		assert.NilError(t, err)
 		assert.Assert(t, specified)
 
@@ -509,13 +517,198 @@ topologySpreadConstraints:
 			cluster.Spec.DisableDefaultPodScheduling = initialize.Bool(true)
 
 			deploy, specified, err := reconciler.generatePGBouncerDeployment(
-				cluster, primary, configmap, secret)
+				cluster, "", primary, configmap, secret)
 			assert.NilError(t, err)
 			assert.Assert(t, specified)
 
 			assert.Assert(t, deploy.Spec.Template.Spec.TopologySpreadConstraints == nil)
 		})
 	})
+
+	t.Run("Autoscaling", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Replicas = initialize.Int32(3)
+		cluster.Spec.Proxy.PGBouncer.Autoscaling = &v1beta1.PGBouncerAutoscalingSpec{
+			MaxReplicas: 5,
+		}
+
+		deploy, specified, err := reconciler.generatePGBouncerDeployment(
+			cluster, "", primary, configmap, secret)
+		assert.NilError(t, err)
+		assert.Assert(t, specified)
+
+		// The HorizontalPodAutoscaler owns the number of replicas.
+		assert.Assert(t, deploy.Spec.Replicas == nil)
+
+		t.Run("Shutdown", func(t *testing.T) {
+			cluster.Spec.Shutdown = initialize.Bool(true)
+
+			deploy, _, err := reconciler.generatePGBouncerDeployment(
+				cluster, "", primary, configmap, secret)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, deploy.Spec.Replicas, initialize.Int32(0))
+		})
+	})
+}
+
+func TestGeneratePGBouncerHorizontalPodAutoscaler(t *testing.T) {
+	reconciler := &Reconciler{Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).Build()}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+
+	t.Run("Unspecified", func(t *testing.T) {
+		hpa, specified, err := reconciler.generatePGBouncerHorizontalPodAutoscaler(cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, !specified)
+		assert.Equal(t, hpa.Name, "hippo-pgbouncer")
+
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{PGBouncer: &v1beta1.PGBouncerPodSpec{}}
+
+		_, specified, err = reconciler.generatePGBouncerHorizontalPodAutoscaler(cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, !specified)
+	})
+
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{PGBouncer: &v1beta1.PGBouncerPodSpec{
+		Autoscaling: &v1beta1.PGBouncerAutoscalingSpec{
+			MinReplicas:                    initialize.Int32(2),
+			MaxReplicas:                    6,
+			TargetCPUUtilizationPercentage: initialize.Int32(70),
+			TargetClientConnections: &v1beta1.PGBouncerConnectionsTarget{
+				Metric: "pgbouncer_client_connections", AverageValue: 200,
+			},
+		},
+	}}
+
+	hpa, specified, err := reconciler.generatePGBouncerHorizontalPodAutoscaler(cluster)
+	assert.NilError(t, err)
+	assert.Assert(t, specified)
+
+	assert.DeepEqual(t, hpa.Labels, map[string]string{
+		"postgres-operator.crunchydata.com/cluster": "hippo",
+		"postgres-operator.crunchydata.com/role":    "pgbouncer",
+	})
+	assert.Equal(t, len(hpa.OwnerReferences), 1)
+
+	assert.Assert(t, marshalMatches(hpa.Spec, `
+maxReplicas: 6
+metrics:
+- resource:
+    name: cpu
+    target:
+      averageUtilization: 70
+      type: Utilization
+  type: Resource
+- pods:
+    metric:
+      name: pgbouncer_client_connections
+    target:
+      averageValue: "200"
+      type: AverageValue
+  type: Pods
+minReplicas: 2
+scaleTargetRef:
+  apiVersion: apps/v1
+  kind: Deployment
+  name: hippo-pgbouncer
+	`))
+}
+
+// clientRecordingApplies records apply patches rather than sending them.
+type clientRecordingApplies struct {
+	client.Client
+	applied *[]string
+}
+
+func (c clientRecordingApplies) Patch(
+	ctx context.Context, object client.Object, patch client.Patch, options ...client.PatchOption,
+) error {
+	if patch.Type() != client.Apply.Type() {
+		return c.Client.Patch(ctx, object, patch, options...)
+	}
+
+	opts := new(client.PatchOptions)
+	opts.ApplyOptions(options)
+	data, err := patch.Data(object)
+	*c.applied = append(*c.applied, opts.FieldManager+": "+string(data))
+	return err
+}
+
+func TestHandOffPGBouncerReplicas(t *testing.T) {
+	ctx := context.Background()
+
+	existing := &appsv1.Deployment{}
+	existing.Namespace, existing.Name = "ns1", "hippo-pgbouncer"
+	existing.Spec.Replicas = initialize.Int32(3)
+	existing.ManagedFields = []metav1.ManagedFieldsEntry{{
+		Manager:   "postgrescluster-controller",
+		Operation: metav1.ManagedFieldsOperationApply,
+		FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{},"f:template":{}}}`)},
+	}}
+
+	var applied []string
+	reconciler := &Reconciler{
+		Owner: "postgrescluster-controller",
+		Client: clientRecordingApplies{
+			Client:  fake.NewClientBuilder().WithScheme(runtime.Scheme).WithObjects(existing).Build(),
+			applied: &applied,
+		},
+	}
+
+	// Nothing happens before the Deployment exists.
+	missing := &appsv1.Deployment{}
+	missing.Namespace, missing.Name = "ns1", "other"
+	assert.NilError(t, reconciler.handOffPGBouncerReplicas(ctx, missing))
+	assert.Equal(t, len(applied), 0)
+
+	// A separate manager keeps the replicas that were applied before.
+	deploy := &appsv1.Deployment{}
+	deploy.Namespace, deploy.Name = "ns1", "hippo-pgbouncer"
+	assert.NilError(t, reconciler.handOffPGBouncerReplicas(ctx, deploy))
+	assert.Equal(t, len(applied), 1)
+	assert.Assert(t, strings.HasPrefix(applied[0], "postgrescluster-controller-handoff: "))
+	assert.Assert(t, strings.Contains(applied[0], `"spec":{"replicas":3}`), "got %q", applied[0])
+
+	t.Run("AlreadyHandedOff", func(t *testing.T) {
+		applied = nil
+		existing := existing.DeepCopy()
+		existing.ResourceVersion = ""
+		existing.ManagedFields[0].FieldsV1.Raw = []byte(`{"f:spec":{"f:template":{}}}`)
+
+		reconciler := &Reconciler{
+			Owner: "postgrescluster-controller",
+			Client: clientRecordingApplies{
+				Client:  fake.NewClientBuilder().WithScheme(runtime.Scheme).WithObjects(existing).Build(),
+				applied: &applied,
+			},
+		}
+		assert.NilError(t, reconciler.handOffPGBouncerReplicas(ctx, deploy))
+		assert.Equal(t, len(applied), 0)
+	})
+}
+
+func TestAppliedField(t *testing.T) {
+	object := &metav1.ObjectMeta{ManagedFields: []metav1.ManagedFieldsEntry{
+		{
+			Manager:   "updater",
+			Operation: metav1.ManagedFieldsOperationUpdate,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+		},
+		{
+			Manager:   "applier",
+			Operation: metav1.ManagedFieldsOperationApply,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:selector":{}}}`)},
+		},
+	}}
+
+	assert.Assert(t, !appliedField(object, "updater", "spec", "replicas"))
+	assert.Assert(t, !appliedField(object, "applier", "spec", "replicas"))
+	assert.Assert(t, appliedField(object, "applier", "spec", "selector"))
+	assert.Assert(t, appliedField(object, "applier", "spec"))
+	assert.Assert(t, !appliedField(object, "nobody", "spec"))
 }
 
 func TestReconcilePGBouncerDisruptionBudget(t *testing.T) {
@@ -545,14 +738,14 @@ func TestReconcilePGBouncerDisruptionBudget(t *testing.T) {
 		cluster.Namespace = ns.Name
 		cluster.Spec.Proxy = nil
 
-		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster))
+		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, ""))
 	})
 
 	t.Run("no replicas in spec", func(t *testing.T) {
 		cluster := testCluster()
 		cluster.Namespace = ns.Name
 		cluster.Spec.Proxy.PGBouncer.Replicas = nil
-		assert.Error(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster),
+		assert.Error(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, ""),
 			"Replicas should be defined")
 	})
 
@@ -561,7 +754,7 @@ func TestReconcilePGBouncerDisruptionBudget(t *testing.T) {
 		cluster.Namespace = ns.Name
 		cluster.Spec.Proxy.PGBouncer.Replicas = initialize.Int32(1)
 		cluster.Spec.Proxy.PGBouncer.MinAvailable = initialize.IntOrStringInt32(0)
-		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster))
+		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, ""))
 		assert.Assert(t, !foundPDB(cluster))
 	})
 
@@ -574,18 +767,18 @@ func TestReconcilePGBouncerDisruptionBudget(t *testing.T) {
 		assert.NilError(t, r.Client.Create(ctx, cluster))
 		t.Cleanup(func() { assert.Check(t, r.Client.Delete(ctx, cluster)) })
 
-		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster))
+		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, ""))
 		assert.Assert(t, foundPDB(cluster))
 
 		t.Run("deleted", func(t *testing.T) {
 			cluster.Spec.Proxy.PGBouncer.MinAvailable = initialize.IntOrStringInt32(0)
-			err := r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster)
+			err := r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, "")
 			if apierrors.IsConflict(err) {
 				// When running in an existing environment another controller will sometimes update
 				// the object. This leads to an error where the ResourceVersion of the object does
 				// not match what we expect. When we run into this conflict, try to reconcile the
 				// object again.
-				err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster)
+				err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, "")
 			}
 			assert.NilError(t, err, errors.Unwrap(err))
 			assert.Assert(t, !foundPDB(cluster))
@@ -601,18 +794,18 @@ func TestReconcilePGBouncerDisruptionBudget(t *testing.T) {
 		assert.NilError(t, r.Client.Create(ctx, cluster))
 		t.Cleanup(func() { assert.Check(t, r.Client.Delete(ctx, cluster)) })
 
-		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster))
+		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, ""))
 		assert.Assert(t, foundPDB(cluster))
 
 		t.Run("deleted", func(t *testing.T) {
 			cluster.Spec.Proxy.PGBouncer.MinAvailable = initialize.IntOrStringString("0%")
-			err := r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster)
+			err := r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, "")
 			if apierrors.IsConflict(err) {
 				// When running in an existing environment another controller will sometimes update
 				// the object. This leads to an error where the ResourceVersion of the object does
 				// not match what we expect. When we run into this conflict, try to reconcile the
 				// object again.
-				err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster)
+				err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, "")
 			}
 			assert.NilError(t, err, errors.Unwrap(err))
 			assert.Assert(t, !foundPDB(cluster))
@@ -621,22 +814,271 @@ func TestReconcilePGBouncerDisruptionBudget(t *testing.T) {
 		t.Run("delete with 00%", func(t *testing.T) {
 			cluster.Spec.Proxy.PGBouncer.MinAvailable = initialize.IntOrStringString("50%")
 
-			assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster))
+			assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, ""))
 			assert.Assert(t, foundPDB(cluster))
 
 			t.Run("deleted", func(t *testing.T) {
 				cluster.Spec.Proxy.PGBouncer.MinAvailable = initialize.IntOrStringString("00%")
-				err := r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster)
+				err := r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, "")
 				if apierrors.IsConflict(err) {
 					// When running in an existing environment another controller will sometimes update
 					// the object. This leads to an error where the ResourceVersion of the object does
 					// not match what we expect. When we run into this conflict, try to reconcile the
 					// object again.
-					err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster)
+					err = r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, "")
 				}
 				assert.NilError(t, err, errors.Unwrap(err))
 				assert.Assert(t, !foundPDB(cluster))
 			})
 		})
 	})
+	t.Run("autoscaling", func(t *testing.T) {
+		cluster := testCluster()
+		cluster.Namespace = ns.Name
+		cluster.Spec.Proxy.PGBouncer.Replicas = initialize.Int32(3)
+		cluster.Spec.Proxy.PGBouncer.Autoscaling = &v1beta1.PGBouncerAutoscalingSpec{
+			MinReplicas: initialize.Int32(1), MaxReplicas: 5,
+		}
+
+		// The default budget follows minReplicas rather than replicas.
+		assert.NilError(t, r.reconcilePGBouncerPodDisruptionBudget(ctx, cluster, ""))
+		assert.Assert(t, !foundPDB(cluster))
+	})
+}
+
+func TestPGBouncerPoolers(t *testing.T) {
+	ctx := context.Background()
+	assert.NilError(t, util.AddAndSetFeatureGates(""))
+	reconciler := &Reconciler{
+		Client:   fake.NewClientBuilder().WithScheme(runtime.Scheme).Build(),
+		Recorder: new(record.FakeRecorder),
+	}
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.UID = "some-uid"
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{
+		PGBouncer: &v1beta1.PGBouncerPodSpec{},
+		Poolers: []v1beta1.PGBouncerPoolerSpec{{
+			Name: "batch",
+			Port: initialize.Int32(6543),
+		}},
+	}
+	cluster.Default()
+
+	t.Run("Service", func(t *testing.T) {
+		service, specified, err := reconciler.generatePGBouncerService(cluster, "batch")
+		assert.NilError(t, err)
+		assert.Assert(t, specified)
+
+		assert.Equal(t, service.Name, "hippo-pgbouncer-batch")
+		assert.DeepEqual(t, service.Spec.Selector, map[string]string{
+			"postgres-operator.crunchydata.com/cluster":          "hippo",
+			"postgres-operator.crunchydata.com/pgbouncer-pooler": "batch",
+			"postgres-operator.crunchydata.com/role":             "pgbouncer-pooler",
+		})
+		assert.Equal(t, service.Spec.Ports[0].Port, int32(6543))
+	})
+
+	t.Run("Deployment", func(t *testing.T) {
+		configmap := &corev1.ConfigMap{}
+		configmap.Name = "hippo-pgbouncer-batch"
+		secret := &corev1.Secret{}
+		secret.Name = "hippo-pgbouncer"
+
+		deploy, specified, err := reconciler.generatePGBouncerDeployment(
+			cluster, "batch", &corev1.SecretProjection{}, configmap, secret)
+		assert.NilError(t, err)
+		assert.Assert(t, specified)
+
+		assert.Equal(t, deploy.Name, "hippo-pgbouncer-batch")
+		assert.DeepEqual(t, deploy.Spec.Selector.MatchLabels, map[string]string{
+			"postgres-operator.crunchydata.com/cluster":          "hippo",
+			"postgres-operator.crunchydata.com/pgbouncer-pooler": "batch",
+			"postgres-operator.crunchydata.com/role":             "pgbouncer-pooler",
+		})
+		assert.Equal(t, *deploy.Spec.Replicas, int32(1))
+		assert.Equal(t, deploy.Spec.Template.Spec.Containers[0].Ports[0].ContainerPort, int32(6543))
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		_, specified, err := reconciler.generatePGBouncerService(cluster, "nope")
+		assert.NilError(t, err)
+		assert.Assert(t, !specified)
+	})
+
+	t.Run("DeleteRemoved", func(t *testing.T) {
+		for _, pooler := range []string{"batch", "old"} {
+			configmap := &corev1.ConfigMap{ObjectMeta: naming.ClusterPGBouncerPooler(cluster, pooler)}
+			configmap.Labels = naming.ClusterPGBouncerPoolerSelector(cluster, pooler).MatchLabels
+			assert.NilError(t, reconciler.setControllerReference(cluster, configmap))
+			assert.NilError(t, reconciler.Client.Create(ctx, configmap))
+		}
+
+		assert.NilError(t, reconciler.deleteRemovedPGBouncerPoolers(ctx, cluster))
+
+		configmaps := &corev1.ConfigMapList{}
+		assert.NilError(t, reconciler.Client.List(ctx, configmaps, client.InNamespace("ns1")))
+		assert.Equal(t, len(configmaps.Items), 1)
+		assert.Equal(t, configmaps.Items[0].Name, "hippo-pgbouncer-batch")
+
+		// Every pooler is removed when PgBouncer is.
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy = nil
+		assert.NilError(t, reconciler.deleteRemovedPGBouncerPoolers(ctx, cluster))
+		assert.NilError(t, reconciler.Client.List(ctx, configmaps, client.InNamespace("ns1")))
+		assert.Equal(t, len(configmaps.Items), 0)
+	})
+}
+
+func TestReconcilePGBouncerPauses(t *testing.T) {
+	ctx := context.Background()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+
+	pod := func(name, deadline string) *corev1.Pod {
+		pod := &corev1.Pod{}
+		pod.Namespace, pod.Name = "ns1", name
+		pod.Labels = map[string]string{
+			"postgres-operator.crunchydata.com/cluster": "hippo",
+			"postgres-operator.crunchydata.com/role":    "pgbouncer",
+		}
+		if deadline != "" {
+			pod.Annotations = map[string]string{
+				"postgres-operator.crunchydata.com/pgbouncer-paused-until": deadline,
+			}
+		}
+		return pod
+	}
+
+	var calls []string
+	reconciler := &Reconciler{
+		Client: fake.NewClientBuilder().WithObjects(
+			pod("running", ""),
+			pod("expired", time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)),
+			pod("garbage", "garbage"),
+			pod("waiting", time.Now().Add(time.Minute).UTC().Format(time.RFC3339)),
+		).Build(),
+		PodExec: func(
+			namespace, pod, container string, _ io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			calls = append(calls, pod+": "+command[len(command)-1])
+			return nil
+		},
+	}
+
+	// Nothing happens without PgBouncer.
+	result, err := reconciler.reconcilePGBouncerPauses(ctx, cluster)
+	assert.NilError(t, err)
+	assert.Assert(t, result.IsZero())
+	assert.Assert(t, len(calls) == 0)
+
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+	cluster.Default()
+
+	// Pods past their deadline are resumed; the others are checked later.
+	result, err = reconciler.reconcilePGBouncerPauses(ctx, cluster)
+	assert.NilError(t, err)
+	assert.Assert(t, result.RequeueAfter > 0 && result.RequeueAfter <= time.Minute+time.Second)
+	assert.DeepEqual(t, calls, []string{
+		"expired: --command=RESUME",
+		"garbage: --command=RESUME",
+	})
+
+	pods := &corev1.PodList{}
+	assert.NilError(t, reconciler.Client.List(ctx, pods))
+	for _, pod := range pods.Items {
+		_, paused := pod.Annotations["postgres-operator.crunchydata.com/pgbouncer-paused-until"]
+		assert.Equal(t, paused, pod.Name == "waiting", "pod %q", pod.Name)
+	}
+
+	t.Run("RemovedPooler", func(t *testing.T) {
+		calls = nil
+		expired := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+
+		// One Pod declares its port; the other has nothing to go on.
+		declared := pod("declared", expired)
+		declared.Labels["postgres-operator.crunchydata.com/pgbouncer-pooler"] = "gone"
+		declared.Spec.Containers = []corev1.Container{{
+			Name:  "pgbouncer",
+			Ports: []corev1.ContainerPort{{Name: "pgbouncer", ContainerPort: 6543}},
+		}}
+		unknown := pod("unknown", expired)
+		unknown.Labels["postgres-operator.crunchydata.com/pgbouncer-pooler"] = "gone"
+
+		assert.NilError(t, reconciler.Client.Create(ctx, declared))
+		assert.NilError(t, reconciler.Client.Create(ctx, unknown))
+
+		_, err := reconciler.reconcilePGBouncerPauses(ctx, cluster)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, calls, []string{"declared: --command=RESUME"})
+
+		for _, pod := range []*corev1.Pod{declared, unknown} {
+			assert.NilError(t, reconciler.Client.Get(ctx, client.ObjectKeyFromObject(pod), pod))
+			_, paused := pod.Annotations["postgres-operator.crunchydata.com/pgbouncer-paused-until"]
+			assert.Assert(t, !paused, "pod %q", pod.Name)
+		}
+	})
+}
+
+func TestValidatePGBouncerConsoleUsers(t *testing.T) {
+	t.Parallel()
+
+	newCluster := func(names ...string) *v1beta1.PostgresCluster {
+		cluster := v1beta1.NewPostgresCluster()
+		cluster.Name = "hippo"
+		cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+		cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+		cluster.Spec.Proxy.PGBouncer.Authentication = new(v1beta1.PGBouncerAuthenticationSpec)
+		for _, name := range names {
+			cluster.Spec.Proxy.PGBouncer.Authentication.ConsoleUsers = append(
+				cluster.Spec.Proxy.PGBouncer.Authentication.ConsoleUsers,
+				v1beta1.PGBouncerConsoleUser{Name: name})
+		}
+		return cluster
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		reconciler := &Reconciler{}
+		assert.Assert(t, reconciler.Recorder == nil,
+			"expected the following to not use a Recorder at all")
+
+		assert.NilError(t, reconciler.validatePGBouncerConsoleUsers(v1beta1.NewPostgresCluster()))
+		assert.NilError(t, reconciler.validatePGBouncerConsoleUsers(newCluster("ops")))
+
+		cluster := newCluster("hippo")
+		cluster.Spec.Users = []v1beta1.PostgresUserSpec{{Name: "app"}}
+		assert.NilError(t, reconciler.validatePGBouncerConsoleUsers(cluster),
+			"the default user is not created when users are specified")
+	})
+
+	t.Run("DefaultUser", func(t *testing.T) {
+		recorder := events.NewRecorder(t, runtime.Scheme)
+		reconciler := &Reconciler{Recorder: recorder}
+
+		err := reconciler.validatePGBouncerConsoleUsers(newCluster("ops", "hippo"))
+		assert.ErrorContains(t, err, "spec.proxy.pgBouncer.authentication.consoleUsers[1].name")
+
+		assert.Equal(t, len(recorder.Events), 1)
+		assert.Equal(t, recorder.Events[0].Reason, "InvalidConsoleUser")
+	})
+
+	t.Run("SpecifiedUsers", func(t *testing.T) {
+		recorder := events.NewRecorder(t, runtime.Scheme)
+		reconciler := &Reconciler{Recorder: recorder}
+
+		cluster := newCluster("app", "ops", "postgres")
+		cluster.Spec.Users = []v1beta1.PostgresUserSpec{{Name: "app"}}
+
+		err := reconciler.validatePGBouncerConsoleUsers(cluster)
+		assert.ErrorContains(t, err, "consoleUsers[0].name")
+		assert.ErrorContains(t, err, "consoleUsers[2].name")
+		assert.ErrorContains(t, err, "cannot be the name of a PostgreSQL user")
+		assert.Assert(t, !strings.Contains(err.Error(), "consoleUsers[1]"))
+
+		assert.Equal(t, len(recorder.Events), 1)
+	})
 }
@@ -45,6 +45,9 @@ func (r *Reconciler) reconcilePGMonitor(ctx context.Context,
 	monitoringSecret *corev1.Secret) error {
 
 	err := r.reconcilePGMonitorExporter(ctx, cluster, instances, monitoringSecret)
+	if err == nil {
+		err = r.reconcilePrometheus(ctx, cluster)
+	}
 
 	return err
 }
@@ -109,7 +112,15 @@ func (r *Reconciler) reconcilePGMonitorExporter(ctx context.Context,
 	// pgMonitor objects.
 
 	action := func(ctx context.Context, exec postgres.Executor) error {
-		return pgmonitor.EnableExporterInPostgreSQL(ctx, exec, monitoringSecret, pgmonitor.ExporterDB, setup)
+		err := pgmonitor.EnableExporterInPostgreSQL(ctx, exec, monitoringSecret, pgmonitor.ExporterDB, setup)
+
+		// Point pgbouncer_fdw at PgBouncer or remove its credentials.
+		if err == nil && pgmonitor.PGBouncerMetricsEnabled(cluster) {
+			err = pgmonitor.EnablePGBouncerMetricsInPostgreSQL(ctx, exec, cluster, monitoringSecret, pgmonitor.ExporterDB)
+		} else if err == nil {
+			err = pgmonitor.DisablePGBouncerMetricsInPostgreSQL(ctx, exec, pgmonitor.ExporterDB)
+		}
+		return err
 	}
 
 	if !pgmonitor.ExporterEnabled(cluster) {
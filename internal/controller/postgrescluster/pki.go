@@ -17,14 +17,25 @@ package postgrescluster
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/patroni"
+	"github.com/crunchydata/postgres-operator/internal/pgbouncer"
 	"github.com/crunchydata/postgres-operator/internal/pki"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -43,12 +54,18 @@ const (
 // to being expired, formatted incorrectly, etc.
 // If it is bad for some reason, a new root certificate is
 // generated for use.
+// During a rotation, the secret also holds the root that comes after or before
+// the current one. Both are trusted alongside the current root. The secret
+// records the phase of that rotation, and this moves it along once every
+// cluster that shares the root has loaded the certificates of the phase.
 func (r *Reconciler) reconcileRootCertificate(
 	ctx context.Context, cluster *v1beta1.PostgresCluster,
 ) (
 	*pki.RootCertificateAuthority, error,
 ) {
 	const keyCertificate, keyPrivateKey = "root.crt", "root.key"
+	const keyNextCertificate, keyNextPrivateKey = "root-next.crt", "root-next.key"
+	const keyPreviousCertificate = "root-previous.crt"
 
 	existing := &corev1.Secret{}
 	existing.Namespace, existing.Name = cluster.Namespace, naming.RootCertSecret
@@ -56,6 +73,8 @@ func (r *Reconciler) reconcileRootCertificate(
 		r.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing)))
 
 	root := &pki.RootCertificateAuthority{}
+	next := &pki.RootCertificateAuthority{}
+	previous := &pki.Certificate{}
 
 	if err == nil {
 		// Unmarshal and validate the stored root. These first errors can
@@ -63,12 +82,80 @@ func (r *Reconciler) reconcileRootCertificate(
 		// correctly regenerated.
 		_ = root.Certificate.UnmarshalText(existing.Data[keyCertificate])
 		_ = root.PrivateKey.UnmarshalText(existing.Data[keyPrivateKey])
+		_ = next.Certificate.UnmarshalText(existing.Data[keyNextCertificate])
+		_ = next.PrivateKey.UnmarshalText(existing.Data[keyNextPrivateKey])
+		_ = previous.UnmarshalText(existing.Data[keyPreviousCertificate])
 
 		if !pki.RootIsValid(root) {
-			root, err = pki.NewRootCertificateAuthority()
+			root, err = pki.NewRootCertificateAuthorityWithPolicy(r.RootCertificates)
 			err = errors.WithStack(err)
 		}
 	}
+	if !pki.RootIsValid(next) {
+		next = nil
+	}
+	if !previous.NotAfter().After(time.Now()) {
+		previous = nil
+	}
+
+	rotationID := existing.Annotations[naming.RootCertificateRotationID]
+	phase := existing.Annotations[naming.RootCertificateRotationPhase]
+
+	// Move to the next phase of the rotation once every cluster has loaded
+	// the certificates of this one.
+	if err == nil && phase != "" && phase != v1beta1.RootCertificateRotationComplete {
+		var loaded bool
+		loaded, err = r.rootCertificateRotationLoaded(ctx, cluster, existing, rotationID, phase)
+
+		if err == nil && loaded {
+			phase = nextRootCertificateRotationPhase(phase)
+		}
+	}
+
+	// Start a rotation that cluster requested once no other is underway.
+	// Otherwise, start one when the root is due for renewal or is not the kind
+	// the operator generates. Every cluster names that rotation the same way.
+	trigger := cluster.GetAnnotations()[naming.RotateRootCertificate]
+	requested := trigger != "" &&
+		(cluster.Status.RootCertificateRotation == nil ||
+			cluster.Status.RootCertificateRotation.Requested != trigger)
+	renew := time.Now().After(root.Certificate.RenewalTime()) ||
+		!pki.RootSatisfiesPolicy(root, r.RootCertificates)
+
+	var started string
+	if err == nil && (phase == "" || phase == v1beta1.RootCertificateRotationComplete) {
+		switch {
+		case requested:
+			started = trigger
+		case renew:
+			started = "renew-" + root.Certificate.NotAfter().UTC().Format("20060102T150405Z")
+		}
+		if started != "" {
+			rotationID, phase = started, v1beta1.RootCertificateRotationTrusting
+		}
+	}
+
+	// Move the roots along according to the phase of the rotation.
+	if err == nil {
+		switch phase {
+		case v1beta1.RootCertificateRotationTrusting:
+			if next == nil {
+				next, err = pki.NewRootCertificateAuthorityWithPolicy(r.RootCertificates)
+				err = errors.WithStack(err)
+			}
+		case v1beta1.RootCertificateRotationReissuing:
+			if next != nil {
+				root, next, previous = next, nil, &root.Certificate
+			}
+		case v1beta1.RootCertificateRotationRemoving, v1beta1.RootCertificateRotationComplete:
+			next, previous = nil, nil
+		}
+	}
+
+	// The root is shared by every cluster in the namespace, but each cluster
+	// decides the kind of leaf certificates it issues.
+	root.LeafPolicy = r.leafCertificatePolicy(cluster)
+	root.Trusted = nil
 
 	intent := &corev1.Secret{}
 	intent.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
@@ -76,6 +163,13 @@ func (r *Reconciler) reconcileRootCertificate(
 	intent.Data = make(map[string][]byte)
 	intent.ObjectMeta.OwnerReferences = existing.ObjectMeta.OwnerReferences
 
+	if phase != "" {
+		intent.Annotations = map[string]string{
+			naming.RootCertificateRotationID:    rotationID,
+			naming.RootCertificateRotationPhase: phase,
+		}
+	}
+
 	// A root secret is scoped to the namespace where postgrescluster(s)
 	// are deployed. For operator deployments with postgresclusters in more than
 	// one namespace, there will be one root per namespace.
@@ -96,18 +190,206 @@ func (r *Reconciler) reconcileRootCertificate(
 		intent.Data[keyPrivateKey], err = root.PrivateKey.MarshalText()
 		err = errors.WithStack(err)
 	}
+	if err == nil && next != nil {
+		root.Trusted = append(root.Trusted, next.Certificate)
+
+		intent.Data[keyNextCertificate], err = next.Certificate.MarshalText()
+		if err == nil {
+			intent.Data[keyNextPrivateKey], err = next.PrivateKey.MarshalText()
+		}
+		err = errors.WithStack(err)
+	}
+	if err == nil && previous != nil {
+		root.Trusted = append(root.Trusted, *previous)
+
+		intent.Data[keyPreviousCertificate], err = previous.MarshalText()
+		err = errors.WithStack(err)
+	}
+
+	// Every cluster in the namespace writes this secret. While a rotation is
+	// underway, send the version that was read so that a stale read cannot
+	// undo a step of the rotation and only one rotation can start. Whoever
+	// loses that race reads the secret again during its next reconcile.
+	if len(existing.UID) != 0 &&
+		phase != "" && phase != v1beta1.RootCertificateRotationComplete {
+		intent.ResourceVersion = existing.ResourceVersion
+	}
 	if err == nil {
 		err = errors.WithStack(r.apply(ctx, intent))
 	}
 
+	// Follow the rotation in the status of cluster.
+	if err == nil && phase != "" {
+		status := cluster.Status.RootCertificateRotation
+		if status == nil {
+			status = &v1beta1.RootCertificateRotationStatus{}
+			cluster.Status.RootCertificateRotation = status
+		}
+		if status.ID != rotationID || status.Phase != phase {
+			status.ID, status.Phase, status.Loaded = rotationID, phase, false
+		}
+		if requested && started == trigger {
+			status.Requested = trigger
+		}
+		if started != "" {
+			r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "RootCertificateRotation",
+				"Started rotation %q of the root certificate authority", started)
+		}
+	}
+
 	return root, err
 }
 
+// nextRootCertificateRotationPhase returns the phase that comes after phase.
+func nextRootCertificateRotationPhase(phase string) string {
+	switch phase {
+	case v1beta1.RootCertificateRotationTrusting:
+		return v1beta1.RootCertificateRotationReissuing
+	case v1beta1.RootCertificateRotationReissuing:
+		return v1beta1.RootCertificateRotationRemoving
+	}
+	return v1beta1.RootCertificateRotationComplete
+}
+
+// +kubebuilder:rbac:groups="postgres-operator.crunchydata.com",resources="postgresclusters",verbs={list}
+
+// rootCertificateRotationLoaded returns true when every cluster that shares
+// the root in secret has loaded the certificates of phase in rotation id.
+// Clusters that are paused or gone are not waited on.
+func (r *Reconciler) rootCertificateRotationLoaded(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	secret *corev1.Secret, id, phase string,
+) (bool, error) {
+	owners := sets.NewString()
+	for _, ref := range secret.OwnerReferences {
+		if ref.Kind == "PostgresCluster" {
+			owners.Insert(string(ref.UID))
+		}
+	}
+
+	clusters := &v1beta1.PostgresClusterList{}
+	err := errors.WithStack(r.Client.List(ctx, clusters, client.InNamespace(secret.Namespace)))
+
+	loaded := err == nil
+	for i := range clusters.Items {
+		other := &clusters.Items[i]
+		if other.UID == cluster.UID {
+			// The status in memory is newer than what was listed.
+			other = cluster
+		}
+		if !owners.Has(string(other.UID)) ||
+			(other.Spec.Paused != nil && *other.Spec.Paused) {
+			continue
+		}
+
+		status := other.Status.RootCertificateRotation
+		loaded = loaded && status != nil &&
+			status.ID == id && status.Phase == phase && status.Loaded
+	}
+
+	return loaded, err
+}
+
+// reconcileRootCertificateRotation confirms that every running instance of
+// cluster has loaded the certificates of the current phase of a rotation of
+// the root certificate authority. PostgreSQL reloads its certificate files
+// when they change; Patroni is asked to reload here. The next phase begins in
+// [Reconciler.reconcileRootCertificate] after every cluster sharing the root
+// has done the same.
+//
+// PgBouncer and pgBackRest reload their certificate files on their own.
+func (r *Reconciler) reconcileRootCertificateRotation(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	instances *observedInstances, root *pki.RootCertificateAuthority,
+) (reconcile.Result, error) {
+	rotation := cluster.Status.RootCertificateRotation
+	if rotation == nil || rotation.Phase == v1beta1.RootCertificateRotationComplete {
+		return reconcile.Result{}, nil
+	}
+
+	// Check on other clusters periodically. A change to the root secret also
+	// triggers a reconcile; see [Reconciler.watchCertificateSecrets].
+	if rotation.Loaded {
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	// PostgreSQL trusts the authorities generated by the operator unless some
+	// other secret provides its certificates. In that case, do not check the
+	// file. Certificates issued by cert-manager do not come from the root.
+	var postgresAuthorities, patroniAuthorities []byte
+	var err error
+	if cluster.Spec.CertManager == nil {
+		patroniAuthorities, err = root.Authorities().MarshalText()
+		err = errors.WithStack(err)
+
+		if cluster.Spec.CustomTLSSecret == nil {
+			postgresAuthorities = patroniAuthorities
+		}
+	}
+
+	for _, instance := range instances.forCluster {
+		// Instances that are not running load the latest files when they start.
+		running, known := instance.IsRunning(naming.ContainerDatabase)
+		if err != nil || cluster.Spec.CertManager != nil ||
+			!running || !known || len(instance.Pods) != 1 {
+			continue
+		}
+
+		pod := instance.Pods[0]
+		exec := func(_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+			return r.PodExec(pod.Namespace, pod.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+		}
+
+		ok := true
+		if len(postgresAuthorities) > 0 {
+			ok, err = postgres.CertificateAuthoritiesLoaded(ctx, exec, postgresAuthorities)
+		}
+		if err == nil && ok {
+			ok, err = patroni.Executor(exec).ReloadCertificates(ctx,
+				naming.PatroniScope(cluster), pod.Name, patroniAuthorities)
+		}
+
+		// Kubernetes updates mounted Secrets eventually. Check again later.
+		if err == nil && !ok {
+			return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+		}
+	}
+
+	if err == nil {
+		rotation.Loaded = true
+
+		if rotation.Phase == v1beta1.RootCertificateRotationRemoving {
+			r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "RootCertificateRotation",
+				"Loaded the final certificates of rotation %q of the root certificate authority", rotation.ID)
+		}
+	}
+
+	return reconcile.Result{Requeue: err == nil}, err
+}
+
+// leafCertificatePolicy returns the operator policy for leaf certificates
+// overridden by the certificate settings of cluster.
+func (r *Reconciler) leafCertificatePolicy(cluster *v1beta1.PostgresCluster) pki.Policy {
+	policy := r.LeafCertificates
+
+	if spec := cluster.Spec.Certificates; spec != nil {
+		if spec.KeyAlgorithm != "" {
+			policy.KeyAlgorithm = pki.KeyAlgorithm(spec.KeyAlgorithm)
+		}
+		if spec.Lifetime != nil {
+			policy.Lifetime = spec.Lifetime.Duration
+		}
+	}
+
+	return policy
+}
+
 // +kubebuilder:rbac:groups="",resources="secrets",verbs={get}
 // +kubebuilder:rbac:groups="",resources="secrets",verbs={create,patch}
 
 // reconcileClusterCertificate first checks if a custom certificate
 // secret is configured. If so, that secret projection is returned.
+// Next, when cert-manager is configured, its secret projection is returned.
 // Otherwise, a secret containing a generated leaf certificate, stored in
 // the relevant secret, has been created and is not 'bad' due to being
 // expired, formatted incorrectly, etc. If it is bad for any reason, a new
@@ -127,6 +409,13 @@ func (r *Reconciler) reconcileClusterCertificate(
 		return cluster.Spec.CustomTLSSecret, nil
 	}
 
+	// cert-manager writes the same three files
+	if cluster.Spec.CertManager != nil {
+		return clusterCertSecretProjection(&corev1.Secret{
+			ObjectMeta: naming.CertManagerCertificate(cluster, naming.CertManagerServer),
+		}), nil
+	}
+
 	const keyCertificate, keyPrivateKey, rootCA = "tls.crt", "tls.key", "ca.crt"
 
 	existing := &corev1.Secret{ObjectMeta: naming.PostgresTLSSecret(cluster)}
@@ -174,7 +463,7 @@ func (r *Reconciler) reconcileClusterCertificate(
 		err = errors.WithStack(err)
 	}
 	if err == nil {
-		intent.Data[rootCA], err = root.Certificate.MarshalText()
+		intent.Data[rootCA], err = root.Authorities().MarshalText()
 		err = errors.WithStack(err)
 	}
 
@@ -239,6 +528,53 @@ func (*Reconciler) instanceCertificate(
 	return leaf, err
 }
 
+// userCertificate populates intent with a client certificate for the
+// PostgreSQL user in spec and returns it. The certificate in existing is kept
+// when it is valid, signed by root, and names the user. Otherwise, a new leaf
+// certificate is generated using the current root certificate.
+func (*Reconciler) userCertificate(
+	spec *v1beta1.PostgresUserSpec, existing, intent *corev1.Secret,
+	root *pki.RootCertificateAuthority,
+) (
+	*pki.LeafCertificate, error,
+) {
+	var err error
+
+	leaf := &pki.LeafCertificate{}
+
+	// PostgreSQL compares the certificate common name to the user name. User
+	// names are not host names, so the certificate has no DNS names.
+	// - https://www.postgresql.org/docs/current/auth-cert.html
+	commonName := string(spec.Name)
+	var dnsNames []string
+
+	if existing != nil {
+		// Unmarshal and validate the stored leaf. These first errors can
+		// be ignored because they result in an invalid leaf which is then
+		// correctly regenerated.
+		_ = leaf.Certificate.UnmarshalText(existing.Data[clusterCertFile])
+		_ = leaf.PrivateKey.UnmarshalText(existing.Data[clusterKeyFile])
+	}
+
+	leaf, err = root.RegenerateLeafWhenNecessary(leaf, commonName, dnsNames)
+	err = errors.WithStack(err)
+
+	if err == nil {
+		intent.Data[clusterCertFile], err = leaf.Certificate.MarshalText()
+		err = errors.WithStack(err)
+	}
+	if err == nil {
+		intent.Data[clusterKeyFile], err = leaf.PrivateKey.MarshalText()
+		err = errors.WithStack(err)
+	}
+	if err == nil {
+		intent.Data[rootCertFile], err = root.Authorities().MarshalText()
+		err = errors.WithStack(err)
+	}
+
+	return leaf, err
+}
+
 // clusterCertSecretProjection returns a secret projection of the postgrescluster's
 // CA, key, and certificate to include in the instance configuration volume.
 func clusterCertSecretProjection(certificate *corev1.Secret) *corev1.SecretProjection {
@@ -262,3 +598,132 @@ func clusterCertSecretProjection(certificate *corev1.Secret) *corev1.SecretProje
 		},
 	}
 }
+
+// certificateStatus describes the PEM-encoded certificate in data. It returns
+// false when data does not contain a certificate.
+func certificateStatus(name, secretName string, data []byte) (v1beta1.CertificateStatus, bool) {
+	var certificate pki.Certificate
+	if len(data) == 0 || certificate.UnmarshalText(data) != nil {
+		return v1beta1.CertificateStatus{}, false
+	}
+
+	renewal := metav1.NewTime(certificate.RenewalTime())
+	return v1beta1.CertificateStatus{
+		Name:       name,
+		SecretName: secretName,
+		NotAfter:   metav1.NewTime(certificate.NotAfter()),
+		RenewAfter: &renewal,
+	}, true
+}
+
+// expiringCertificates returns the names of certificates that should have been
+// replaced before now.
+func expiringCertificates(certificates []v1beta1.CertificateStatus, now time.Time) []string {
+	var names []string
+	for _, certificate := range certificates {
+		if certificate.RenewAfter != nil && now.After(certificate.RenewAfter.Time) {
+			names = append(names, certificate.Name)
+		}
+	}
+	return names
+}
+
+// projectionKey returns the key of the item in projection that is mounted at
+// path. When no items are specified, the key serves as the path.
+func projectionKey(projection *corev1.SecretProjection, path string) string {
+	for _, item := range projection.Items {
+		if item.Path == path {
+			return item.Key
+		}
+	}
+	return path
+}
+
+// +kubebuilder:rbac:groups="",resources="secrets",verbs={get}
+
+// reconcileCertificateStatus records when the certificates of cluster expire
+// in its status. Certificates that are past their renewal time have not been
+// replaced by the operator, cert-manager, or whoever provides a custom secret;
+// these are reported in a Warning event and the CertificatesExpiring condition.
+func (r *Reconciler) reconcileCertificateStatus(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	root *pki.RootCertificateAuthority, primaryCertificate *corev1.SecretProjection,
+) error {
+	var statuses []v1beta1.CertificateStatus
+
+	// Certificates issued by cert-manager do not come from the root.
+	if data, err := root.Certificate.MarshalText(); err == nil && cluster.Spec.CertManager == nil {
+		if status, ok := certificateStatus("root", naming.RootCertSecret, data); ok {
+			statuses = append(statuses, status)
+		}
+	}
+
+	type location struct{ name, secret, key string }
+	locations := []location{{
+		name:   "server",
+		secret: primaryCertificate.Name,
+		key:    projectionKey(primaryCertificate, clusterCertFile),
+	}}
+
+	switch {
+	case cluster.Spec.CustomReplicationClientTLSSecret != nil:
+		custom := cluster.Spec.CustomReplicationClientTLSSecret
+		locations = append(locations, location{
+			name: "replication", secret: custom.Name,
+			key: projectionKey(custom, naming.ReplicationCert),
+		})
+	case cluster.Spec.CertManager != nil:
+		locations = append(locations, location{
+			name:   "replication",
+			secret: naming.CertManagerCertificate(cluster, naming.CertManagerReplication).Name,
+			key:    naming.ReplicationCert,
+		})
+	default:
+		locations = append(locations, location{
+			name:   "replication",
+			secret: naming.ReplicationClientCertSecret(cluster).Name,
+			key:    naming.ReplicationCert,
+		})
+	}
+
+	if cluster.Spec.Proxy != nil && cluster.Spec.Proxy.PGBouncer != nil {
+		secret, key := pgbouncer.FrontendCertificateSecret(cluster)
+		locations = append(locations, location{name: "pgbouncer", secret: secret, key: key})
+	}
+
+	var err error
+	for _, l := range locations {
+		secret := &corev1.Secret{}
+		secret.Namespace, secret.Name = cluster.Namespace, l.secret
+
+		if err == nil {
+			err = errors.WithStack(client.IgnoreNotFound(
+				r.Client.Get(ctx, client.ObjectKeyFromObject(secret), secret)))
+		}
+		if status, ok := certificateStatus(l.name, l.secret, secret.Data[l.key]); ok {
+			statuses = append(statuses, status)
+		}
+	}
+
+	if err == nil {
+		cluster.Status.Certificates = statuses
+
+		if expiring := expiringCertificates(statuses, time.Now()); len(expiring) > 0 {
+			message := fmt.Sprintf("Certificates have not been renewed: %s",
+				strings.Join(expiring, ", "))
+
+			r.Recorder.Event(cluster, corev1.EventTypeWarning, "CertificateExpiring", message)
+			meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+				ObservedGeneration: cluster.GetGeneration(),
+				Type:               v1beta1.CertificatesExpiring,
+				Status:             metav1.ConditionTrue,
+				Reason:             "NotRenewed",
+				Message:            message,
+			})
+		} else {
+			meta.RemoveStatusCondition(&cluster.Status.Conditions, v1beta1.CertificatesExpiring)
+		}
+	}
+
+	return err
+}
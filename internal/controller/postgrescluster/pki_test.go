@@ -18,10 +18,12 @@ package postgrescluster
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"gotest.tools/v3/assert"
@@ -30,9 +32,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/crunchydata/postgres-operator/internal/controller/runtime"
+	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/pki"
+	"github.com/crunchydata/postgres-operator/internal/testing/events"
 	"github.com/crunchydata/postgres-operator/internal/testing/require"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -410,3 +416,451 @@ func getCertFromSecret(
 	fromSecret := &pki.Certificate{}
 	return fromSecret, fromSecret.UnmarshalText(secretCRT)
 }
+
+func TestUserCertificate(t *testing.T) {
+	r := &Reconciler{}
+
+	root, err := pki.NewRootCertificateAuthority()
+	assert.NilError(t, err)
+
+	spec := &v1beta1.PostgresUserSpec{Name: "some-user"}
+	intent := &corev1.Secret{Data: make(map[string][]byte)}
+
+	t.Run("NoExisting", func(t *testing.T) {
+		leaf, err := r.userCertificate(spec, nil, intent, root)
+		assert.NilError(t, err)
+		assert.Equal(t, leaf.Certificate.CommonName(), "some-user")
+		assert.Assert(t, len(leaf.Certificate.DNSNames()) == 0)
+
+		fromSecret := &pki.LeafCertificate{}
+		assert.NilError(t, fromSecret.Certificate.UnmarshalText(intent.Data["tls.crt"]))
+		assert.NilError(t, fromSecret.PrivateKey.UnmarshalText(intent.Data["tls.key"]))
+		assert.DeepEqual(t, fromSecret, leaf)
+
+		rootCert, err := root.Certificate.MarshalText()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, intent.Data["ca.crt"], rootCert)
+	})
+
+	t.Run("KeepsValid", func(t *testing.T) {
+		existing := intent.DeepCopy()
+		leaf, err := r.userCertificate(spec, existing, intent, root)
+		assert.NilError(t, err)
+
+		before := &pki.LeafCertificate{}
+		assert.NilError(t, before.Certificate.UnmarshalText(existing.Data["tls.crt"]))
+		assert.Assert(t, leaf.Certificate.Equal(before.Certificate))
+	})
+
+	t.Run("RenamedUser", func(t *testing.T) {
+		existing := intent.DeepCopy()
+		renamed := &v1beta1.PostgresUserSpec{Name: "other"}
+		leaf, err := r.userCertificate(renamed, existing, intent, root)
+		assert.NilError(t, err)
+		assert.Equal(t, leaf.Certificate.CommonName(), "other")
+	})
+
+	t.Run("NewRoot", func(t *testing.T) {
+		existing := intent.DeepCopy()
+		newRoot, err := pki.NewRootCertificateAuthority()
+		assert.NilError(t, err)
+
+		before := &pki.LeafCertificate{}
+		assert.NilError(t, before.Certificate.UnmarshalText(existing.Data["tls.crt"]))
+
+		leaf, err := r.userCertificate(spec, existing, intent, newRoot)
+		assert.NilError(t, err)
+		assert.Assert(t, !leaf.Certificate.Equal(before.Certificate))
+	})
+}
+
+func TestLeafCertificatePolicy(t *testing.T) {
+	r := &Reconciler{LeafCertificates: pki.Policy{
+		KeyAlgorithm: pki.RSA, Lifetime: 720 * time.Hour,
+	}}
+
+	cluster := new(v1beta1.PostgresCluster)
+	assert.DeepEqual(t, r.leafCertificatePolicy(cluster), r.LeafCertificates)
+
+	cluster.Spec.Certificates = &v1beta1.CertificatesSpec{KeyAlgorithm: "ECDSA"}
+	assert.DeepEqual(t, r.leafCertificatePolicy(cluster), pki.Policy{
+		KeyAlgorithm: pki.ECDSA, Lifetime: 720 * time.Hour,
+	})
+
+	cluster.Spec.Certificates.Lifetime = &metav1.Duration{Duration: 48 * time.Hour}
+	assert.DeepEqual(t, r.leafCertificatePolicy(cluster), pki.Policy{
+		KeyAlgorithm: pki.ECDSA, Lifetime: 48 * time.Hour,
+	})
+}
+
+func TestCertificateStatus(t *testing.T) {
+	_, ok := certificateStatus("any", "any", nil)
+	assert.Assert(t, !ok)
+
+	_, ok = certificateStatus("any", "any", []byte("garbage"))
+	assert.Assert(t, !ok)
+
+	root, err := pki.NewRootCertificateAuthority()
+	assert.NilError(t, err)
+	root.LeafPolicy.Lifetime = 30 * time.Hour
+
+	leaf, err := root.GenerateLeafCertificate("some-cn", nil)
+	assert.NilError(t, err)
+
+	data, err := leaf.Certificate.MarshalText()
+	assert.NilError(t, err)
+
+	status, ok := certificateStatus("server", "some-secret", data)
+	assert.Assert(t, ok)
+	assert.Equal(t, status.Name, "server")
+	assert.Equal(t, status.SecretName, "some-secret")
+	assert.Assert(t, status.NotAfter.Time.Equal(leaf.Certificate.NotAfter()))
+	assert.Assert(t, status.RenewAfter.Time.Equal(leaf.Certificate.RenewalTime()))
+
+	t.Run("Expiring", func(t *testing.T) {
+		statuses := []v1beta1.CertificateStatus{status, {Name: "unknown"}}
+
+		assert.Assert(t, len(expiringCertificates(statuses, time.Now())) == 0)
+		assert.DeepEqual(t,
+			expiringCertificates(statuses, status.RenewAfter.Add(time.Second)),
+			[]string{"server"})
+	})
+}
+
+func TestReconcileCertificateStatus(t *testing.T) {
+	ctx := context.Background()
+	r := &Reconciler{
+		Client:   fake.NewClientBuilder().WithScheme(runtime.Scheme).Build(),
+		Recorder: events.NewRecorder(t, runtime.Scheme),
+	}
+
+	root, err := pki.NewRootCertificateAuthority()
+	assert.NilError(t, err)
+
+	cluster := v1beta1.NewPostgresCluster()
+	cluster.Namespace, cluster.Name = "ns1", "hippo"
+	primary := &corev1.SecretProjection{}
+	primary.Name = "some-secret"
+
+	assert.NilError(t, r.reconcileCertificateStatus(ctx, cluster, root, primary))
+	assert.Equal(t, len(cluster.Status.Certificates), 1)
+	assert.Equal(t, cluster.Status.Certificates[0].Name, "root")
+
+	// Certificates issued by cert-manager do not come from the root.
+	cluster.Spec.CertManager = &v1beta1.CertManagerSpec{}
+	assert.NilError(t, r.reconcileCertificateStatus(ctx, cluster, root, primary))
+	assert.Assert(t, len(cluster.Status.Certificates) == 0)
+}
+
+func TestProjectionKey(t *testing.T) {
+	projection := &corev1.SecretProjection{}
+	assert.Equal(t, projectionKey(projection, "tls.crt"), "tls.crt")
+
+	projection.Items = []corev1.KeyToPath{
+		{Key: "some-key", Path: "tls.key"},
+		{Key: "some-cert", Path: "tls.crt"},
+	}
+	assert.Equal(t, projectionKey(projection, "tls.crt"), "some-cert")
+}
+
+func TestNextRootCertificateRotationPhase(t *testing.T) {
+	phase := v1beta1.RootCertificateRotationTrusting
+	for _, expected := range []string{"Reissuing", "Removing", "Complete", "Complete"} {
+		phase = nextRootCertificateRotationPhase(phase)
+		assert.Equal(t, phase, expected)
+	}
+}
+
+func TestRootCertificateRotationLoaded(t *testing.T) {
+	ctx := context.Background()
+
+	newCluster := func(name string, status *v1beta1.RootCertificateRotationStatus) *v1beta1.PostgresCluster {
+		cluster := v1beta1.NewPostgresCluster()
+		cluster.Namespace, cluster.Name = "ns1", name
+		cluster.UID = types.UID(name + "-uid")
+		cluster.Status.RootCertificateRotation = status
+		return cluster
+	}
+	loaded := &v1beta1.RootCertificateRotationStatus{ID: "one", Phase: "Trusting", Loaded: true}
+
+	hippo := newCluster("hippo", loaded.DeepCopy())
+	rhino := newCluster("rhino", &v1beta1.RootCertificateRotationStatus{ID: "one", Phase: "Trusting"})
+	stranger := newCluster("stranger", nil)
+
+	r := &Reconciler{Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).
+		WithObjects(hippo, rhino, stranger).Build()}
+
+	secret := &corev1.Secret{}
+	secret.Namespace, secret.Name = "ns1", naming.RootCertSecret
+	for _, owner := range []*v1beta1.PostgresCluster{hippo, rhino} {
+		secret.OwnerReferences = append(secret.OwnerReferences, metav1.OwnerReference{
+			Kind: "PostgresCluster", Name: owner.Name, UID: owner.UID,
+		})
+	}
+
+	// Another owner has not loaded the certificates of this phase.
+	result, err := r.rootCertificateRotationLoaded(ctx, hippo, secret, "one", "Trusting")
+	assert.NilError(t, err)
+	assert.Assert(t, !result)
+
+	// The status in memory is used for the cluster being reconciled.
+	// Clusters that do not own the secret are not waited on.
+	rhino.Status.RootCertificateRotation.Loaded = true
+	result, err = r.rootCertificateRotationLoaded(ctx, rhino, secret, "one", "Trusting")
+	assert.NilError(t, err)
+	assert.Assert(t, result)
+
+	// Statuses of another phase or rotation do not count.
+	result, err = r.rootCertificateRotationLoaded(ctx, rhino, secret, "one", "Reissuing")
+	assert.NilError(t, err)
+	assert.Assert(t, !result)
+
+	result, err = r.rootCertificateRotationLoaded(ctx, rhino, secret, "two", "Trusting")
+	assert.NilError(t, err)
+	assert.Assert(t, !result)
+
+	t.Run("Paused", func(t *testing.T) {
+		paused := newCluster("paused", nil)
+		paused.Spec.Paused = initialize.Bool(true)
+		assert.NilError(t, r.Client.Create(ctx, paused))
+
+		secret := secret.DeepCopy()
+		secret.OwnerReferences = append(secret.OwnerReferences, metav1.OwnerReference{
+			Kind: "PostgresCluster", Name: paused.Name, UID: paused.UID,
+		})
+
+		result, err := r.rootCertificateRotationLoaded(ctx, rhino, secret, "one", "Trusting")
+		assert.NilError(t, err)
+		assert.Assert(t, result)
+	})
+}
+
+func TestReconcileRootCertificateRotation(t *testing.T) {
+	ctx := context.Background()
+
+	root, err := pki.NewRootCertificateAuthority()
+	assert.NilError(t, err)
+
+	runningInstance := func(name string) *Instance {
+		pod := &corev1.Pod{}
+		pod.Namespace, pod.Name = "ns1", name+"-0"
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  naming.ContainerDatabase,
+			State: corev1.ContainerState{Running: new(corev1.ContainerStateRunning)},
+		}}
+		return &Instance{Name: name, Pods: []*corev1.Pod{pod}}
+	}
+	instances := &observedInstances{forCluster: []*Instance{
+		runningInstance("hippo-a"), runningInstance("hippo-b"),
+		{Name: "hippo-stopped"},
+	}}
+
+	var postgresOutput, patroniOutput string
+	var calls []string
+	r := &Reconciler{
+		Recorder: events.NewRecorder(t, runtime.Scheme),
+		PodExec: func(
+			namespace, pod, container string,
+			stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			calls = append(calls, pod+" "+command[0])
+
+			output := postgresOutput
+			if command[0] == "bash" {
+				output = patroniOutput
+			}
+			_, err := stdout.Write([]byte(output))
+			return err
+		},
+	}
+
+	cluster := v1beta1.NewPostgresCluster()
+	result, err := r.reconcileRootCertificateRotation(ctx, cluster, instances, root)
+	assert.NilError(t, err)
+	assert.Assert(t, result.IsZero())
+	assert.Assert(t, len(calls) == 0)
+
+	cluster.Status.RootCertificateRotation = &v1beta1.RootCertificateRotationStatus{
+		ID: "one", Phase: "Trusting",
+	}
+
+	// Wait for the mounted files to change.
+	postgresOutput = "f\n"
+	result, err = r.reconcileRootCertificateRotation(ctx, cluster, instances, root)
+	assert.NilError(t, err)
+	assert.Assert(t, result.RequeueAfter > 0)
+	assert.DeepEqual(t, calls, []string{"hippo-a-0 psql"})
+	assert.Assert(t, !cluster.Status.RootCertificateRotation.Loaded)
+
+	postgresOutput, patroniOutput = "t\n", "false\n"
+	calls = nil
+	result, err = r.reconcileRootCertificateRotation(ctx, cluster, instances, root)
+	assert.NilError(t, err)
+	assert.Assert(t, result.RequeueAfter > 0)
+	assert.DeepEqual(t, calls, []string{"hippo-a-0 psql", "hippo-a-0 bash"})
+	assert.Assert(t, !cluster.Status.RootCertificateRotation.Loaded)
+
+	// Every running instance is checked and Patroni reloads in one pass.
+	patroniOutput = "true\n"
+	calls = nil
+	result, err = r.reconcileRootCertificateRotation(ctx, cluster, instances, root)
+	assert.NilError(t, err)
+	assert.Assert(t, result.Requeue)
+	assert.DeepEqual(t, calls, []string{
+		"hippo-a-0 psql", "hippo-a-0 bash",
+		"hippo-b-0 psql", "hippo-b-0 bash",
+	})
+	assert.Assert(t, cluster.Status.RootCertificateRotation.Loaded)
+
+	// Then it waits on other clusters.
+	calls = nil
+	result, err = r.reconcileRootCertificateRotation(ctx, cluster, instances, root)
+	assert.NilError(t, err)
+	assert.Assert(t, result.RequeueAfter > 0)
+	assert.Assert(t, len(calls) == 0)
+
+	t.Run("CustomTLS", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.CustomTLSSecret = &corev1.SecretProjection{}
+		cluster.Status.RootCertificateRotation.Loaded = false
+
+		calls = nil
+		_, err := r.reconcileRootCertificateRotation(ctx, cluster, instances, root)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, calls, []string{"hippo-a-0 bash", "hippo-b-0 bash"})
+		assert.Assert(t, cluster.Status.RootCertificateRotation.Loaded)
+	})
+
+	t.Run("CertManager", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.CertManager = &v1beta1.CertManagerSpec{}
+		cluster.Status.RootCertificateRotation.Loaded = false
+
+		calls = nil
+		_, err := r.reconcileRootCertificateRotation(ctx, cluster, instances, root)
+		assert.NilError(t, err)
+		assert.Assert(t, len(calls) == 0)
+		assert.Assert(t, cluster.Status.RootCertificateRotation.Loaded)
+	})
+}
+
+func TestReconcileRootCertificateRenewal(t *testing.T) {
+	_, tClient := setupKubernetes(t)
+	require.ParallelCapacity(t, 1)
+
+	ctx := context.Background()
+	r := &Reconciler{
+		Client:   tClient,
+		Owner:    ControllerName,
+		Recorder: events.NewRecorder(t, runtime.Scheme),
+	}
+
+	cluster := testCluster()
+	cluster.Namespace = setupNamespace(t, tClient).Name
+	assert.NilError(t, tClient.Create(ctx, cluster))
+
+	original, err := r.reconcileRootCertificate(ctx, cluster)
+	assert.NilError(t, err)
+	assert.Assert(t, cluster.Status.RootCertificateRotation == nil)
+
+	// A root that is not the kind the operator generates is rotated rather
+	// than replaced, so certificates it signed remain trusted.
+	r.RootCertificates = pki.Policy{KeyAlgorithm: pki.RSA}
+	trusting, err := r.reconcileRootCertificate(ctx, cluster)
+	assert.NilError(t, err)
+	assert.Assert(t, trusting.Certificate.Equal(original.Certificate))
+	assert.Equal(t, len(trusting.Trusted), 1)
+	assert.Equal(t, trusting.Trusted[0].KeyAlgorithm(), pki.RSA)
+
+	rotation := cluster.Status.RootCertificateRotation
+	assert.Assert(t, rotation != nil)
+	assert.Assert(t, strings.HasPrefix(rotation.ID, "renew-"), "got %q", rotation.ID)
+	assert.Equal(t, rotation.Phase, "Trusting")
+	assert.Equal(t, rotation.Requested, "")
+}
+
+func TestReconcileRootCertificatePhases(t *testing.T) {
+	_, tClient := setupKubernetes(t)
+	require.ParallelCapacity(t, 1)
+
+	ctx := context.Background()
+	r := &Reconciler{
+		Client:   tClient,
+		Owner:    ControllerName,
+		Recorder: events.NewRecorder(t, runtime.Scheme),
+	}
+
+	cluster := testCluster()
+	cluster.Namespace = setupNamespace(t, tClient).Name
+	assert.NilError(t, tClient.Create(ctx, cluster))
+
+	original, err := r.reconcileRootCertificate(ctx, cluster)
+	assert.NilError(t, err)
+	assert.Assert(t, len(original.Trusted) == 0)
+
+	// A new root is trusted but does not yet sign anything.
+	cluster.Annotations = map[string]string{naming.RotateRootCertificate: "one"}
+	trusting, err := r.reconcileRootCertificate(ctx, cluster)
+	assert.NilError(t, err)
+	assert.Equal(t, cluster.Status.RootCertificateRotation.Phase, "Trusting")
+	assert.Assert(t, trusting.Certificate.Equal(original.Certificate))
+	assert.Equal(t, len(trusting.Trusted), 1)
+
+	// Another cluster in the namespace trusts the same new root.
+	other := testCluster()
+	other.Name, other.Namespace = "other", cluster.Namespace
+	assert.NilError(t, tClient.Create(ctx, other))
+
+	shared, err := r.reconcileRootCertificate(ctx, other)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, shared.Authorities(), trusting.Authorities())
+
+	// The next phase waits until every cluster sharing the root has loaded
+	// the certificates of this one.
+	cluster.Status.RootCertificateRotation.Loaded = true
+	waiting, err := r.reconcileRootCertificate(ctx, cluster)
+	assert.NilError(t, err)
+	assert.Equal(t, cluster.Status.RootCertificateRotation.Phase, "Trusting")
+	assert.DeepEqual(t, waiting.Authorities(), trusting.Authorities())
+
+	// Another request waits until this rotation is done.
+	cluster.Annotations[naming.RotateRootCertificate] = "two"
+
+	advance := func() *pki.RootCertificateAuthority {
+		t.Helper()
+		for _, c := range []*v1beta1.PostgresCluster{cluster, other} {
+			c.Status.RootCertificateRotation.Loaded = true
+		}
+		assert.NilError(t, tClient.Status().Update(ctx, other))
+
+		root, err := r.reconcileRootCertificate(ctx, cluster)
+		assert.NilError(t, err)
+		assert.Assert(t, !cluster.Status.RootCertificateRotation.Loaded)
+
+		// The other cluster follows the phase recorded on the secret.
+		_, err = r.reconcileRootCertificate(ctx, other)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, other.Status.RootCertificateRotation.Phase,
+			cluster.Status.RootCertificateRotation.Phase)
+		return root
+	}
+
+	// The new root signs and the original remains trusted.
+	reissuing := advance()
+	assert.Equal(t, cluster.Status.RootCertificateRotation.Phase, "Reissuing")
+	assert.Assert(t, reissuing.Certificate.Equal(trusting.Trusted[0]))
+	assert.DeepEqual(t, reissuing.Trusted, pki.Certificates{original.Certificate})
+
+	// The original is no longer trusted.
+	removing := advance()
+	assert.Equal(t, cluster.Status.RootCertificateRotation.Phase, "Removing")
+	assert.Assert(t, removing.Certificate.Equal(reissuing.Certificate))
+	assert.Assert(t, len(removing.Trusted) == 0)
+
+	// The waiting request starts once the rotation completes.
+	next := advance()
+	assert.DeepEqual(t, cluster.Status.RootCertificateRotation,
+		&v1beta1.RootCertificateRotationStatus{ID: "two", Phase: "Trusting", Requested: "two"})
+	assert.Assert(t, next.Certificate.Equal(removing.Certificate))
+	assert.Equal(t, len(next.Trusted), 1)
+}
@@ -38,6 +38,8 @@ import (
 	"github.com/crunchydata/postgres-operator/internal/logging"
 	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/pgaudit"
+	"github.com/crunchydata/postgres-operator/internal/pgbouncer"
+	"github.com/crunchydata/postgres-operator/internal/pki"
 	"github.com/crunchydata/postgres-operator/internal/postgis"
 	"github.com/crunchydata/postgres-operator/internal/postgres"
 	pgpassword "github.com/crunchydata/postgres-operator/internal/postgres/password"
@@ -132,18 +134,29 @@ func (r *Reconciler) generatePostgresUserSecret(
 	}
 
 	// When PgBouncer is enabled, include values for connecting through it.
-	if cluster.Spec.Proxy != nil && cluster.Spec.Proxy.PGBouncer != nil {
-		pgBouncer := naming.ClusterPGBouncer(cluster)
+	// Keys for each additional pooler have its name, e.g. "pgbouncer-batch-uri".
+	for _, pooler := range append([]string{""}, pgbouncer.PoolerNames(cluster)...) {
+		proxied := pgbouncer.Pooler(cluster, pooler)
+		if proxied.Spec.Proxy == nil || proxied.Spec.Proxy.PGBouncer == nil {
+			continue
+		}
+
+		prefix := "pgbouncer-"
+		if pooler != "" {
+			prefix += pooler + "-"
+		}
+
+		pgBouncer := naming.ClusterPGBouncerPooler(cluster, pooler)
 		hostname := pgBouncer.Name + "." + pgBouncer.Namespace + ".svc"
-		port := fmt.Sprint(*cluster.Spec.Proxy.PGBouncer.Port)
+		port := fmt.Sprint(*proxied.Spec.Proxy.PGBouncer.Port)
 
-		intent.Data["pgbouncer-host"] = []byte(hostname)
-		intent.Data["pgbouncer-port"] = []byte(port)
+		intent.Data[prefix+"host"] = []byte(hostname)
+		intent.Data[prefix+"port"] = []byte(port)
 
 		if len(spec.Databases) > 0 {
 			database := string(spec.Databases[0])
 
-			intent.Data["pgbouncer-uri"] = []byte((&url.URL{
+			intent.Data[prefix+"uri"] = []byte((&url.URL{
 				Scheme: "postgresql",
 				User:   url.UserPassword(username, string(intent.Data["password"])),
 				Host:   net.JoinHostPort(hostname, port),
@@ -159,12 +172,30 @@ func (r *Reconciler) generatePostgresUserSecret(
 			query.Set("user", username)
 			query.Set("password", string(intent.Data["password"]))
 			query.Set("prepareThreshold", "0")
-			intent.Data["pgbouncer-jdbc-uri"] = []byte((&url.URL{
+			intent.Data[prefix+"jdbc-uri"] = []byte((&url.URL{
 				Scheme:   "jdbc:postgresql",
 				Host:     net.JoinHostPort(hostname, port),
 				Path:     database,
 				RawQuery: query.Encode(),
 			}).String())
+
+			// Read-only pools connect to replicas through the same PgBouncer.
+			if pgbouncer.ReadOnlyEnabled(proxied) {
+				readOnly := pgbouncer.ReadOnlyDatabase(proxied, database)
+
+				intent.Data[prefix+"ro-uri"] = []byte((&url.URL{
+					Scheme: "postgresql",
+					User:   url.UserPassword(username, string(intent.Data["password"])),
+					Host:   net.JoinHostPort(hostname, port),
+					Path:   readOnly,
+				}).String())
+				intent.Data[prefix+"ro-jdbc-uri"] = []byte((&url.URL{
+					Scheme:   "jdbc:postgresql",
+					Host:     net.JoinHostPort(hostname, port),
+					Path:     readOnly,
+					RawQuery: query.Encode(),
+				}).String())
+			}
 		}
 	}
 
@@ -297,10 +328,11 @@ func (r *Reconciler) reconcilePostgresDatabases(
 // passwords in PostgreSQL.
 func (r *Reconciler) reconcilePostgresUsers(
 	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+	root *pki.RootCertificateAuthority,
 ) error {
 	r.validatePostgresUsers(cluster)
 
-	users, secrets, err := r.reconcilePostgresUserSecrets(ctx, cluster)
+	users, secrets, err := r.reconcilePostgresUserSecrets(ctx, cluster, root)
 	if err == nil {
 		err = r.reconcilePostgresUsersInPostgreSQL(ctx, cluster, instances, users, secrets)
 	}
@@ -348,15 +380,71 @@ func (r *Reconciler) validatePostgresUsers(cluster *v1beta1.PostgresCluster) {
 	}
 }
 
+var (
+	// reHBAOption matches the names of authentication method options.
+	reHBAOption = regexp.MustCompile(`^[a-z_]+$`)
+
+	// reUserName matches the user names allowed by v1beta1.PostgresUserSpec.
+	reUserName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+)
+
+// validatePostgresAuthentication emits a warning and returns an error when
+// cluster.Spec.Authentication contains rules that PostgreSQL cannot load.
+// The CRD validates most fields; this checks what it cannot. NOTE(validation)
+func (r *Reconciler) validatePostgresAuthentication(cluster *v1beta1.PostgresCluster) error {
+	if cluster.Spec.Authentication == nil {
+		return nil
+	}
+
+	path := field.NewPath("spec", "authentication", "rules")
+	errs := field.ErrorList{}
+
+	for i, rule := range cluster.Spec.Authentication.Rules {
+		if rule.Address != "" {
+			if rule.Connection == "local" {
+				errs = append(errs,
+					field.Invalid(path.Index(i).Child("address"), rule.Address,
+						`not allowed for "local" connections`))
+			} else if _, _, err := net.ParseCIDR(rule.Address); err != nil {
+				errs = append(errs,
+					field.Invalid(path.Index(i).Child("address"), rule.Address,
+						"should be an IP address range in CIDR notation"))
+			}
+		}
+		for k, v := range rule.Options {
+			if !reHBAOption.MatchString(k) {
+				errs = append(errs,
+					field.Invalid(path.Index(i).Child("options").Key(k), k,
+						fmt.Sprintf("should match '%s'", reHBAOption)))
+			}
+			if strings.ContainsAny(v, "\r\n") {
+				errs = append(errs,
+					field.Invalid(path.Index(i).Child("options").Key(k), v,
+						"cannot contain line breaks"))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		err := errs.ToAggregate()
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, "InvalidAuthentication",
+			err.Error())
+		return err
+	}
+	return nil
+}
+
 // +kubebuilder:rbac:groups="",resources="secrets",verbs={list}
 // +kubebuilder:rbac:groups="",resources="secrets",verbs={create,delete,patch}
 
 // reconcilePostgresUserSecrets writes Secrets for the PostgreSQL users
 // specified in cluster and deletes existing Secrets that are not specified.
-// It returns the user specifications it acted on (because defaults) and the
-// Secrets it wrote.
+// Client certificates for those users are signed by root unless cert-manager
+// issues them. It returns the user
+// specifications it acted on (because defaults) and the Secrets it wrote.
 func (r *Reconciler) reconcilePostgresUserSecrets(
 	ctx context.Context, cluster *v1beta1.PostgresCluster,
+	root *pki.RootCertificateAuthority,
 ) (
 	[]v1beta1.PostgresUserSpec, map[string]*corev1.Secret, error,
 ) {
@@ -367,7 +455,6 @@ func (r *Reconciler) reconcilePostgresUserSecrets(
 	specUsers := cluster.Spec.Users
 	if specUsers == nil {
 		path := field.NewPath("spec", "users").Index(0).Child("name")
-		reUser := regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
 		allErrors := field.ErrorList{}
 
 		// User names cannot be too long. PostgresCluster.Name is a DNS
@@ -378,10 +465,10 @@ func (r *Reconciler) reconcilePostgresUserSecrets(
 					fmt.Sprintf("should be at most %d chars long", 63)))
 		}
 		// See v1beta1.PostgresRoleSpec validation markers.
-		if !reUser.MatchString(cluster.Name) {
+		if !reUserName.MatchString(cluster.Name) {
 			allErrors = append(allErrors,
 				field.Invalid(path, cluster.Name,
-					fmt.Sprintf("should match '%s'", reUser)))
+					fmt.Sprintf("should match '%s'", reUserName)))
 		}
 
 		if len(allErrors) > 0 {
@@ -482,6 +569,13 @@ func (r *Reconciler) reconcilePostgresUserSecrets(
 		if err == nil {
 			userSecrets[userName], err = r.generatePostgresUserSecret(cluster, user, secret)
 		}
+		if err == nil && user.ClientCertificate != nil && *user.ClientCertificate {
+			if cluster.Spec.CertManager != nil {
+				err = r.userCertManagerCertificate(ctx, cluster, user, userSecrets[userName])
+			} else {
+				_, err = r.userCertificate(user, secret, userSecrets[userName], root)
+			}
+		}
 		if err == nil {
 			err = errors.WithStack(r.apply(ctx, userSecrets[userName]))
 		}
@@ -233,7 +233,52 @@ func TestGeneratePostgresUserSecret(t *testing.T) {
 				`^jdbc:postgresql://hippo2-pgbouncer.ns1.svc:10220/yes`+
 					`[?]password=[^&]+&prepareThreshold=0&user=some-user-name$`,
 				string(secret.Data["pgbouncer-jdbc-uri"])))
+			assert.Assert(t, secret.Data["pgbouncer-ro-uri"] == nil)
+			assert.Assert(t, secret.Data["pgbouncer-ro-jdbc-uri"] == nil)
 		}
+
+		t.Run("ReadOnly", func(t *testing.T) {
+			cluster := cluster.DeepCopy()
+			cluster.Spec.Proxy.PGBouncer.ReadOnly = &v1beta1.PGBouncerReadOnlySpec{
+				Suffix: "_replica",
+			}
+
+			secret, err := reconciler.generatePostgresUserSecret(cluster, &spec, nil)
+			assert.NilError(t, err)
+
+			if assert.Check(t, secret != nil) {
+				assert.Assert(t, cmp.Regexp(
+					`^postgresql://some-user-name:[^@]+@hippo2-pgbouncer.ns1.svc:10220/yes_replica$`,
+					string(secret.Data["pgbouncer-ro-uri"])))
+				assert.Assert(t, cmp.Regexp(
+					`^jdbc:postgresql://hippo2-pgbouncer.ns1.svc:10220/yes_replica`+
+						`[?]password=[^&]+&prepareThreshold=0&user=some-user-name$`,
+					string(secret.Data["pgbouncer-ro-jdbc-uri"])))
+			}
+		})
+
+		t.Run("Poolers", func(t *testing.T) {
+			cluster := cluster.DeepCopy()
+			cluster.Spec.Proxy.Poolers = []v1beta1.PGBouncerPoolerSpec{{
+				Name: "batch", Port: initialize.Int32(10221),
+			}}
+
+			secret, err := reconciler.generatePostgresUserSecret(cluster, &spec, nil)
+			assert.NilError(t, err)
+
+			if assert.Check(t, secret != nil) {
+				assert.Equal(t, string(secret.Data["pgbouncer-host"]), "hippo2-pgbouncer.ns1.svc")
+				assert.Equal(t, string(secret.Data["pgbouncer-batch-host"]), "hippo2-pgbouncer-batch.ns1.svc")
+				assert.Equal(t, string(secret.Data["pgbouncer-batch-port"]), "10221")
+				assert.Assert(t, cmp.Regexp(
+					`^postgresql://some-user-name:[^@]+@hippo2-pgbouncer-batch.ns1.svc:10221/yes$`,
+					string(secret.Data["pgbouncer-batch-uri"])))
+				assert.Assert(t, cmp.Regexp(
+					`^jdbc:postgresql://hippo2-pgbouncer-batch.ns1.svc:10221/yes`+
+						`[?]password=[^&]+&prepareThreshold=0&user=some-user-name$`,
+					string(secret.Data["pgbouncer-batch-jdbc-uri"])))
+			}
+		})
 	})
 }
 
@@ -742,3 +787,60 @@ func TestValidatePostgresUsers(t *testing.T) {
 		reconciler.validatePostgresUsers(cluster)
 	})
 }
+
+func TestValidatePostgresAuthentication(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Empty", func(t *testing.T) {
+		cluster := v1beta1.NewPostgresCluster()
+		reconciler := &Reconciler{}
+
+		assert.NilError(t, reconciler.validatePostgresAuthentication(cluster))
+
+		cluster.Spec.Authentication = &v1beta1.PostgresAuthenticationSpec{}
+		assert.NilError(t, reconciler.validatePostgresAuthentication(cluster))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		cluster := v1beta1.NewPostgresCluster()
+		cluster.Name = "pg2"
+		cluster.Spec.Authentication = &v1beta1.PostgresAuthenticationSpec{
+			Rules: []v1beta1.PostgresHBARule{
+				{Connection: "local", Address: "10.0.0.0/8", Method: "peer"},
+				{Connection: "host", Address: "10.0.0.300/8", Method: "md5"},
+				{Connection: "hostssl", Method: "cert", Options: map[string]string{
+					"Bad-Key": "x", "map": "a\nb",
+				}},
+			},
+		}
+
+		recorder := events.NewRecorder(t, runtime.Scheme)
+		reconciler := &Reconciler{Recorder: recorder}
+
+		err := reconciler.validatePostgresAuthentication(cluster)
+		assert.ErrorContains(t, err, "spec.authentication.rules[0].address")
+		assert.ErrorContains(t, err, "spec.authentication.rules[1].address")
+		assert.ErrorContains(t, err, "spec.authentication.rules[2].options[Bad-Key]")
+		assert.ErrorContains(t, err, "cannot contain line breaks")
+
+		assert.Equal(t, len(recorder.Events), 1)
+		assert.Equal(t, recorder.Events[0].Regarding.Name, cluster.Name)
+		assert.Equal(t, recorder.Events[0].Reason, "InvalidAuthentication")
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		cluster := v1beta1.NewPostgresCluster()
+		cluster.Spec.Authentication = &v1beta1.PostgresAuthenticationSpec{
+			Rules: []v1beta1.PostgresHBARule{
+				{Connection: "hostssl", Address: "fd00::/8", Method: "scram-sha-256"},
+				{Connection: "local", Method: "peer", Options: map[string]string{"map": "local"}},
+			},
+		}
+
+		reconciler := &Reconciler{}
+		assert.Assert(t, reconciler.Recorder == nil,
+			"expected the following to not use a Recorder at all")
+
+		assert.NilError(t, reconciler.validatePostgresAuthentication(cluster))
+	})
+}
@@ -0,0 +1,392 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/pgmonitor"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// prometheusPodMonitorGVK and prometheusRuleGVK are the kinds of the
+// Prometheus Operator objects this package writes. The operator does not
+// depend on the Prometheus Operator, so these are handled as unstructured
+// objects and skipped when their CRDs are not installed.
+// - https://prometheus-operator.dev/docs/operator/api/
+var (
+	prometheusPodMonitorGVK = schema.GroupVersionKind{
+		Group: "monitoring.coreos.com", Version: "v1", Kind: "PodMonitor",
+	}
+	prometheusRuleGVK = schema.GroupVersionKind{
+		Group: "monitoring.coreos.com", Version: "v1", Kind: "PrometheusRule",
+	}
+)
+
+// prometheusEnabled returns true when cluster should have Prometheus Operator
+// objects for its exporter.
+func prometheusEnabled(cluster *v1beta1.PostgresCluster) bool {
+	return pgmonitor.ExporterEnabled(cluster) && cluster.Spec.Monitoring.Prometheus != nil
+}
+
+// prometheusDuration formats d as a Prometheus duration in whole seconds.
+// - https://prometheus.io/docs/prometheus/latest/configuration/configuration/#duration
+func prometheusDuration(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(d/time.Second))
+}
+
+// prometheusLabelName returns the name Prometheus gives to the Kubernetes
+// label when it discovers targets. Characters that are not allowed in
+// Prometheus label names become underscores.
+func prometheusLabelName(label string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, label)
+}
+
+// prometheusClusterSelector returns the PromQL label matcher of metrics
+// scraped from cluster. See [prometheusPodMonitor].
+func prometheusClusterSelector(cluster *v1beta1.PostgresCluster) string {
+	return fmt.Sprintf(`pg_cluster=%q`, cluster.Namespace+":"+cluster.Name)
+}
+
+// prometheusObject returns an unstructured object of kind with objectMeta,
+// the metadata of cluster, and spec.
+func prometheusObject(
+	cluster *v1beta1.PostgresCluster, kind schema.GroupVersionKind,
+	objectMeta metav1.ObjectMeta, spec map[string]any,
+) *unstructured.Unstructured {
+	labels := naming.Merge(
+		cluster.Spec.Metadata.GetLabelsOrNil(),
+		cluster.Spec.Monitoring.Prometheus.Metadata.GetLabelsOrNil(),
+		map[string]string{
+			naming.LabelCluster: cluster.Name,
+			naming.LabelRole:    naming.RoleMonitoring,
+		})
+	annotations := naming.Merge(
+		cluster.Spec.Metadata.GetAnnotationsOrNil(),
+		cluster.Spec.Monitoring.Prometheus.Metadata.GetAnnotationsOrNil())
+
+	object := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	object.SetGroupVersionKind(kind)
+	object.SetNamespace(objectMeta.Namespace)
+	object.SetName(objectMeta.Name)
+	object.SetLabels(labels)
+	if len(annotations) > 0 {
+		object.SetAnnotations(annotations)
+	}
+	return object
+}
+
+// prometheusPGBouncerMetrics matches the names of metrics that the exporter
+// reads from PgBouncer through pgbouncer_fdw.
+const prometheusPGBouncerMetrics = "ccp_pgbouncer_.*"
+
+// prometheusPodMonitor returns a PodMonitor that scrapes the exporter on every
+// instance of cluster. Metrics are labeled with the namespace and name of
+// cluster in "pg_cluster" and the role of the instance in "role", the same as
+// the Prometheus configuration of pgMonitor. Statistics of PgBouncer are left
+// to [prometheusPGBouncerPodMonitor].
+// - https://prometheus-operator.dev/docs/operator/api/#monitoring.coreos.com/v1.PodMonitor
+func prometheusPodMonitor(cluster *v1beta1.PostgresCluster) *unstructured.Unstructured {
+	endpoint := prometheusExporterEndpoint(cluster)
+	endpoint["metricRelabelings"] = []any{
+		map[string]any{
+			"sourceLabels": stringsToAny([]string{"__name__"}),
+			"regex":        prometheusPGBouncerMetrics,
+			"action":       "drop",
+		},
+	}
+
+	return prometheusObject(cluster, prometheusPodMonitorGVK,
+		naming.ClusterPrometheus(cluster), map[string]any{
+			"podMetricsEndpoints": []any{endpoint},
+			"selector": map[string]any{
+				"matchLabels": stringMapToAny(map[string]string{
+					naming.LabelCluster:            cluster.Name,
+					naming.LabelPGMonitorDiscovery: "true",
+				}),
+			},
+		})
+}
+
+// prometheusPGBouncerPodMonitor returns a PodMonitor of the statistics of
+// PgBouncer in cluster. PgBouncer has no exporter of its own; the exporter on
+// every instance reads the same statistics through pgbouncer_fdw. This scrapes
+// them from only the primary so they are not counted once per instance.
+func prometheusPGBouncerPodMonitor(cluster *v1beta1.PostgresCluster) *unstructured.Unstructured {
+	endpoint := prometheusExporterEndpoint(cluster)
+	endpoint["metricRelabelings"] = []any{
+		map[string]any{
+			"sourceLabels": stringsToAny([]string{"__name__"}),
+			"regex":        prometheusPGBouncerMetrics,
+			"action":       "keep",
+		},
+	}
+
+	return prometheusObject(cluster, prometheusPodMonitorGVK,
+		naming.ClusterPGBouncerPrometheus(cluster), map[string]any{
+			"podMetricsEndpoints": []any{endpoint},
+			"selector": map[string]any{
+				"matchLabels": stringMapToAny(map[string]string{
+					naming.LabelCluster:            cluster.Name,
+					naming.LabelPGMonitorDiscovery: "true",
+					naming.LabelRole:               naming.RolePatroniLeader,
+				}),
+			},
+		})
+}
+
+// prometheusExporterEndpoint returns the PodMetricsEndpoint of the exporter
+// on the instances of cluster.
+func prometheusExporterEndpoint(cluster *v1beta1.PostgresCluster) map[string]any {
+	interval := 30 * time.Second
+	if spec := cluster.Spec.Monitoring.Prometheus; spec.ScrapeInterval != nil {
+		interval = spec.ScrapeInterval.Duration
+	}
+
+	discovered := func(label string) string {
+		return "__meta_kubernetes_pod_label_" + prometheusLabelName(label)
+	}
+
+	endpoint := map[string]any{
+		"port":     naming.PortExporter,
+		"interval": prometheusDuration(interval),
+		"relabelings": []any{
+			map[string]any{
+				"sourceLabels": stringsToAny([]string{
+					"__meta_kubernetes_namespace", discovered(naming.LabelCluster),
+				}),
+				"separator":   ":",
+				"targetLabel": "pg_cluster",
+			},
+			map[string]any{
+				"sourceLabels": stringsToAny([]string{discovered(naming.LabelRole)}),
+				"targetLabel":  "role",
+			},
+		},
+	}
+
+	// The exporter serves HTTPS when it has a custom certificate. That
+	// certificate need not name the IP address of each Pod, so Prometheus
+	// encrypts the connection without verifying it.
+	if cluster.Spec.Monitoring.PGMonitor.Exporter.CustomTLSSecret != nil {
+		endpoint["scheme"] = "https"
+		endpoint["tlsConfig"] = map[string]any{"insecureSkipVerify": true}
+	}
+
+	return endpoint
+}
+
+// prometheusRule returns a PrometheusRule with the standard pgMonitor alerts
+// about cluster at the thresholds in its spec.
+// - https://prometheus-operator.dev/docs/operator/api/#monitoring.coreos.com/v1.PrometheusRule
+// - https://github.com/CrunchyData/pgmonitor/tree/main/prometheus
+func prometheusRule(cluster *v1beta1.PostgresCluster) *unstructured.Unstructured {
+	archiveFailure := 5 * time.Minute
+	diskUsage := int32(80)
+	fullBackupAge := 7 * 24 * time.Hour
+	replicationLag := resource.MustParse("50Mi")
+
+	if alerts := cluster.Spec.Monitoring.Prometheus.Alerts; alerts != nil {
+		if alerts.ArchiveFailure != nil {
+			archiveFailure = alerts.ArchiveFailure.Duration
+		}
+		if alerts.DiskUsagePercent != nil {
+			diskUsage = *alerts.DiskUsagePercent
+		}
+		if alerts.FullBackupAge != nil {
+			fullBackupAge = alerts.FullBackupAge.Duration
+		}
+		if alerts.ReplicationLag != nil {
+			replicationLag = *alerts.ReplicationLag
+		}
+	}
+
+	selector := prometheusClusterSelector(cluster)
+	rule := func(alert, expr, severity, summary string) any {
+		return map[string]any{
+			"alert": alert,
+			"expr":  expr,
+			"for":   "1m",
+			"labels": map[string]any{
+				"severity": severity,
+			},
+			"annotations": map[string]any{
+				"summary": summary,
+			},
+		}
+	}
+
+	rules := []any{
+		rule("PGReplicationByteLag",
+			fmt.Sprintf(`ccp_replication_lag_size_bytes{%s} > %d`,
+				selector, replicationLag.Value()),
+			"warning",
+			"Replica {{ $labels.pod }} is more than "+replicationLag.String()+" behind the primary"),
+
+		rule("PGArchiveCommandStatus",
+			fmt.Sprintf(`ccp_archive_command_status_seconds_since_last_fail{%s} > %d`,
+				selector, int64(archiveFailure/time.Second)),
+			"critical",
+			"The archive command on {{ $labels.pod }} has been failing for more than "+
+				prometheusDuration(archiveFailure)),
+
+		rule("PGBackRestLastCompletedFull",
+			fmt.Sprintf(`ccp_backrest_last_full_backup_time_since_completion_seconds{%s} > %d`,
+				selector, int64(fullBackupAge/time.Second)),
+			"warning",
+			"The most recent full backup of {{ $labels.pg_cluster }} is older than "+
+				prometheusDuration(fullBackupAge)),
+
+		rule("PGDiskUsage",
+			fmt.Sprintf(`100 * (1 - ccp_nodemx_data_disk_available_bytes{%[1]s}`+
+				` / ccp_nodemx_data_disk_total_bytes{%[1]s}) > %[2]d`,
+				selector, diskUsage),
+			"warning",
+			fmt.Sprintf("Volume %s on {{ $labels.pod }} is more than %d%% full",
+				"{{ $labels.mount_point }}", diskUsage)),
+	}
+
+	return prometheusObject(cluster, prometheusRuleGVK,
+		naming.ClusterPrometheus(cluster), map[string]any{
+			"groups": []any{
+				map[string]any{
+					"name":  "postgres-operator",
+					"rules": rules,
+				},
+			},
+		})
+}
+
+// +kubebuilder:rbac:groups="monitoring.coreos.com",resources="podmonitors",verbs={get}
+// +kubebuilder:rbac:groups="monitoring.coreos.com",resources="podmonitors",verbs={create,patch,delete}
+// +kubebuilder:rbac:groups="monitoring.coreos.com",resources="prometheusrules",verbs={get}
+// +kubebuilder:rbac:groups="monitoring.coreos.com",resources="prometheusrules",verbs={create,patch,delete}
+
+// reconcilePrometheus writes the PodMonitors and PrometheusRule of cluster or
+// deletes them when they are not wanted. It reports in the
+// "PrometheusMonitorsReady" condition when the Prometheus Operator CRDs are
+// not installed.
+func (r *Reconciler) reconcilePrometheus(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) error {
+	type object struct {
+		kind   schema.GroupVersionKind
+		meta   metav1.ObjectMeta
+		wanted bool
+		intent func(*v1beta1.PostgresCluster) *unstructured.Unstructured
+	}
+
+	enabled := prometheusEnabled(cluster)
+	objects := []object{{
+		kind: prometheusPodMonitorGVK, meta: naming.ClusterPrometheus(cluster),
+		wanted: enabled, intent: prometheusPodMonitor,
+	}, {
+		kind: prometheusPodMonitorGVK, meta: naming.ClusterPGBouncerPrometheus(cluster),
+		wanted: enabled && pgmonitor.PGBouncerMetricsEnabled(cluster),
+		intent: prometheusPGBouncerPodMonitor,
+	}, {
+		kind: prometheusRuleGVK, meta: naming.ClusterPrometheus(cluster),
+		wanted: enabled, intent: prometheusRule,
+	}}
+
+	var err error
+	var missing []string
+
+	for _, o := range objects {
+		var err1 error
+
+		if !o.wanted {
+			existing := &unstructured.Unstructured{}
+			existing.SetGroupVersionKind(o.kind)
+			existing.SetNamespace(o.meta.Namespace)
+			existing.SetName(o.meta.Name)
+
+			err1 = errors.WithStack(r.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing))
+			if err1 == nil {
+				err1 = errors.WithStack(r.deleteControlled(ctx, cluster, existing))
+			}
+			err1 = client.IgnoreNotFound(err1)
+
+		} else {
+			intent := o.intent(cluster)
+
+			err1 = errors.WithStack(r.setControllerReference(cluster, intent))
+			if err1 == nil {
+				err1 = errors.WithStack(r.applyUnstructured(ctx, intent))
+			}
+		}
+
+		if meta.IsNoMatchError(errors.Cause(err1)) {
+			logging.FromContext(ctx).V(1).Info("Prometheus Operator is not installed",
+				"kind", o.kind.Kind)
+			if o.wanted {
+				missing = append(missing, o.kind.Kind)
+			}
+			err1 = nil
+		}
+		if err == nil {
+			err = err1
+		}
+	}
+
+	switch {
+	case err != nil:
+	case !enabled:
+		meta.RemoveStatusCondition(&cluster.Status.Conditions, v1beta1.PrometheusMonitorsReady)
+	case len(missing) > 0:
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "PrometheusOperatorNotInstalled",
+			"spec.monitoring.prometheus is set but the Prometheus Operator API is not available: %s",
+			strings.Join(sets.NewString(missing...).List(), ", "))
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			Type:               v1beta1.PrometheusMonitorsReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: cluster.GetGeneration(),
+			Reason:             "PrometheusOperatorNotInstalled",
+			Message: "The Prometheus Operator API is not available: " +
+				strings.Join(sets.NewString(missing...).List(), ", "),
+		})
+	default:
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			Type:               v1beta1.PrometheusMonitorsReady,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: cluster.GetGeneration(),
+			Reason:             "Applied",
+			Message:            "The PodMonitors and PrometheusRule are up to date.",
+		})
+	}
+
+	return err
+}
@@ -0,0 +1,311 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/controller/runtime"
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/testing/cmp"
+	"github.com/crunchydata/postgres-operator/internal/testing/events"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestPrometheusLabelName(t *testing.T) {
+	assert.Equal(t, prometheusLabelName("postgres-operator.crunchydata.com/cluster"),
+		"postgres_operator_crunchydata_com_cluster")
+}
+
+func TestPrometheusPodMonitor(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.Spec.Monitoring = &v1beta1.MonitoringSpec{
+		PGMonitor: &v1beta1.PGMonitorSpec{Exporter: &v1beta1.ExporterSpec{}},
+		Prometheus: &v1beta1.PrometheusSpec{
+			Metadata: &v1beta1.Metadata{Labels: map[string]string{"release": "prom"}},
+		},
+	}
+
+	assert.Assert(t, marshalMatches(prometheusPodMonitor(cluster), `
+apiVersion: monitoring.coreos.com/v1
+kind: PodMonitor
+metadata:
+  labels:
+    postgres-operator.crunchydata.com/cluster: hippo
+    postgres-operator.crunchydata.com/role: monitoring
+    release: prom
+  name: hippo-pgmonitor
+  namespace: ns1
+spec:
+  podMetricsEndpoints:
+  - interval: 30s
+    metricRelabelings:
+    - action: drop
+      regex: ccp_pgbouncer_.*
+      sourceLabels:
+      - __name__
+    port: exporter
+    relabelings:
+    - separator: ':'
+      sourceLabels:
+      - __meta_kubernetes_namespace
+      - __meta_kubernetes_pod_label_postgres_operator_crunchydata_com_cluster
+      targetLabel: pg_cluster
+    - sourceLabels:
+      - __meta_kubernetes_pod_label_postgres_operator_crunchydata_com_role
+      targetLabel: role
+  selector:
+    matchLabels:
+      postgres-operator.crunchydata.com/cluster: hippo
+      postgres-operator.crunchydata.com/crunchy-postgres-exporter: "true"
+	`))
+
+	t.Run("CustomInterval", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Monitoring.Prometheus.ScrapeInterval = &metav1.Duration{Duration: 2 * time.Minute}
+
+		endpoints, _, _ := unstructured.NestedSlice(
+			prometheusPodMonitor(cluster).Object, "spec", "podMetricsEndpoints")
+		assert.Equal(t, endpoints[0].(map[string]any)["interval"], "120s")
+	})
+
+	t.Run("CustomTLS", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Monitoring.PGMonitor.Exporter.CustomTLSSecret = &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "exporter-tls"},
+		}
+
+		endpoints, _, _ := unstructured.NestedSlice(
+			prometheusPodMonitor(cluster).Object, "spec", "podMetricsEndpoints")
+		assert.Equal(t, endpoints[0].(map[string]any)["scheme"], "https")
+	})
+}
+
+func TestPrometheusPGBouncerPodMonitor(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.Spec.Monitoring = &v1beta1.MonitoringSpec{
+		PGMonitor:  &v1beta1.PGMonitorSpec{Exporter: &v1beta1.ExporterSpec{PGBouncer: true}},
+		Prometheus: &v1beta1.PrometheusSpec{},
+	}
+
+	assert.Assert(t, marshalMatches(prometheusPGBouncerPodMonitor(cluster), `
+apiVersion: monitoring.coreos.com/v1
+kind: PodMonitor
+metadata:
+  labels:
+    postgres-operator.crunchydata.com/cluster: hippo
+    postgres-operator.crunchydata.com/role: monitoring
+  name: hippo-pgbouncer-pgmonitor
+  namespace: ns1
+spec:
+  podMetricsEndpoints:
+  - interval: 30s
+    metricRelabelings:
+    - action: keep
+      regex: ccp_pgbouncer_.*
+      sourceLabels:
+      - __name__
+    port: exporter
+    relabelings:
+    - separator: ':'
+      sourceLabels:
+      - __meta_kubernetes_namespace
+      - __meta_kubernetes_pod_label_postgres_operator_crunchydata_com_cluster
+      targetLabel: pg_cluster
+    - sourceLabels:
+      - __meta_kubernetes_pod_label_postgres_operator_crunchydata_com_role
+      targetLabel: role
+  selector:
+    matchLabels:
+      postgres-operator.crunchydata.com/cluster: hippo
+      postgres-operator.crunchydata.com/crunchy-postgres-exporter: "true"
+      postgres-operator.crunchydata.com/role: master
+	`))
+}
+
+func TestPrometheusRule(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.Spec.Monitoring = &v1beta1.MonitoringSpec{
+		PGMonitor:  &v1beta1.PGMonitorSpec{Exporter: &v1beta1.ExporterSpec{}},
+		Prometheus: &v1beta1.PrometheusSpec{},
+	}
+
+	expressions := func(rule *unstructured.Unstructured) map[string]string {
+		groups, _, _ := unstructured.NestedSlice(rule.Object, "spec", "groups")
+		result := map[string]string{}
+		for _, rule := range groups[0].(map[string]any)["rules"].([]any) {
+			rule := rule.(map[string]any)
+			result[rule["alert"].(string)] = rule["expr"].(string)
+		}
+		return result
+	}
+
+	rule := prometheusRule(cluster)
+	assert.Equal(t, rule.GetKind(), "PrometheusRule")
+	assert.Equal(t, rule.GetName(), "hippo-pgmonitor")
+	assert.DeepEqual(t, expressions(rule), map[string]string{
+		"PGArchiveCommandStatus":      `ccp_archive_command_status_seconds_since_last_fail{pg_cluster="ns1:hippo"} > 300`,
+		"PGBackRestLastCompletedFull": `ccp_backrest_last_full_backup_time_since_completion_seconds{pg_cluster="ns1:hippo"} > 604800`,
+		"PGDiskUsage":                 `100 * (1 - ccp_nodemx_data_disk_available_bytes{pg_cluster="ns1:hippo"} / ccp_nodemx_data_disk_total_bytes{pg_cluster="ns1:hippo"}) > 80`,
+		"PGReplicationByteLag":        `ccp_replication_lag_size_bytes{pg_cluster="ns1:hippo"} > 52428800`,
+	})
+
+	t.Run("Thresholds", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		lag := resource.MustParse("1Gi")
+		cluster.Spec.Monitoring.Prometheus.Alerts = &v1beta1.PrometheusAlertsSpec{
+			ArchiveFailure:   &metav1.Duration{Duration: 15 * time.Minute},
+			DiskUsagePercent: initialize.Int32(95),
+			FullBackupAge:    &metav1.Duration{Duration: 24 * time.Hour},
+			ReplicationLag:   &lag,
+		}
+
+		assert.DeepEqual(t, expressions(prometheusRule(cluster)), map[string]string{
+			"PGArchiveCommandStatus":      `ccp_archive_command_status_seconds_since_last_fail{pg_cluster="ns1:hippo"} > 900`,
+			"PGBackRestLastCompletedFull": `ccp_backrest_last_full_backup_time_since_completion_seconds{pg_cluster="ns1:hippo"} > 86400`,
+			"PGDiskUsage":                 `100 * (1 - ccp_nodemx_data_disk_available_bytes{pg_cluster="ns1:hippo"} / ccp_nodemx_data_disk_total_bytes{pg_cluster="ns1:hippo"}) > 95`,
+			"PGReplicationByteLag":        `ccp_replication_lag_size_bytes{pg_cluster="ns1:hippo"} > 1073741824`,
+		})
+	})
+}
+
+// clientWithoutPrometheus behaves like a Kubernetes API that does not have
+// the Prometheus Operator CRDs.
+type clientWithoutPrometheus struct{ client.Client }
+
+func (c clientWithoutPrometheus) noMatch(object client.Object) error {
+	if kind := object.GetObjectKind().GroupVersionKind(); kind.Group == prometheusPodMonitorGVK.Group {
+		return &meta.NoKindMatchError{GroupKind: kind.GroupKind(), SearchedVersions: []string{kind.Version}}
+	}
+	return nil
+}
+
+func (c clientWithoutPrometheus) Get(
+	ctx context.Context, key client.ObjectKey, object client.Object,
+) error {
+	if err := c.noMatch(object); err != nil {
+		return err
+	}
+	return c.Client.Get(ctx, key, object)
+}
+
+func (c clientWithoutPrometheus) Patch(
+	ctx context.Context, object client.Object, patch client.Patch, options ...client.PatchOption,
+) error {
+	if err := c.noMatch(object); err != nil {
+		return err
+	}
+	return c.Client.Patch(ctx, object, patch, options...)
+}
+
+func TestReconcilePrometheus(t *testing.T) {
+	ctx := context.Background()
+
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Namespace = "ns1"
+	cluster.Name = "hippo"
+	cluster.UID = "some-uid"
+	cluster.Spec.Monitoring = &v1beta1.MonitoringSpec{
+		PGMonitor:  &v1beta1.PGMonitorSpec{Exporter: &v1beta1.ExporterSpec{}},
+		Prometheus: &v1beta1.PrometheusSpec{},
+	}
+
+	t.Run("NotInstalled", func(t *testing.T) {
+		reconciler := &Reconciler{
+			Client: clientWithoutPrometheus{
+				fake.NewClientBuilder().WithScheme(runtime.Scheme).Build(),
+			},
+			Owner:    client.FieldOwner(t.Name()),
+			Recorder: events.NewRecorder(t, runtime.Scheme),
+		}
+		recorder := reconciler.Recorder.(*events.Recorder)
+
+		cluster := cluster.DeepCopy()
+		assert.NilError(t, reconciler.reconcilePrometheus(ctx, cluster))
+
+		condition := meta.FindStatusCondition(cluster.Status.Conditions, v1beta1.PrometheusMonitorsReady)
+		assert.Assert(t, condition != nil)
+		assert.Equal(t, condition.Status, metav1.ConditionFalse)
+		assert.Equal(t, condition.Reason, "PrometheusOperatorNotInstalled")
+		assert.Assert(t, cmp.Contains(condition.Message, "PodMonitor, PrometheusRule"))
+
+		assert.Equal(t, len(recorder.Events), 1)
+		assert.Equal(t, recorder.Events[0].Type, corev1.EventTypeWarning)
+		assert.Equal(t, recorder.Events[0].Reason, "PrometheusOperatorNotInstalled")
+
+		cluster.Spec.Monitoring.Prometheus = nil
+		assert.NilError(t, reconciler.reconcilePrometheus(ctx, cluster))
+		assert.Assert(t, meta.FindStatusCondition(cluster.Status.Conditions,
+			v1beta1.PrometheusMonitorsReady) == nil)
+		assert.Equal(t, len(recorder.Events), 1)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		scheme := k8sruntime.NewScheme()
+		assert.NilError(t, clientgoscheme.AddToScheme(scheme))
+		assert.NilError(t, v1beta1.AddToScheme(scheme))
+		scheme.AddKnownTypeWithName(prometheusPodMonitorGVK, &unstructured.Unstructured{})
+		scheme.AddKnownTypeWithName(prometheusRuleGVK, &unstructured.Unstructured{})
+
+		reconciler := &Reconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		}
+
+		existing := prometheusPodMonitor(cluster)
+		assert.NilError(t, reconciler.setControllerReference(cluster, existing))
+		assert.NilError(t, reconciler.Client.Create(ctx, existing))
+
+		pgbouncer := prometheusPGBouncerPodMonitor(cluster)
+		assert.NilError(t, reconciler.setControllerReference(cluster, pgbouncer))
+		assert.NilError(t, reconciler.Client.Create(ctx, pgbouncer))
+
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Monitoring.Prometheus = nil
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			Type: v1beta1.PrometheusMonitorsReady, Status: metav1.ConditionTrue, Reason: "Applied",
+		})
+		assert.NilError(t, reconciler.reconcilePrometheus(ctx, cluster))
+
+		err := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(existing), existing)
+		assert.Assert(t, apierrors.IsNotFound(err), "expected deleted, got %v", err)
+
+		err = reconciler.Client.Get(ctx, client.ObjectKeyFromObject(pgbouncer), pgbouncer)
+		assert.Assert(t, apierrors.IsNotFound(err), "expected deleted, got %v", err)
+
+		assert.Assert(t, meta.FindStatusCondition(cluster.Status.Conditions,
+			v1beta1.PrometheusMonitorsReady) == nil)
+	})
+}
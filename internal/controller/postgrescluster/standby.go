@@ -0,0 +1,249 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/patroni"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// parseLSN returns the numeric value of a WAL location, such as "0/3000060".
+// - https://www.postgresql.org/docs/current/datatype-pg-lsn.html
+func parseLSN(lsn string) (uint64, bool) {
+	hi, lo, ok := strings.Cut(lsn, "/")
+	if !ok {
+		return 0, false
+	}
+	h, err1 := strconv.ParseUint(hi, 16, 32)
+	l, err2 := strconv.ParseUint(lo, 16, 32)
+	return h<<32 | l, err1 == nil && err2 == nil
+}
+
+// standbyReplay returns the last WAL location replayed by a standby and, when
+// it is streaming from a host, how many bytes it has yet to replay.
+// - https://www.postgresql.org/docs/current/monitoring-stats.html#MONITORING-PG-STAT-WAL-RECEIVER-VIEW
+func standbyReplay(ctx context.Context, exec postgres.Executor) (string, *int64, error) {
+	stdout, stderr, err := exec.Exec(ctx, strings.NewReader(`
+SELECT pg_catalog.pg_last_wal_replay_lsn() AS replay,
+       COALESCE((SELECT GREATEST(0, pg_catalog.pg_wal_lsn_diff(
+           latest_end_lsn, pg_catalog.pg_last_wal_replay_lsn()))::bigint
+         FROM pg_catalog.pg_stat_wal_receiver)::text, '') AS lag \gset
+\echo :replay :lag
+`),
+		map[string]string{
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+	if err != nil {
+		return "", nil, errors.WithMessage(err, stderr)
+	}
+
+	var lag *int64
+	fields := strings.Fields(stdout)
+	if len(fields) == 0 {
+		return "", nil, errors.Errorf("unexpected output: %q", stdout)
+	}
+	if len(fields) > 1 {
+		bytes, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return "", nil, errors.WithStack(err)
+		}
+		lag = &bytes
+	}
+	return fields[0], lag, nil
+}
+
+// shutdownCheckpoint returns the location of the last checkpoint in the
+// control file of a standby when that checkpoint was written at shutdown. It
+// is empty otherwise. Right after a primary is demoted, this is the last WAL
+// it wrote as a primary.
+// - https://www.postgresql.org/docs/current/functions-info.html#FUNCTIONS-PG-CONTROL-CHECKPOINT
+func shutdownCheckpoint(ctx context.Context, exec postgres.Executor) (string, error) {
+	stdout, stderr, err := exec.Exec(ctx, strings.NewReader(`
+SELECT CASE WHEN checkpoint_lsn = redo_lsn THEN checkpoint_lsn::text ELSE '' END AS lsn
+  FROM pg_catalog.pg_control_checkpoint() \gset
+\echo :lsn
+`),
+		map[string]string{
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+	if err != nil {
+		return "", errors.WithMessage(err, stderr)
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
+// +kubebuilder:rbac:groups="postgres-operator.crunchydata.com",resources="postgresclusters",verbs={get}
+
+// promotionLSN returns the WAL location that cluster must replay before it is
+// promoted. It is empty when that location is not yet known.
+func (r *Reconciler) promotionLSN(
+	ctx context.Context, cluster *v1beta1.PostgresCluster,
+) (string, error) {
+	after := cluster.Spec.Standby.PromoteAfter
+	if after.LSN != "" {
+		return after.LSN, nil
+	}
+
+	source := &v1beta1.PostgresCluster{}
+	err := errors.WithStack(client.IgnoreNotFound(r.Client.Get(ctx,
+		client.ObjectKey{Namespace: cluster.Namespace, Name: after.ClusterName}, source)))
+
+	if err == nil && source.Status.Standby != nil {
+		return source.Status.Standby.DemotedLSN, nil
+	}
+	return "", err
+}
+
+// reconcileStandby reports the WAL replay of a standby cluster in its status
+// and refreshes it periodically. When a primary becomes a standby, it records
+// the shutdown checkpoint where it stopped writing. When a standby with
+// spec.standby.promoteAfter is told to stop being a standby, it continues to
+// follow its source until it replays WAL up to that point. The
+// "StandbyPromotionHeld" condition reports the wait. Setting
+// spec.standby.promoteAfter.force ends it when the named cluster never
+// reports where it stopped.
+//
+// A switchover between data centers is then: enable standby on the primary,
+// disable standby on the other cluster, and let each side catch up.
+func (r *Reconciler) reconcileStandby(
+	ctx context.Context, cluster *v1beta1.PostgresCluster, instances *observedInstances,
+) (reconcile.Result, error) {
+	log := logging.FromContext(ctx)
+	spec := cluster.Spec.Standby
+	status := cluster.Status.Standby
+
+	var leader *corev1.Pod
+	for _, instance := range instances.forCluster {
+		if len(instance.Pods) == 1 &&
+			(patroni.PodIsPrimary(instance.Pods[0]) || patroni.PodIsStandbyLeader(instance.Pods[0])) {
+			leader = instance.Pods[0]
+		}
+	}
+
+	// Refresh the replay of a standby periodically; nothing else changes
+	// when WAL arrives.
+	result := reconcile.Result{}
+
+	switch {
+	case leader != nil && patroni.PodIsPrimary(leader):
+		// A primary with standby enabled is about to be demoted by Patroni.
+		if spec != nil && spec.Enabled {
+			status = &v1beta1.PostgresStandbyStatus{Demoted: true}
+		} else {
+			status = nil
+		}
+
+	case leader != nil && patroni.PodIsStandbyLeader(leader):
+		if status == nil {
+			status = &v1beta1.PostgresStandbyStatus{}
+		}
+		result.RequeueAfter = 30 * time.Second
+
+		exec := func(_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+			return r.PodExec(leader.Namespace, leader.Name, naming.ContainerDatabase, stdin, stdout, stderr, command...)
+		}
+
+		// Nothing has been replayed since the demotion, so the control file
+		// still has the checkpoint written when the primary shut down.
+		var err error
+		if status.Demoted && status.DemotedLSN == "" && status.ReplayLSN == "" {
+			status.DemotedLSN, err = shutdownCheckpoint(ctx, exec)
+		}
+
+		// Keep the previous values when the standby cannot be reached.
+		if err == nil {
+			var replay string
+			var lag *int64
+			if replay, lag, err = standbyReplay(ctx, exec); err == nil {
+				status.ReplayLSN, status.LagBytes = replay, lag
+			}
+		}
+		if err != nil {
+			log.Error(err, "unable to check standby replay", "pod", leader.Name)
+		}
+	}
+	cluster.Status.Standby = status
+
+	if status == nil || spec == nil || spec.Enabled || spec.PromoteAfter == nil {
+		meta.RemoveStatusCondition(&cluster.Status.Conditions, v1beta1.StandbyPromotionHeld)
+		return result, nil
+	}
+
+	// This standby is being promoted. Keep following its source until it has
+	// replayed everything its source wrote. This changes only the in-memory
+	// copy of the spec; the stored spec still says to promote.
+	target, err := r.promotionLSN(ctx, cluster)
+	want, wantOK := parseLSN(target)
+	have, haveOK := parseLSN(status.ReplayLSN)
+
+	switch {
+	case err != nil:
+		return result, err
+
+	case target == "" && spec.PromoteAfter.Force:
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "StandbyPromotionForced",
+			"Promoting without knowing where PostgresCluster %q stopped writing; replayed %s",
+			spec.PromoteAfter.ClusterName, status.ReplayLSN)
+
+	case target == "":
+		spec.Enabled = true
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			Type:               v1beta1.StandbyPromotionHeld,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: cluster.GetGeneration(),
+			Reason:             "DemotedLSNUnknown",
+			Message: fmt.Sprintf("PostgresCluster %q has not reported where it stopped writing. "+
+				"Set spec.standby.promoteAfter.force to promote without it.", spec.PromoteAfter.ClusterName),
+		})
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+
+	case !wantOK || !haveOK || have < want:
+		spec.Enabled = true
+		meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+			Type:               v1beta1.StandbyPromotionHeld,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: cluster.GetGeneration(),
+			Reason:             "WaitingForReplay",
+			Message: fmt.Sprintf("Waiting to replay WAL up to %s before promotion; replayed %s",
+				target, status.ReplayLSN),
+		})
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "StandbyPromotionWaiting",
+			"Waiting to replay WAL up to %s before promotion; replayed %s", target, status.ReplayLSN)
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	meta.RemoveStatusCondition(&cluster.Status.Conditions, v1beta1.StandbyPromotionHeld)
+	return result, nil
+}
@@ -0,0 +1,304 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgrescluster
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crunchydata/postgres-operator/internal/controller/runtime"
+	"github.com/crunchydata/postgres-operator/internal/testing/cmp"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestParseLSN(t *testing.T) {
+	for _, tt := range []struct {
+		lsn   string
+		value uint64
+		ok    bool
+	}{
+		{lsn: "0/0", value: 0, ok: true},
+		{lsn: "0/3000060", value: 0x3000060, ok: true},
+		{lsn: "16/B374D848", value: 0x16_B374D848, ok: true},
+		{lsn: "", ok: false},
+		{lsn: "3000060", ok: false},
+		{lsn: "0/xyz", ok: false},
+	} {
+		value, ok := parseLSN(tt.lsn)
+		assert.Equal(t, ok, tt.ok, "%q", tt.lsn)
+		if tt.ok {
+			assert.Equal(t, value, tt.value, "%q", tt.lsn)
+		}
+	}
+}
+
+func TestStandbyReplay(t *testing.T) {
+	ctx := context.Background()
+
+	var output string
+	exec := func(
+		_ context.Context, stdin io.Reader, stdout, _ io.Writer, command ...string,
+	) error {
+		b, _ := io.ReadAll(stdin)
+		assert.Assert(t, cmp.Contains(string(b), "pg_last_wal_replay_lsn()"))
+		assert.Assert(t, cmp.Contains(string(b), "pg_stat_wal_receiver"))
+		assert.Assert(t, cmp.Contains(command, "--set=ON_ERROR_STOP=on"))
+
+		_, err := io.Copy(stdout, strings.NewReader(output))
+		return err
+	}
+
+	output = "0/3000060 1024\n"
+	replay, lag, err := standbyReplay(ctx, exec)
+	assert.NilError(t, err)
+	assert.Equal(t, replay, "0/3000060")
+	assert.Assert(t, lag != nil)
+	assert.Equal(t, *lag, int64(1024))
+
+	// Nothing streaming, such as when following a pgBackRest repository.
+	output = "0/3000060 \n"
+	replay, lag, err = standbyReplay(ctx, exec)
+	assert.NilError(t, err)
+	assert.Equal(t, replay, "0/3000060")
+	assert.Assert(t, lag == nil)
+
+	output = ""
+	_, _, err = standbyReplay(ctx, exec)
+	assert.ErrorContains(t, err, "unexpected")
+}
+
+func TestShutdownCheckpoint(t *testing.T) {
+	ctx := context.Background()
+
+	exec := func(
+		_ context.Context, stdin io.Reader, stdout, _ io.Writer, command ...string,
+	) error {
+		b, _ := io.ReadAll(stdin)
+		assert.Assert(t, cmp.Contains(string(b), "pg_control_checkpoint()"))
+		assert.Assert(t, cmp.Contains(string(b), "checkpoint_lsn = redo_lsn"))
+		assert.Assert(t, cmp.Contains(command, "--set=ON_ERROR_STOP=on"))
+
+		_, err := io.Copy(stdout, strings.NewReader("0/5000028\n"))
+		return err
+	}
+
+	lsn, err := shutdownCheckpoint(ctx, exec)
+	assert.NilError(t, err)
+	assert.Equal(t, lsn, "0/5000028")
+}
+
+func TestReconcileStandby(t *testing.T) {
+	ctx := context.Background()
+
+	leader := func(role string) *observedInstances {
+		return &observedInstances{forCluster: []*Instance{{
+			Name: "one",
+			Pods: []*corev1.Pod{{ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns1", Name: "one-0",
+				Annotations: map[string]string{"status": `{"role":"` + role + `"}`},
+			}}},
+		}}}
+	}
+
+	var checkpoint, replay string
+	var execErr error
+	reconciler := &Reconciler{Recorder: new(record.FakeRecorder)}
+	reconciler.PodExec = func(
+		namespace, pod, container string, stdin io.Reader, stdout, _ io.Writer, _ ...string,
+	) error {
+		assert.Equal(t, namespace, "ns1")
+		assert.Equal(t, pod, "one-0")
+		assert.Equal(t, container, "database")
+		if execErr != nil {
+			return execErr
+		}
+
+		b, _ := io.ReadAll(stdin)
+		if strings.Contains(string(b), "pg_control_checkpoint") {
+			_, err := stdout.Write([]byte(checkpoint + "\n"))
+			return err
+		}
+		_, err := stdout.Write([]byte(replay + " \n"))
+		return err
+	}
+
+	t.Run("Primary", func(t *testing.T) {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Status.Standby = &v1beta1.PostgresStandbyStatus{ReplayLSN: "0/1"}
+
+		result, err := reconciler.reconcileStandby(ctx, cluster, leader("master"))
+		assert.NilError(t, err)
+		assert.Assert(t, result.IsZero())
+		assert.Assert(t, cluster.Status.Standby == nil)
+	})
+
+	t.Run("Demotion", func(t *testing.T) {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Spec.Standby = &v1beta1.PostgresStandbySpec{Enabled: true, Host: "other"}
+
+		// Patroni has yet to demote the primary.
+		_, err := reconciler.reconcileStandby(ctx, cluster, leader("master"))
+		assert.NilError(t, err)
+		assert.DeepEqual(t, cluster.Status.Standby,
+			&v1beta1.PostgresStandbyStatus{Demoted: true})
+
+		// The shutdown checkpoint is where it stopped writing. The standby
+		// is checked again later.
+		checkpoint, replay = "0/5000028", "0/50000A0"
+		result, err := reconciler.reconcileStandby(ctx, cluster, leader("standby_leader"))
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, 30*time.Second)
+		assert.Equal(t, cluster.Status.Standby.DemotedLSN, "0/5000028")
+		assert.Equal(t, cluster.Status.Standby.ReplayLSN, "0/50000A0")
+
+		checkpoint, replay = "0/6000000", "0/6000000"
+		_, err = reconciler.reconcileStandby(ctx, cluster, leader("standby_leader"))
+		assert.NilError(t, err)
+		assert.Equal(t, cluster.Status.Standby.DemotedLSN, "0/5000028")
+		assert.Equal(t, cluster.Status.Standby.ReplayLSN, "0/6000000")
+	})
+
+	t.Run("DemotionAfterRestartpoint", func(t *testing.T) {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Spec.Standby = &v1beta1.PostgresStandbySpec{Enabled: true, Host: "other"}
+		cluster.Status.Standby = &v1beta1.PostgresStandbyStatus{Demoted: true}
+
+		// The control file no longer has the shutdown checkpoint.
+		checkpoint, replay = "", "0/7000000"
+		_, err := reconciler.reconcileStandby(ctx, cluster, leader("standby_leader"))
+		assert.NilError(t, err)
+		assert.Equal(t, cluster.Status.Standby.DemotedLSN, "")
+		assert.Equal(t, cluster.Status.Standby.ReplayLSN, "0/7000000")
+	})
+
+	t.Run("ExecError", func(t *testing.T) {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Spec.Standby = &v1beta1.PostgresStandbySpec{Enabled: true, Host: "other"}
+		cluster.Status.Standby = &v1beta1.PostgresStandbyStatus{ReplayLSN: "0/1"}
+
+		execErr = errors.New("boom")
+		defer func() { execErr = nil }()
+
+		result, err := reconciler.reconcileStandby(ctx, cluster, leader("standby_leader"))
+		assert.NilError(t, err, "expected reconcile to continue")
+		assert.Equal(t, result.RequeueAfter, 30*time.Second)
+		assert.Equal(t, cluster.Status.Standby.ReplayLSN, "0/1")
+	})
+
+	t.Run("NewStandby", func(t *testing.T) {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Spec.Standby = &v1beta1.PostgresStandbySpec{Enabled: true, RepoName: "repo1"}
+
+		replay = "0/5000028"
+		_, err := reconciler.reconcileStandby(ctx, cluster, leader("standby_leader"))
+		assert.NilError(t, err)
+		assert.DeepEqual(t, cluster.Status.Standby,
+			&v1beta1.PostgresStandbyStatus{ReplayLSN: "0/5000028"})
+	})
+
+	t.Run("PromoteAfterLSN", func(t *testing.T) {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Spec.Standby = &v1beta1.PostgresStandbySpec{
+			Enabled: false, Host: "other",
+			PromoteAfter: &v1beta1.PostgresStandbyPromotionSpec{LSN: "0/5000028"},
+		}
+		cluster.Status.Standby = new(v1beta1.PostgresStandbyStatus)
+
+		// Behind; keep following the source.
+		replay = "0/5000000"
+		result, err := reconciler.reconcileStandby(ctx, cluster, leader("standby_leader"))
+		assert.NilError(t, err)
+		assert.Equal(t, result.RequeueAfter, 10*time.Second)
+		assert.Assert(t, cluster.Spec.Standby.Enabled)
+
+		held := meta.FindStatusCondition(cluster.Status.Conditions, v1beta1.StandbyPromotionHeld)
+		assert.Assert(t, held != nil)
+		assert.Equal(t, held.Reason, "WaitingForReplay")
+
+		// Caught up; promote.
+		cluster.Spec.Standby.Enabled = false
+		replay = "0/5000028"
+		_, err = reconciler.reconcileStandby(ctx, cluster, leader("standby_leader"))
+		assert.NilError(t, err)
+		assert.Assert(t, !cluster.Spec.Standby.Enabled)
+		assert.Assert(t, meta.FindStatusCondition(cluster.Status.Conditions, v1beta1.StandbyPromotionHeld) == nil)
+
+		// Promoted.
+		result, err = reconciler.reconcileStandby(ctx, cluster, leader("master"))
+		assert.NilError(t, err)
+		assert.Assert(t, result.IsZero())
+		assert.Assert(t, cluster.Status.Standby == nil)
+	})
+
+	t.Run("PromoteAfterCluster", func(t *testing.T) {
+		source := new(v1beta1.PostgresCluster)
+		source.Namespace, source.Name = "ns1", "east"
+
+		reconciler := *reconciler
+		reconciler.Client = fake.NewClientBuilder().WithScheme(runtime.Scheme).
+			WithObjects(source).Build()
+
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Namespace, cluster.Name = "ns1", "west"
+		cluster.Spec.Standby = &v1beta1.PostgresStandbySpec{
+			Enabled: false, RepoName: "repo1",
+			PromoteAfter: &v1beta1.PostgresStandbyPromotionSpec{ClusterName: "east"},
+		}
+		cluster.Status.Standby = new(v1beta1.PostgresStandbyStatus)
+		replay = "0/5000028"
+
+		// The source has not been demoted.
+		_, err := reconciler.reconcileStandby(ctx, cluster, leader("standby_leader"))
+		assert.NilError(t, err)
+		assert.Assert(t, cluster.Spec.Standby.Enabled)
+
+		held := meta.FindStatusCondition(cluster.Status.Conditions, v1beta1.StandbyPromotionHeld)
+		assert.Assert(t, held != nil)
+		assert.Equal(t, held.Reason, "DemotedLSNUnknown")
+
+		// Force promotes without the location.
+		forced := cluster.DeepCopy()
+		forced.Spec.Standby.Enabled = false
+		forced.Spec.Standby.PromoteAfter.Force = true
+		_, err = reconciler.reconcileStandby(ctx, forced, leader("standby_leader"))
+		assert.NilError(t, err)
+		assert.Assert(t, !forced.Spec.Standby.Enabled)
+		assert.Assert(t, meta.FindStatusCondition(forced.Status.Conditions, v1beta1.StandbyPromotionHeld) == nil)
+
+		// The source stopped writing at a location that has been replayed.
+		source.Status.Standby = &v1beta1.PostgresStandbyStatus{
+			Demoted: true, DemotedLSN: "0/5000028",
+		}
+		assert.NilError(t, reconciler.Client.Status().Update(ctx, source))
+
+		cluster.Spec.Standby.Enabled = false
+		_, err = reconciler.reconcileStandby(ctx, cluster, leader("standby_leader"))
+		assert.NilError(t, err)
+		assert.Assert(t, !cluster.Spec.Standby.Enabled)
+	})
+
+}
@@ -16,6 +16,8 @@
 package postgrescluster
 
 import (
+	"context"
+
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -24,6 +26,7 @@ import (
 
 	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/patroni"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 // watchPods returns a handler.EventHandler for Pods.
@@ -72,3 +75,91 @@ func (*Reconciler) watchPods() handler.Funcs {
 		},
 	}
 }
+
+// watchCertificateSecrets returns a handler.EventHandler for Secrets that hold
+// cluster certificates. Secrets issued by cert-manager are not owned by any
+// cluster, so they are matched by their labels instead. The root secret is
+// shared by every cluster in its namespace, so each owner is reconciled when
+// it changes.
+func (*Reconciler) watchCertificateSecrets() handler.Funcs {
+	enqueue := func(object client.Object, q workqueue.RateLimitingInterface) {
+		if object.GetName() == naming.RootCertSecret {
+			for _, ref := range object.GetOwnerReferences() {
+				if ref.Kind == "PostgresCluster" {
+					q.Add(reconcile.Request{NamespacedName: client.ObjectKey{
+						Namespace: object.GetNamespace(),
+						Name:      ref.Name,
+					}})
+				}
+			}
+			return
+		}
+
+		labels := object.GetLabels()
+		cluster := labels[naming.LabelCluster]
+
+		if len(cluster) != 0 && len(labels[naming.LabelClusterCertificate]) != 0 {
+			q.Add(reconcile.Request{NamespacedName: client.ObjectKey{
+				Namespace: object.GetNamespace(),
+				Name:      cluster,
+			}})
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.Object, q)
+		},
+		UpdateFunc: func(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.ObjectNew, q)
+		},
+	}
+}
+
+// +kubebuilder:rbac:groups="postgres-operator.crunchydata.com",resources="restoregrants",verbs={list,watch}
+
+// watchRestoreGrants returns a handler.EventHandler for RestoreGrants. It
+// queues the clusters in target namespaces that restore from the namespace of
+// a RestoreGrant so they can proceed once they are allowed.
+func (r *Reconciler) watchRestoreGrants() handler.EventHandler {
+	restoresFrom := func(cluster *v1beta1.PostgresCluster, namespace string) bool {
+		var sources []*v1beta1.PostgresClusterDataSource
+		if cluster.Spec.DataSource != nil {
+			sources = append(sources, cluster.Spec.DataSource.PostgresCluster)
+		}
+		if cluster.Spec.Backups.PGBackRest.Restore != nil {
+			sources = append(sources, cluster.Spec.Backups.PGBackRest.Restore.PostgresClusterDataSource)
+		}
+		for _, source := range sources {
+			if source != nil && source.ClusterNamespace == namespace {
+				return true
+			}
+		}
+		return false
+	}
+
+	return handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+		grant, ok := object.(*v1beta1.RestoreGrant)
+		if !ok {
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, namespace := range grant.Spec.TargetNamespaces {
+			clusters := &v1beta1.PostgresClusterList{}
+			if err := r.Client.List(context.Background(), clusters,
+				client.InNamespace(namespace)); err != nil {
+				continue
+			}
+
+			for i := range clusters.Items {
+				if restoresFrom(&clusters.Items[i], grant.Namespace) {
+					requests = append(requests, reconcile.Request{
+						NamespacedName: client.ObjectKeyFromObject(&clusters.Items[i]),
+					})
+				}
+			}
+		}
+		return requests
+	})
+}
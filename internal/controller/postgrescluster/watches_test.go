@@ -16,15 +16,20 @@
 package postgrescluster
 
 import (
+	"context"
 	"testing"
 
 	"gotest.tools/v3/assert"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crunchydata/postgres-operator/internal/controller/runtime"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 func TestWatchPodsUpdate(t *testing.T) {
@@ -141,3 +146,135 @@ func TestWatchPodsUpdate(t *testing.T) {
 		queue.Done(item)
 	})
 }
+
+func TestWatchCertificateSecrets(t *testing.T) {
+	queue := controllertest.Queue{Interface: workqueue.New()}
+	reconciler := &Reconciler{}
+
+	create := reconciler.watchCertificateSecrets().CreateFunc
+	update := reconciler.watchCertificateSecrets().UpdateFunc
+	assert.Assert(t, create != nil)
+	assert.Assert(t, update != nil)
+
+	// Cluster label, but nothing else; no reconcile.
+	update(event.UpdateEvent{
+		ObjectOld: &corev1.Secret{},
+		ObjectNew: &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					"postgres-operator.crunchydata.com/cluster": "starfish",
+				},
+			},
+		},
+	}, queue)
+	assert.Equal(t, queue.Len(), 0)
+
+	// Certificate issued; one reconcile by label.
+	create(event.CreateEvent{
+		Object: &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "some-ns",
+				Labels: map[string]string{
+					"postgres-operator.crunchydata.com/cluster":             "starfish",
+					"postgres-operator.crunchydata.com/cluster-certificate": "server",
+				},
+			},
+		},
+	}, queue)
+	assert.Equal(t, queue.Len(), 1)
+
+	item, _ := queue.Get()
+	expected := reconcile.Request{}
+	expected.Namespace = "some-ns"
+	expected.Name = "starfish"
+	assert.Equal(t, item, expected)
+	queue.Done(item)
+
+	// Root secret changed; one reconcile for each cluster that owns it.
+	update(event.UpdateEvent{
+		ObjectOld: &corev1.Secret{},
+		ObjectNew: &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "some-ns",
+				Name:      "pgo-root-cacert",
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "PostgresCluster", Name: "hippo"},
+					{Kind: "ConfigMap", Name: "unrelated"},
+					{Kind: "PostgresCluster", Name: "rhino"},
+				},
+			},
+		},
+	}, queue)
+	assert.Equal(t, queue.Len(), 2)
+
+	for _, name := range []string{"hippo", "rhino"} {
+		item, _ := queue.Get()
+		expected.Name = name
+		assert.Equal(t, item, expected)
+		queue.Done(item)
+	}
+}
+
+func TestWatchRestoreGrants(t *testing.T) {
+	queue := controllertest.Queue{Interface: workqueue.New()}
+
+	cloned := &v1beta1.PostgresCluster{}
+	cloned.Namespace, cloned.Name = "target", "cloned"
+	cloned.Spec.DataSource = &v1beta1.DataSource{
+		PostgresCluster: &v1beta1.PostgresClusterDataSource{
+			ClusterName: "hippo", ClusterNamespace: "source",
+		},
+	}
+
+	restored := &v1beta1.PostgresCluster{}
+	restored.Namespace, restored.Name = "target", "restored"
+	restored.Spec.Backups.PGBackRest.Restore = &v1beta1.PGBackRestRestore{
+		PostgresClusterDataSource: &v1beta1.PostgresClusterDataSource{
+			ClusterName: "hippo", ClusterNamespace: "source",
+		},
+	}
+
+	unrelated := &v1beta1.PostgresCluster{}
+	unrelated.Namespace, unrelated.Name = "target", "unrelated"
+
+	reconciler := &Reconciler{
+		Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).
+			WithObjects(cloned, restored, unrelated).Build(),
+	}
+
+	grant := &v1beta1.RestoreGrant{}
+	grant.Namespace, grant.Name = "source", "some-grant"
+	grant.Spec.TargetNamespaces = []string{"target", "elsewhere"}
+
+	reconciler.watchRestoreGrants().Create(event.CreateEvent{Object: grant}, queue)
+	assert.Equal(t, queue.Len(), 2)
+
+	for i := 0; i < 2; i++ {
+		item, _ := queue.Get()
+		request := item.(reconcile.Request)
+		assert.Equal(t, request.Namespace, "target")
+		assert.Assert(t, request.Name == "cloned" || request.Name == "restored")
+		queue.Done(item)
+	}
+
+	t.Run("Granted", func(t *testing.T) {
+		ctx := context.Background()
+
+		reconciler := &Reconciler{
+			Client: fake.NewClientBuilder().WithScheme(runtime.Scheme).
+				WithObjects(grant).Build(),
+		}
+
+		granted, err := reconciler.restoreGranted(ctx, "source", "hippo", "target")
+		assert.NilError(t, err)
+		assert.Assert(t, granted)
+
+		granted, err = reconciler.restoreGranted(ctx, "source", "hippo", "other")
+		assert.NilError(t, err)
+		assert.Assert(t, !granted)
+
+		granted, err = reconciler.restoreGranted(ctx, "target", "cloned", "source")
+		assert.NilError(t, err)
+		assert.Assert(t, !granted)
+	})
+}
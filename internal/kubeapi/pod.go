@@ -0,0 +1,34 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package kubeapi
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodIsReady returns whether or not pod has a true Ready condition. It is not
+// known until the kubelet reports that condition.
+// - https://docs.k8s.io/concepts/workloads/pods/pod-lifecycle/#pod-conditions
+func PodIsReady(pod *corev1.Pod) (ready bool, known bool) {
+	if pod != nil {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady {
+				return condition.Status == corev1.ConditionTrue, true
+			}
+		}
+	}
+	return false, false
+}
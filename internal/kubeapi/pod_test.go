@@ -0,0 +1,59 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package kubeapi
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodIsReady(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		pod          *corev1.Pod
+		ready, known bool
+	}{
+		{name: "Nil"},
+		{name: "NoConditions", pod: &corev1.Pod{}},
+		{
+			name: "OtherConditions",
+			pod: &corev1.Pod{Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+				{Type: corev1.PodScheduled, Status: corev1.ConditionTrue},
+			}}},
+		},
+		{
+			name: "NotReady", known: true,
+			pod: &corev1.Pod{Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionFalse},
+			}}},
+		},
+		{
+			name: "Ready", ready: true, known: true,
+			pod: &corev1.Pod{Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+				{Type: corev1.PodScheduled, Status: corev1.ConditionTrue},
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+			}}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, known := PodIsReady(tt.pod)
+			if ready != tt.ready || known != tt.known {
+				t.Fatalf("expected (%v, %v), got (%v, %v)", tt.ready, tt.known, ready, known)
+			}
+		})
+	}
+}
@@ -59,6 +59,29 @@ const (
 	// bind all addresses does not work in certain IPv6 environments.
 	PGBackRestIPVersion = annotationPrefix + "pgbackrest-ip-version"
 
+	// PrimaryServiceRedirect is the annotation that is added to a PostgresCluster to resolve its
+	// primary Service to the primary of another PostgresCluster in the same namespace. The value
+	// of the annotation is the name of that other PostgresCluster. PGUpgrade uses this to move
+	// connections to a cluster that was upgraded through logical replication.
+	PrimaryServiceRedirect = annotationPrefix + "redirect-primary-service"
+
+	// RotateRootCertificate is the annotation that is added to a PostgresCluster to replace the
+	// root certificate authority in its namespace. The value of the annotation is a unique
+	// identifier for the rotation (e.g. a timestamp), which is stored in the PostgresCluster
+	// status to track its progress.
+	RotateRootCertificate = annotationPrefix + "rotate-root-certificate"
+
+	// RootCertificateRotationID and RootCertificateRotationPhase are annotations on the root
+	// certificate Secret that record the rotation underway in its namespace. Every PostgresCluster
+	// that shares the root follows them.
+	RootCertificateRotationID    = annotationPrefix + "root-rotation-id"
+	RootCertificateRotationPhase = annotationPrefix + "root-rotation-phase"
+
+	// PGBouncerPausedUntil is the annotation that is added to a PgBouncer Pod while its connection
+	// pools are paused for a change of primary. The value is the time, in RFC 3339 format, after
+	// which the operator resumes them.
+	PGBouncerPausedUntil = annotationPrefix + "pgbouncer-paused-until"
+
 	// PostgresExporterCollectorsAnnotation is an annotation used to allow users to control whether or
 	// not postgres_exporter default metrics, settings, and collectors are enabled. The value "None"
 	// disables all postgres_exporter defaults. Disabling the defaults may cause errors in dashboards.
@@ -30,6 +30,11 @@ func TestAnnotationsValid(t *testing.T) {
 	assert.Assert(t, nil == validation.IsQualifiedName(PGBackRestCurrentConfig))
 	assert.Assert(t, nil == validation.IsQualifiedName(PGBackRestRestore))
 	assert.Assert(t, nil == validation.IsQualifiedName(PGBackRestIPVersion))
+	assert.Assert(t, nil == validation.IsQualifiedName(PrimaryServiceRedirect))
+	assert.Assert(t, nil == validation.IsQualifiedName(RotateRootCertificate))
+	assert.Assert(t, nil == validation.IsQualifiedName(RootCertificateRotationID))
+	assert.Assert(t, nil == validation.IsQualifiedName(RootCertificateRotationPhase))
+	assert.Assert(t, nil == validation.IsQualifiedName(PGBouncerPausedUntil))
 	assert.Assert(t, nil == validation.IsQualifiedName(PostgresExporterCollectorsAnnotation))
 	assert.Assert(t, nil == validation.IsQualifiedName(CrunchyBridgeClusterAdoptionAnnotation))
 }
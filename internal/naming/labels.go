@@ -51,6 +51,9 @@ const (
 	// LabelMovePGWalDir is used to identify the Job that moves an existing pg_wal directory.
 	LabelMovePGWalDir = labelPrefix + "move-pgwal-dir"
 
+	// LabelPGBouncerPooler is used to identify objects of an additional PgBouncer pooler.
+	LabelPGBouncerPooler = labelPrefix + "pgbouncer-pooler"
+
 	// LabelPGBackRest is used to indicate that a resource is for pgBackRest
 	LabelPGBackRest = labelPrefix + "pgbackrest"
 
@@ -105,6 +108,10 @@ const (
 	// RolePGBouncer is the LabelRole applied to PgBouncer objects.
 	RolePGBouncer = "pgbouncer"
 
+	// RolePGBouncerPooler is the LabelRole applied to objects of additional
+	// PgBouncer poolers.
+	RolePGBouncerPooler = "pgbouncer-pooler"
+
 	// RolePGAdmin is the LabelRole applied to pgAdmin objects.
 	RolePGAdmin = "pgadmin"
 
@@ -141,6 +141,28 @@ const (
 	ReplicationCACertPath = "replication/ca.crt"
 )
 
+const (
+	// CertManagerInstance is the purpose of the cert-manager Certificate that
+	// Patroni presents on each instance.
+	CertManagerInstance = "instance"
+
+	// CertManagerPGBackRest is the purpose of the cert-manager Certificate
+	// that pgBackRest presents as both client and server.
+	CertManagerPGBackRest = "pgbackrest"
+
+	// CertManagerPGBouncer is the purpose of the cert-manager Certificate
+	// that PgBouncer presents to its clients.
+	CertManagerPGBouncer = "pgbouncer"
+
+	// CertManagerReplication is the purpose of the cert-manager Certificate
+	// that the replication user presents to PostgreSQL.
+	CertManagerReplication = "replication"
+
+	// CertManagerServer is the purpose of the cert-manager Certificate that
+	// PostgreSQL presents to its clients.
+	CertManagerServer = "server"
+)
+
 const (
 	// PGBackRestRepoContainerName is the name assigned to the container used to run pgBackRest
 	PGBackRestRepoContainerName = "pgbackrest"
@@ -229,6 +251,28 @@ func ClusterPGBouncer(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
 	}
 }
 
+// ClusterPGBouncerPooler returns the ObjectMeta necessary to lookup the
+// ConfigMap, Deployment, PodDisruptionBudget or Service of the PgBouncer pooler
+// of cluster named pooler. An empty name is the pooler of [ClusterPGBouncer].
+func ClusterPGBouncerPooler(cluster *v1beta1.PostgresCluster, pooler string) metav1.ObjectMeta {
+	if pooler == "" {
+		return ClusterPGBouncer(cluster)
+	}
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      cluster.Name + "-pgbouncer-" + pooler,
+	}
+}
+
+// ClusterPGBouncerConsole returns the ObjectMeta necessary to lookup the
+// Secret of cluster's PgBouncer admin console users.
+func ClusterPGBouncerConsole(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      cluster.Name + "-pgbouncer-console",
+	}
+}
+
 // ClusterPodService returns the ObjectMeta necessary to lookup the Service
 // that is responsible for the network identity of Pods.
 func ClusterPodService(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
@@ -374,6 +418,24 @@ func ExporterQueriesConfigMap(cluster *v1beta1.PostgresCluster) metav1.ObjectMet
 	}
 }
 
+// ClusterPrometheus returns the ObjectMeta necessary to lookup and create the
+// Prometheus Operator PodMonitor and PrometheusRule of cluster.
+func ClusterPrometheus(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      cluster.Name + "-pgmonitor",
+	}
+}
+
+// ClusterPGBouncerPrometheus returns the ObjectMeta necessary to lookup and
+// create the Prometheus Operator PodMonitor of PgBouncer statistics of cluster.
+func ClusterPGBouncerPrometheus(cluster *v1beta1.PostgresCluster) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      cluster.Name + "-pgbouncer-pgmonitor",
+	}
+}
+
 // OperatorConfigurationSecret returns the ObjectMeta necessary to lookup the
 // Secret containing PGO configuration.
 func OperatorConfigurationSecret() metav1.ObjectMeta {
@@ -392,6 +454,31 @@ func ReplicationClientCertSecret(cluster *v1beta1.PostgresCluster) metav1.Object
 	}
 }
 
+// CertManagerCertificate returns the ObjectMeta necessary to lookup the
+// cert-manager Certificate for purpose and the Secret it issues. See the
+// CertManager constants for the purposes of a cluster.
+func CertManagerCertificate(cluster *v1beta1.PostgresCluster, purpose string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: cluster.Namespace,
+		Name:      cluster.Name + "-tls-" + purpose,
+	}
+}
+
+// CertManagerInstanceCertificate returns the ObjectMeta necessary to lookup
+// the cert-manager Certificate and Secret of the Patroni API of instance.
+func CertManagerInstanceCertificate(instance metav1.Object) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: instance.GetNamespace(),
+		Name:      instance.GetName() + "-tls",
+	}
+}
+
+// CertManagerUserCertificate returns the ObjectMeta necessary to lookup the
+// cert-manager Certificate and Secret of a PostgreSQL user's client certificate.
+func CertManagerUserCertificate(cluster *v1beta1.PostgresCluster, username string) metav1.ObjectMeta {
+	return CertManagerCertificate(cluster, "pguser-"+username)
+}
+
 // PatroniDistributedConfiguration returns the ObjectMeta necessary to lookup
 // the DCS created by Patroni for cluster. This same name is used for both
 // ConfigMap and Endpoints. See Patroni DCS "config_path".
@@ -95,6 +95,7 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 			{"ClusterConfigMap", ClusterConfigMap(cluster)},
 			{"ClusterPGAdmin", ClusterPGAdmin(cluster)},
 			{"ClusterPGBouncer", ClusterPGBouncer(cluster)},
+			{"ClusterPGBouncerPooler", ClusterPGBouncerPooler(cluster, "batch")},
 			{"PatroniDistributedConfiguration", PatroniDistributedConfiguration(cluster)},
 			{"PatroniLeaderConfigMap", PatroniLeaderConfigMap(cluster)},
 			{"PatroniTrigger", PatroniTrigger(cluster)},
@@ -115,6 +116,7 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 	t.Run("Deployments", func(t *testing.T) {
 		testUniqueAndValid(t, []test{
 			{"ClusterPGBouncer", ClusterPGBouncer(cluster)},
+			{"ClusterPGBouncerPooler", ClusterPGBouncerPooler(cluster, "batch")},
 		})
 	})
 
@@ -129,6 +131,20 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 		testUniqueAndValid(t, []test{
 			{"InstanceSetPDB", InstanceSet(cluster, instanceSet)},
 			{"PGBouncerPDB", ClusterPGBouncer(cluster)},
+			{"PGBouncerPoolerPDB", ClusterPGBouncerPooler(cluster, "batch")},
+		})
+	})
+
+	t.Run("PodMonitors", func(t *testing.T) {
+		testUniqueAndValid(t, []test{
+			{"ClusterPrometheus", ClusterPrometheus(cluster)},
+			{"ClusterPGBouncerPrometheus", ClusterPGBouncerPrometheus(cluster)},
+		})
+	})
+
+	t.Run("PrometheusRules", func(t *testing.T) {
+		testUniqueAndValid(t, []test{
+			{"ClusterPrometheus", ClusterPrometheus(cluster)},
 		})
 	})
 
@@ -149,6 +165,7 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 	t.Run("Secrets", func(t *testing.T) {
 		names := testUniqueAndValid(t, []test{
 			{"ClusterPGBouncer", ClusterPGBouncer(cluster)},
+			{"ClusterPGBouncerConsole", ClusterPGBouncerConsole(cluster)},
 			{"DeprecatedPostgresUserSecret", DeprecatedPostgresUserSecret(cluster)},
 			{"PostgresTLSSecret", PostgresTLSSecret(cluster)},
 			{"ReplicationClientCertSecret", ReplicationClientCertSecret(cluster)},
@@ -174,6 +191,24 @@ func TestClusterNamesUniqueAndValid(t *testing.T) {
 				assert.Assert(t, !strings.HasPrefix(name, prefix), "%q may collide", name)
 			}
 		})
+
+		t.Run("CertManagerCertificate", func(t *testing.T) {
+			for _, value := range []metav1.ObjectMeta{
+				CertManagerCertificate(cluster, CertManagerPGBackRest),
+				CertManagerCertificate(cluster, CertManagerPGBouncer),
+				CertManagerCertificate(cluster, CertManagerReplication),
+				CertManagerCertificate(cluster, CertManagerServer),
+				CertManagerUserCertificate(cluster, "some-user"),
+			} {
+				assert.Equal(t, value.Namespace, cluster.Namespace)
+				assert.Assert(t, nil == validation.IsDNS1123Label(value.Name))
+			}
+
+			prefix := CertManagerCertificate(cluster, "").Name
+			for _, name := range names.List() {
+				assert.Assert(t, !strings.HasPrefix(name, prefix), "%q may collide", name)
+			}
+		})
 	})
 
 	t.Run("ServiceAccounts", func(t *testing.T) {
@@ -257,6 +292,7 @@ func TestInstanceNamesUniqueAndValid(t *testing.T) {
 	t.Run("Secrets", func(t *testing.T) {
 		names := sets.NewString()
 		for _, tt := range []test{
+			{"CertManagerInstanceCertificate", CertManagerInstanceCertificate(instance)},
 			{"InstanceCertificates", InstanceCertificates(instance)},
 		} {
 			t.Run(tt.name, func(t *testing.T) {
@@ -125,6 +125,35 @@ func ClusterPGBouncerSelector(cluster *v1beta1.PostgresCluster) metav1.LabelSele
 	}
 }
 
+// ClusterPGBouncerPoolerSelector selects things labeled for the PgBouncer pooler of
+// cluster named pooler. An empty name selects things of [ClusterPGBouncerSelector].
+func ClusterPGBouncerPoolerSelector(cluster *v1beta1.PostgresCluster, pooler string) metav1.LabelSelector {
+	if pooler == "" {
+		return ClusterPGBouncerSelector(cluster)
+	}
+	return metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			LabelCluster:         cluster.Name,
+			LabelRole:            RolePGBouncerPooler,
+			LabelPGBouncerPooler: pooler,
+		},
+	}
+}
+
+// ClusterPGBouncerPoolers selects things labeled for any additional PgBouncer
+// pooler in cluster.
+func ClusterPGBouncerPoolers(cluster string) metav1.LabelSelector {
+	return metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			LabelCluster: cluster,
+			LabelRole:    RolePGBouncerPooler,
+		},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: LabelPGBouncerPooler, Operator: metav1.LabelSelectorOpExists},
+		},
+	}
+}
+
 // ClusterPostgresUsers selects things labeled for PostgreSQL users in cluster.
 func ClusterPostgresUsers(cluster string) metav1.LabelSelector {
 	return metav1.LabelSelector{
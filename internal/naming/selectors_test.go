@@ -135,6 +135,36 @@ func TestClusterPGBouncerSelector(t *testing.T) {
 	assert.ErrorContains(t, err, "Invalid")
 }
 
+func TestClusterPGBouncerPoolerSelector(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Name = "something"
+
+	s, err := AsSelector(ClusterPGBouncerPoolerSelector(cluster, ""))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, s.String(), strings.Join([]string{
+		"postgres-operator.crunchydata.com/cluster=something",
+		"postgres-operator.crunchydata.com/role=pgbouncer",
+	}, ","))
+
+	s, err = AsSelector(ClusterPGBouncerPoolerSelector(cluster, "batch"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, s.String(), strings.Join([]string{
+		"postgres-operator.crunchydata.com/cluster=something",
+		"postgres-operator.crunchydata.com/pgbouncer-pooler=batch",
+		"postgres-operator.crunchydata.com/role=pgbouncer-pooler",
+	}, ","))
+}
+
+func TestClusterPGBouncerPoolers(t *testing.T) {
+	s, err := AsSelector(ClusterPGBouncerPoolers("something"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, s.String(), strings.Join([]string{
+		"postgres-operator.crunchydata.com/cluster=something",
+		"postgres-operator.crunchydata.com/pgbouncer-pooler",
+		"postgres-operator.crunchydata.com/role=pgbouncer-pooler",
+	}, ","))
+}
+
 func TestClusterPostgresUsers(t *testing.T) {
 	s, err := AsSelector(ClusterPostgresUsers("something"))
 	assert.NilError(t, err)
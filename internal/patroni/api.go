@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"path"
 	"strings"
 
 	"github.com/crunchydata/postgres-operator/internal/logging"
@@ -156,6 +157,40 @@ func (exec Executor) ReplaceConfiguration(
 	return err
 }
 
+// ReloadCertificates asks Patroni on member in scope to reload its
+// configuration and certificate files by calling "patronictl". Patroni loads a
+// new certificate for its REST API only when asked. When authorities is not
+// empty, Patroni is asked only when its file of trusted authorities contains
+// exactly authorities, and it returns false when the file has other contents.
+func (exec Executor) ReloadCertificates(
+	ctx context.Context, scope, member string, authorities []byte,
+) (bool, error) {
+	var stdout, stderr bytes.Buffer
+
+	// Compare the file to stdin with "cmp" before calling "POST /reload".
+	// - https://patroni.readthedocs.io/en/latest/rest_api.html
+	script := strings.Join([]string{
+		`if [[ -n "$1" ]] && ! cmp --silent -- "$1" -; then echo 'false'; exit; fi`,
+		`patronictl reload --force "$2" "$3" >&2 && echo 'true'`,
+	}, "\n")
+
+	file := ""
+	if len(authorities) > 0 {
+		file = path.Join(configDirectory, certAuthorityConfigPath)
+	}
+
+	err := exec(ctx, bytes.NewReader(authorities), &stdout, &stderr,
+		"bash", "-ceu", "--", script, "-", file, scope, member)
+
+	log := logging.FromContext(ctx)
+	log.V(1).Info("reloaded certificates",
+		"stdout", stdout.String(),
+		"stderr", stderr.String(),
+	)
+
+	return err == nil && strings.TrimSpace(stdout.String()) == "true", err
+}
+
 // RestartPendingMembers looks up Patroni members with role in scope and restarts
 // those that have a pending restart.
 func (exec Executor) RestartPendingMembers(ctx context.Context, role, scope string) error {
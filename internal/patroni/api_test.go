@@ -298,3 +298,42 @@ func TestExecutorGetTimeline(t *testing.T) {
 		assert.Equal(t, tl, int64(4))
 	})
 }
+
+func TestExecutorReloadCertificates(t *testing.T) {
+	t.Run("Arguments", func(t *testing.T) {
+		expected := errors.New("bang")
+		exec := func(
+			_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			assert.DeepEqual(t, command[:3], []string{"bash", "-ceu", "--"})
+			assert.DeepEqual(t, command[4:], []string{
+				"-", "/etc/patroni/~postgres-operator/patroni.ca-roots", "some-scope", "some-member",
+			})
+
+			b, err := io.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Equal(t, string(b), "some\nbundle")
+			return expected
+		}
+
+		ok, actual := Executor(exec).ReloadCertificates(
+			context.Background(), "some-scope", "some-member", []byte("some\nbundle"))
+		assert.Equal(t, expected, actual)
+		assert.Assert(t, !ok)
+	})
+
+	t.Run("NoAuthorities", func(t *testing.T) {
+		exec := func(
+			_ context.Context, _ io.Reader, stdout, _ io.Writer, command ...string,
+		) error {
+			assert.Equal(t, command[5], "", "expected no file to compare")
+			_, err := stdout.Write([]byte("true\n"))
+			return err
+		}
+
+		ok, err := Executor(exec).ReloadCertificates(
+			context.Background(), "scope", "member", nil)
+		assert.NilError(t, err)
+		assert.Assert(t, ok)
+	})
+}
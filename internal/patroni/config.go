@@ -264,6 +264,11 @@ func DynamicConfiguration(
 	for i := range pgHBAs.Mandatory {
 		hba = append(hba, pgHBAs.Mandatory[i].String())
 	}
+	if authn := cluster.Spec.Authentication; authn != nil {
+		for i := range authn.Rules {
+			hba = append(hba, postgres.NewHBAFromRule(authn.Rules[i]).String())
+		}
+	}
 	if section, ok := postgresql["pg_hba"].([]any); ok {
 		for i := range section {
 			// any pg_hba values that are not strings will be skipped
@@ -280,6 +285,25 @@ func DynamicConfiguration(
 	}
 	postgresql["pg_hba"] = hba
 
+	// Copy the "postgresql.pg_ident" section after any specified user name maps.
+	ident := []string{}
+	if authn := cluster.Spec.Authentication; authn != nil {
+		for i := range authn.IdentMaps {
+			ident = append(ident, postgres.NewUserNameMapFromSpec(authn.IdentMaps[i]).String())
+		}
+	}
+	if section, ok := postgresql["pg_ident"].([]any); ok {
+		for i := range section {
+			// any pg_ident values that are not strings will be skipped
+			if value, ok := section[i].(string); ok {
+				ident = append(ident, value)
+			}
+		}
+	}
+	if len(ident) > 0 {
+		postgresql["pg_ident"] = ident
+	}
+
 	// Enabling `pg_rewind` allows a former primary to automatically rejoin the
 	// cluster even if it has commits that were not sent to a replica. In other
 	// words, this favors availability over consistency. Without it, the former
@@ -567,6 +567,105 @@ func TestDynamicConfiguration(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "postgresql.pg_hba: authentication rules after mandatory",
+			cluster: &v1beta1.PostgresCluster{
+				Spec: v1beta1.PostgresClusterSpec{
+					Authentication: &v1beta1.PostgresAuthenticationSpec{
+						Rules: []v1beta1.PostgresHBARule{
+							{Connection: "hostssl", Users: []v1beta1.PostgresIdentifier{"app"}, Method: "scram-sha-256"},
+							{Connection: "host", Address: "10.0.0.0/8", Method: "reject"},
+						},
+					},
+				},
+			},
+			input: map[string]any{
+				"postgresql": map[string]any{
+					"pg_hba": []any{"custom"},
+				},
+			},
+			hbas: postgres.HBAs{
+				Mandatory: []postgres.HostBasedAuthentication{
+					*postgres.NewHBA().Local().Method("peer"),
+				},
+				Default: []postgres.HostBasedAuthentication{
+					*postgres.NewHBA().TLS().Method("md5"),
+				},
+			},
+			expected: map[string]any{
+				"loop_wait": int32(10),
+				"ttl":       int32(30),
+				"postgresql": map[string]any{
+					"parameters": map[string]any{},
+					"pg_hba": []string{
+						"local all all peer",
+						`hostssl all "app" all scram-sha-256`,
+						`host all all "10.0.0.0/8" reject`,
+						"custom",
+					},
+					"use_pg_rewind": true,
+					"use_slots":     false,
+				},
+			},
+		},
+		{
+			name: "postgresql.pg_hba: no default when authentication rules",
+			cluster: &v1beta1.PostgresCluster{
+				Spec: v1beta1.PostgresClusterSpec{
+					Authentication: &v1beta1.PostgresAuthenticationSpec{
+						Rules: []v1beta1.PostgresHBARule{
+							{Connection: "hostssl", Method: "cert"},
+						},
+					},
+				},
+			},
+			hbas: postgres.HBAs{
+				Default: []postgres.HostBasedAuthentication{
+					*postgres.NewHBA().TLS().Method("md5"),
+				},
+			},
+			expected: map[string]any{
+				"loop_wait": int32(10),
+				"ttl":       int32(30),
+				"postgresql": map[string]any{
+					"parameters":    map[string]any{},
+					"pg_hba":        []string{"hostssl all all all cert"},
+					"use_pg_rewind": true,
+					"use_slots":     false,
+				},
+			},
+		},
+		{
+			name: "postgresql.pg_ident: ident maps before input",
+			cluster: &v1beta1.PostgresCluster{
+				Spec: v1beta1.PostgresClusterSpec{
+					Authentication: &v1beta1.PostgresAuthenticationSpec{
+						IdentMaps: []v1beta1.PostgresIdentMap{
+							{Name: "certs", SystemUser: "app.example.com", DatabaseUser: "app"},
+						},
+					},
+				},
+			},
+			input: map[string]any{
+				"postgresql": map[string]any{
+					"pg_ident": []any{"custom", 1},
+				},
+			},
+			expected: map[string]any{
+				"loop_wait": int32(10),
+				"ttl":       int32(30),
+				"postgresql": map[string]any{
+					"parameters": map[string]any{},
+					"pg_hba":     []string{},
+					"pg_ident": []string{
+						`certs "app.example.com" "app"`,
+						"custom",
+					},
+					"use_pg_rewind": true,
+					"use_slots":     false,
+				},
+			},
+		},
 		{
 			name: "standby_cluster: input passes through",
 			input: map[string]any{
@@ -25,6 +25,11 @@ import (
 )
 
 const (
+	// These are the keys of a typical TLS Secret.
+	tlsAuthoritySecretKey   = "ca.crt"
+	tlsCertificateSecretKey = corev1.TLSCertKey
+	tlsPrivateKeySecretKey  = corev1.TLSPrivateKeyKey
+
 	certAuthorityAbsolutePath        = configDirectory + "/" + certAuthorityProjectionPath
 	certClientPrivateKeyAbsolutePath = configDirectory + "/" + certClientPrivateKeyProjectionPath
 	certClientAbsolutePath           = configDirectory + "/" + certClientProjectionPath
@@ -87,8 +92,9 @@ func clientCertificates() []corev1.KeyToPath {
 	}
 }
 
-// clientCommonName returns a client certificate common name (CN) for cluster.
-func clientCommonName(cluster metav1.Object) string {
+// ClientCommonName returns the common name (CN) of the client certificate
+// pgBackRest uses throughout cluster.
+func ClientCommonName(cluster metav1.Object) string {
 	// The common name (ASN.1 OID 2.5.4.3) of a certificate must be
 	// 64 characters or less. ObjectMeta.UID is a UUID in its 36-character
 	// string representation.
@@ -49,7 +49,7 @@ func TestClientCommonName(t *testing.T) {
 	t.Parallel()
 
 	cluster := &metav1.ObjectMeta{UID: uuid.NewUUID()}
-	cn := clientCommonName(cluster)
+	cn := ClientCommonName(cluster)
 
 	assert.Assert(t, cmp.Regexp("^[-[:xdigit:]]{36}$", string(cluster.UID)),
 		"expected Kubernetes UID to be a UUID string")
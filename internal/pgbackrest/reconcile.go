@@ -435,6 +435,22 @@ func InstanceCertificates(ctx context.Context,
 	return err
 }
 
+// CertManagerInstanceCertificates populates the shared Secret of an instance
+// with the pgBackRest server certificate that cert-manager issued in
+// inCertificate.
+func CertManagerInstanceCertificates(
+	inCluster *v1beta1.PostgresCluster,
+	inCertificate *corev1.Secret,
+	outInstanceCertificates *corev1.Secret,
+) {
+	if DedicatedRepoHostEnabled(inCluster) {
+		initialize.ByteMap(&outInstanceCertificates.Data)
+
+		outInstanceCertificates.Data[certInstanceSecretKey] = inCertificate.Data[tlsCertificateSecretKey]
+		outInstanceCertificates.Data[certInstancePrivateKeySecretKey] = inCertificate.Data[tlsPrivateKeySecretKey]
+	}
+}
+
 // ReplicaCreateCommand returns the command that can initialize the PostgreSQL
 // data directory on an instance from one of cluster's repositories. It returns
 // nil when no repository is available.
@@ -534,7 +550,7 @@ func Secret(ctx context.Context,
 		// option can stay the same when PostgreSQL instances and repository
 		// hosts are added or removed.
 		leaf := &pki.LeafCertificate{}
-		commonName := clientCommonName(inCluster)
+		commonName := ClientCommonName(inCluster)
 		dnsNames := []string{commonName}
 
 		if err == nil {
@@ -549,7 +565,7 @@ func Secret(ctx context.Context,
 		}
 
 		if err == nil {
-			outSecret.Data[certAuthoritySecretKey], err = certFile(inRoot.Certificate)
+			outSecret.Data[certAuthoritySecretKey], err = certFile(inRoot.Authorities())
 		}
 		if err == nil {
 			outSecret.Data[certClientPrivateKeySecretKey], err = certFile(leaf.PrivateKey)
@@ -588,3 +604,23 @@ func Secret(ctx context.Context,
 
 	return err
 }
+
+// CertManagerSecret populates the pgBackRest Secret with the certificate that
+// cert-manager issued in inCertificate. The entire cluster presents that one
+// certificate as both client and server, so its common name (CN) must be
+// ClientCommonName and its DNS names must cover every repository host.
+func CertManagerSecret(
+	inRepoHost *appsv1.StatefulSet,
+	inCertificate *corev1.Secret,
+	outSecret *corev1.Secret,
+) {
+	if inRepoHost != nil {
+		initialize.ByteMap(&outSecret.Data)
+
+		outSecret.Data[certAuthoritySecretKey] = inCertificate.Data[tlsAuthoritySecretKey]
+		outSecret.Data[certClientPrivateKeySecretKey] = inCertificate.Data[tlsPrivateKeySecretKey]
+		outSecret.Data[certClientSecretKey] = inCertificate.Data[tlsCertificateSecretKey]
+		outSecret.Data[certRepoPrivateKeySecretKey] = inCertificate.Data[tlsPrivateKeySecretKey]
+		outSecret.Data[certRepoSecretKey] = inCertificate.Data[tlsCertificateSecretKey]
+	}
+}
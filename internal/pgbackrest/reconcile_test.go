@@ -1050,3 +1050,46 @@ func TestSecret(t *testing.T) {
 		assert.Assert(t, !reflect.DeepEqual(leaf.PrivateKey, leaf2.PrivateKey))
 	})
 }
+
+func TestCertManagerSecret(t *testing.T) {
+	t.Parallel()
+
+	issued := &corev1.Secret{Data: map[string][]byte{
+		"ca.crt":  []byte("authority"),
+		"tls.crt": []byte("certificate"),
+		"tls.key": []byte("private-key"),
+	}}
+
+	t.Run("NoRepoHost", func(t *testing.T) {
+		intent := new(corev1.Secret)
+		CertManagerSecret(nil, issued, intent)
+		assert.Assert(t, intent.Data == nil)
+	})
+
+	intent := new(corev1.Secret)
+	CertManagerSecret(new(appsv1.StatefulSet), issued, intent)
+
+	assert.DeepEqual(t, intent.Data, map[string][]byte{
+		"pgbackrest.ca-roots":      []byte("authority"),
+		"pgbackrest-client.crt":    []byte("certificate"),
+		"pgbackrest-client.key":    []byte("private-key"),
+		"pgbackrest-repo-host.crt": []byte("certificate"),
+		"pgbackrest-repo-host.key": []byte("private-key"),
+	})
+
+	t.Run("Instance", func(t *testing.T) {
+		cluster := new(v1beta1.PostgresCluster)
+		cluster.Spec.Backups.PGBackRest.Repos = []v1beta1.PGBackRestRepo{{
+			Name:   "repo1",
+			Volume: &v1beta1.RepoPVC{},
+		}}
+
+		instance := new(corev1.Secret)
+		CertManagerInstanceCertificates(cluster, issued, instance)
+
+		assert.DeepEqual(t, instance.Data, map[string][]byte{
+			"pgbackrest-server.crt": []byte("certificate"),
+			"pgbackrest-server.key": []byte("private-key"),
+		})
+	})
+}
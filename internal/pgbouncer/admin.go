@@ -0,0 +1,106 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgbouncer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// Executor provides methods for calling the PgBouncer admin console from
+// inside a PgBouncer container.
+type Executor func(
+	ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+) error
+
+// Pause tells the PgBouncer listening on port to wait for server connections
+// to be released and then hold new queries. It waits up to timeout for that to
+// happen and resumes PgBouncer when it does not. Once paused, PgBouncer holds
+// queries until [Executor.Resume]; the caller is responsible for that.
+// - https://www.pgbouncer.org/usage.html#pause-db
+func (exec Executor) Pause(ctx context.Context, port int32, timeout time.Duration) error {
+	var stdout, stderr bytes.Buffer
+
+	const script = `
+connection="$1" seconds="$2"
+if ! timeout "${seconds}" psql --no-psqlrc --quiet "${connection}" --command='PAUSE'
+then
+  psql --no-psqlrc --quiet "${connection}" --command='RESUME' || true
+  exit 1
+fi
+`
+	seconds := int64(timeout.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	err := exec(ctx, nil, &stdout, &stderr,
+		"bash", "-ceu", "--", script, "pause",
+		adminConnection(port), fmt.Sprint(seconds))
+
+	log := logging.FromContext(ctx)
+	log.V(1).Info("paused pgbouncer",
+		"stdout", stdout.String(),
+		"stderr", stderr.String(),
+	)
+
+	return err
+}
+
+// Resume tells the PgBouncer listening on port to continue after [Executor.Pause].
+// It is not an error when PgBouncer is not paused.
+// - https://www.pgbouncer.org/usage.html#resume-db
+func (exec Executor) Resume(ctx context.Context, port int32) error {
+	var stdout, stderr bytes.Buffer
+
+	err := exec(ctx, nil, &stdout, &stderr, adminCommand(port, "RESUME")...)
+
+	log := logging.FromContext(ctx)
+	log.V(1).Info("resumed pgbouncer",
+		"stdout", stdout.String(),
+		"stderr", stderr.String(),
+	)
+
+	// PgBouncer reports an error when it is already running.
+	if err != nil && strings.Contains(stderr.String(), "not paused") {
+		err = nil
+	}
+
+	return err
+}
+
+// PauseDuringSwitchover returns whether or not PgBouncer pools should be paused
+// while the primary of cluster changes and the longest they should remain so.
+func PauseDuringSwitchover(cluster *v1beta1.PostgresCluster) (bool, time.Duration) {
+	if cluster.Spec.Proxy == nil || cluster.Spec.Proxy.PGBouncer == nil ||
+		cluster.Spec.Proxy.PGBouncer.Switchover == nil ||
+		!cluster.Spec.Proxy.PGBouncer.Switchover.Pause {
+		return false, 0
+	}
+
+	timeout := 30 * time.Second
+	if spec := cluster.Spec.Proxy.PGBouncer.Switchover; spec.Timeout != nil {
+		timeout = spec.Timeout.Duration
+	}
+	return true, timeout
+}
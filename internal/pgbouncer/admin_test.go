@@ -0,0 +1,144 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgbouncer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crunchydata/postgres-operator/internal/testing/require"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestExecutorPause(t *testing.T) {
+	t.Run("Arguments", func(t *testing.T) {
+		shellcheck := require.ShellCheck(t)
+
+		called := false
+		executor := func(
+			_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			called = true
+			assert.Assert(t, stdin == nil, "expected no stdin, got %T", stdin)
+			assert.Assert(t, stderr != nil, "should capture stderr")
+			assert.Assert(t, stdout != nil, "should capture stdout")
+
+			assert.DeepEqual(t, command[:3], []string{"bash", "-ceu", "--"})
+			assert.DeepEqual(t, command[4:], []string{"pause", adminConnection(6543), "15"})
+
+			// Expect shellcheck to be happy with the script.
+			file := filepath.Join(t.TempDir(), "script.bash")
+			assert.NilError(t, os.WriteFile(file, []byte(command[3]), 0o600))
+
+			cmd := exec.Command(shellcheck, "--enable=all", file)
+			output, err := cmd.CombinedOutput()
+			assert.NilError(t, err, "%q\n%s", cmd.Args, output)
+			return nil
+		}
+
+		assert.NilError(t, Executor(executor).Pause(context.Background(), 6543, 15*time.Second))
+		assert.Assert(t, called)
+	})
+
+	t.Run("AtLeastOneSecond", func(t *testing.T) {
+		_ = Executor(func(
+			_ context.Context, _ io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			assert.Equal(t, command[len(command)-1], "1")
+			return nil
+		}).Pause(context.Background(), 6543, time.Millisecond)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		expected := errors.New("bang")
+		actual := Executor(func(
+			context.Context, io.Reader, io.Writer, io.Writer, ...string,
+		) error {
+			return expected
+		}).Pause(context.Background(), 6543, time.Second)
+
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func TestExecutorResume(t *testing.T) {
+	t.Run("Arguments", func(t *testing.T) {
+		called := false
+		exec := func(
+			_ context.Context, stdin io.Reader, stdout, stderr io.Writer, command ...string,
+		) error {
+			called = true
+			assert.DeepEqual(t, command, adminCommand(6543, "RESUME"))
+			assert.Assert(t, stdin == nil, "expected no stdin, got %T", stdin)
+			return nil
+		}
+
+		assert.NilError(t, Executor(exec).Resume(context.Background(), 6543))
+		assert.Assert(t, called)
+	})
+
+	t.Run("NotPaused", func(t *testing.T) {
+		err := Executor(func(
+			_ context.Context, _ io.Reader, _, stderr io.Writer, _ ...string,
+		) error {
+			_, _ = stderr.Write([]byte(`ERROR:  pooler is not paused/suspended`))
+			return errors.New("exit status 1")
+		}).Resume(context.Background(), 6543)
+
+		assert.NilError(t, err)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		expected := errors.New("bang")
+		actual := Executor(func(
+			context.Context, io.Reader, io.Writer, io.Writer, ...string,
+		) error {
+			return expected
+		}).Resume(context.Background(), 6543)
+
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func TestPauseDuringSwitchover(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+
+	enabled, _ := PauseDuringSwitchover(cluster)
+	assert.Assert(t, !enabled, "expected disabled without PgBouncer")
+
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+	enabled, _ = PauseDuringSwitchover(cluster)
+	assert.Assert(t, !enabled, "expected disabled by default")
+
+	cluster.Spec.Proxy.PGBouncer.Switchover = &v1beta1.PGBouncerSwitchoverSpec{Pause: true}
+	enabled, timeout := PauseDuringSwitchover(cluster)
+	assert.Assert(t, enabled)
+	assert.Equal(t, timeout, 30*time.Second)
+
+	cluster.Spec.Proxy.PGBouncer.Switchover.Timeout = &metav1.Duration{Duration: time.Minute}
+	_, timeout = PauseDuringSwitchover(cluster)
+	assert.Equal(t, timeout, time.Minute)
+}
@@ -17,6 +17,9 @@ package pgbouncer
 
 import (
 	corev1 "k8s.io/api/core/v1"
+
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 const (
@@ -65,6 +68,40 @@ func backendAuthority(postgres *corev1.SecretProjection) corev1.VolumeProjection
 	return corev1.VolumeProjection{Secret: result}
 }
 
+// customCertificate returns a projection of the PgBouncer certificate when it
+// comes from somewhere other than the PgBouncer Secret. A custom secret takes
+// precedence over the Secret written by cert-manager.
+func customCertificate(cluster *v1beta1.PostgresCluster) *corev1.SecretProjection {
+	if custom := cluster.Spec.Proxy.PGBouncer.CustomTLSSecret; custom != nil {
+		return custom
+	}
+	if cluster.Spec.CertManager != nil {
+		return &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{
+				Name: naming.CertManagerCertificate(cluster, naming.CertManagerPGBouncer).Name,
+			},
+		}
+	}
+	return nil
+}
+
+// FrontendCertificateSecret returns the name of the Secret and the key in that
+// Secret of the certificate PgBouncer presents to clients.
+func FrontendCertificateSecret(cluster *v1beta1.PostgresCluster) (string, string) {
+	custom := customCertificate(cluster)
+	if custom == nil {
+		return naming.ClusterPGBouncer(cluster).Name, certFrontendSecretKey
+	}
+
+	// The custom projection expects Path to match typical Keys.
+	for _, item := range custom.Items {
+		if item.Path == tlsCertificateSecretKey {
+			return custom.Name, item.Key
+		}
+	}
+	return custom.Name, tlsCertificateSecretKey
+}
+
 // frontendCertificate creates a volume projection of the PgBouncer certificate.
 func frontendCertificate(
 	custom *corev1.SecretProjection, secret *corev1.Secret,
@@ -20,6 +20,8 @@ import (
 
 	"gotest.tools/v3/assert"
 	corev1 "k8s.io/api/core/v1"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 func TestBackendAuthority(t *testing.T) {
@@ -49,6 +51,65 @@ secret:
 	`))
 }
 
+func TestCustomCertificate(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Name = "hippo"
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{
+		PGBouncer: &v1beta1.PGBouncerPodSpec{},
+	}
+
+	assert.Assert(t, customCertificate(cluster) == nil)
+
+	t.Run("CertManager", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.CertManager = &v1beta1.CertManagerSpec{}
+
+		assert.Assert(t, marshalMatches(customCertificate(cluster), `
+name: hippo-tls-pgbouncer
+		`))
+	})
+
+	t.Run("CustomTakesPrecedence", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.CertManager = &v1beta1.CertManagerSpec{}
+		cluster.Spec.Proxy.PGBouncer.CustomTLSSecret = &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "some-name"},
+		}
+
+		assert.Assert(t, marshalMatches(customCertificate(cluster), `
+name: some-name
+		`))
+	})
+}
+
+func TestFrontendCertificateSecret(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Name = "hippo"
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{
+		PGBouncer: &v1beta1.PGBouncerPodSpec{},
+	}
+
+	name, key := FrontendCertificateSecret(cluster)
+	assert.Equal(t, name, "hippo-pgbouncer")
+	assert.Equal(t, key, "pgbouncer-frontend.crt")
+
+	cluster.Spec.CertManager = &v1beta1.CertManagerSpec{}
+	name, key = FrontendCertificateSecret(cluster)
+	assert.Equal(t, name, "hippo-tls-pgbouncer")
+	assert.Equal(t, key, "tls.crt")
+
+	cluster.Spec.Proxy.PGBouncer.CustomTLSSecret = &corev1.SecretProjection{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "some-name"},
+		Items: []corev1.KeyToPath{
+			{Key: "some-key", Path: "tls.key"},
+			{Key: "some-cert", Path: "tls.crt"},
+		},
+	}
+	name, key = FrontendCertificateSecret(cluster)
+	assert.Equal(t, name, "some-name")
+	assert.Equal(t, key, "some-cert")
+}
+
 func TestFrontendCertificate(t *testing.T) {
 	secret := new(corev1.Secret)
 	secret.Name = "op-secret"
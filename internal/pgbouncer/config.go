@@ -21,8 +21,12 @@ import (
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/pgmonitor"
+	"github.com/crunchydata/postgres-operator/internal/postgres"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
@@ -31,16 +35,19 @@ const (
 
 	authFileAbsolutePath  = configDirectory + "/" + authFileProjectionPath
 	emptyFileAbsolutePath = configDirectory + "/" + emptyFileProjectionPath
+	hbaFileAbsolutePath   = configDirectory + "/" + hbaFileProjectionPath
 	iniFileAbsolutePath   = configDirectory + "/" + iniFileProjectionPath
 
 	authFileProjectionPath  = "~postgres-operator/users.txt"
 	emptyFileProjectionPath = "pgbouncer.ini"
+	hbaFileProjectionPath   = "~postgres-operator/hba.conf"
 	iniFileProjectionPath   = "~postgres-operator.ini"
 
 	authFileSecretKey   = "pgbouncer-users.txt" // #nosec G101 this is a name, not a credential
 	passwordSecretKey   = "pgbouncer-password"  // #nosec G101 this is a name, not a credential
 	verifierSecretKey   = "pgbouncer-verifier"  // #nosec G101 this is a name, not a credential
 	emptyConfigMapKey   = "pgbouncer-empty"
+	hbaFileConfigMapKey = "pgbouncer-hba.conf"
 	iniFileConfigMapKey = "pgbouncer.ini"
 )
 
@@ -71,8 +78,9 @@ func (vs iniValueSet) String() string {
 	return b.String()
 }
 
-// authFileContents returns a PgBouncer user database.
-func authFileContents(password string) []byte {
+// authFileContents returns a PgBouncer user database. The "auth_user" has
+// password, and each of others has the SCRAM verifier in that map.
+func authFileContents(password string, others map[string]string) []byte {
 	// > There should be at least 2 fields, surrounded by double quotes.
 	// > Double quotes in a field value can be escaped by writing two double quotes.
 	// - https://www.pgbouncer.org/config.html#authentication-file-format
@@ -80,9 +88,19 @@ func authFileContents(password string) []byte {
 		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
 	}
 
-	user1 := quote(postgresqlUser) + " " + quote(password) + "\n"
+	result := quote(postgresqlUser) + " " + quote(password) + "\n"
 
-	return []byte(user1)
+	names := make([]string, 0, len(others))
+	for name := range others {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		result += quote(name) + " " + quote(others[name]) + "\n"
+	}
+
+	return []byte(result)
 }
 
 func clusterINI(cluster *v1beta1.PostgresCluster) string {
@@ -110,12 +128,10 @@ func clusterINI(cluster *v1beta1.PostgresCluster) string {
 		"auth_query": "SELECT username, password from pgbouncer.get_auth($1)",
 		"auth_user":  postgresqlUser,
 
-		// TODO(cbandy): Use an HBA file to control authentication of PgBouncer
-		// accounts; e.g. "admin_users" below.
-		// - https://www.pgbouncer.org/config.html#hba-file-format
-		//"auth_hba_file": "",
-		//"auth_type":     "hba",
-		//"admin_users": "pgbouncer",
+		// Allow the "auth_user" into the admin console so that probes and the
+		// drain hook can inspect and stop PgBouncer. See [adminCommand].
+		"admin_users": strings.Join(append([]string{postgresqlUser},
+			consoleUsers(cluster, "Admin")...), ","),
 
 		// Require TLS encryption on client connections.
 		"client_tls_sslmode":   "require",
@@ -135,6 +151,29 @@ func clusterINI(cluster *v1beta1.PostgresCluster) string {
 		"unix_socket_dir": "",
 	}
 
+	// Allow the monitoring user and any other console users to read statistics
+	// from the admin console. See [pgmonitor.EnablePGBouncerMetricsInPostgreSQL].
+	stats := consoleUsers(cluster, "Stats")
+	if pgmonitor.PGBouncerMetricsEnabled(cluster) {
+		stats = append([]string{pgmonitor.MonitoringUser}, stats...)
+	}
+	if len(stats) > 0 {
+		global["stats_users"] = strings.Join(stats, ",")
+	}
+
+	// Authenticate clients using the HBA file when there are rules for it.
+	// - https://www.pgbouncer.org/config.html#hba-file-format
+	if hbaEnabled(cluster) {
+		global["auth_type"] = "hba"
+		global["auth_hba_file"] = hbaFileAbsolutePath
+	}
+
+	// Apply any validated pool settings.
+	pools := cluster.Spec.Proxy.PGBouncer.Config.Pools
+	for k, v := range poolSettings(pools) {
+		global[k] = v
+	}
+
 	// Override the above with any specified settings.
 	for k, v := range cluster.Spec.Proxy.PGBouncer.Config.Global {
 		global[k] = v
@@ -154,17 +193,64 @@ func clusterINI(cluster *v1beta1.PostgresCluster) string {
 	// When that database does not exist, the client will experience timeouts
 	// or errors that sound like PgBouncer misconfiguration.
 	// - https://github.com/pgbouncer/pgbouncer/issues/352
+	//
+	// After a logical upgrade, the pools connect to the Services of the new
+	// cluster so that its certificate matches their hostnames.
+	backend := backendCluster(cluster)
 	databases := iniValueSet{
 		"*": fmt.Sprintf("host=%s port=%d",
-			naming.ClusterPrimaryService(cluster).Name, postgresPort),
+			naming.ClusterPrimaryService(backend).Name, postgresPort),
 	}
 
 	// Replace the above with any specified databases.
 	if len(cluster.Spec.Proxy.PGBouncer.Config.Databases) > 0 {
-		databases = iniValueSet(cluster.Spec.Proxy.PGBouncer.Config.Databases)
+		databases = iniValueSet{}
+		for k, v := range cluster.Spec.Proxy.PGBouncer.Config.Databases {
+			databases[k] = v
+		}
+	}
+
+	// Add read-only pools that connect to cluster's replica service. The
+	// wildcard cannot match them, so each database is listed by name. Those
+	// specified above take precedence.
+	for _, database := range readOnlyDatabases(cluster) {
+		name := quoteDatabaseName(ReadOnlyDatabase(cluster, database))
+		if _, exists := databases[name]; !exists {
+			databases[name] = fmt.Sprintf("host=%s port=%d dbname=%s",
+				naming.ClusterReplicaService(backend).Name, postgresPort,
+				quoteConnectionValue(database))
+		}
+	}
+
+	// Add the pool settings of particular databases. Those with an entry above
+	// get the settings appended, and those without get an entry that connects
+	// to the primary. Specified databases are left as they are.
+	for _, database := range poolDatabases(pools) {
+		name := quoteDatabaseName(database.Name)
+		if _, specified := cluster.Spec.Proxy.PGBouncer.Config.Databases[name]; specified {
+			continue
+		}
+		if _, specified := cluster.Spec.Proxy.PGBouncer.Config.Databases[database.Name]; specified {
+			continue
+		}
+		if _, exists := databases[name]; !exists {
+			databases[name] = fmt.Sprintf("host=%s port=%d",
+				naming.ClusterPrimaryService(backend).Name, postgresPort)
+		}
+		databases[name] += databasePoolSettings(database)
 	}
 
-	users := iniValueSet(cluster.Spec.Proxy.PGBouncer.Config.Users)
+	// Add the pool settings of particular users then any specified users.
+	// User names are quoted the same way as database names.
+	users := iniValueSet{}
+	for _, user := range poolUsers(pools) {
+		if settings := userPoolSettings(user); settings != "" {
+			users[quoteDatabaseName(user.Name)] = settings
+		}
+	}
+	for k, v := range cluster.Spec.Proxy.PGBouncer.Config.Users {
+		users[k] = v
+	}
 
 	// Include any custom configuration file, then apply global settings, then
 	// pool definitions.
@@ -181,6 +267,201 @@ func clusterINI(cluster *v1beta1.PostgresCluster) string {
 	return result
 }
 
+// backendCluster returns the cluster whose Services the pools of cluster
+// connect to. That is cluster itself unless its primary Service is redirected
+// to another cluster by [naming.PrimaryServiceRedirect].
+func backendCluster(cluster *v1beta1.PostgresCluster) *v1beta1.PostgresCluster {
+	if other := cluster.GetAnnotations()[naming.PrimaryServiceRedirect]; other != "" {
+		return &v1beta1.PostgresCluster{ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace, Name: other,
+		}}
+	}
+	return cluster
+}
+
+// consoleUsers returns the sorted names of admin console users in cluster
+// that have role.
+func consoleUsers(cluster *v1beta1.PostgresCluster, role string) []string {
+	var names []string
+	if spec := cluster.Spec.Proxy.PGBouncer.Authentication; spec != nil {
+		for _, user := range spec.ConsoleUsers {
+			if user.Role == role || (user.Role == "" && role == "Stats") {
+				names = append(names, user.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hbaEnabled returns true when PgBouncer in cluster authenticates clients
+// using an HBA file.
+func hbaEnabled(cluster *v1beta1.PostgresCluster) bool {
+	spec := cluster.Spec.Proxy.PGBouncer.Authentication
+	return spec != nil && len(spec.Rules) > 0
+}
+
+// hbaFileContents returns the PgBouncer HBA file of cluster. The rules that
+// the operator requires come first so that specified rules cannot lock out
+// probes, the drain hook, or the monitoring user.
+func hbaFileContents(cluster *v1beta1.PostgresCluster) string {
+	rules := []*postgres.HostBasedAuthentication{
+		// Probes and the drain hook connect to the admin console from inside
+		// the pod. The "auth_user" cannot connect from anywhere else.
+		postgres.NewHBA().TLS().Database("pgbouncer").User(postgresqlUser).
+			Network("127.0.0.1/32").Method("scram-sha-256"),
+		postgres.NewHBA().TLS().Database("pgbouncer").User(postgresqlUser).
+			Network("::1/128").Method("scram-sha-256"),
+		postgres.NewHBA().TCP().User(postgresqlUser).Method("reject"),
+	}
+
+	// The monitoring user connects to the admin console from PostgreSQL.
+	if pgmonitor.PGBouncerMetricsEnabled(cluster) {
+		rules = append(rules, postgres.NewHBA().TLS().Database("pgbouncer").
+			User(pgmonitor.MonitoringUser).Method("scram-sha-256"))
+	}
+
+	for _, rule := range cluster.Spec.Proxy.PGBouncer.Authentication.Rules {
+		rules = append(rules, postgres.NewHBAFromRule(rule))
+	}
+
+	result := iniGeneratedWarning + "\n"
+	for _, rule := range rules {
+		result += rule.String() + "\n"
+	}
+	return result
+}
+
+// poolSettings returns the global settings in pools.
+// - https://www.pgbouncer.org/config.html#pool-settings
+func poolSettings(pools *v1beta1.PGBouncerPoolSettings) iniValueSet {
+	settings := iniValueSet{}
+	if pools == nil {
+		return settings
+	}
+
+	integer := func(key string, value *int32) {
+		if value != nil {
+			settings[key] = fmt.Sprint(*value)
+		}
+	}
+	if pools.PoolMode != "" {
+		settings["pool_mode"] = pools.PoolMode
+	}
+	integer("default_pool_size", pools.DefaultPoolSize)
+	integer("min_pool_size", pools.MinPoolSize)
+	integer("reserve_pool_size", pools.ReservePoolSize)
+	integer("max_client_conn", pools.MaxClientConnections)
+	integer("server_idle_timeout", pools.ServerIdleTimeoutSeconds)
+	integer("max_prepared_statements", pools.MaxPreparedStatements)
+	integer("query_wait_timeout", pools.QueryWaitTimeoutSeconds)
+
+	return settings
+}
+
+// poolDatabases returns the database settings in pools, if any.
+func poolDatabases(pools *v1beta1.PGBouncerPoolSettings) []v1beta1.PGBouncerDatabasePoolSettings {
+	if pools == nil {
+		return nil
+	}
+	return pools.Databases
+}
+
+// poolUsers returns the user settings in pools, if any.
+func poolUsers(pools *v1beta1.PGBouncerPoolSettings) []v1beta1.PGBouncerUserPoolSettings {
+	if pools == nil {
+		return nil
+	}
+	return pools.Users
+}
+
+// databasePoolSettings returns the settings of database formatted as
+// connection parameters, each preceded by a space.
+// - https://www.pgbouncer.org/config.html#pool-configuration
+func databasePoolSettings(database v1beta1.PGBouncerDatabasePoolSettings) string {
+	var b strings.Builder
+	if database.PoolMode != "" {
+		_, _ = fmt.Fprintf(&b, " pool_mode=%s", database.PoolMode)
+	}
+	for _, setting := range []struct {
+		key   string
+		value *int32
+	}{
+		{"pool_size", database.PoolSize},
+		{"min_pool_size", database.MinPoolSize},
+		{"reserve_pool", database.ReservePoolSize},
+		{"max_db_connections", database.MaxDBConnections},
+	} {
+		if setting.value != nil {
+			_, _ = fmt.Fprintf(&b, " %s=%d", setting.key, *setting.value)
+		}
+	}
+	return b.String()
+}
+
+// userPoolSettings returns the settings of user formatted for the users section.
+// - https://www.pgbouncer.org/config.html#section-users
+func userPoolSettings(user v1beta1.PGBouncerUserPoolSettings) string {
+	var settings []string
+	if user.PoolMode != "" {
+		settings = append(settings, "pool_mode="+user.PoolMode)
+	}
+	if user.MaxUserConnections != nil {
+		settings = append(settings, fmt.Sprintf("max_user_connections=%d", *user.MaxUserConnections))
+	}
+	return strings.Join(settings, " ")
+}
+
+// quoteConnectionValue returns value quoted for a PgBouncer connection string.
+// - https://www.pgbouncer.org/config.html#section-databases
+func quoteConnectionValue(value string) string {
+	return `'` + strings.ReplaceAll(value, `'`, `''`) + `'`
+}
+
+// quoteDatabaseName returns name quoted for the PgBouncer "databases" section
+// when it contains characters other than letters, digits, and underscores.
+// - https://www.pgbouncer.org/config.html#section-databases
+func quoteDatabaseName(name string) string {
+	if strings.Trim(name, "_0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz") == "" {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// ReadOnlyDatabase returns the name of the read-only pool for database.
+func ReadOnlyDatabase(cluster *v1beta1.PostgresCluster, database string) string {
+	suffix := "_ro"
+	if spec := cluster.Spec.Proxy.PGBouncer.ReadOnly; spec != nil && spec.Suffix != "" {
+		suffix = spec.Suffix
+	}
+	return database + suffix
+}
+
+// ReadOnlyEnabled returns true when PgBouncer in cluster has read-only pools.
+func ReadOnlyEnabled(cluster *v1beta1.PostgresCluster) bool {
+	return cluster.Spec.Proxy != nil && cluster.Spec.Proxy.PGBouncer != nil &&
+		cluster.Spec.Proxy.PGBouncer.ReadOnly != nil
+}
+
+// readOnlyDatabases returns the sorted names of databases that have
+// read-only pools in cluster.
+func readOnlyDatabases(cluster *v1beta1.PostgresCluster) []string {
+	if !ReadOnlyEnabled(cluster) {
+		return nil
+	}
+
+	names := sets.NewString()
+	for _, database := range cluster.Spec.Proxy.PGBouncer.ReadOnly.Databases {
+		names.Insert(string(database))
+	}
+	for _, user := range cluster.Spec.Users {
+		for _, database := range user.Databases {
+			names.Insert(string(database))
+		}
+	}
+	return names.List()
+}
+
 // podConfigFiles returns projections of PgBouncer's configuration files to
 // include in the configuration volume.
 func podConfigFiles(
@@ -209,16 +490,25 @@ func podConfigFiles(
 	projections = append(projections, config.Files...)
 
 	// Add our non-empty configurations last so that they take precedence.
+	// The HBA file is present only when it is in use.
+	items := []corev1.KeyToPath{{
+		Key:  iniFileConfigMapKey,
+		Path: iniFileProjectionPath,
+	}}
+	if _, ok := configmap.Data[hbaFileConfigMapKey]; ok {
+		items = append(items, corev1.KeyToPath{
+			Key:  hbaFileConfigMapKey,
+			Path: hbaFileProjectionPath,
+		})
+	}
+
 	projections = append(projections, []corev1.VolumeProjection{
 		{
 			ConfigMap: &corev1.ConfigMapProjection{
 				LocalObjectReference: corev1.LocalObjectReference{
 					Name: configmap.Name,
 				},
-				Items: []corev1.KeyToPath{{
-					Key:  iniFileConfigMapKey,
-					Path: iniFileProjectionPath,
-				}},
+				Items: items,
 			},
 		},
 		{
@@ -266,3 +556,44 @@ done
 
 	return []string{"bash", "-ceu", "--", wrapper, name, configDirectory}
 }
+
+// adminCommand returns a command that executes sql in the admin console of the
+// PgBouncer listening on port. It connects as the "auth_user" using the
+// password in the PGPASSWORD environment variable.
+// - https://www.pgbouncer.org/usage.html#admin-console
+func adminCommand(port int32, sql string) []string {
+	return []string{"psql", "--no-psqlrc", "--quiet", adminConnection(port), "--command=" + sql}
+}
+
+// adminConnection returns a libpq connection string to the admin console of
+// the PgBouncer listening on port. Client connections require TLS.
+func adminConnection(port int32) string {
+	return fmt.Sprintf(
+		"host=localhost port=%d dbname=pgbouncer user=%s sslmode=require connect_timeout=5",
+		port, postgresqlUser)
+}
+
+// drainCommand returns a command that stops PgBouncer after its clients
+// disconnect. It is intended to run as a preStop hook so that Kubernetes
+// waits, up to the pod's termination grace period, before signaling PgBouncer.
+func drainCommand(port int32) []string {
+	// PgBouncer 1.23 and newer stop accepting new clients and exit once the
+	// existing ones disconnect. Wait here until that happens; otherwise, use
+	// PAUSE to wait for server connections to be released. PAUSE returns when
+	// they are, and the signal that follows stops PgBouncer.
+	// - https://www.pgbouncer.org/usage.html#shutdown
+	// - https://www.pgbouncer.org/usage.html#pause-db
+	//
+	// Coreutils `sleep` uses a lot of memory, so the following opens a file
+	// descriptor and uses the timeout of the builtin `read` to wait.
+	// See [reloadCommand].
+	const script = `if psql --no-psqlrc --quiet "$1" --command='SHUTDOWN WAIT_FOR_CLIENTS'
+then
+  exec {fd}<> <(:)
+  while pgrep --exact pgbouncer > /dev/null; do read -r -t 1 -u "${fd}" || true; done
+else
+  psql --no-psqlrc --quiet "$1" --command='PAUSE'
+fi
+`
+	return []string{"bash", "-ceu", "--", script, "drain", adminConnection(port)}
+}
@@ -27,6 +27,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/testing/cmp"
 	"github.com/crunchydata/postgres-operator/internal/testing/require"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -45,8 +48,17 @@ func TestAuthFileContents(t *testing.T) {
 	t.Parallel()
 
 	password := `very"random`
-	data := authFileContents(password)
+	data := authFileContents(password, nil)
 	assert.Equal(t, string(data), `"_crunchypgbouncer" "very""random"`+"\n")
+
+	data = authFileContents(password, map[string]string{
+		"zebra": "SCRAM-SHA-256$z", "a\"b": "SCRAM-SHA-256$a",
+	})
+	assert.Equal(t, string(data), strings.Join([]string{
+		`"_crunchypgbouncer" "very""random"`,
+		`"a""b" "SCRAM-SHA-256$a"`,
+		`"zebra" "SCRAM-SHA-256$z"`,
+	}, "\n")+"\n")
 }
 
 func TestClusterINI(t *testing.T) {
@@ -72,6 +84,7 @@ func TestClusterINI(t *testing.T) {
 %include /etc/pgbouncer/pgbouncer.ini
 
 [pgbouncer]
+admin_users = _crunchypgbouncer
 auth_file = /etc/pgbouncer/~postgres-operator/users.txt
 auth_query = SELECT username, password from pgbouncer.get_auth($1)
 auth_user = _crunchypgbouncer
@@ -112,6 +125,7 @@ unix_socket_dir =
 %include /etc/pgbouncer/pgbouncer.ini
 
 [pgbouncer]
+admin_users = _crunchypgbouncer
 auth_file = /etc/pgbouncer/~postgres-operator/users.txt
 auth_query = SELECT username, password from pgbouncer.get_auth($1)
 auth_user = _crunchypgbouncer
@@ -141,6 +155,223 @@ app = mode=rad
 	})
 }
 
+func TestClusterINIMonitoring(t *testing.T) {
+	t.Parallel()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Default()
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+	cluster.Spec.Proxy.PGBouncer.Port = new(int32)
+
+	assert.Assert(t, !strings.Contains(clusterINI(cluster), "stats_users"))
+
+	cluster.Spec.Monitoring = &v1beta1.MonitoringSpec{PGMonitor: &v1beta1.PGMonitorSpec{
+		Exporter: &v1beta1.ExporterSpec{PGBouncer: true},
+	}}
+	assert.Assert(t, cmp.Contains(clusterINI(cluster), "\nstats_users = ccp_monitoring\n"))
+}
+
+func TestClusterINIAuthentication(t *testing.T) {
+	t.Parallel()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Default()
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+	cluster.Spec.Proxy.PGBouncer.Port = new(int32)
+
+	assert.Assert(t, cmp.Contains(clusterINI(cluster), "\nadmin_users = _crunchypgbouncer\n"))
+	assert.Assert(t, !strings.Contains(clusterINI(cluster), "auth_type"))
+
+	cluster.Spec.Proxy.PGBouncer.Authentication = &v1beta1.PGBouncerAuthenticationSpec{
+		ConsoleUsers: []v1beta1.PGBouncerConsoleUser{
+			{Name: "ops", Role: "Admin"},
+			{Name: "viewer", Role: "Stats"},
+			{Name: "dashboard"},
+		},
+	}
+	cluster.Spec.Monitoring = &v1beta1.MonitoringSpec{PGMonitor: &v1beta1.PGMonitorSpec{
+		Exporter: &v1beta1.ExporterSpec{PGBouncer: true},
+	}}
+
+	ini := clusterINI(cluster)
+	assert.Assert(t, cmp.Contains(ini, "\nadmin_users = _crunchypgbouncer,ops\n"))
+	assert.Assert(t, cmp.Contains(ini, "\nstats_users = ccp_monitoring,dashboard,viewer\n"))
+	assert.Assert(t, !strings.Contains(ini, "auth_type"), "expected no HBA without rules")
+
+	cluster.Spec.Proxy.PGBouncer.Authentication.Rules = []v1beta1.PostgresHBARule{
+		{Connection: "hostssl", Method: "scram-sha-256"},
+	}
+
+	ini = clusterINI(cluster)
+	assert.Assert(t, cmp.Contains(ini, "\nauth_hba_file = /etc/pgbouncer/~postgres-operator/hba.conf\n"))
+	assert.Assert(t, cmp.Contains(ini, "\nauth_type = hba\n"))
+}
+
+func TestHBAFileContents(t *testing.T) {
+	t.Parallel()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+	cluster.Spec.Proxy.PGBouncer.Authentication = &v1beta1.PGBouncerAuthenticationSpec{
+		Rules: []v1beta1.PostgresHBARule{
+			{Connection: "hostssl", Users: []v1beta1.PostgresIdentifier{"ops"},
+				Databases: []v1beta1.PostgresIdentifier{"pgbouncer"},
+				Address:   "10.0.0.0/8", Method: "scram-sha-256"},
+			{Connection: "hostssl", Method: "md5"},
+		},
+	}
+
+	assert.Equal(t, hbaFileContents(cluster), strings.Trim(`
+# Generated by postgres-operator. DO NOT EDIT.
+# Your changes will not be saved.
+
+hostssl "pgbouncer" "_crunchypgbouncer" "127.0.0.1/32" scram-sha-256
+hostssl "pgbouncer" "_crunchypgbouncer" "::1/128" scram-sha-256
+host all "_crunchypgbouncer" all reject
+hostssl "pgbouncer" "ops" "10.0.0.0/8" scram-sha-256
+hostssl all all all md5
+	`, "\t\n")+"\n")
+
+	t.Run("Monitoring", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Monitoring = &v1beta1.MonitoringSpec{PGMonitor: &v1beta1.PGMonitorSpec{
+			Exporter: &v1beta1.ExporterSpec{PGBouncer: true},
+		}}
+
+		assert.Assert(t, cmp.Contains(hbaFileContents(cluster), `
+host all "_crunchypgbouncer" all reject
+hostssl "pgbouncer" "ccp_monitoring" all scram-sha-256
+hostssl "pgbouncer" "ops"`))
+	})
+}
+
+func TestClusterINIReadOnly(t *testing.T) {
+	t.Parallel()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Default()
+	cluster.Name = "hippo"
+
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+	cluster.Spec.Proxy.PGBouncer.Port = new(int32)
+	cluster.Spec.Users = []v1beta1.PostgresUserSpec{
+		{Name: "app", Databases: []v1beta1.PostgresIdentifier{"app", "it's"}},
+		{Name: "other", Databases: []v1beta1.PostgresIdentifier{"app"}},
+	}
+
+	assert.Assert(t, !strings.Contains(clusterINI(cluster), "hippo-replicas"))
+
+	cluster.Spec.Proxy.PGBouncer.ReadOnly = &v1beta1.PGBouncerReadOnlySpec{
+		Databases: []v1beta1.PostgresIdentifier{"extra"},
+	}
+	assert.Assert(t, cmp.Contains(clusterINI(cluster), `
+[databases]
+"it's_ro" = host=hippo-replicas port=5432 dbname='it''s'
+* = host=hippo-primary port=5432
+app_ro = host=hippo-replicas port=5432 dbname='app'
+extra_ro = host=hippo-replicas port=5432 dbname='extra'
+`))
+
+	t.Run("Suffix", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.ReadOnly.Suffix = "_replica"
+		assert.Equal(t, ReadOnlyDatabase(cluster, "app"), "app_replica")
+		assert.Assert(t, cmp.Contains(clusterINI(cluster),
+			"\napp_replica = host=hippo-replicas port=5432 dbname='app'\n"))
+	})
+
+	t.Run("CustomTakesPrecedence", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Config.Databases = map[string]string{
+			"app_ro": "conn=str",
+		}
+		assert.Assert(t, cmp.Contains(clusterINI(cluster), `
+[databases]
+"it's_ro" = host=hippo-replicas port=5432 dbname='it''s'
+app_ro = conn=str
+extra_ro = host=hippo-replicas port=5432 dbname='extra'
+`))
+	})
+
+	t.Run("Redirect", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Annotations = map[string]string{naming.PrimaryServiceRedirect: "rhino"}
+		assert.Assert(t, cmp.Contains(clusterINI(cluster), `
+[databases]
+"it's_ro" = host=rhino-replicas port=5432 dbname='it''s'
+* = host=rhino-primary port=5432
+app_ro = host=rhino-replicas port=5432 dbname='app'
+extra_ro = host=rhino-replicas port=5432 dbname='extra'
+`))
+	})
+}
+
+func TestClusterINIPools(t *testing.T) {
+	t.Parallel()
+
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Default()
+	cluster.Name = "hippo"
+
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+	cluster.Spec.Proxy.PGBouncer.Port = new(int32)
+	cluster.Spec.Proxy.PGBouncer.ReadOnly = &v1beta1.PGBouncerReadOnlySpec{
+		Databases: []v1beta1.PostgresIdentifier{"app"},
+	}
+
+	assert.Assert(t, !strings.Contains(clusterINI(cluster), "pool_mode"))
+
+	cluster.Spec.Proxy.PGBouncer.Config.Pools = &v1beta1.PGBouncerPoolSettings{
+		PoolMode:             "transaction",
+		DefaultPoolSize:      initialize.Int32(30),
+		MaxClientConnections: initialize.Int32(400),
+		Databases: []v1beta1.PGBouncerDatabasePoolSettings{
+			{Name: "app_ro", PoolSize: initialize.Int32(5), PoolMode: "statement"},
+			{Name: "reports", MaxDBConnections: initialize.Int32(2)},
+			{Name: "custom", PoolSize: initialize.Int32(1)},
+		},
+		Users: []v1beta1.PGBouncerUserPoolSettings{
+			{Name: "app", MaxUserConnections: initialize.Int32(50)},
+			{Name: "batch", PoolMode: "session"},
+			{Name: "custom", PoolMode: "session"},
+		},
+	}
+	cluster.Spec.Proxy.PGBouncer.Config.Global = map[string]string{
+		"max_client_conn": "1000",
+	}
+	cluster.Spec.Proxy.PGBouncer.Config.Databases = map[string]string{
+		"*":      "host=hippo-primary port=5432",
+		"custom": "host=elsewhere",
+	}
+	cluster.Spec.Proxy.PGBouncer.Config.Users = map[string]string{
+		"custom": "max_user_connections=1",
+	}
+
+	ini := clusterINI(cluster)
+	assert.Assert(t, cmp.Contains(ini, "\ndefault_pool_size = 30\n"))
+	assert.Assert(t, cmp.Contains(ini, "\npool_mode = transaction\n"))
+	assert.Assert(t, cmp.Contains(ini, "\nmax_client_conn = 1000\n"),
+		"expected global settings to take precedence")
+
+	assert.Assert(t, cmp.Contains(ini, `
+[databases]
+* = host=hippo-primary port=5432
+app_ro = host=hippo-replicas port=5432 dbname='app' pool_mode=statement pool_size=5
+custom = host=elsewhere
+reports = host=hippo-primary port=5432 max_db_connections=2
+
+[users]
+app = max_user_connections=50
+batch = pool_mode=session
+custom = max_user_connections=1
+`))
+}
+
 func TestPodConfigFiles(t *testing.T) {
 	t.Parallel()
 
@@ -210,6 +441,30 @@ func TestPodConfigFiles(t *testing.T) {
 	})
 }
 
+func TestPodConfigFilesHBA(t *testing.T) {
+	t.Parallel()
+
+	configmap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "some-cm"}}
+	configmap.Data = map[string]string{"pgbouncer-hba.conf": "anything"}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "some-shh"}}
+
+	projections := podConfigFiles(v1beta1.PGBouncerConfiguration{}, configmap, secret)
+	assert.Assert(t, marshalMatches(projections[1:], `
+- configMap:
+    items:
+    - key: pgbouncer.ini
+      path: ~postgres-operator.ini
+    - key: pgbouncer-hba.conf
+      path: ~postgres-operator/hba.conf
+    name: some-cm
+- secret:
+    items:
+    - key: pgbouncer-users.txt
+      path: ~postgres-operator/users.txt
+    name: some-shh
+	`))
+}
+
 func TestReloadCommand(t *testing.T) {
 	shellcheck := require.ShellCheck(t)
 	command := reloadCommand("some-name")
@@ -228,3 +483,31 @@ func TestReloadCommand(t *testing.T) {
 	output, err := cmd.CombinedOutput()
 	assert.NilError(t, err, "%q\n%s", cmd.Args, output)
 }
+
+func TestAdminCommand(t *testing.T) {
+	assert.DeepEqual(t, adminCommand(6543, "SHOW POOLS"), []string{
+		"psql", "--no-psqlrc", "--quiet",
+		"host=localhost port=6543 dbname=pgbouncer user=_crunchypgbouncer sslmode=require connect_timeout=5",
+		"--command=SHOW POOLS",
+	})
+}
+
+func TestDrainCommand(t *testing.T) {
+	shellcheck := require.ShellCheck(t)
+	command := drainCommand(6543)
+
+	// Expect a bash command with an inline script.
+	assert.DeepEqual(t, command[:3], []string{"bash", "-ceu", "--"})
+	assert.Assert(t, len(command) > 3)
+	assert.Equal(t, command[len(command)-1], adminConnection(6543))
+
+	// Write out that inline script.
+	dir := t.TempDir()
+	file := filepath.Join(dir, "script.bash")
+	assert.NilError(t, os.WriteFile(file, []byte(command[3]), 0o600))
+
+	// Expect shellcheck to be happy.
+	cmd := exec.Command(shellcheck, "--enable=all", file)
+	output, err := cmd.CombinedOutput()
+	assert.NilError(t, err, "%q\n%s", cmd.Args, output)
+}
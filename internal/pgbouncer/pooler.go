@@ -0,0 +1,106 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgbouncer
+
+import (
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+// PoolerNames returns the names of the additional PgBouncer poolers in cluster.
+func PoolerNames(cluster *v1beta1.PostgresCluster) []string {
+	if cluster.Spec.Proxy == nil || cluster.Spec.Proxy.PGBouncer == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(cluster.Spec.Proxy.Poolers))
+	for _, pooler := range cluster.Spec.Proxy.Poolers {
+		names = append(names, pooler.Name)
+	}
+	return names
+}
+
+// Pooler returns cluster as seen by the PgBouncer pooler named name. The
+// functions of this package then apply to that pooler. When name is empty,
+// it returns cluster. Otherwise, it returns a copy of cluster in which the
+// pooler replaces spec.proxy.pgBouncer; that copy has no proxy when there is
+// no such pooler.
+//
+// A pooler shares its Secret, image, and authentication settings with
+// spec.proxy.pgBouncer. Its configuration is added to that of spec.proxy.pgBouncer.
+// It is never autoscaled.
+func Pooler(cluster *v1beta1.PostgresCluster, name string) *v1beta1.PostgresCluster {
+	if name == "" {
+		return cluster
+	}
+
+	out := cluster.DeepCopy()
+
+	var pooler *v1beta1.PGBouncerPoolerSpec
+	if out.Spec.Proxy != nil && out.Spec.Proxy.PGBouncer != nil {
+		for i := range out.Spec.Proxy.Poolers {
+			if out.Spec.Proxy.Poolers[i].Name == name {
+				pooler = &out.Spec.Proxy.Poolers[i]
+			}
+		}
+	}
+	if pooler == nil {
+		out.Spec.Proxy = nil
+		return out
+	}
+
+	spec := out.Spec.Proxy.PGBouncer
+	spec.Autoscaling = nil
+	spec.MinAvailable = pooler.MinAvailable
+	spec.Port = pooler.Port
+	spec.Replicas = pooler.Replicas
+
+	if pooler.Resources != nil {
+		spec.Resources = *pooler.Resources
+	}
+	if pooler.Service != nil {
+		spec.Service = pooler.Service
+	} else if spec.Service != nil {
+		// Another Service cannot have the same node port.
+		spec.Service.NodePort = nil
+	}
+
+	spec.Config.Files = append(spec.Config.Files, pooler.Config.Files...)
+	spec.Config.Global = mergeSettings(spec.Config.Global, pooler.Config.Global)
+	spec.Config.Databases = mergeSettings(spec.Config.Databases, pooler.Config.Databases)
+	spec.Config.Users = mergeSettings(spec.Config.Users, pooler.Config.Users)
+
+	if pooler.Config.Pools != nil {
+		spec.Config.Pools = pooler.Config.Pools
+	}
+
+	return out
+}
+
+// mergeSettings returns the settings of base overridden by those of overlay.
+func mergeSettings(base, overlay map[string]string) map[string]string {
+	if len(overlay) == 0 {
+		return base
+	}
+
+	out := make(map[string]string, len(base)+len(overlay))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range overlay {
+		out[k] = v
+	}
+	return out
+}
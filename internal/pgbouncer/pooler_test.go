@@ -0,0 +1,144 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pgbouncer
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/crunchydata/postgres-operator/internal/initialize"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
+)
+
+func TestPoolerNames(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	assert.Assert(t, PoolerNames(cluster) == nil)
+
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{
+		Poolers: []v1beta1.PGBouncerPoolerSpec{{Name: "web"}, {Name: "batch"}},
+	}
+	assert.Assert(t, PoolerNames(cluster) == nil, "expected none without PgBouncer")
+
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+	assert.DeepEqual(t, PoolerNames(cluster), []string{"web", "batch"})
+}
+
+func TestPooler(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{
+		PGBouncer: &v1beta1.PGBouncerPodSpec{
+			Autoscaling: &v1beta1.PGBouncerAutoscalingSpec{MaxReplicas: 5},
+			Config: v1beta1.PGBouncerConfiguration{
+				Global: map[string]string{"pool_mode": "transaction", "max_client_conn": "500"},
+			},
+			Image: "some-image",
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("1"),
+			}},
+		},
+		Poolers: []v1beta1.PGBouncerPoolerSpec{{
+			Name: "batch",
+			Config: v1beta1.PGBouncerConfiguration{
+				Global:    map[string]string{"pool_mode": "session"},
+				Databases: map[string]string{"reports": "host=elsewhere"},
+			},
+		}},
+	}
+	cluster.Default()
+
+	t.Run("Main", func(t *testing.T) {
+		assert.Assert(t, Pooler(cluster, "") == cluster)
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		out := Pooler(cluster, "nope")
+		assert.Assert(t, out.Spec.Proxy == nil)
+		assert.Assert(t, cluster.Spec.Proxy != nil, "expected no change to the original")
+	})
+
+	t.Run("Inherit", func(t *testing.T) {
+		out := Pooler(cluster, "batch")
+		spec := out.Spec.Proxy.PGBouncer
+
+		assert.Equal(t, out.Name, cluster.Name)
+		assert.Equal(t, spec.Image, "some-image")
+		assert.Assert(t, spec.Autoscaling == nil, "expected poolers to not autoscale")
+		assert.Equal(t, *spec.Port, int32(5432))
+		assert.Equal(t, *spec.Replicas, int32(1))
+		assert.DeepEqual(t, spec.Resources, cluster.Spec.Proxy.PGBouncer.Resources)
+
+		assert.DeepEqual(t, spec.Config.Global, map[string]string{
+			"pool_mode": "session", "max_client_conn": "500",
+		})
+		assert.DeepEqual(t, spec.Config.Databases, map[string]string{
+			"reports": "host=elsewhere",
+		})
+
+		// The original is unchanged.
+		assert.Equal(t, cluster.Spec.Proxy.PGBouncer.Config.Global["pool_mode"], "transaction")
+	})
+
+	t.Run("InheritService", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Service = &v1beta1.ServiceSpec{
+			Type: "NodePort", NodePort: initialize.Int32(30432),
+		}
+
+		spec := Pooler(cluster, "batch").Spec.Proxy.PGBouncer
+		assert.Equal(t, spec.Service.Type, "NodePort")
+		assert.Assert(t, spec.Service.NodePort == nil, "expected Kubernetes to choose a port")
+
+		// The original is unchanged.
+		assert.Equal(t, *cluster.Spec.Proxy.PGBouncer.Service.NodePort, int32(30432))
+	})
+
+	t.Run("Override", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		pooler := &cluster.Spec.Proxy.Poolers[0]
+		pooler.Port = initialize.Int32(6543)
+		pooler.Replicas = initialize.Int32(3)
+		pooler.Resources = &corev1.ResourceRequirements{Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("1Gi"),
+		}}
+		pooler.Service = &v1beta1.ServiceSpec{Type: "LoadBalancer"}
+
+		spec := Pooler(cluster, "batch").Spec.Proxy.PGBouncer
+		assert.Equal(t, *spec.Port, int32(6543))
+		assert.Equal(t, *spec.Replicas, int32(3))
+		assert.DeepEqual(t, spec.Resources, *pooler.Resources)
+		assert.Equal(t, spec.Service.Type, "LoadBalancer")
+	})
+
+	t.Run("Pools", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Config.Pools = &v1beta1.PGBouncerPoolSettings{
+			PoolMode: "transaction",
+		}
+
+		spec := Pooler(cluster, "batch").Spec.Proxy.PGBouncer
+		assert.Equal(t, spec.Config.Pools.PoolMode, "transaction")
+
+		cluster.Spec.Proxy.Poolers[0].Config.Pools = &v1beta1.PGBouncerPoolSettings{
+			PoolMode: "session",
+		}
+
+		spec = Pooler(cluster, "batch").Spec.Proxy.PGBouncer
+		assert.Equal(t, spec.Config.Pools.PoolMode, "session")
+	})
+}
@@ -17,14 +17,19 @@ package pgbouncer
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"net/url"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/crunchydata/postgres-operator/internal/config"
 	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/naming"
+	"github.com/crunchydata/postgres-operator/internal/pgmonitor"
 	"github.com/crunchydata/postgres-operator/internal/pki"
 	"github.com/crunchydata/postgres-operator/internal/postgres"
 	"github.com/crunchydata/postgres-operator/internal/util"
@@ -45,6 +50,12 @@ func ConfigMap(
 
 	outConfigMap.Data[emptyConfigMapKey] = ""
 	outConfigMap.Data[iniFileConfigMapKey] = clusterINI(inCluster)
+
+	if hbaEnabled(inCluster) {
+		outConfigMap.Data[hbaFileConfigMapKey] = hbaFileContents(inCluster)
+	} else {
+		delete(outConfigMap.Data, hbaFileConfigMapKey)
+	}
 }
 
 // Secret populates the PgBouncer Secret.
@@ -53,6 +64,8 @@ func Secret(ctx context.Context,
 	inRoot *pki.RootCertificateAuthority,
 	inSecret *corev1.Secret,
 	inService *corev1.Service,
+	inMonitoringSecret *corev1.Secret,
+	inConsoleSecret *corev1.Secret,
 	outSecret *corev1.Secret,
 ) error {
 	if inCluster.Spec.Proxy == nil || inCluster.Spec.Proxy.PGBouncer == nil {
@@ -74,19 +87,44 @@ func Secret(ctx context.Context,
 		err = errors.WithStack(err)
 	}
 
+	// The monitoring user authenticates to the admin console using the same
+	// SCRAM verifier it has in PostgreSQL.
+	others := map[string]string{}
+	if pgmonitor.PGBouncerMetricsEnabled(inCluster) && inMonitoringSecret != nil {
+		others[pgmonitor.MonitoringUser] = string(inMonitoringSecret.Data["verifier"])
+	}
+
+	// Admin console users authenticate using the SCRAM verifiers generated
+	// by [ConsoleSecret].
+	if spec := inCluster.Spec.Proxy.PGBouncer.Authentication; spec != nil && inConsoleSecret != nil {
+		for _, user := range spec.ConsoleUsers {
+			if verifier := inConsoleSecret.Data[user.Name+"-verifier"]; len(verifier) > 0 {
+				others[user.Name] = string(verifier)
+			}
+		}
+	}
+
 	if err == nil {
 		// Store the SCRAM verifier alongside the plaintext password so that
 		// later reconciles don't generate it repeatedly.
-		outSecret.Data[authFileSecretKey] = authFileContents(password)
+		outSecret.Data[authFileSecretKey] = authFileContents(password, others)
 		outSecret.Data[passwordSecretKey] = []byte(password)
 		outSecret.Data[verifierSecretKey] = []byte(verifier)
 	}
 
-	if inCluster.Spec.Proxy.PGBouncer.CustomTLSSecret == nil {
+	if customCertificate(inCluster) == nil {
 		leaf := &pki.LeafCertificate{}
 		dnsNames := naming.ServiceDNSNames(ctx, inService)
 		dnsFQDN := dnsNames[0]
 
+		// Additional poolers share this certificate, so it includes their
+		// Service names as well.
+		for _, name := range PoolerNames(inCluster) {
+			dnsNames = append(dnsNames, naming.ServiceDNSNames(ctx, &corev1.Service{
+				ObjectMeta: naming.ClusterPGBouncerPooler(inCluster, name),
+			})...)
+		}
+
 		if err == nil {
 			// Unmarshal and validate the stored leaf. These first errors can
 			// be ignored because they result in an invalid leaf which is then
@@ -99,7 +137,7 @@ func Secret(ctx context.Context,
 		}
 
 		if err == nil {
-			outSecret.Data[certFrontendAuthoritySecretKey], err = inRoot.Certificate.MarshalText()
+			outSecret.Data[certFrontendAuthoritySecretKey], err = inRoot.Authorities().MarshalText()
 		}
 		if err == nil {
 			outSecret.Data[certFrontendPrivateKeySecretKey], err = leaf.PrivateKey.MarshalText()
@@ -112,6 +150,55 @@ func Secret(ctx context.Context,
 	return err
 }
 
+// ConsoleSecret populates the Secret of PgBouncer admin console users. Each
+// user has a password, a SCRAM verifier, and a connection URI.
+func ConsoleSecret(
+	inCluster *v1beta1.PostgresCluster,
+	inSecret *corev1.Secret,
+	inService *corev1.Service,
+	outSecret *corev1.Secret,
+) error {
+	if inCluster.Spec.Proxy == nil || inCluster.Spec.Proxy.PGBouncer == nil ||
+		inCluster.Spec.Proxy.PGBouncer.Authentication == nil {
+		// There are no console users; there is nothing to do.
+		return nil
+	}
+
+	var err error
+	initialize.ByteMap(&outSecret.Data)
+
+	hostname := inService.Name + "." + inService.Namespace + ".svc"
+	port := fmt.Sprint(*inCluster.Spec.Proxy.PGBouncer.Port)
+
+	outSecret.Data["host"] = []byte(hostname)
+	outSecret.Data["port"] = []byte(port)
+	outSecret.Data["dbname"] = []byte("pgbouncer")
+
+	for _, user := range inCluster.Spec.Proxy.PGBouncer.Authentication.ConsoleUsers {
+		// Use the existing password and verifier. Generate both when either is missing.
+		password := string(inSecret.Data[user.Name+"-password"])
+		verifier := string(inSecret.Data[user.Name+"-verifier"])
+
+		if err == nil && (len(password) == 0 || len(verifier) == 0) {
+			password, verifier, err = generatePassword()
+			err = errors.WithStack(err)
+		}
+
+		if err == nil {
+			outSecret.Data[user.Name+"-password"] = []byte(password)
+			outSecret.Data[user.Name+"-verifier"] = []byte(verifier)
+			outSecret.Data[user.Name+"-uri"] = []byte((&url.URL{
+				Scheme: "postgresql",
+				User:   url.UserPassword(user.Name, password),
+				Host:   net.JoinHostPort(hostname, port),
+				Path:   "pgbouncer",
+			}).String())
+		}
+	}
+
+	return err
+}
+
 // Pod populates a PodSpec with the container and volumes needed to run PgBouncer.
 func Pod(
 	inCluster *v1beta1.PostgresCluster,
@@ -132,7 +219,7 @@ func Pod(
 	configVolume.Projected = &corev1.ProjectedVolumeSource{
 		Sources: append(append([]corev1.VolumeProjection{},
 			podConfigFiles(inCluster.Spec.Proxy.PGBouncer.Config, inConfigMap, inSecret)...),
-			frontendCertificate(inCluster.Spec.Proxy.PGBouncer.CustomTLSSecret, inSecret),
+			frontendCertificate(customCertificate(inCluster), inSecret),
 			backendAuthority(inPostgreSQLCertificate),
 		),
 	}
@@ -155,8 +242,47 @@ func Pod(
 		VolumeMounts: []corev1.VolumeMount{configVolumeMount},
 	}
 
-	// TODO container.LivenessProbe?
-	// TODO container.ReadinessProbe?
+	// Probes and the drain hook connect to the admin console as the "auth_user".
+	// Its password comes from the same Secret as the authentication file.
+	container.Env = []corev1.EnvVar{{
+		Name: "PGPASSWORD",
+		ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: inSecret.Name},
+			Key:                  passwordSecretKey,
+		}},
+	}}
+
+	// PgBouncer is alive so long as it is accepting connections. Ready means
+	// it is also authenticating clients and answering in the admin console.
+	container.LivenessProbe = &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			TCPSocket: &corev1.TCPSocketAction{
+				Port: intstr.FromString(naming.PortPGBouncer),
+			},
+		},
+		PeriodSeconds:    10,
+		FailureThreshold: 3,
+	}
+	container.ReadinessProbe = &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{
+				Command: adminCommand(*inCluster.Spec.Proxy.PGBouncer.Port, "SHOW VERSION"),
+			},
+		},
+		PeriodSeconds:    10,
+		TimeoutSeconds:   5,
+		FailureThreshold: 3,
+	}
+
+	// Let clients finish before PgBouncer is signaled to stop. Kubernetes waits
+	// for this hook up to the pod's termination grace period.
+	container.Lifecycle = &corev1.Lifecycle{
+		PreStop: &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{
+				Command: drainCommand(*inCluster.Spec.Proxy.PGBouncer.Port),
+			},
+		},
+	}
 
 	reloader := corev1.Container{
 		Name: naming.ContainerPGBouncerConfig,
@@ -197,6 +323,7 @@ func Pod(
 	}
 
 	outPod.Volumes = []corev1.Volume{configVolume}
+	outPod.TerminationGracePeriodSeconds = inCluster.Spec.Proxy.PGBouncer.TerminationGracePeriodSeconds
 }
 
 // PostgreSQL populates outHBAs with any records needed to run PgBouncer.
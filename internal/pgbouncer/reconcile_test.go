@@ -17,13 +17,17 @@ package pgbouncer
 
 import (
 	"context"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"gotest.tools/v3/assert"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/pki"
 	"github.com/crunchydata/postgres-operator/internal/postgres"
 	"github.com/crunchydata/postgres-operator/internal/util"
@@ -57,6 +61,21 @@ func TestConfigMap(t *testing.T) {
 	before := config.DeepCopy()
 	ConfigMap(cluster, config)
 	assert.DeepEqual(t, before, config)
+
+	t.Run("HBA", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Authentication = &v1beta1.PGBouncerAuthenticationSpec{
+			Rules: []v1beta1.PostgresHBARule{{Connection: "hostssl", Method: "md5"}},
+		}
+
+		ConfigMap(cluster, config)
+		assert.DeepEqual(t, config.Data["pgbouncer-hba.conf"], hbaFileContents(cluster))
+
+		// The file goes away with the rules.
+		cluster.Spec.Proxy.PGBouncer.Authentication = nil
+		ConfigMap(cluster, config)
+		assert.DeepEqual(t, before, config)
+	})
 }
 
 func TestSecret(t *testing.T) {
@@ -74,7 +93,7 @@ func TestSecret(t *testing.T) {
 	t.Run("Disabled", func(t *testing.T) {
 		// Nothing happens when PgBouncer is disabled.
 		constant := intent.DeepCopy()
-		assert.NilError(t, Secret(ctx, cluster, root, existing, service, intent))
+		assert.NilError(t, Secret(ctx, cluster, root, existing, service, nil, nil, intent))
 		assert.DeepEqual(t, constant, intent)
 	})
 
@@ -83,7 +102,7 @@ func TestSecret(t *testing.T) {
 	cluster.Default()
 
 	constant := existing.DeepCopy()
-	assert.NilError(t, Secret(ctx, cluster, root, existing, service, intent))
+	assert.NilError(t, Secret(ctx, cluster, root, existing, service, nil, nil, intent))
 	assert.DeepEqual(t, constant, existing)
 
 	// A password should be generated.
@@ -96,7 +115,91 @@ func TestSecret(t *testing.T) {
 	// Assuming the intent is written, no change when called again.
 	existing.Data = intent.Data
 	before := intent.DeepCopy()
-	assert.NilError(t, Secret(ctx, cluster, root, existing, service, intent))
+	assert.NilError(t, Secret(ctx, cluster, root, existing, service, nil, nil, intent))
+	assert.DeepEqual(t, before, intent)
+
+	t.Run("Monitoring", func(t *testing.T) {
+		monitoring := &corev1.Secret{Data: map[string][]byte{
+			"password": []byte("pass"), "verifier": []byte("SCRAM-SHA-256$some"),
+		}}
+
+		// The monitoring user is absent until PgBouncer metrics are enabled.
+		assert.NilError(t, Secret(ctx, cluster, root, existing, service, monitoring, nil, intent))
+		assert.Assert(t, !strings.Contains(string(intent.Data["pgbouncer-users.txt"]), "ccp_monitoring"))
+
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Monitoring = &v1beta1.MonitoringSpec{PGMonitor: &v1beta1.PGMonitorSpec{
+			Exporter: &v1beta1.ExporterSpec{PGBouncer: true},
+		}}
+
+		assert.NilError(t, Secret(ctx, cluster, root, existing, service, monitoring, nil, intent))
+		assert.Assert(t, strings.Contains(string(intent.Data["pgbouncer-users.txt"]),
+			`"ccp_monitoring" "SCRAM-SHA-256$some"`))
+	})
+
+	t.Run("ConsoleUsers", func(t *testing.T) {
+		console := &corev1.Secret{Data: map[string][]byte{
+			"ops-password": []byte("pass"), "ops-verifier": []byte("SCRAM-SHA-256$ops"),
+			"gone-password": []byte("pass"), "gone-verifier": []byte("SCRAM-SHA-256$gone"),
+		}}
+
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Proxy.PGBouncer.Authentication = &v1beta1.PGBouncerAuthenticationSpec{
+			ConsoleUsers: []v1beta1.PGBouncerConsoleUser{{Name: "ops"}},
+		}
+
+		assert.NilError(t, Secret(ctx, cluster, root, existing, service, nil, console, intent))
+		assert.Assert(t, strings.Contains(string(intent.Data["pgbouncer-users.txt"]),
+			`"ops" "SCRAM-SHA-256$ops"`))
+		assert.Assert(t, !strings.Contains(string(intent.Data["pgbouncer-users.txt"]), "gone"),
+			"expected only users in the spec")
+	})
+}
+
+func TestConsoleSecret(t *testing.T) {
+	t.Parallel()
+
+	cluster := new(v1beta1.PostgresCluster)
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "hippo-pgbouncer"}}
+	existing := new(corev1.Secret)
+	intent := new(corev1.Secret)
+
+	t.Run("Disabled", func(t *testing.T) {
+		// Nothing happens when there are no console users.
+		constant := intent.DeepCopy()
+		assert.NilError(t, ConsoleSecret(cluster, existing, service, intent))
+		assert.DeepEqual(t, constant, intent)
+	})
+
+	cluster.Spec.Proxy = new(v1beta1.PostgresProxySpec)
+	cluster.Spec.Proxy.PGBouncer = new(v1beta1.PGBouncerPodSpec)
+	cluster.Spec.Proxy.PGBouncer.Authentication = &v1beta1.PGBouncerAuthenticationSpec{
+		ConsoleUsers: []v1beta1.PGBouncerConsoleUser{{Name: "ops", Role: "Admin"}},
+	}
+	cluster.Default()
+
+	assert.NilError(t, ConsoleSecret(cluster, existing, service, intent))
+	assert.Equal(t, string(intent.Data["host"]), "hippo-pgbouncer.ns1.svc")
+	assert.Equal(t, string(intent.Data["port"]), "5432")
+	assert.Equal(t, string(intent.Data["dbname"]), "pgbouncer")
+
+	// A password should be generated.
+	password := string(intent.Data["ops-password"])
+	assert.Assert(t, len(password) != 0)
+	assert.Assert(t, strings.HasPrefix(string(intent.Data["ops-verifier"]), "SCRAM-SHA-256$"))
+
+	uri, err := url.Parse(string(intent.Data["ops-uri"]))
+	assert.NilError(t, err)
+	assert.Equal(t, uri.Host, "hippo-pgbouncer.ns1.svc:5432")
+	assert.Equal(t, uri.Path, "/pgbouncer")
+	assert.Equal(t, uri.User.Username(), "ops")
+	secret, _ := uri.User.Password()
+	assert.Equal(t, secret, password)
+
+	// Assuming the intent is written, no change when called again.
+	existing.Data = intent.Data
+	before := intent.DeepCopy()
+	assert.NilError(t, ConsoleSecret(cluster, existing, service, intent))
 	assert.DeepEqual(t, before, intent)
 }
 
@@ -134,11 +237,51 @@ containers:
 - command:
   - pgbouncer
   - /etc/pgbouncer/~postgres-operator.ini
+  env:
+  - name: PGPASSWORD
+    valueFrom:
+      secretKeyRef:
+        key: pgbouncer-password
+  lifecycle:
+    preStop:
+      exec:
+        command:
+        - bash
+        - -ceu
+        - --
+        - |
+          if psql --no-psqlrc --quiet "$1" --command='SHUTDOWN WAIT_FOR_CLIENTS'
+          then
+            exec {fd}<> <(:)
+            while pgrep --exact pgbouncer > /dev/null; do read -r -t 1 -u "${fd}" || true; done
+          else
+            psql --no-psqlrc --quiet "$1" --command='PAUSE'
+          fi
+        - drain
+        - host=localhost port=5432 dbname=pgbouncer user=_crunchypgbouncer sslmode=require
+          connect_timeout=5
+  livenessProbe:
+    failureThreshold: 3
+    periodSeconds: 10
+    tcpSocket:
+      port: pgbouncer
   name: pgbouncer
   ports:
   - containerPort: 5432
     name: pgbouncer
     protocol: TCP
+  readinessProbe:
+    exec:
+      command:
+      - psql
+      - --no-psqlrc
+      - --quiet
+      - host=localhost port=5432 dbname=pgbouncer user=_crunchypgbouncer sslmode=require
+        connect_timeout=5
+      - --command=SHOW VERSION
+    failureThreshold: 3
+    periodSeconds: 10
+    timeoutSeconds: 5
   resources: {}
   securityContext:
     allowPrivilegeEscalation: false
@@ -229,6 +372,7 @@ volumes:
 		cluster.Spec.Proxy.PGBouncer.Resources.Requests = corev1.ResourceList{
 			corev1.ResourceCPU: resource.MustParse("100m"),
 		}
+		cluster.Spec.Proxy.PGBouncer.TerminationGracePeriodSeconds = initialize.Int64(90)
 		cluster.Spec.Proxy.PGBouncer.CustomTLSSecret = &corev1.SecretProjection{
 			LocalObjectReference: corev1.LocalObjectReference{Name: "tls-name"},
 			Items: []corev1.KeyToPath{
@@ -244,13 +388,53 @@ containers:
 - command:
   - pgbouncer
   - /etc/pgbouncer/~postgres-operator.ini
+  env:
+  - name: PGPASSWORD
+    valueFrom:
+      secretKeyRef:
+        key: pgbouncer-password
   image: image-town
   imagePullPolicy: Always
+  lifecycle:
+    preStop:
+      exec:
+        command:
+        - bash
+        - -ceu
+        - --
+        - |
+          if psql --no-psqlrc --quiet "$1" --command='SHUTDOWN WAIT_FOR_CLIENTS'
+          then
+            exec {fd}<> <(:)
+            while pgrep --exact pgbouncer > /dev/null; do read -r -t 1 -u "${fd}" || true; done
+          else
+            psql --no-psqlrc --quiet "$1" --command='PAUSE'
+          fi
+        - drain
+        - host=localhost port=5432 dbname=pgbouncer user=_crunchypgbouncer sslmode=require
+          connect_timeout=5
+  livenessProbe:
+    failureThreshold: 3
+    periodSeconds: 10
+    tcpSocket:
+      port: pgbouncer
   name: pgbouncer
   ports:
   - containerPort: 5432
     name: pgbouncer
     protocol: TCP
+  readinessProbe:
+    exec:
+      command:
+      - psql
+      - --no-psqlrc
+      - --quiet
+      - host=localhost port=5432 dbname=pgbouncer user=_crunchypgbouncer sslmode=require
+        connect_timeout=5
+      - --command=SHOW VERSION
+    failureThreshold: 3
+    periodSeconds: 10
+    timeoutSeconds: 5
   resources:
     requests:
       cpu: 100m
@@ -306,6 +490,7 @@ containers:
   - mountPath: /etc/pgbouncer
     name: pgbouncer-config
     readOnly: true
+terminationGracePeriodSeconds: 90
 volumes:
 - name: pgbouncer-config
   projected:
@@ -354,13 +539,53 @@ containers:
 - command:
   - pgbouncer
   - /etc/pgbouncer/~postgres-operator.ini
+  env:
+  - name: PGPASSWORD
+    valueFrom:
+      secretKeyRef:
+        key: pgbouncer-password
   image: image-town
   imagePullPolicy: Always
+  lifecycle:
+    preStop:
+      exec:
+        command:
+        - bash
+        - -ceu
+        - --
+        - |
+          if psql --no-psqlrc --quiet "$1" --command='SHUTDOWN WAIT_FOR_CLIENTS'
+          then
+            exec {fd}<> <(:)
+            while pgrep --exact pgbouncer > /dev/null; do read -r -t 1 -u "${fd}" || true; done
+          else
+            psql --no-psqlrc --quiet "$1" --command='PAUSE'
+          fi
+        - drain
+        - host=localhost port=5432 dbname=pgbouncer user=_crunchypgbouncer sslmode=require
+          connect_timeout=5
+  livenessProbe:
+    failureThreshold: 3
+    periodSeconds: 10
+    tcpSocket:
+      port: pgbouncer
   name: pgbouncer
   ports:
   - containerPort: 5432
     name: pgbouncer
     protocol: TCP
+  readinessProbe:
+    exec:
+      command:
+      - psql
+      - --no-psqlrc
+      - --quiet
+      - host=localhost port=5432 dbname=pgbouncer user=_crunchypgbouncer sslmode=require
+        connect_timeout=5
+      - --command=SHOW VERSION
+    failureThreshold: 3
+    periodSeconds: 10
+    timeoutSeconds: 5
   resources:
     requests:
       cpu: 100m
@@ -415,6 +640,7 @@ containers:
   - mountPath: /etc/pgbouncer
     name: pgbouncer-config
     readOnly: true
+terminationGracePeriodSeconds: 90
 volumes:
 - name: pgbouncer-config
   projected:
@@ -19,8 +19,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/util/sets"
+
 	"github.com/crunchydata/postgres-operator/internal/logging"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -46,8 +49,8 @@ var (
 	ExporterWebConfigFileFlag = "--web.config.file=/web-config/web-config.yml"
 )
 
-// Defaults for certain values used in queries.yml
-// TODO(dsessler7): make these values configurable via spec
+// Defaults for certain values used in queries.yml. These can be changed in
+// the spec; see [valuesForQueries].
 var DefaultValuesForQueries = map[string]string{
 	"PGBACKREST_INFO_THROTTLE_MINUTES":    "10",
 	"PG_STAT_STATEMENTS_LIMIT":            "20",
@@ -60,13 +63,17 @@ func GenerateDefaultExporterQueries(ctx context.Context, cluster *v1beta1.Postgr
 	var queries string
 	baseQueries := []string{"backrest", "global", "global_dbsize", "per_db", "nodemx"}
 	queriesConfigDir := GetQueriesConfigDir(ctx)
+	excludedFiles, excludedQueries := excludedQueries(cluster)
 
-	// TODO: When we add pgbouncer support we will do something like the following:
-	// if pgbouncerEnabled() {
-	// 	baseQueries = append(baseQueries, "pgbouncer")
-	// }
+	// PgBouncer statistics are read through pgbouncer_fdw in PostgreSQL.
+	if PGBouncerMetricsEnabled(cluster) {
+		baseQueries = append(baseQueries, "pgbouncer")
+	}
 
 	for _, queryType := range baseQueries {
+		if excludedFiles.Has(queryType) {
+			continue
+		}
 		queriesContents, err := os.ReadFile(fmt.Sprintf("%s/queries_%s.yml", queriesConfigDir, queryType))
 		if err != nil {
 			// log an error, but continue to next iteration
@@ -77,25 +84,29 @@ func GenerateDefaultExporterQueries(ctx context.Context, cluster *v1beta1.Postgr
 	}
 
 	// Add general queries for specific postgres version
-	queriesGeneral, err := os.ReadFile(fmt.Sprintf("%s/pg%d/queries_general.yml", queriesConfigDir, cluster.Spec.PostgresVersion))
-	if err != nil {
-		// log an error, but continue
-		log.Error(err, fmt.Sprintf("Query file %s/pg%d/queries_general.yml does not exist (it should)...", queriesConfigDir, cluster.Spec.PostgresVersion))
-	} else {
-		queries += string(queriesGeneral) + "\n"
+	if !excludedFiles.Has("general") {
+		queriesGeneral, err := os.ReadFile(fmt.Sprintf("%s/pg%d/queries_general.yml", queriesConfigDir, cluster.Spec.PostgresVersion))
+		if err != nil {
+			// log an error, but continue
+			log.Error(err, fmt.Sprintf("Query file %s/pg%d/queries_general.yml does not exist (it should)...", queriesConfigDir, cluster.Spec.PostgresVersion))
+		} else {
+			queries += string(queriesGeneral) + "\n"
+		}
 	}
 
 	// Add pg_stat_statement queries for specific postgres version
-	queriesPgStatStatements, err := os.ReadFile(fmt.Sprintf("%s/pg%d/queries_pg_stat_statements.yml", queriesConfigDir, cluster.Spec.PostgresVersion))
-	if err != nil {
-		// log an error, but continue
-		log.Error(err, fmt.Sprintf("Query file %s/pg%d/queries_pg_stat_statements.yml not loaded.", queriesConfigDir, cluster.Spec.PostgresVersion))
-	} else {
-		queries += string(queriesPgStatStatements) + "\n"
+	if !excludedFiles.Has("pg_stat_statements") {
+		queriesPgStatStatements, err := os.ReadFile(fmt.Sprintf("%s/pg%d/queries_pg_stat_statements.yml", queriesConfigDir, cluster.Spec.PostgresVersion))
+		if err != nil {
+			// log an error, but continue
+			log.Error(err, fmt.Sprintf("Query file %s/pg%d/queries_pg_stat_statements.yml not loaded.", queriesConfigDir, cluster.Spec.PostgresVersion))
+		} else {
+			queries += string(queriesPgStatStatements) + "\n"
+		}
 	}
 
 	// If postgres version >= 12, add pg_stat_statements_reset queries
-	if cluster.Spec.PostgresVersion >= 12 {
+	if cluster.Spec.PostgresVersion >= 12 && !excludedFiles.Has("pg_stat_statements_reset_info") {
 		queriesPgStatStatementsReset, err := os.ReadFile(fmt.Sprintf("%s/pg%d/queries_pg_stat_statements_reset_info.yml", queriesConfigDir, cluster.Spec.PostgresVersion))
 		if err != nil {
 			// log an error, but continue
@@ -106,13 +117,77 @@ func GenerateDefaultExporterQueries(ctx context.Context, cluster *v1beta1.Postgr
 	}
 
 	// Find and replace default values in queries
-	for k, v := range DefaultValuesForQueries {
+	for k, v := range valuesForQueries(cluster) {
 		queries = strings.ReplaceAll(queries, fmt.Sprintf("#%s#", k), v)
 	}
 
-	// TODO: Add ability to exclude certain user-specified queries
+	return removeQueries(queries, excludedQueries)
+}
+
+// valuesForQueries returns [DefaultValuesForQueries] overridden by any
+// settings in the spec of cluster.
+func valuesForQueries(cluster *v1beta1.PostgresCluster) map[string]string {
+	values := make(map[string]string, len(DefaultValuesForQueries))
+	for k, v := range DefaultValuesForQueries {
+		values[k] = v
+	}
+	if !ExporterEnabled(cluster) || cluster.Spec.Monitoring.PGMonitor.Exporter.Queries == nil {
+		return values
+	}
+
+	spec := cluster.Spec.Monitoring.PGMonitor.Exporter.Queries
+	for k, v := range map[string]*int32{
+		"PGBACKREST_INFO_THROTTLE_MINUTES":    spec.PGBackRestInfoThrottleMinutes,
+		"PG_STAT_STATEMENTS_LIMIT":            spec.PGStatStatementsLimit,
+		"PG_STAT_STATEMENTS_THROTTLE_MINUTES": spec.PGStatStatementsThrottleMinutes,
+	} {
+		if v != nil {
+			values[k] = fmt.Sprint(*v)
+		}
+	}
+	return values
+}
 
-	return queries
+// excludedQueries returns the built-in query files and queries that cluster
+// should not run.
+func excludedQueries(cluster *v1beta1.PostgresCluster) (files, queries sets.String) {
+	files, queries = sets.NewString(), sets.NewString()
+	if !ExporterEnabled(cluster) || cluster.Spec.Monitoring.PGMonitor.Exporter.Queries == nil {
+		return
+	}
+
+	spec := cluster.Spec.Monitoring.PGMonitor.Exporter.Queries
+	for _, file := range spec.ExcludeFiles {
+		files.Insert(string(file))
+	}
+	for _, query := range spec.ExcludeQueries {
+		queries.Insert(string(query))
+	}
+	return
+}
+
+// queryNameLine matches the first line of a query in a queries file. Each
+// query is a top-level key of the YAML.
+var queryNameLine = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*):`)
+
+// removeQueries returns queries without those in names. A query begins at a
+// line that starts with its name and continues until the next query.
+func removeQueries(queries string, names sets.String) string {
+	if names.Len() == 0 {
+		return queries
+	}
+
+	var b strings.Builder
+	skipping := false
+	for _, line := range strings.SplitAfter(queries, "\n") {
+		if match := queryNameLine.FindStringSubmatch(line); match != nil {
+			skipping = names.Has(match[1])
+		}
+		if !skipping {
+			b.WriteString(line)
+		}
+	}
+	return b.String()
 }
 
 // ExporterStartCommand generates an entrypoint that will create a master queries file and
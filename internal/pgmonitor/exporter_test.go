@@ -22,8 +22,10 @@ import (
 	"testing"
 
 	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/yaml"
 
+	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/testing/cmp"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -49,6 +51,85 @@ func TestGenerateDefaultExporterQueries(t *testing.T) {
 		assert.Assert(t, strings.Contains(queries, "ccp_pg_stat_statements_reset"),
 			"Queries do not contain 'ccp_pg_stat_statements_reset' query when they should.")
 	})
+
+	t.Run("PGBouncer", func(t *testing.T) {
+		queries := GenerateDefaultExporterQueries(ctx, cluster)
+		assert.Assert(t, !strings.Contains(queries, "ccp_pgbouncer"),
+			"Queries contain PgBouncer queries when they should not.")
+
+		cluster.Spec.Monitoring = &v1beta1.MonitoringSpec{PGMonitor: &v1beta1.PGMonitorSpec{
+			Exporter: &v1beta1.ExporterSpec{PGBouncer: true},
+		}}
+		cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{PGBouncer: &v1beta1.PGBouncerPodSpec{}}
+
+		queries = GenerateDefaultExporterQueries(ctx, cluster)
+		assert.Assert(t, strings.Contains(queries, "ccp_pgbouncer"),
+			"Queries do not contain PgBouncer queries when they should.")
+	})
+
+	t.Run("Exclude", func(t *testing.T) {
+		cluster := cluster.DeepCopy()
+		cluster.Spec.Monitoring.PGMonitor.Exporter.Queries = &v1beta1.ExporterQueriesSpec{
+			ExcludeFiles:   []v1beta1.ExporterQueryFile{"pgbouncer"},
+			ExcludeQueries: []v1beta1.ExporterQueryName{"ccp_database_size"},
+		}
+
+		queries := GenerateDefaultExporterQueries(ctx, cluster)
+		assert.Assert(t, !strings.Contains(queries, "ccp_pgbouncer"))
+		assert.Assert(t, !strings.Contains(queries, "ccp_database_size:"))
+	})
+}
+
+func TestValuesForQueries(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	assert.DeepEqual(t, valuesForQueries(cluster), DefaultValuesForQueries)
+
+	cluster.Spec.Monitoring = &v1beta1.MonitoringSpec{PGMonitor: &v1beta1.PGMonitorSpec{
+		Exporter: &v1beta1.ExporterSpec{Queries: &v1beta1.ExporterQueriesSpec{
+			PGStatStatementsLimit:           initialize.Int32(5),
+			PGStatStatementsThrottleMinutes: initialize.Int32(30),
+		}},
+	}}
+	assert.DeepEqual(t, valuesForQueries(cluster), map[string]string{
+		"PGBACKREST_INFO_THROTTLE_MINUTES":    "10",
+		"PG_STAT_STATEMENTS_LIMIT":            "5",
+		"PG_STAT_STATEMENTS_THROTTLE_MINUTES": "30",
+	})
+	assert.Equal(t, DefaultValuesForQueries["PG_STAT_STATEMENTS_LIMIT"], "20",
+		"expected no change to the defaults")
+}
+
+func TestRemoveQueries(t *testing.T) {
+	queries := strings.TrimSpace(`
+ccp_database_size:
+  query: "SELECT datname as dbname, pg_database_size(datname) as bytes FROM pg_catalog.pg_database"
+  metrics:
+    - dbname:
+        usage: "LABEL"
+
+# Table sizes can be expensive with many tables.
+ccp_table_size:
+  query: "SELECT 1"
+  metrics:
+    - size_bytes:
+        usage: "GAUGE"
+
+ccp_connection_stats:
+  query: "SELECT 2"
+`) + "\n"
+
+	assert.Equal(t, removeQueries(queries, nil), queries)
+	assert.Equal(t, removeQueries(queries, sets.NewString("ccp_table_size", "missing")), strings.TrimSpace(`
+ccp_database_size:
+  query: "SELECT datname as dbname, pg_database_size(datname) as bytes FROM pg_catalog.pg_database"
+  metrics:
+    - dbname:
+        usage: "LABEL"
+
+# Table sizes can be expensive with many tables.
+ccp_connection_stats:
+  query: "SELECT 2"
+`)+"\n")
 }
 
 func TestExporterStartCommand(t *testing.T) {
@@ -17,11 +17,14 @@ package pgmonitor
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/crunchydata/postgres-operator/internal/logging"
+	"github.com/crunchydata/postgres-operator/internal/naming"
 	"github.com/crunchydata/postgres-operator/internal/postgres"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -147,3 +150,140 @@ func EnableExporterInPostgreSQL(ctx context.Context, exec postgres.Executor,
 
 	return err
 }
+
+// pgbouncerServer is the foreign server through which pgbouncer_fdw reads from
+// the PgBouncer admin console. Older versions of the extension expect this name.
+// - https://github.com/CrunchyData/pgbouncer_fdw
+const pgbouncerServer = "pgbouncer"
+
+// pgbouncerServers returns the options of a foreign server for each PgBouncer
+// Service of cluster: that of spec.proxy.pgBouncer and one for every
+// additional pooler.
+func pgbouncerServers(cluster *v1beta1.PostgresCluster) map[string]map[string]string {
+	// PgBouncer requires TLS on client connections. Its Services are in the
+	// same namespace as PostgreSQL.
+	server := func(host string, port *int32) map[string]string {
+		return map[string]string{
+			"dbname":  "pgbouncer",
+			"host":    host,
+			"port":    fmt.Sprint(*port),
+			"sslmode": "require",
+		}
+	}
+
+	servers := map[string]map[string]string{
+		pgbouncerServer: server(naming.ClusterPGBouncer(cluster).Name, cluster.Spec.Proxy.PGBouncer.Port),
+	}
+	for _, pooler := range cluster.Spec.Proxy.Poolers {
+		servers[pgbouncerServer+"-"+pooler.Name] = server(
+			naming.ClusterPGBouncerPooler(cluster, pooler.Name).Name, pooler.Port)
+	}
+	return servers
+}
+
+// EnablePGBouncerMetricsInPostgreSQL installs pgbouncer_fdw in `database` and
+// points it at the PgBouncer Services of cluster, one foreign server each.
+// Versions of the extension with a targets table read from all of them; older
+// versions read only from spec.proxy.pgBouncer. Each Service chooses one of
+// its Pods, so statistics of a pooler that has more than one replica, or that
+// autoscales, come from one of its Pods at a time. The monitoring user reads
+// PgBouncer statistics with its own password.
+func EnablePGBouncerMetricsInPostgreSQL(ctx context.Context, exec postgres.Executor,
+	cluster *v1beta1.PostgresCluster, monitoringSecret *corev1.Secret, database string) error {
+	log := logging.FromContext(ctx)
+
+	servers, _ := json.Marshal(pgbouncerServers(cluster))
+
+	stdout, stderr, err := exec.ExecInDatabasesFromQuery(ctx,
+		`SELECT :'database'`,
+		strings.Join([]string{
+			// Quiet NOTICE messages from IF EXISTS statements.
+			// - https://www.postgresql.org/docs/current/runtime-config-client.html
+			`SET client_min_messages = WARNING;`,
+
+			// pgbouncer_fdw depends on dblink. Keep its objects alongside the
+			// other pgMonitor objects in the `monitor` schema.
+			`CREATE EXTENSION IF NOT EXISTS pgbouncer_fdw WITH SCHEMA monitor CASCADE;`,
+			`ALTER EXTENSION pgbouncer_fdw UPDATE;`,
+
+			// Drop the servers of poolers that are gone along with their user mappings.
+			`SELECT pg_catalog.format('DROP SERVER %I CASCADE', srvname)`,
+			`  FROM pg_catalog.pg_foreign_server`,
+			` WHERE srvname LIKE :'server' || '-%'`,
+			`   AND srvname NOT IN (SELECT pg_catalog.json_object_keys(:'servers'))`,
+			`\gexec`,
+
+			// Create each server then add or replace each of its options.
+			`SELECT pg_catalog.format('CREATE SERVER %I FOREIGN DATA WRAPPER dblink_fdw', t.key)`,
+			`  FROM pg_catalog.json_each(:'servers') t`,
+			` WHERE NOT EXISTS (SELECT 1 FROM pg_catalog.pg_foreign_server WHERE srvname = t.key)`,
+			`\gexec`,
+			`SELECT pg_catalog.format('ALTER SERVER %I OPTIONS (%s %I %L)', s.srvname,`,
+			`  CASE WHEN o.key IN (SELECT option_name FROM pg_catalog.pg_options_to_table(s.srvoptions))`,
+			`  THEN 'SET' ELSE 'ADD' END, o.key, o.value)`,
+			`  FROM pg_catalog.pg_foreign_server s, pg_catalog.json_each(:'servers') t,`,
+			`       pg_catalog.json_each_text(t.value) o`,
+			` WHERE s.srvname = t.key`,
+			`\gexec`,
+
+			// Map the monitoring user to itself in PgBouncer.
+			`SELECT pg_catalog.format('DROP USER MAPPING IF EXISTS FOR %I SERVER %I', :'username', t.key),`,
+			`       pg_catalog.format('CREATE USER MAPPING FOR %I SERVER %I OPTIONS (user %L, password %L)',`,
+			`         :'username', t.key, :'username', :'password'),`,
+			`       pg_catalog.format('GRANT USAGE ON FOREIGN SERVER %I TO %I', t.key, :'username')`,
+			`  FROM pg_catalog.json_each(:'servers') t`,
+			`\gexec`,
+			`GRANT SELECT ON ALL TABLES IN SCHEMA monitor TO :"username";`,
+
+			// Read from every server when the extension has a targets table.
+			`SELECT 'DELETE FROM monitor.pgbouncer_fdw_targets',`,
+			`       pg_catalog.format('INSERT INTO monitor.pgbouncer_fdw_targets (target_host, active)' ||`,
+			`         ' SELECT pg_catalog.json_object_keys(%L), true', :'servers')`,
+			` WHERE pg_catalog.to_regclass('monitor.pgbouncer_fdw_targets') IS NOT NULL`,
+			`\gexec`,
+		}, "\n"),
+		map[string]string{
+			"database": database,
+			"password": string(monitoringSecret.Data["password"]),
+			"server":   pgbouncerServer,
+			"servers":  string(servers),
+			"username": MonitoringUser,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful commands to stdout.
+		},
+	)
+
+	log.V(1).Info("enabled PgBouncer metrics", "database", database, "stdout", stdout, "stderr", stderr)
+
+	return err
+}
+
+// DisablePGBouncerMetricsInPostgreSQL removes the credentials that the
+// monitoring user has for each PgBouncer in `database`, if any.
+func DisablePGBouncerMetricsInPostgreSQL(ctx context.Context, exec postgres.Executor,
+	database string) error {
+	log := logging.FromContext(ctx)
+
+	stdout, stderr, err := exec.ExecInDatabasesFromQuery(ctx,
+		`SELECT :'database'`,
+		strings.Join([]string{
+			`SELECT pg_catalog.format('DROP USER MAPPING FOR %I SERVER %I', :'username', srvname)`,
+			`  FROM pg_catalog.pg_user_mappings`,
+			` WHERE (srvname = :'server' OR srvname LIKE :'server' || '-%') AND usename = :'username'`,
+			`\gexec`,
+		}, "\n"),
+		map[string]string{
+			"database": database,
+			"server":   pgbouncerServer,
+			"username": MonitoringUser,
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful commands to stdout.
+		},
+	)
+
+	log.V(1).Info("disabled PgBouncer metrics", "database", database, "stdout", stdout, "stderr", stderr)
+
+	return err
+}
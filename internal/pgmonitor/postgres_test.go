@@ -16,11 +16,15 @@
 package pgmonitor
 
 import (
+	"context"
+	"io"
 	"strings"
 	"testing"
 
 	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
 
+	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/postgres"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -99,3 +103,65 @@ func TestPostgreSQLParameters(t *testing.T) {
 		assert.Assert(t, strings.Contains(libs, "daisy"))
 	})
 }
+
+func TestEnablePGBouncerMetricsInPostgreSQL(t *testing.T) {
+	ctx := context.Background()
+	cluster := &v1beta1.PostgresCluster{}
+	cluster.Name = "hippo"
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{
+		PGBouncer: &v1beta1.PGBouncerPodSpec{},
+		Poolers:   []v1beta1.PGBouncerPoolerSpec{{Name: "batch", Port: initialize.Int32(6432)}},
+	}
+	cluster.Default()
+
+	secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("secret")}}
+
+	calls := 0
+	exec := func(
+		_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		calls++
+
+		b, err := io.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(string(b), "CREATE EXTENSION IF NOT EXISTS pgbouncer_fdw"))
+		assert.Assert(t, strings.Contains(string(b), "CREATE USER MAPPING FOR %I SERVER %I"))
+		assert.Assert(t, strings.Contains(string(b), "monitor.pgbouncer_fdw_targets"))
+
+		// There is one server for each PgBouncer Service.
+		script := strings.Join(command, "\n")
+		assert.Assert(t, strings.Contains(script, `--set=database=postgres`))
+		assert.Assert(t, strings.Contains(script, `--set=password=secret`))
+		assert.Assert(t, strings.Contains(script, `--set=server=pgbouncer`))
+		assert.Assert(t, strings.Contains(script, `--set=servers={`+
+			`"pgbouncer":{"dbname":"pgbouncer","host":"hippo-pgbouncer","port":"5432","sslmode":"require"},`+
+			`"pgbouncer-batch":{"dbname":"pgbouncer","host":"hippo-pgbouncer-batch","port":"6432","sslmode":"require"}}`),
+			"got %q", script)
+		return nil
+	}
+
+	assert.NilError(t, EnablePGBouncerMetricsInPostgreSQL(ctx, exec, cluster, secret, "postgres"))
+	assert.Equal(t, calls, 1)
+}
+
+func TestDisablePGBouncerMetricsInPostgreSQL(t *testing.T) {
+	ctx := context.Background()
+
+	calls := 0
+	exec := func(
+		_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+	) error {
+		calls++
+
+		b, err := io.ReadAll(stdin)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(string(b), "DROP USER MAPPING FOR"))
+
+		script := strings.Join(command, "\n")
+		assert.Assert(t, strings.Contains(script, `--set=username=ccp_monitoring`))
+		return nil
+	}
+
+	assert.NilError(t, DisablePGBouncerMetricsInPostgreSQL(ctx, exec, "postgres"))
+	assert.Equal(t, calls, 1)
+}
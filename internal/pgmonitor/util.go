@@ -49,3 +49,12 @@ func ExporterEnabled(cluster *v1beta1.PostgresCluster) bool {
 	}
 	return true
 }
+
+// PGBouncerMetricsEnabled returns true if the monitoring exporter should
+// collect PgBouncer statistics
+func PGBouncerMetricsEnabled(cluster *v1beta1.PostgresCluster) bool {
+	if !ExporterEnabled(cluster) || !cluster.Spec.Monitoring.PGMonitor.Exporter.PGBouncer {
+		return false
+	}
+	return cluster.Spec.Proxy != nil && cluster.Spec.Proxy.PGBouncer != nil
+}
@@ -37,3 +37,19 @@ func TestExporterEnabled(t *testing.T) {
 	assert.Assert(t, ExporterEnabled(cluster))
 
 }
+
+func TestPGBouncerMetricsEnabled(t *testing.T) {
+	cluster := &v1beta1.PostgresCluster{}
+	assert.Assert(t, !PGBouncerMetricsEnabled(cluster))
+
+	cluster.Spec.Monitoring = &v1beta1.MonitoringSpec{}
+	cluster.Spec.Monitoring.PGMonitor = &v1beta1.PGMonitorSpec{}
+	cluster.Spec.Monitoring.PGMonitor.Exporter = &v1beta1.ExporterSpec{}
+	assert.Assert(t, !PGBouncerMetricsEnabled(cluster))
+
+	cluster.Spec.Monitoring.PGMonitor.Exporter.PGBouncer = true
+	assert.Assert(t, !PGBouncerMetricsEnabled(cluster), "expected PgBouncer to be required")
+
+	cluster.Spec.Proxy = &v1beta1.PostgresProxySpec{PGBouncer: &v1beta1.PGBouncerPodSpec{}}
+	assert.Assert(t, PGBouncerMetricsEnabled(cluster))
+}
@@ -16,28 +16,53 @@
 package pki
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"math/big"
 	"time"
 )
 
-// certificateSignatureAlgorithm is ECDSA with SHA-384, the recommended
-// signature algorithm with the P-256 curve.
-const certificateSignatureAlgorithm = x509.ECDSAWithSHA384
+const (
+	// rsaKeySize is the size of RSA keys. Clients that cannot use ECDSA keys
+	// tend to be old, and 2048 bits is what they are most likely to support.
+	rsaKeySize = 2048
+
+	defaultLeafLifetime = time.Hour * 24 * 365
+	defaultRootLifetime = time.Hour * 24 * 365 * 10
+
+	// Certificates are valid a little before they are generated to allow for
+	// clock skew between machines.
+	leafStartValid = time.Hour * -1
+	rootStartValid = time.Hour * -1
+)
 
 // currentTime returns the current local time. It is a variable so it can be
 // replaced during testing.
 var currentTime = time.Now
 
-// generateKey returns a random ECDSA key using a P-256 curve. This curve is
-// roughly equivalent to an RSA 3072-bit key but requires less bits to achieve
-// the equivalent cryptographic strength. Additionally, ECDSA is FIPS 140-2
-// compliant.
-func generateKey() (*ecdsa.PrivateKey, error) {
+// certificateSignatureAlgorithm returns the algorithm to use when signing
+// with key. ECDSA with SHA-384 is the recommended signature algorithm with the
+// P-256 curve.
+func certificateSignatureAlgorithm(key crypto.Signer) x509.SignatureAlgorithm {
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		return x509.SHA256WithRSA
+	}
+	return x509.ECDSAWithSHA384
+}
+
+// generateKey returns a random key of algorithm. The default is an ECDSA key
+// using a P-256 curve. This curve is roughly equivalent to an RSA 3072-bit key
+// but requires less bits to achieve the equivalent cryptographic strength.
+// Additionally, ECDSA is FIPS 140-2 compliant.
+func generateKey(algorithm KeyAlgorithm) (crypto.Signer, error) {
+	if algorithm == RSA {
+		return rsa.GenerateKey(rand.Reader, rsaKeySize)
+	}
 	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 }
 
@@ -47,27 +72,29 @@ func generateSerialNumber() (*big.Int, error) {
 }
 
 func generateLeafCertificate(
-	signer *x509.Certificate, signerPrivate *ecdsa.PrivateKey,
-	signeePublic *ecdsa.PublicKey, serialNumber *big.Int,
-	commonName string, dnsNames []string,
+	signer *x509.Certificate, signerPrivate crypto.Signer,
+	signeePublic crypto.PublicKey, serialNumber *big.Int,
+	commonName string, dnsNames []string, lifetime time.Duration,
 ) (*x509.Certificate, error) {
-	const leafExpiration = time.Hour * 24 * 365
-	const leafStartValid = time.Hour * -1
-
 	now := currentTime()
 	template := &x509.Certificate{
 		BasicConstraintsValid: true,
 		DNSNames:              dnsNames,
 		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
 		NotBefore:             now.Add(leafStartValid),
-		NotAfter:              now.Add(leafExpiration),
+		NotAfter:              now.Add(lifetime),
 		SerialNumber:          serialNumber,
-		SignatureAlgorithm:    certificateSignatureAlgorithm,
+		SignatureAlgorithm:    certificateSignatureAlgorithm(signerPrivate),
 		Subject: pkix.Name{
 			CommonName: commonName,
 		},
 	}
 
+	// A certificate is not valid after its signer expires, so do not claim so.
+	if template.NotAfter.After(signer.NotAfter) {
+		template.NotAfter = signer.NotAfter
+	}
+
 	bytes, err := x509.CreateCertificate(rand.Reader, template, signer,
 		signeePublic, signerPrivate)
 
@@ -76,11 +103,9 @@ func generateLeafCertificate(
 }
 
 func generateRootCertificate(
-	privateKey *ecdsa.PrivateKey, serialNumber *big.Int,
+	privateKey crypto.Signer, serialNumber *big.Int, lifetime time.Duration,
 ) (*x509.Certificate, error) {
 	const rootCommonName = "postgres-operator-ca"
-	const rootExpiration = time.Hour * 24 * 365 * 10
-	const rootStartValid = time.Hour * -1
 
 	now := currentTime()
 	template := &x509.Certificate{
@@ -89,9 +114,9 @@ func generateRootCertificate(
 		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
 		MaxPathLenZero:        true, // there are no intermediate certificates
 		NotBefore:             now.Add(rootStartValid),
-		NotAfter:              now.Add(rootExpiration),
+		NotAfter:              now.Add(lifetime),
 		SerialNumber:          serialNumber,
-		SignatureAlgorithm:    certificateSignatureAlgorithm,
+		SignatureAlgorithm:    certificateSignatureAlgorithm(privateKey),
 		Subject: pkix.Name{
 			CommonName: rootCommonName,
 		},
@@ -31,6 +31,10 @@ const (
 	// pemLabelECDSAKey is the textual encoding label for an elliptic curve private key
 	// according to RFC 5915. See https://tools.ietf.org/html/rfc5915.
 	pemLabelECDSAKey = "EC PRIVATE KEY"
+
+	// pemLabelRSAKey is the textual encoding label for an RSA private key
+	// according to RFC 8017. See https://tools.ietf.org/html/rfc8017.
+	pemLabelRSAKey = "RSA PRIVATE KEY"
 )
 
 var (
@@ -67,6 +71,52 @@ func (c *Certificate) UnmarshalText(data []byte) error {
 	return err
 }
 
+var (
+	_ encoding.TextMarshaler   = Certificates{}
+	_ encoding.TextUnmarshaler = (*Certificates)(nil)
+)
+
+// MarshalText returns the PEM encodings of cs one after the other.
+func (cs Certificates) MarshalText() ([]byte, error) {
+	if len(cs) == 0 {
+		return Certificate{}.MarshalText()
+	}
+
+	var out []byte
+	for i := range cs {
+		b, err := cs[i].MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+	}
+	return out, nil
+}
+
+// UnmarshalText populates cs from the PEM encodings in data.
+func (cs *Certificates) UnmarshalText(data []byte) error {
+	var result Certificates
+
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != pemLabelCertificate {
+			return fmt.Errorf("not a PEM-encoded certificate")
+		}
+
+		parsed, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return err
+		}
+		result = append(result, Certificate{x509: parsed})
+	}
+
+	if len(result) == 0 {
+		return fmt.Errorf("not a PEM-encoded certificate")
+	}
+
+	*cs = result
+	return nil
+}
+
 var (
 	_ encoding.TextMarshaler   = PrivateKey{}
 	_ encoding.TextMarshaler   = (*PrivateKey)(nil)
@@ -75,6 +125,13 @@ var (
 
 // MarshalText returns a PEM encoding of k that OpenSSL understands.
 func (k PrivateKey) MarshalText() ([]byte, error) {
+	if k.rsa != nil {
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  pemLabelRSAKey,
+			Bytes: x509.MarshalPKCS1PrivateKey(k.rsa),
+		}), nil
+	}
+
 	if k.ecdsa == nil {
 		k.ecdsa = new(ecdsa.PrivateKey)
 	}
@@ -94,13 +151,21 @@ func (k PrivateKey) MarshalText() ([]byte, error) {
 func (k *PrivateKey) UnmarshalText(data []byte) error {
 	block, _ := pem.Decode(data)
 
+	if block != nil && block.Type == pemLabelRSAKey {
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err == nil {
+			k.ecdsa, k.rsa = nil, key
+		}
+		return err
+	}
+
 	if block == nil || block.Type != pemLabelECDSAKey {
 		return fmt.Errorf("not a PEM-encoded private key")
 	}
 
 	key, err := x509.ParseECPrivateKey(block.Bytes)
 	if err == nil {
-		k.ecdsa = key
+		k.ecdsa, k.rsa = key, nil
 	}
 	return err
 }
@@ -16,7 +16,9 @@
 package pki
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/rsa"
 	"crypto/x509"
 	"math/big"
 	"time"
@@ -24,12 +26,55 @@ import (
 
 const renewalRatio = 3
 
+// KeyAlgorithm is the kind of private key in a certificate.
+type KeyAlgorithm string
+
+const (
+	// ECDSA keys use the P-256 curve. This is the default.
+	ECDSA KeyAlgorithm = "ECDSA"
+
+	// RSA keys are 2048 bits.
+	RSA KeyAlgorithm = "RSA"
+)
+
+// Policy describes the certificates generated by this package. The zero value
+// generates ECDSA keys, ten-year root certificates, and one-year leaf
+// certificates.
+type Policy struct {
+	// KeyAlgorithm of generated private keys.
+	KeyAlgorithm KeyAlgorithm
+
+	// Lifetime of generated certificates.
+	Lifetime time.Duration
+}
+
+func (p Policy) keyAlgorithm() KeyAlgorithm {
+	if p.KeyAlgorithm == "" {
+		return ECDSA
+	}
+	return p.KeyAlgorithm
+}
+
+func (p Policy) lifetime(otherwise time.Duration) time.Duration {
+	if p.Lifetime <= 0 {
+		return otherwise
+	}
+	return p.Lifetime
+}
+
 // Certificate represents an X.509 certificate that conforms to the Internet
 // PKI Profile, RFC 5280.
 type Certificate struct{ x509 *x509.Certificate }
 
+// Certificates is a bundle of certificates, such as the authorities trusted
+// by a client or server.
+type Certificates []Certificate
+
 // PrivateKey represents the private key of a Certificate.
-type PrivateKey struct{ ecdsa *ecdsa.PrivateKey }
+type PrivateKey struct {
+	ecdsa *ecdsa.PrivateKey
+	rsa   *rsa.PrivateKey
+}
 
 // Equal reports whether c and other have the same value.
 func (c Certificate) Equal(other Certificate) bool {
@@ -53,6 +98,37 @@ func (c Certificate) DNSNames() []string {
 	return append([]string{}, c.x509.DNSNames...)
 }
 
+// KeyAlgorithm returns the kind of public key in the certificate.
+func (c Certificate) KeyAlgorithm() KeyAlgorithm {
+	if c.x509 == nil {
+		return ""
+	}
+	switch c.x509.PublicKeyAlgorithm {
+	case x509.ECDSA:
+		return ECDSA
+	case x509.RSA:
+		return RSA
+	}
+	return KeyAlgorithm(c.x509.PublicKeyAlgorithm.String())
+}
+
+// NotAfter returns the time after which the certificate is not valid.
+func (c Certificate) NotAfter() time.Time {
+	if c.x509 == nil {
+		return time.Time{}
+	}
+	return c.x509.NotAfter
+}
+
+// RenewalTime returns the time after which this package would replace the
+// certificate. Certificates are replaced when 1/3 of their lifetime remains.
+func (c Certificate) RenewalTime() time.Time {
+	if c.x509 == nil {
+		return time.Time{}
+	}
+	return renewalTime(c.x509.NotBefore, c.x509.NotAfter)
+}
+
 // hasSubject checks that c has these values in its subject.
 func (c Certificate) hasSubject(commonName string, dnsNames []string) bool {
 	ok := c.x509 != nil &&
@@ -68,12 +144,44 @@ func (c Certificate) hasSubject(commonName string, dnsNames []string) bool {
 
 // Equal reports whether k and other have the same value.
 func (k PrivateKey) Equal(other PrivateKey) bool {
+	if k.rsa != nil || other.rsa != nil {
+		return k.rsa != nil && k.rsa.Equal(other.rsa)
+	}
 	if k.ecdsa == nil || other.ecdsa == nil {
 		return k.ecdsa == other.ecdsa
 	}
 	return k.ecdsa.Equal(other.ecdsa)
 }
 
+// signer returns k as an interface that can sign certificates, or nil.
+func (k PrivateKey) signer() crypto.Signer {
+	switch {
+	case k.rsa != nil:
+		return k.rsa
+	case k.ecdsa != nil:
+		return k.ecdsa
+	}
+	return nil
+}
+
+// set stores key in k.
+func (k *PrivateKey) set(key crypto.Signer) {
+	k.ecdsa, _ = key.(*ecdsa.PrivateKey)
+	k.rsa, _ = key.(*rsa.PrivateKey)
+}
+
+// publicKeyEqual reports whether k is the private key of public.
+func (k PrivateKey) publicKeyEqual(public crypto.PublicKey) bool {
+	type equaler interface{ Equal(crypto.PublicKey) bool }
+
+	if signer := k.signer(); signer != nil {
+		if key, ok := signer.Public().(equaler); ok {
+			return key.Equal(public)
+		}
+	}
+	return false
+}
+
 // LeafCertificate is a certificate and private key pair that can be validated
 // by RootCertificateAuthority.
 type LeafCertificate struct {
@@ -86,21 +194,40 @@ type LeafCertificate struct {
 type RootCertificateAuthority struct {
 	Certificate Certificate
 	PrivateKey  PrivateKey
+
+	// LeafPolicy describes the certificates generated by this authority.
+	LeafPolicy Policy
+
+	// Trusted are other authorities that should be trusted alongside this one,
+	// such as those before or after it during a rotation.
+	Trusted Certificates
+}
+
+// Authorities returns the certificate of root followed by those it trusts.
+func (root *RootCertificateAuthority) Authorities() Certificates {
+	return append(Certificates{root.Certificate}, root.Trusted...)
 }
 
 // NewRootCertificateAuthority generates a new key and self-signed certificate
 // for issuing other certificates.
 func NewRootCertificateAuthority() (*RootCertificateAuthority, error) {
+	return NewRootCertificateAuthorityWithPolicy(Policy{})
+}
+
+// NewRootCertificateAuthorityWithPolicy generates a new key and self-signed
+// certificate according to policy for issuing other certificates.
+func NewRootCertificateAuthorityWithPolicy(policy Policy) (*RootCertificateAuthority, error) {
 	var root RootCertificateAuthority
 	var serial *big.Int
 
-	key, err := generateKey()
+	key, err := generateKey(policy.keyAlgorithm())
 	if err == nil {
 		serial, err = generateSerialNumber()
 	}
 	if err == nil {
-		root.PrivateKey.ecdsa = key
-		root.Certificate.x509, err = generateRootCertificate(key, serial)
+		root.PrivateKey.set(key)
+		root.Certificate.x509, err = generateRootCertificate(key, serial,
+			policy.lifetime(defaultRootLifetime))
 	}
 
 	return &root, err
@@ -136,28 +263,33 @@ func RootIsValid(root *RootCertificateAuthority) bool {
 
 	// It is signed by this private key.
 	ok = ok &&
-		root.PrivateKey.ecdsa != nil &&
-		root.PrivateKey.ecdsa.PublicKey.Equal(root.Certificate.x509.PublicKey)
+		root.PrivateKey.publicKeyEqual(root.Certificate.x509.PublicKey)
 
 	return ok
 }
 
-// GenerateLeafCertificate generates a new key and certificate signed by root.
+// RootSatisfiesPolicy checks if the key of root is the kind described by policy.
+func RootSatisfiesPolicy(root *RootCertificateAuthority, policy Policy) bool {
+	return root != nil && root.Certificate.KeyAlgorithm() == policy.keyAlgorithm()
+}
+
+// GenerateLeafCertificate generates a new key and certificate signed by root
+// according to its LeafPolicy.
 func (root *RootCertificateAuthority) GenerateLeafCertificate(
 	commonName string, dnsNames []string,
 ) (*LeafCertificate, error) {
 	var leaf LeafCertificate
 	var serial *big.Int
 
-	key, err := generateKey()
+	key, err := generateKey(root.LeafPolicy.keyAlgorithm())
 	if err == nil {
 		serial, err = generateSerialNumber()
 	}
 	if err == nil {
-		leaf.PrivateKey.ecdsa = key
+		leaf.PrivateKey.set(key)
 		leaf.Certificate.x509, err = generateLeafCertificate(
-			root.Certificate.x509, root.PrivateKey.ecdsa, &key.PublicKey, serial,
-			commonName, dnsNames)
+			root.Certificate.x509, root.PrivateKey.signer(), key.Public(), serial,
+			commonName, dnsNames, root.LeafPolicy.lifetime(defaultLeafLifetime))
 	}
 
 	return &leaf, err
@@ -194,8 +326,7 @@ func (root *RootCertificateAuthority) leafIsValid(leaf *LeafCertificate) bool {
 
 	// It is signed by this private key.
 	ok = ok &&
-		leaf.PrivateKey.ecdsa != nil &&
-		leaf.PrivateKey.ecdsa.PublicKey.Equal(leaf.Certificate.x509.PublicKey)
+		leaf.PrivateKey.publicKeyEqual(leaf.Certificate.x509.PublicKey)
 
 	// It is not yet past the "renewal by" time,
 	// as defined by the before and after times of the certificate's expiration
@@ -203,16 +334,27 @@ func (root *RootCertificateAuthority) leafIsValid(leaf *LeafCertificate) bool {
 	ok = ok && isBeforeRenewalTime(leaf.Certificate.x509.NotBefore,
 		leaf.Certificate.x509.NotAfter)
 
+	// It has the kind of key and a lifetime no longer than the policy allows.
+	ok = ok &&
+		leaf.Certificate.KeyAlgorithm() == root.LeafPolicy.keyAlgorithm() &&
+		leaf.Certificate.x509.NotAfter.Sub(leaf.Certificate.x509.NotBefore) <=
+			root.LeafPolicy.lifetime(defaultLeafLifetime)-leafStartValid
+
 	return ok
 }
 
+// renewalTime returns the time when 1/3rd of the lifetime between before and
+// after remains.
+func renewalTime(before, after time.Time) time.Time {
+	renewalDuration := after.Sub(before) / renewalRatio
+	return after.Add(-1 * renewalDuration)
+}
+
 // isBeforeRenewalTime checks if the result of `currentTime`
 // is after the default renewal time of
 // 1/3rds before the certificate's expiry
 func isBeforeRenewalTime(before, after time.Time) bool {
-	renewalDuration := after.Sub(before) / renewalRatio
-	renewalTime := after.Add(-1 * renewalDuration)
-	return currentTime().Before(renewalTime)
+	return currentTime().Before(renewalTime(before, after))
 }
 
 // RegenerateLeafWhenNecessary returns leaf when it is valid according to this
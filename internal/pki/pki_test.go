@@ -145,6 +145,19 @@ func TestRootCertificateAuthority(t *testing.T) {
 	// a proper chain in [TestLeafCertificate].
 }
 
+func TestRootCertificateAuthorityAuthorities(t *testing.T) {
+	root, err := NewRootCertificateAuthority()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, root.Authorities(), Certificates{root.Certificate})
+
+	other, err := NewRootCertificateAuthority()
+	assert.NilError(t, err)
+
+	root.Trusted = Certificates{other.Certificate}
+	assert.DeepEqual(t, root.Authorities(), Certificates{root.Certificate, other.Certificate})
+	assert.Equal(t, len(root.Trusted), 1, "expected no change to Trusted")
+}
+
 func TestRootIsInvalid(t *testing.T) {
 	t.Run("NoCertificate", func(t *testing.T) {
 		assert.Assert(t, !RootIsValid(nil))
@@ -183,7 +196,9 @@ func TestRootIsInvalid(t *testing.T) {
 		leaf, err := root.GenerateLeafCertificate("", nil)
 		assert.NilError(t, err)
 
-		assert.Assert(t, !RootIsValid((*RootCertificateAuthority)(leaf)))
+		assert.Assert(t, !RootIsValid(&RootCertificateAuthority{
+			Certificate: leaf.Certificate, PrivateKey: leaf.PrivateKey,
+		}))
 	})
 
 	t.Run("TooEarly", func(t *testing.T) {
@@ -367,7 +382,9 @@ func TestLeafIsInvalid(t *testing.T) {
 	})
 
 	t.Run("IsAuthority", func(t *testing.T) {
-		assert.Assert(t, !root.leafIsValid((*LeafCertificate)(root)))
+		assert.Assert(t, !root.leafIsValid(&LeafCertificate{
+			Certificate: root.Certificate, PrivateKey: root.PrivateKey,
+		}))
 	})
 
 	t.Run("TooEarly", func(t *testing.T) {
@@ -446,6 +463,66 @@ func TestRegenerateLeaf(t *testing.T) {
 	assert.Assert(t, !after.Certificate.Equal(before.Certificate))
 }
 
+func TestPolicy(t *testing.T) {
+	root, err := NewRootCertificateAuthorityWithPolicy(Policy{
+		KeyAlgorithm: RSA, Lifetime: time.Hour * 24 * 30,
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, RootIsValid(root))
+	assert.Assert(t, RootSatisfiesPolicy(root, Policy{KeyAlgorithm: RSA}))
+	assert.Assert(t, !RootSatisfiesPolicy(root, Policy{}))
+
+	assert.Equal(t, root.Certificate.KeyAlgorithm(), RSA)
+	assert.Equal(t, root.Certificate.x509.SignatureAlgorithm, x509.SHA256WithRSA)
+	assert.Assert(t, root.Certificate.NotAfter().Before(time.Now().Add(time.Hour*24*31)))
+
+	root.LeafPolicy = Policy{Lifetime: time.Hour * 24 * 7}
+
+	leaf, err := root.GenerateLeafCertificate("some-cn", nil)
+	assert.NilError(t, err)
+	assert.Assert(t, root.leafIsValid(leaf))
+
+	assert.Equal(t, leaf.Certificate.KeyAlgorithm(), ECDSA)
+	assert.Equal(t, leaf.Certificate.x509.SignatureAlgorithm, x509.SHA256WithRSA)
+	assert.Assert(t, leaf.Certificate.NotAfter().Before(time.Now().Add(time.Hour*24*8)))
+	assert.Assert(t, leaf.Certificate.RenewalTime().Before(leaf.Certificate.NotAfter()))
+
+	t.Run("OpenSSLVerify", func(t *testing.T) {
+		openssl := require.OpenSSL(t)
+		basicOpenSSLVerify(t, openssl, root.Certificate, leaf.Certificate)
+	})
+
+	t.Run("KeyAlgorithmChanged", func(t *testing.T) {
+		root := *root
+		root.LeafPolicy.KeyAlgorithm = RSA
+		assert.Assert(t, !root.leafIsValid(leaf))
+
+		next, err := root.RegenerateLeafWhenNecessary(leaf, "some-cn", nil)
+		assert.NilError(t, err)
+		assert.Equal(t, next.Certificate.KeyAlgorithm(), RSA)
+		assert.Assert(t, root.leafIsValid(next))
+	})
+
+	t.Run("LongerThanRoot", func(t *testing.T) {
+		root := *root
+		root.LeafPolicy = Policy{Lifetime: time.Hour * 24 * 365}
+
+		leaf, err := root.GenerateLeafCertificate("some-cn", nil)
+		assert.NilError(t, err)
+		assert.Assert(t, root.leafIsValid(leaf))
+		assert.Equal(t, leaf.Certificate.NotAfter(), root.Certificate.NotAfter())
+	})
+
+	t.Run("LifetimeShortened", func(t *testing.T) {
+		root := *root
+		root.LeafPolicy.Lifetime = time.Hour * 24
+		assert.Assert(t, !root.leafIsValid(leaf))
+
+		root.LeafPolicy.Lifetime = time.Hour * 24 * 14
+		assert.Assert(t, root.leafIsValid(leaf), "longer lifetimes are fine")
+	})
+}
+
 func basicOpenSSLVerify(t *testing.T, openssl string, root, leaf Certificate) {
 	verify := func(t testing.TB, args ...string) {
 		t.Helper()
@@ -0,0 +1,47 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/crunchydata/postgres-operator/internal/naming"
+)
+
+// BaseBackupCommand returns the command and arguments to copy a running
+// PostgreSQL server into a directory next to pgdata using "pg_basebackup". The
+// copy is moved into place when Patroni bootstraps the cluster. WAL files go to
+// waldir with a symlink from the copy.
+//
+// The connection is configured through the environment. Replication requires
+// a client certificate, so the mounted certificate files are copied to set
+// permissions PostgreSQL accepts.
+// - https://www.postgresql.org/docs/current/app-pgbasebackup.html
+// - https://www.postgresql.org/docs/current/libpq-envars.html
+func BaseBackupCommand(pgdata, waldir string) []string {
+	// Remove anything left by a previous attempt; pg_basebackup requires that
+	// both directories be empty or absent.
+	script := fmt.Sprintf(`declare -r pgdata="$1" waldir="$2"
+install -D --mode=0600 -t %q %q/{%s,%s,%s}
+rm -rf "${pgdata}_bootstrap" "${waldir}"
+pg_basebackup --pgdata="${pgdata}_bootstrap" --waldir="${waldir}" \
+  --wal-method=stream --checkpoint=fast --no-password --progress --verbose
+rm -f "${pgdata}_bootstrap/patroni.dynamic.json"`,
+		naming.ReplicationTmp, naming.CertMountPath+naming.ReplicationDirectory,
+		naming.ReplicationCert, naming.ReplicationPrivateKey, naming.ReplicationCACert)
+
+	return []string{"bash", "-ceu", "--", script, "-", pgdata, waldir}
+}
@@ -0,0 +1,52 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"context"
+	"strings"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+)
+
+// CertificateAuthoritiesLoaded returns true when the file of trusted
+// authorities that PostgreSQL reads contains exactly authorities. It returns
+// false when the file has other contents, such as when Kubernetes has not yet
+// updated a mounted Secret. PostgreSQL is signaled to reload shortly after its
+// certificate files change; see [reloadCommand].
+// - https://www.postgresql.org/docs/current/ssl-tcp.html#SSL-SERVER-FILES
+func CertificateAuthoritiesLoaded(ctx context.Context, exec Executor, authorities []byte) (bool, error) {
+	log := logging.FromContext(ctx)
+
+	// The comparison happens in PostgreSQL so that the file is read by the
+	// same user that will load it.
+	// - https://www.postgresql.org/docs/current/functions-admin.html
+	stdout, stderr, err := exec.Exec(ctx, strings.NewReader(`
+SELECT pg_catalog.pg_read_file(:'file') = :'authorities' AS loaded \gset
+\echo :loaded
+`),
+		map[string]string{
+			"authorities": string(authorities),
+			"file":        "/pgconf/tls/ca.crt",
+
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	log.V(1).Info("compared PostgreSQL certificates", "stdout", stdout, "stderr", stderr)
+
+	return err == nil && strings.TrimSpace(stdout) == "t", err
+}
@@ -17,7 +17,10 @@ package postgres
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 // NewHBAs returns HostBasedAuthentication records required by this package.
@@ -46,6 +49,42 @@ func NewHBAs() HBAs {
 	}
 }
 
+// NewHBAFromRule returns an HBA record that represents rule. Omitted databases,
+// users, and addresses match all of their kind. Keywords such as "replication"
+// and "sameuser" and user names that start with a plus (+) are not quoted so
+// that they keep their special meaning.
+func NewHBAFromRule(rule v1beta1.PostgresHBARule) *HostBasedAuthentication {
+	hba := NewHBA().Method(rule.Method)
+	hba.origin = rule.Connection
+
+	if len(rule.Databases) > 0 {
+		names := make([]string, len(rule.Databases))
+		for i := range rule.Databases {
+			names[i] = hba.keywordOrQuote(string(rule.Databases[i]),
+				"all", "replication", "samegroup", "samerole", "sameuser")
+		}
+		hba.database = strings.Join(names, ",")
+	}
+	if len(rule.Users) > 0 {
+		names := make([]string, len(rule.Users))
+		for i := range rule.Users {
+			if name := string(rule.Users[i]); strings.HasPrefix(name, "+") && len(name) > 1 {
+				names[i] = "+" + hba.quote(name[1:])
+			} else {
+				names[i] = hba.keywordOrQuote(name, "all")
+			}
+		}
+		hba.user = strings.Join(names, ",")
+	}
+	if rule.Address != "" {
+		hba.Network(rule.Address)
+	}
+	if len(rule.Options) > 0 {
+		hba.Options(rule.Options)
+	}
+	return hba
+}
+
 // HBAs is a pairing of HostBasedAuthentication records.
 type HBAs struct{ Mandatory, Default []HostBasedAuthentication }
 
@@ -64,6 +103,24 @@ func (HostBasedAuthentication) quote(value string) string {
 	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
 }
 
+// keywordOrQuote returns value without quotes when it is one of keywords.
+func (hba HostBasedAuthentication) keywordOrQuote(value string, keywords ...string) string {
+	for _, keyword := range keywords {
+		if value == keyword {
+			return value
+		}
+	}
+	return hba.quote(value)
+}
+
+func (hba HostBasedAuthentication) quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i := range values {
+		quoted[i] = hba.quote(values[i])
+	}
+	return strings.Join(quoted, ",")
+}
+
 // AllDatabases makes hba match connections made to any database.
 func (hba *HostBasedAuthentication) AllDatabases() *HostBasedAuthentication {
 	hba.database = "all"
@@ -82,9 +139,9 @@ func (hba *HostBasedAuthentication) AllUsers() *HostBasedAuthentication {
 	return hba
 }
 
-// Database makes hba match connections made to a specific database.
-func (hba *HostBasedAuthentication) Database(name string) *HostBasedAuthentication {
-	hba.database = hba.quote(name)
+// Database makes hba match connections made to specific databases.
+func (hba *HostBasedAuthentication) Database(names ...string) *HostBasedAuthentication {
+	hba.database = hba.quoteList(names)
 	return hba
 }
 
@@ -112,11 +169,18 @@ func (hba *HostBasedAuthentication) NoSSL() *HostBasedAuthentication {
 	return hba
 }
 
-// Options specifies any options for the authentication method.
+// Options specifies any options for the authentication method. They are
+// sorted by name so that hba is always formatted the same way.
 func (hba *HostBasedAuthentication) Options(opts map[string]string) *HostBasedAuthentication {
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
 	hba.options = ""
-	for k, v := range opts {
-		hba.options = fmt.Sprintf("%s %s=%s", hba.options, k, hba.quote(v))
+	for _, k := range keys {
+		hba.options = fmt.Sprintf("%s %s=%s", hba.options, k, hba.quote(opts[k]))
 	}
 	return hba
 }
@@ -152,9 +216,9 @@ func (hba *HostBasedAuthentication) TCP() *HostBasedAuthentication {
 	return hba
 }
 
-// User makes hba match connections by a specific user.
-func (hba *HostBasedAuthentication) User(name string) *HostBasedAuthentication {
-	hba.user = hba.quote(name)
+// User makes hba match connections by specific users.
+func (hba *HostBasedAuthentication) User(names ...string) *HostBasedAuthentication {
+	hba.user = hba.quoteList(names)
 	return hba
 }
 
@@ -168,3 +232,26 @@ func (hba HostBasedAuthentication) String() string {
 	return strings.TrimSpace(fmt.Sprintf("%s %s %s %s %s %s",
 		hba.origin, hba.database, hba.user, hba.address, hba.method, hba.options))
 }
+
+// UserNameMap represents a single record for pg_ident.conf.
+// - https://www.postgresql.org/docs/current/auth-username-maps.html
+type UserNameMap struct {
+	name, system, user string
+}
+
+// NewUserNameMap returns a pg_ident record that allows the operating system or
+// certificate user system to connect as the PostgreSQL user user.
+func NewUserNameMap(name, system, user string) *UserNameMap {
+	return &UserNameMap{name: name, system: system, user: user}
+}
+
+// NewUserNameMapFromSpec returns a pg_ident record that represents spec.
+func NewUserNameMapFromSpec(spec v1beta1.PostgresIdentMap) *UserNameMap {
+	return NewUserNameMap(spec.Name, spec.SystemUser, string(spec.DatabaseUser))
+}
+
+// String returns ident formatted for the pg_ident.conf file without a newline.
+func (ident UserNameMap) String() string {
+	quote := HostBasedAuthentication{}.quote
+	return fmt.Sprintf("%s %s %s", ident.name, quote(ident.system), quote(ident.user))
+}
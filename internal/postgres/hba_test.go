@@ -22,6 +22,7 @@ import (
 	"gotest.tools/v3/assert"
 
 	"github.com/crunchydata/postgres-operator/internal/testing/cmp"
+	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
 
 func TestNewHBAs(t *testing.T) {
@@ -70,4 +71,50 @@ func TestHostBasedAuthentication(t *testing.T) {
 
 	assert.Equal(t, `hostnossl all all all reject`,
 		NewHBA().NoSSL().Method("reject").String())
+
+	assert.Equal(t, `host "one","two" "x","y" all md5`,
+		NewHBA().TCP().Database("one", "two").User("x", "y").Method("md5").String())
+}
+
+func TestNewHBAFromRule(t *testing.T) {
+	assert.Equal(t, `hostssl all all all scram-sha-256`,
+		NewHBAFromRule(v1beta1.PostgresHBARule{
+			Connection: "hostssl", Method: "scram-sha-256",
+		}).String())
+
+	assert.Equal(t, `local "app","other" "app" peer  map="local"`,
+		NewHBAFromRule(v1beta1.PostgresHBARule{
+			Connection: "local",
+			Databases:  []v1beta1.PostgresIdentifier{"app", "other"},
+			Users:      []v1beta1.PostgresIdentifier{"app"},
+			Method:     "peer",
+			Options:    map[string]string{"map": "local"},
+		}).String())
+
+	assert.Equal(t, `hostssl all "a""b" "192.168.0.0/16" cert  clientcert="verify-full" map="certs"`,
+		NewHBAFromRule(v1beta1.PostgresHBARule{
+			Connection: "hostssl",
+			Users:      []v1beta1.PostgresIdentifier{`a"b`},
+			Address:    "192.168.0.0/16",
+			Method:     "cert",
+			Options:    map[string]string{"map": "certs", "clientcert": "verify-full"},
+		}).String())
+
+	assert.Equal(t, `host replication,sameuser,"app" +"admins",all,"+" all scram-sha-256`,
+		NewHBAFromRule(v1beta1.PostgresHBARule{
+			Connection: "host",
+			Databases:  []v1beta1.PostgresIdentifier{"replication", "sameuser", "app"},
+			Users:      []v1beta1.PostgresIdentifier{"+admins", "all", "+"},
+			Method:     "scram-sha-256",
+		}).String())
+}
+
+func TestUserNameMap(t *testing.T) {
+	assert.Equal(t, `certs "app.example.com" "app"`,
+		NewUserNameMap("certs", "app.example.com", "app").String())
+
+	assert.Equal(t, `sso "/^(.*)@example\.com$" "\1"`,
+		NewUserNameMapFromSpec(v1beta1.PostgresIdentMap{
+			Name: "sso", SystemUser: `/^(.*)@example\.com$`, DatabaseUser: `\1`,
+		}).String())
 }
@@ -56,6 +56,20 @@ func sanitizeAlterRoleOptions(options string) string {
 	return strings.TrimPrefix(sql, AlterRolePrefix)
 }
 
+// UserCertificateHBAs populates outHBAs with records that require users with
+// client certificates to present them over TLS. These records come before any
+// others, so those users cannot authenticate with a password.
+func UserCertificateHBAs(inCluster *v1beta1.PostgresCluster, outHBAs *HBAs) {
+	for i := range inCluster.Spec.Users {
+		spec := inCluster.Spec.Users[i]
+
+		if spec.ClientCertificate != nil && *spec.ClientCertificate {
+			outHBAs.Mandatory = append(outHBAs.Mandatory,
+				*NewHBA().TLS().User(string(spec.Name)).Method("cert"))
+		}
+	}
+}
+
 // WriteUsersInPostgreSQL calls exec to create users that do not exist in
 // PostgreSQL. Once they exist, it updates their options and passwords and
 // grants them access to their specified databases. The databases must already
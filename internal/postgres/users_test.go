@@ -24,6 +24,7 @@ import (
 
 	"gotest.tools/v3/assert"
 
+	"github.com/crunchydata/postgres-operator/internal/initialize"
 	"github.com/crunchydata/postgres-operator/internal/testing/cmp"
 	"github.com/crunchydata/postgres-operator/pkg/apis/postgres-operator.crunchydata.com/v1beta1"
 )
@@ -46,6 +47,22 @@ func TestSanitizeAlterRoleOptions(t *testing.T) {
 	})
 }
 
+func TestUserCertificateHBAs(t *testing.T) {
+	cluster := new(v1beta1.PostgresCluster)
+	cluster.Spec.Users = []v1beta1.PostgresUserSpec{
+		{Name: "password"},
+		{Name: "disabled", ClientCertificate: initialize.Bool(false)},
+		{Name: "enabled", ClientCertificate: initialize.Bool(true)},
+	}
+
+	hbas := new(HBAs)
+	UserCertificateHBAs(cluster, hbas)
+
+	assert.Equal(t, len(hbas.Default), 0)
+	assert.Equal(t, len(hbas.Mandatory), 1)
+	assert.Equal(t, hbas.Mandatory[0].String(), `hostssl all "enabled" all cert`)
+}
+
 func TestWriteUsersInPostgreSQL(t *testing.T) {
 	ctx := context.Background()
 
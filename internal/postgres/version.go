@@ -0,0 +1,50 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/crunchydata/postgres-operator/internal/logging"
+)
+
+// ServerVersionNum returns the "server_version_num" of the running PostgreSQL,
+// such as 160002 for PostgreSQL 16.2. The major version is the result divided
+// by 10000.
+// - https://www.postgresql.org/docs/current/runtime-config-preset.html
+func ServerVersionNum(ctx context.Context, exec Executor) (int, error) {
+	stdout, stderr, err := exec.Exec(ctx, strings.NewReader(`
+SELECT pg_catalog.current_setting('server_version_num') AS version \gset
+\echo :version
+`),
+		map[string]string{
+			"ON_ERROR_STOP": "on", // Abort when any one statement fails.
+			"QUIET":         "on", // Do not print successful statements to stdout.
+		})
+
+	logging.FromContext(ctx).V(1).Info("checked PostgreSQL version",
+		"stdout", stdout, "stderr", stderr)
+
+	var version int
+	if err == nil {
+		version, err = strconv.Atoi(strings.TrimSpace(stdout))
+	}
+	return version, errors.WithStack(err)
+}
@@ -0,0 +1,67 @@
+/*
+ Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/crunchydata/postgres-operator/internal/testing/cmp"
+)
+
+func TestServerVersionNum(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Arguments", func(t *testing.T) {
+		expected := errors.New("pass-through")
+		exec := func(
+			_ context.Context, stdin io.Reader, _, _ io.Writer, command ...string,
+		) error {
+			b, err := io.ReadAll(stdin)
+			assert.NilError(t, err)
+			assert.Assert(t, cmp.Contains(string(b), `current_setting('server_version_num')`))
+			assert.Assert(t, cmp.Contains(command, "--set=ON_ERROR_STOP=on"))
+			return expected
+		}
+
+		_, err := ServerVersionNum(ctx, exec)
+		assert.ErrorIs(t, err, expected)
+	})
+
+	t.Run("Output", func(t *testing.T) {
+		var output string
+		exec := func(
+			_ context.Context, _ io.Reader, stdout, _ io.Writer, _ ...string,
+		) error {
+			_, err := io.Copy(stdout, strings.NewReader(output))
+			return err
+		}
+
+		output = "160002\n"
+		version, err := ServerVersionNum(ctx, exec)
+		assert.NilError(t, err)
+		assert.Equal(t, version, 160002)
+
+		output = "\n"
+		_, err = ServerVersionNum(ctx, exec)
+		assert.ErrorContains(t, err, "invalid syntax")
+	})
+}
@@ -134,3 +134,91 @@ func TestPostgresUserOptions(t *testing.T) {
 		assert.NilError(t, cc.Create(ctx, cluster, client.DryRunAll))
 	})
 }
+
+func TestPostgresAuthenticationRules(t *testing.T) {
+	ctx := context.Background()
+	cc := require.Kubernetes(t)
+	t.Parallel()
+
+	namespace := require.Namespace(t, cc)
+	base := v1beta1.NewPostgresCluster()
+
+	// Start with a bunch of required fields.
+	assert.NilError(t, yaml.Unmarshal([]byte(`{
+		postgresVersion: 16,
+		backups: {
+			pgbackrest: {
+				repos: [{ name: repo1 }],
+			},
+		},
+		instances: [{
+			dataVolumeClaimSpec: {
+				accessModes: [ReadWriteOnce],
+				resources: { requests: { storage: 1Mi } },
+			},
+		}],
+	}`), &base.Spec))
+
+	base.Namespace = namespace.Name
+	base.Name = "postgres-authentication-rules"
+
+	assert.NilError(t, cc.Create(ctx, base.DeepCopy(), client.DryRunAll),
+		"expected this base cluster to be valid")
+
+	// See [internal/controller/postgrescluster.TestValidatePostgresAuthentication]
+
+	t.Run("NoTrust", func(t *testing.T) {
+		cluster := base.DeepCopy()
+		cluster.Spec.Authentication = &v1beta1.PostgresAuthenticationSpec{
+			Rules: []v1beta1.PostgresHBARule{
+				{Connection: "host", Method: "trust"},
+			},
+		}
+
+		err := cc.Create(ctx, cluster, client.DryRunAll)
+		assert.Assert(t, apierrors.IsInvalid(err))
+		assert.ErrorContains(t, err, "unsafe")
+	})
+
+	t.Run("NoLocalAddress", func(t *testing.T) {
+		cluster := base.DeepCopy()
+		cluster.Spec.Authentication = &v1beta1.PostgresAuthenticationSpec{
+			Rules: []v1beta1.PostgresHBARule{
+				{Connection: "local", Address: "10.0.0.0/8", Method: "peer"},
+			},
+		}
+
+		err := cc.Create(ctx, cluster, client.DryRunAll)
+		assert.Assert(t, apierrors.IsInvalid(err))
+		assert.ErrorContains(t, err, "cannot have an address")
+	})
+
+	t.Run("Connection", func(t *testing.T) {
+		cluster := base.DeepCopy()
+		cluster.Spec.Authentication = &v1beta1.PostgresAuthenticationSpec{
+			Rules: []v1beta1.PostgresHBARule{
+				{Connection: "tcp", Method: "md5"},
+			},
+		}
+
+		err := cc.Create(ctx, cluster, client.DryRunAll)
+		assert.Assert(t, apierrors.IsInvalid(err))
+		assert.ErrorContains(t, err, "spec.authentication.rules[0].connection")
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		cluster := base.DeepCopy()
+		cluster.Spec.Authentication = &v1beta1.PostgresAuthenticationSpec{
+			Rules: []v1beta1.PostgresHBARule{
+				{Connection: "hostssl", Address: "10.0.0.0/8", Method: "cert",
+					Options: map[string]string{"map": "certs"}},
+				{Connection: "local", Users: []v1beta1.PostgresIdentifier{"app"}, Method: "peer"},
+			},
+			IdentMaps: []v1beta1.PostgresIdentMap{
+				{Name: "certs", SystemUser: "app.example.com", DatabaseUser: "app"},
+			},
+		}
+
+		assert.NilError(t, cc.Create(ctx, cluster, client.DryRunAll))
+	})
+}
@@ -17,6 +17,7 @@ package v1beta1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -53,6 +54,163 @@ type PGBouncerConfiguration struct {
 	// More info: https://www.pgbouncer.org/config.html#section-users
 	// +optional
 	Users map[string]string `json:"users,omitempty"`
+
+	// Common settings of connection pools. These are validated before they
+	// reach PgBouncer. Settings in global, databases, and users take
+	// precedence over these.
+	// +optional
+	Pools *PGBouncerPoolSettings `json:"pools,omitempty"`
+}
+
+// PGBouncerPoolSettings defines the common settings of PgBouncer connection
+// pools and overrides of them for particular databases and users.
+// More info: https://www.pgbouncer.org/config.html#pool-settings
+// +kubebuilder:validation:XValidation:rule=`!has(self.minPoolSize) || !has(self.defaultPoolSize) || self.minPoolSize <= self.defaultPoolSize`,message="minPoolSize cannot exceed defaultPoolSize"
+type PGBouncerPoolSettings struct {
+	// When a server connection is returned to its pool: after the client
+	// disconnects, after each transaction, or after each statement.
+	// +optional
+	// +kubebuilder:default=session
+	// +kubebuilder:validation:Enum={session,transaction,statement}
+	PoolMode string `json:"poolMode,omitempty"`
+
+	// Number of server connections allowed per user/database pair.
+	// +optional
+	// +kubebuilder:default=20
+	// +kubebuilder:validation:Minimum=1
+	DefaultPoolSize *int32 `json:"defaultPoolSize,omitempty"`
+
+	// Number of server connections to keep open in each pool when there
+	// are fewer.
+	// +optional
+	// +kubebuilder:default=0
+	// +kubebuilder:validation:Minimum=0
+	MinPoolSize *int32 `json:"minPoolSize,omitempty"`
+
+	// Number of additional server connections allowed in a pool when clients
+	// wait longer than "reserve_pool_timeout".
+	// +optional
+	// +kubebuilder:default=0
+	// +kubebuilder:validation:Minimum=0
+	ReservePoolSize *int32 `json:"reservePoolSize,omitempty"`
+
+	// Number of client connections allowed to each PgBouncer pod.
+	// +optional
+	// +kubebuilder:default=100
+	// +kubebuilder:validation:Minimum=1
+	MaxClientConnections *int32 `json:"maxClientConnections,omitempty"`
+
+	// Seconds a server connection can be idle before it is closed. Zero
+	// disables this timeout.
+	// +optional
+	// +kubebuilder:default=600
+	// +kubebuilder:validation:Minimum=0
+	ServerIdleTimeoutSeconds *int32 `json:"serverIdleTimeoutSeconds,omitempty"`
+
+	// Number of prepared statements PgBouncer tracks on each server connection
+	// so that clients can use them in transaction and statement modes. Zero
+	// disables this tracking.
+	// +optional
+	// +kubebuilder:default=200
+	// +kubebuilder:validation:Minimum=0
+	MaxPreparedStatements *int32 `json:"maxPreparedStatements,omitempty"`
+
+	// Seconds a client query can wait for a server connection before the
+	// client is disconnected. Zero disables this timeout.
+	// +optional
+	// +kubebuilder:default=120
+	// +kubebuilder:validation:Minimum=0
+	QueryWaitTimeoutSeconds *int32 `json:"queryWaitTimeoutSeconds,omitempty"`
+
+	// Settings of the pools of particular databases.
+	// More info: https://www.pgbouncer.org/config.html#section-databases
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=64
+	// +optional
+	Databases []PGBouncerDatabasePoolSettings `json:"databases,omitempty"`
+
+	// Settings of the pools of particular users.
+	// More info: https://www.pgbouncer.org/config.html#section-users
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=64
+	// +optional
+	Users []PGBouncerUserPoolSettings `json:"users,omitempty"`
+}
+
+// PGBouncerDatabasePoolSettings defines settings of the pools of a database.
+type PGBouncerDatabasePoolSettings struct {
+	// The database requested by clients. When it has no entry in the
+	// databases section, one is added that connects to the primary.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +required
+	Name string `json:"name"`
+
+	// When a server connection is returned to the pools of this database.
+	// +optional
+	// +kubebuilder:validation:Enum={session,transaction,statement}
+	PoolMode string `json:"poolMode,omitempty"`
+
+	// Number of server connections allowed per user of this database.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	PoolSize *int32 `json:"poolSize,omitempty"`
+
+	// Number of server connections to keep open in each pool of this database.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MinPoolSize *int32 `json:"minPoolSize,omitempty"`
+
+	// Number of additional server connections allowed in each pool of this
+	// database.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	ReservePoolSize *int32 `json:"reservePoolSize,omitempty"`
+
+	// Number of server connections allowed to this database by all its pools.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxDBConnections *int32 `json:"maxDBConnections,omitempty"`
+}
+
+// PGBouncerUserPoolSettings defines settings of the pools of a user.
+type PGBouncerUserPoolSettings struct {
+	// The user name of clients.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +required
+	Name string `json:"name"`
+
+	// When a server connection is returned to the pools of this user.
+	// +optional
+	// +kubebuilder:validation:Enum={session,transaction,statement}
+	PoolMode string `json:"poolMode,omitempty"`
+
+	// Number of server connections allowed to this user by all its pools.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxUserConnections *int32 `json:"maxUserConnections,omitempty"`
+}
+
+// PGBouncerReadOnlySpec defines connection pools to PostgreSQL replicas.
+type PGBouncerReadOnlySpec struct {
+
+	// Databases that have a read-only pool in addition to those of the users
+	// in spec.users.
+	// +listType=set
+	// +optional
+	Databases []PostgresIdentifier `json:"databases,omitempty"`
+
+	// The string appended to a database name to make the name of its
+	// read-only pool.
+	// +kubebuilder:default="_ro"
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=16
+	// +kubebuilder:validation:Pattern=`^[_0-9A-Za-z]+$`
+	// +optional
+	Suffix string `json:"suffix,omitempty"`
 }
 
 // PGBouncerPodSpec defines the desired state of a PgBouncer connection pooler.
@@ -60,6 +218,18 @@ type PGBouncerPodSpec struct {
 	// +optional
 	Metadata *Metadata `json:"metadata,omitempty"`
 
+	// Users of the PgBouncer admin console and rules that control how clients
+	// authenticate to PgBouncer.
+	// +optional
+	Authentication *PGBouncerAuthenticationSpec `json:"authentication,omitempty"`
+
+	// Scale the number of PgBouncer pods between a minimum and maximum based on
+	// observed metrics. When set, the replicas field is ignored and a
+	// HorizontalPodAutoscaler controls the number of pods.
+	// More info: https://kubernetes.io/docs/tasks/run-application/horizontal-pod-autoscale/
+	// +optional
+	Autoscaling *PGBouncerAutoscalingSpec `json:"autoscaling,omitempty"`
+
 	// Scheduling constraints of a PgBouncer pod. Changing this value causes
 	// PgBouncer to restart.
 	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node
@@ -106,7 +276,12 @@ type PGBouncerPodSpec struct {
 	// +optional
 	PriorityClassName *string `json:"priorityClassName,omitempty"`
 
-	// Number of desired PgBouncer pods.
+	// Connection pools that connect to PostgreSQL replicas rather than the
+	// primary. Each is named after a database with a suffix, e.g. "app_ro".
+	// +optional
+	ReadOnly *PGBouncerReadOnlySpec `json:"readOnly,omitempty"`
+
+	// Number of desired PgBouncer pods. Ignored when autoscaling is set.
 	// +optional
 	// +kubebuilder:default=1
 	// +kubebuilder:validation:Minimum=0
@@ -131,6 +306,20 @@ type PGBouncerPodSpec struct {
 	// +optional
 	Sidecars *PGBouncerSidecars `json:"sidecars,omitempty"`
 
+	// Coordination between PgBouncer and planned changes of the PostgreSQL
+	// primary, such as a switchover or a rolling update.
+	// +optional
+	Switchover *PGBouncerSwitchoverSpec `json:"switchover,omitempty"`
+
+	// Duration in seconds PgBouncer has to drain client connections before it
+	// is stopped. When a pod is deleted, PgBouncer stops accepting new clients
+	// and waits for existing ones to disconnect. Changing this value causes
+	// PgBouncer to restart.
+	// More info: https://kubernetes.io/docs/concepts/workloads/pods/pod-lifecycle/#pod-termination
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+
 	// Tolerations of a PgBouncer pod. Changing this value causes PgBouncer to
 	// restart.
 	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration
@@ -144,6 +333,112 @@ type PGBouncerPodSpec struct {
 	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
 }
 
+// PGBouncerAuthenticationSpec defines the admin console users of PgBouncer
+// and its host-based authentication rules.
+type PGBouncerAuthenticationSpec struct {
+	// Users of the PgBouncer admin console. These users exist only in PgBouncer;
+	// the operator generates their passwords and stores them in the Secret
+	// named "<cluster>-pgbouncer-console".
+	// More info: https://www.pgbouncer.org/usage.html#admin-console
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=20
+	// +optional
+	ConsoleUsers []PGBouncerConsoleUser `json:"consoleUsers,omitempty"`
+
+	// Host-based authentication rules for PgBouncer, in order. When set,
+	// PgBouncer checks each client connection against the rules the operator
+	// requires and then these. Connections that match no rule are rejected.
+	// More info: https://www.pgbouncer.org/config.html#hba-file-format
+	// +listType=atomic
+	// +kubebuilder:validation:MaxItems=64
+	// +kubebuilder:validation:XValidation:rule=`self.all(r, r.connection in ['host','hostssl','hostnossl'])`,message=`PgBouncer rules must be "host", "hostssl", or "hostnossl"`
+	// +optional
+	Rules []PostgresHBARule `json:"rules,omitempty"`
+}
+
+// PGBouncerConsoleUser is a user of the PgBouncer admin console.
+type PGBouncerConsoleUser struct {
+	// The name of this user. It must be a valid Kubernetes name so that it
+	// can be used in Secret keys. It cannot be the name of a PostgreSQL user
+	// because PgBouncer authenticates both from the same file.
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +required
+	Name string `json:"name"`
+
+	// Whether this user can run every admin console command or only those
+	// that read statistics, such as SHOW POOLS.
+	// More info: https://www.pgbouncer.org/config.html#admin_users
+	// +kubebuilder:default=Stats
+	// +kubebuilder:validation:Enum={Admin,Stats}
+	// +optional
+	Role string `json:"role,omitempty"`
+}
+
+// PGBouncerAutoscalingSpec defines the range and targets of PgBouncer
+// autoscaling. When no target is set, Kubernetes targets 80% average CPU
+// utilization.
+// +kubebuilder:validation:XValidation:rule=`!has(self.minReplicas) || self.minReplicas <= self.maxReplicas`,message="minReplicas cannot exceed maxReplicas"
+type PGBouncerAutoscalingSpec struct {
+	// Lower limit for the number of PgBouncer pods.
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// Upper limit for the number of PgBouncer pods.
+	// +required
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// Target average CPU utilization of PgBouncer pods, as a percentage of
+	// their requested CPU. PgBouncer resources should include a CPU request.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+
+	// Target average number of client connections per PgBouncer pod. This is
+	// read from a custom "Pods" metric that must be served by a metrics adapter,
+	// such as the Prometheus Adapter.
+	// More info: https://kubernetes.io/docs/tasks/run-application/horizontal-pod-autoscale-walkthrough/#autoscaling-on-multiple-metrics-and-custom-metrics
+	// +optional
+	TargetClientConnections *PGBouncerConnectionsTarget `json:"targetClientConnections,omitempty"`
+}
+
+// PGBouncerConnectionsTarget identifies a custom metric of client connections
+// and its target average value.
+type PGBouncerConnectionsTarget struct {
+	// Name of the custom metric that counts client connections of each pod.
+	// +optional
+	// +kubebuilder:default=pgbouncer_client_connections
+	// +kubebuilder:validation:MinLength=1
+	Metric string `json:"metric,omitempty"`
+
+	// Average number of client connections per pod.
+	// +required
+	// +kubebuilder:validation:Minimum=1
+	AverageValue int32 `json:"averageValue"`
+}
+
+// PGBouncerSwitchoverSpec defines how PgBouncer behaves while the PostgreSQL
+// primary changes.
+type PGBouncerSwitchoverSpec struct {
+	// Whether or not to pause PgBouncer connection pools before a planned change
+	// of the PostgreSQL primary and resume them after. Clients wait rather than
+	// receive errors while a new primary is elected.
+	// More info: https://www.pgbouncer.org/usage.html#pause-db
+	// +required
+	Pause bool `json:"pause"`
+
+	// The longest pools remain paused. The operator resumes them after this
+	// duration even when the change of primary has not finished.
+	// +optional
+	// +kubebuilder:default="30s"
+	// +kubebuilder:validation:XValidation:rule=`duration(self) >= duration('1s') && duration(self) <= duration('10m')`,message="must be between 1s and 10m"
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
 // PGBouncerSidecars defines the configuration for pgBouncer sidecar containers
 type PGBouncerSidecars struct {
 	// Defines the configuration for the pgBouncer config sidecar container
@@ -165,6 +460,78 @@ func (s *PGBouncerPodSpec) Default() {
 	}
 }
 
+// PGBouncerPoolerSpec defines an additional PgBouncer connection pooler.
+// +kubebuilder:validation:XValidation:rule=`!(self.name in ['jdbc','ro','ro-jdbc'])`,message="this name conflicts with keys in user Secrets"
+type PGBouncerPoolerSpec struct {
+	// The name of this pooler. It is part of the names of its objects and of
+	// its keys in user Secrets, e.g. "pgbouncer-<name>-uri".
+	// +kubebuilder:validation:MaxLength=20
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +required
+	Name string `json:"name"`
+
+	// Configuration settings for the PgBouncer process of this pooler. These
+	// are added to and take precedence over those of pgBouncer, so this is
+	// where to set a different "pool_mode".
+	// More info: https://www.pgbouncer.org/config.html
+	// +optional
+	Config PGBouncerConfiguration `json:"config,omitempty"`
+
+	// Port on which this pooler should listen for client connections.
+	// +optional
+	// +kubebuilder:default=5432
+	// +kubebuilder:validation:Minimum=1024
+	Port *int32 `json:"port,omitempty"`
+
+	// Number of desired pods of this pooler.
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Minimum number of pods of this pooler that should be available at a time.
+	// Defaults to one when the replicas field is greater than one.
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// Compute resources of a PgBouncer container of this pooler. When omitted,
+	// the resources of pgBouncer are used.
+	// More info: https://kubernetes.io/docs/concepts/configuration/manage-resources-containers
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Specification of the service that exposes this pooler. When omitted,
+	// the Service is like the one of pgBouncer without its node port.
+	// +optional
+	Service *ServiceSpec `json:"service,omitempty"`
+}
+
+// Default returns the default values for a PgBouncer pooler.
+func (s *PGBouncerPoolerSpec) Default() {
+	if s.Port == nil {
+		s.Port = new(int32)
+		*s.Port = 5432
+	}
+
+	if s.Replicas == nil {
+		s.Replicas = new(int32)
+		*s.Replicas = 1
+	}
+}
+
+// PGBouncerPoolerStatus is the observed state of an additional PgBouncer pooler.
+type PGBouncerPoolerStatus struct {
+	// The name of the pooler.
+	// +required
+	Name string `json:"name"`
+
+	// Total number of ready pods.
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// Total number of non-terminated pods.
+	Replicas int32 `json:"replicas,omitempty"`
+}
+
 type PGBouncerPodStatus struct {
 
 	// Identifies the revision of PgBouncer assets that have been installed into
@@ -15,7 +15,11 @@
 
 package v1beta1
 
-import corev1 "k8s.io/api/core/v1"
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 // PGMonitorSpec defines the desired state of the pgMonitor tool suite
 type PGMonitorSpec struct {
@@ -43,8 +47,119 @@ type ExporterSpec struct {
 	// +optional
 	Image string `json:"image,omitempty"`
 
+	// Whether or not to collect PgBouncer pool, client, server, and wait-time
+	// statistics. The exporter reads them through pgbouncer_fdw, which must be
+	// available in the PostgreSQL image. Requires PgBouncer in spec.proxy.
+	// Each PgBouncer Service, including those of additional poolers, is read
+	// separately; additional poolers require a version of pgbouncer_fdw with
+	// the pgbouncer_fdw_targets table. A Service sends each read to one of its
+	// Pods, so statistics of a pooler with more than one replica describe one
+	// of its Pods at a time.
+	// More info: https://github.com/CrunchyData/pgbouncer_fdw
+	// +optional
+	PGBouncer bool `json:"pgbouncer,omitempty"`
+
+	// Settings of the built-in queries and which of them to skip. Changing
+	// these causes the exporter to reload its queries.
+	// +optional
+	Queries *ExporterQueriesSpec `json:"queries,omitempty"`
+
 	// Changing this value causes PostgreSQL and the exporter to restart.
 	// More info: https://kubernetes.io/docs/concepts/configuration/manage-resources-containers
 	// +optional
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 }
+
+// ExporterQueryFile is the name of a file of built-in exporter queries.
+// +kubebuilder:validation:Enum={backrest,general,global,global_dbsize,nodemx,per_db,pgbouncer,pg_stat_statements,pg_stat_statements_reset_info}
+type ExporterQueryFile string
+
+// ExporterQueryName is the name of a built-in exporter query.
+// +kubebuilder:validation:MaxLength=100
+// +kubebuilder:validation:Pattern=`^[A-Za-z_][A-Za-z0-9_]*$`
+type ExporterQueryName string
+
+// ExporterQueriesSpec defines the settings of the built-in exporter queries.
+// More info: https://github.com/CrunchyData/pgmonitor/tree/main/postgres_exporter
+type ExporterQueriesSpec struct {
+	// Built-in query files to skip. The "pgbouncer" file is also skipped when
+	// exporter.pgbouncer is disabled.
+	// +listType=set
+	// +optional
+	ExcludeFiles []ExporterQueryFile `json:"excludeFiles,omitempty"`
+
+	// Names of built-in queries to skip, such as "ccp_table_size". The metrics
+	// of a query are named after it.
+	// +listType=set
+	// +optional
+	// +kubebuilder:validation:MaxItems=100
+	ExcludeQueries []ExporterQueryName `json:"excludeQueries,omitempty"`
+
+	// Minutes between calls to pgBackRest for repository information.
+	// +optional
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=0
+	PGBackRestInfoThrottleMinutes *int32 `json:"pgBackRestInfoThrottleMinutes,omitempty"`
+
+	// The number of statements reported by the pg_stat_statements queries.
+	// +optional
+	// +kubebuilder:default=20
+	// +kubebuilder:validation:Minimum=1
+	PGStatStatementsLimit *int32 `json:"pgStatStatementsLimit,omitempty"`
+
+	// Minutes between reads of pg_stat_statements. When -1, it is read every
+	// time the exporter is scraped.
+	// +optional
+	// +kubebuilder:default=-1
+	// +kubebuilder:validation:Minimum=-1
+	PGStatStatementsThrottleMinutes *int32 `json:"pgStatStatementsThrottleMinutes,omitempty"`
+}
+
+// PrometheusSpec defines the Prometheus Operator objects of a cluster. There
+// is a PodMonitor for the exporter on every instance and a PrometheusRule with
+// alerts about the cluster. When exporter.pgbouncer is enabled, a second
+// PodMonitor scrapes PgBouncer statistics from the exporter on the primary.
+type PrometheusSpec struct {
+	// Thresholds of the alerts in the PrometheusRule.
+	// +optional
+	Alerts *PrometheusAlertsSpec `json:"alerts,omitempty"`
+
+	// Labels and annotations for the PodMonitors and PrometheusRule. These
+	// are how a Prometheus selects them.
+	// +optional
+	Metadata *Metadata `json:"metadata,omitempty"`
+
+	// How often Prometheus scrapes the exporter.
+	// +optional
+	// +kubebuilder:default="30s"
+	// +kubebuilder:validation:XValidation:rule=`duration(self) >= duration('1s')`,message="must be at least one second"
+	ScrapeInterval *metav1.Duration `json:"scrapeInterval,omitempty"`
+}
+
+// PrometheusAlertsSpec defines the thresholds of the standard pgMonitor alerts.
+// More info: https://access.crunchydata.com/documentation/pgmonitor/latest/
+type PrometheusAlertsSpec struct {
+	// How long the archive command may fail before alerting.
+	// +optional
+	// +kubebuilder:default="5m"
+	// +kubebuilder:validation:XValidation:rule=`duration(self) >= duration('1m')`,message="must be at least one minute"
+	ArchiveFailure *metav1.Duration `json:"archiveFailure,omitempty"`
+
+	// The percentage of a data volume that may be used before alerting.
+	// +optional
+	// +kubebuilder:default=80
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	DiskUsagePercent *int32 `json:"diskUsagePercent,omitempty"`
+
+	// How old the most recent full backup may be before alerting.
+	// +optional
+	// +kubebuilder:default="168h"
+	// +kubebuilder:validation:XValidation:rule=`duration(self) >= duration('1h')`,message="must be at least one hour"
+	FullBackupAge *metav1.Duration `json:"fullBackupAge,omitempty"`
+
+	// How much WAL a replica may be behind its primary before alerting.
+	// +optional
+	// +kubebuilder:default="50Mi"
+	ReplicationLag *resource.Quantity `json:"replicationLag,omitempty"`
+}
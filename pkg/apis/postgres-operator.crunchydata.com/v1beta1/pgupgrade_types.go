@@ -20,6 +20,7 @@ import (
 )
 
 // PGUpgradeSpec defines the desired state of PGUpgrade
+// +kubebuilder:validation:XValidation:rule=`!has(self.transferMode) || self.transferMode != 'Clone' || self.toPostgresVersion >= 12`,message="Clone requires toPostgresVersion 12 or greater"
 type PGUpgradeSpec struct {
 
 	// +optional
@@ -30,6 +31,30 @@ type PGUpgradeSpec struct {
 	// +kubebuilder:validation:MinLength=1
 	PostgresClusterName string `json:"postgresClusterName"`
 
+	// Whether to upgrade the cluster or only check that it can be upgraded.
+	// A check runs "pg_upgrade --check" against a clone of the volumes of the
+	// primary while the cluster stays online. The storage provider of those
+	// volumes must support cloning. Findings are reported in the "Compatible"
+	// condition and in a ConfigMap named after this PGUpgrade.
+	// More info: https://kubernetes.io/docs/concepts/storage/volume-pvc-datasource/
+	//
+	// Logical upgrades the cluster without stopping it: a new PostgresCluster
+	// at the new version subscribes to every database of the running cluster.
+	// Annotate this PGUpgrade with "postgres-operator.crunchydata.com/cutover"
+	// to stop writes to the old cluster and send connections to its primary
+	// Service to the new cluster. Cutover revokes CONNECT on every database of
+	// the old cluster from all roles but superusers and records those grants
+	// in status. They are granted again when the annotation is removed or this
+	// PGUpgrade is deleted before cutover finishes; after that, they stay
+	// revoked. Deleting this PGUpgrade or the new cluster before cutover
+	// removes replication slots, publications, and a role from the old cluster.
+	// More info: https://www.postgresql.org/docs/current/logical-replication.html
+	// +optional
+	// +kubebuilder:default=Upgrade
+	// +kubebuilder:validation:Enum={Upgrade,Check,Logical}
+	// +kubebuilder:validation:XValidation:rule=`self == oldSelf`,message="mode cannot be changed"
+	Mode string `json:"mode,omitempty"`
+
 	// The image name to use for major PostgreSQL upgrades.
 	// +optional
 	Image *string `json:"image,omitempty"`
@@ -78,6 +103,37 @@ type PGUpgradeSpec struct {
 	// +optional
 	ToPostgresImage string `json:"toPostgresImage,omitempty"`
 
+	// How pg_upgrade transfers data files to the new data directory. Link
+	// hard links the files and is the fastest, but the old data directory
+	// cannot be started afterward. Clone makes copy-on-write copies of the
+	// files; the filesystem of the volume must support reflinks. Copy copies
+	// the files and needs enough space for a second data directory. With Clone
+	// and Copy, the old data directories of the primary and replicas are kept.
+	// More info: https://www.postgresql.org/docs/current/pgupgrade.html
+	// +optional
+	// +kubebuilder:default=Link
+	// +kubebuilder:validation:Enum={Link,Clone,Copy}
+	TransferMode string `json:"transferMode,omitempty"`
+
+	// The number of processes or threads pg_upgrade uses to process databases
+	// and tablespaces in parallel. This helps most when there are many
+	// databases or tablespaces.
+	// More info: https://www.postgresql.org/docs/current/pgupgrade.html
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	Jobs *int32 `json:"jobs,omitempty"`
+
+	// Settings for the Logical mode.
+	// +optional
+	Logical *PGUpgradeLogicalSpec `json:"logical,omitempty"`
+
+	// A full pgBackRest backup to take before the upgrade. The cluster must be
+	// running while the backup is taken; the upgrade waits for it to finish.
+	// The cluster can then be restored from this backup at the old version and
+	// image by annotating this PGUpgrade with "postgres-operator.crunchydata.com/rollback".
+	// +optional
+	Backup *PGUpgradeBackupSpec `json:"backup,omitempty"`
+
 	// Resource requirements for the PGUpgrade container.
 	// +optional
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
@@ -102,6 +158,127 @@ type PGUpgradeSpec struct {
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 }
 
+// PGUpgradeBackupSpec defines the backup taken before a PGUpgrade.
+type PGUpgradeBackupSpec struct {
+	// The name of the pgBackRest repo to run the backup command against.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=^repo[1-4]
+	RepoName string `json:"repoName"`
+
+	// Whether or not to restore the backup when the upgrade Job fails.
+	// +optional
+	RollbackOnFailure bool `json:"rollbackOnFailure,omitempty"`
+}
+
+// PGUpgradeLogicalSpec defines a PGUpgrade through logical replication.
+type PGUpgradeLogicalSpec struct {
+	// The name of the PostgresCluster to create at the new version. It is
+	// a copy of the old cluster without its data source. Defaults to the name
+	// of the old cluster followed by the new version, e.g. "hippo-pg16".
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z]([-a-z0-9]*[a-z0-9])?$`
+	TargetClusterName string `json:"targetClusterName,omitempty"`
+}
+
+// PGUpgradeLogicalStatus describes a PGUpgrade through logical replication.
+type PGUpgradeLogicalStatus struct {
+	// The name of the PostgresCluster at the new version.
+	// +kubebuilder:validation:Required
+	TargetClusterName string `json:"targetClusterName"`
+
+	// The databases that are replicated to the new cluster.
+	// +optional
+	Databases []string `json:"databases,omitempty"`
+
+	// The amount of WAL the new cluster has yet to confirm, in bytes.
+	// +optional
+	ReplicationLagBytes *int64 `json:"replicationLagBytes,omitempty"`
+
+	// The CONNECT privileges that cutover revoked from roles of the old
+	// cluster. They are recorded before they are revoked.
+	// +optional
+	RevokedConnect *PGUpgradeRevokedConnect `json:"revokedConnect,omitempty"`
+
+	// The time connections moved to the new cluster.
+	// +optional
+	CutoverTime *metav1.Time `json:"cutoverTime,omitempty"`
+}
+
+// PGUpgradeRevokedConnect describes the CONNECT privileges that cutover
+// revoked in the old cluster.
+type PGUpgradeRevokedConnect struct {
+	// +optional
+	// +listType=atomic
+	Grants []PGUpgradeConnectGrant `json:"grants,omitempty"`
+}
+
+// PGUpgradeConnectGrant is the CONNECT privilege of a role on a database.
+type PGUpgradeConnectGrant struct {
+	// +kubebuilder:validation:Required
+	Database string `json:"database"`
+
+	// The role that had the privilege. Empty means PUBLIC.
+	// +optional
+	Role string `json:"role,omitempty"`
+}
+
+// PGUpgradeCheckStatus describes the check of a PGUpgrade in progress.
+type PGUpgradeCheckStatus struct {
+	// The name of the instance whose volumes are cloned for the check. It stays
+	// the same until the check finishes, even when another instance becomes
+	// the primary.
+	// +optional
+	Instance string `json:"instance,omitempty"`
+}
+
+// PGUpgradeBackupStatus describes the backup taken before a PGUpgrade.
+type PGUpgradeBackupStatus struct {
+	// The name of the pgBackRest repo that has the backup.
+	// +kubebuilder:validation:Required
+	RepoName string `json:"repoName"`
+
+	// The pgBackRest label of the backup.
+	// +optional
+	Label string `json:"label,omitempty"`
+
+	// The spec.image of the cluster before the upgrade. A rollback sets it
+	// again.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// The spec.backups.pgbackrest.manual of the cluster before the upgrade.
+	// It is set again once the backup is taken.
+	// +optional
+	Manual *PGBackRestManualBackup `json:"manual,omitempty"`
+
+	// The spec.backups.pgbackrest.restore of the cluster before the upgrade.
+	// It is set again once a rollback finishes.
+	// +optional
+	Restore *PGBackRestRestore `json:"restore,omitempty"`
+}
+
+// PGUpgradeStepStatus describes one step of a PGUpgrade.
+type PGUpgradeStepStatus struct {
+	// The name of the step: initdb, check, upgrade, or remove-data followed
+	// by the name of an instance.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Whether the step is Running, Succeeded, or Failed.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum={Running,Succeeded,Failed}
+	State string `json:"state"`
+
+	// The time the step started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// The time the step finished.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
 // PGUpgradeStatus defines the observed state of PGUpgrade
 type PGUpgradeStatus struct {
 	// conditions represent the observations of PGUpgrade's current state.
@@ -110,6 +287,25 @@ type PGUpgradeStatus struct {
 	// +listMapKey=type
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
+	// The pgBackRest backup taken before the upgrade.
+	// +optional
+	Backup *PGUpgradeBackupStatus `json:"backup,omitempty"`
+
+	// The check that is running, if any.
+	// +optional
+	Check *PGUpgradeCheckStatus `json:"check,omitempty"`
+
+	// The progress of a Logical upgrade.
+	// +optional
+	Logical *PGUpgradeLogicalStatus `json:"logical,omitempty"`
+
+	// The progress of each step of the upgrade. The output of each finished
+	// step is kept in a ConfigMap named after this PGUpgrade with "-logs".
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Steps []PGUpgradeStepStatus `json:"steps,omitempty"`
+
 	// observedGeneration represents the .metadata.generation on which the status was based.
 	// +optional
 	// +kubebuilder:validation:Minimum=0
@@ -70,4 +70,107 @@ type PostgresUserSpec struct {
 	// Properties of the password generated for this user.
 	// +optional
 	Password *PostgresPasswordSpec `json:"password,omitempty"`
+
+	// Whether or not to issue a client certificate for this user. The
+	// certificate is signed by the cluster certificate authority and stored in
+	// the user Secret as "tls.crt", "tls.key", and "ca.crt". When enabled, a
+	// required authentication rule makes this user present the certificate
+	// over TLS to connect. This user can no longer log in with a password, so
+	// it cannot connect through PgBouncer.
+	// More info: https://www.postgresql.org/docs/current/auth-cert.html
+	// +optional
+	ClientCertificate *bool `json:"clientCertificate,omitempty"`
+}
+
+type PostgresAuthenticationSpec struct {
+	// Host-based authentication rules for PostgreSQL. PostgreSQL compares each
+	// connection to these rules in order, after the rules that are required by
+	// the operator and before any rules in
+	// "spec.patroni.dynamicConfiguration.postgresql.pg_hba". The first rule that
+	// matches determines how the connection must authenticate. When neither
+	// has rules, a default rule that requires TLS and a password is used.
+	// More info: https://www.postgresql.org/docs/current/auth-pg-hba-conf.html
+	// +listType=atomic
+	// +kubebuilder:validation:MaxItems=64
+	// +optional
+	Rules []PostgresHBARule `json:"rules,omitempty"`
+
+	// User name maps that translate operating system or certificate user names
+	// into PostgreSQL user names. These are referenced by the "map" option of
+	// authentication rules.
+	// More info: https://www.postgresql.org/docs/current/auth-username-maps.html
+	// +listType=atomic
+	// +kubebuilder:validation:MaxItems=64
+	// +optional
+	IdentMaps []PostgresIdentMap `json:"identMaps,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule=`self.connection != "local" || !has(self.address)`,message=`"local" rules cannot have an address`
+type PostgresHBARule struct {
+	// The kind of connection this rule matches. "local" matches Unix-domain
+	// sockets, "host" matches any TCP/IP connection, and "hostssl" matches
+	// only TCP/IP connections that use TLS.
+	// +kubebuilder:validation:Enum={local,host,hostssl,hostnossl,hostgssenc,hostnogssenc}
+	// +kubebuilder:validation:Required
+	Connection string `json:"connection"`
+
+	// Databases this rule matches. When omitted, this rule matches all databases.
+	// The keywords "all", "replication", "samegroup", "samerole", and "sameuser"
+	// keep their special meaning.
+	// +listType=set
+	// +kubebuilder:validation:MaxItems=20
+	// +optional
+	Databases []PostgresIdentifier `json:"databases,omitempty"`
+
+	// Users this rule matches. When omitted, this rule matches all users. The
+	// keyword "all" keeps its special meaning, and a name that starts with a
+	// plus (+) matches members of that role.
+	// +listType=set
+	// +kubebuilder:validation:MaxItems=20
+	// +optional
+	Users []PostgresIdentifier `json:"users,omitempty"`
+
+	// The block of client IP addresses this rule matches, in CIDR notation.
+	// When omitted, this rule matches all addresses. Not allowed for "local"
+	// rules.
+	// +kubebuilder:validation:MaxLength=43
+	// +kubebuilder:validation:Pattern=`^[0-9a-fA-F:.]+/[0-9]{1,3}$`
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// The authentication method to use when a connection matches this rule.
+	// The method "reject" refuses connections that match this rule.
+	// More info: https://www.postgresql.org/docs/current/auth-methods.html
+	// +kubebuilder:validation:MaxLength=20
+	// +kubebuilder:validation:Pattern=`^[a-z0-9-]+$`
+	// +kubebuilder:validation:XValidation:rule=`self != "trust"`,message=`the "trust" method is unsafe`
+	// +kubebuilder:validation:Required
+	Method string `json:"method"`
+
+	// Options for the authentication method, such as "clientcert" or "map".
+	// +kubebuilder:validation:MaxProperties=20
+	// +mapType=atomic
+	// +optional
+	Options map[string]string `json:"options,omitempty"`
+}
+
+type PostgresIdentMap struct {
+	// The name of this map. Authentication rules refer to it using the "map"
+	// option.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-_a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// The operating system or certificate user name to match. A value that
+	// starts with a slash (/) is a regular expression.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=200
+	// +kubebuilder:validation:Pattern=`^[[:print:]]+$`
+	// +kubebuilder:validation:Required
+	SystemUser string `json:"systemUser"`
+
+	// The PostgreSQL user name that SystemUser may connect as.
+	// +kubebuilder:validation:Required
+	DatabaseUser PostgresIdentifier `json:"databaseUser"`
 }
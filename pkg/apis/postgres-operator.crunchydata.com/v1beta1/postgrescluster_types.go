@@ -28,6 +28,10 @@ type PostgresClusterSpec struct {
 	// +optional
 	Metadata *Metadata `json:"metadata,omitempty"`
 
+	// Authentication settings for the PostgreSQL server
+	// +optional
+	Authentication *PostgresAuthenticationSpec `json:"authentication,omitempty"`
+
 	// Specifies a data source for bootstrapping the PostgreSQL cluster.
 	// +optional
 	DataSource *DataSource `json:"dataSource,omitempty"`
@@ -36,6 +40,19 @@ type PostgresClusterSpec struct {
 	// +kubebuilder:validation:Required
 	Backups Backups `json:"backups"`
 
+	// Issue the PostgreSQL server, replication, pgBackRest, PgBouncer, and user
+	// client certificates using cert-manager rather than the operator's root
+	// certificate authority. The issuer must populate ca.crt in the Secrets it
+	// writes, and custom TLS secrets take precedence over it.
+	// More info: https://cert-manager.io/docs/usage/certificate/
+	// +optional
+	CertManager *CertManagerSpec `json:"certManager,omitempty"`
+
+	// Settings for the certificates of this cluster. These do not affect
+	// custom TLS secrets.
+	// +optional
+	Certificates *CertificatesSpec `json:"certificates,omitempty"`
+
 	// The secret containing the Certificates and Keys to encrypt PostgreSQL
 	// traffic will need to contain the server TLS certificate, TLS key and the
 	// Certificate Authority certificate with the data keys set to tls.crt,
@@ -198,6 +215,12 @@ type DataSource struct {
 	// +optional
 	PostgresCluster *PostgresClusterDataSource `json:"postgresCluster,omitempty"`
 
+	// Defines a running PostgresCluster that can be copied with pg_basebackup to
+	// pre-populate the PostgreSQL data directory for a new PostgreSQL cluster.
+	// This does not use any backups of that cluster.
+	// +optional
+	Streaming *StreamingDataSource `json:"streaming,omitempty"`
+
 	// Defines any existing volumes to reuse for this PostgresCluster.
 	// +optional
 	Volumes *DataSourceVolumes `json:"volumes,omitempty"`
@@ -246,6 +269,42 @@ type DatabaseInitSQL struct {
 	Key string `json:"key"`
 }
 
+// StreamingDataSource defines a data source for bootstrapping PostgreSQL clusters by copying
+// a running PostgresCluster over a replication connection.
+type StreamingDataSource struct {
+
+	// The name of a running PostgresCluster in the same namespace to copy. It must
+	// have the same PostgreSQL major version as the new PostgresCluster.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	ClusterName string `json:"clusterName"`
+
+	// Whether to copy the primary or a replica of the source cluster.
+	// +kubebuilder:default=primary
+	// +kubebuilder:validation:Enum={primary,replica}
+	// +optional
+	Role string `json:"role,omitempty"`
+
+	// Resource requirements for the pg_basebackup Job.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Scheduling constraints of the pg_basebackup Job.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Priority class name for the pg_basebackup Job pod.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/pod-priority-preemption/
+	// +optional
+	PriorityClassName *string `json:"priorityClassName,omitempty"`
+
+	// Tolerations of the pg_basebackup Job.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
 // PostgresClusterDataSource defines a data source for bootstrapping PostgreSQL clusters using a
 // an existing PostgresCluster.
 type PostgresClusterDataSource struct {
@@ -326,9 +385,66 @@ type Backups struct {
 	PGBackRest PGBackRestArchive `json:"pgbackrest"`
 }
 
+// CertificatesSpec defines the keys and lifetimes of cluster certificates.
+type CertificatesSpec struct {
+
+	// The kind of private key in each certificate. Certificates are signed by
+	// the root certificate authority of the namespace, and the operator
+	// setting for root certificates decides its kind of key. Clients that
+	// cannot use ECDSA keys likely need both set to RSA. Defaults to the
+	// operator setting, which defaults to ECDSA.
+	// +kubebuilder:validation:Enum={ECDSA,RSA}
+	// +optional
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+
+	// How long each certificate is valid, such as "2160h". Certificates are
+	// replaced when a third of this remains. No certificate is valid after
+	// the root certificate authority that signed it. Defaults to the operator setting,
+	// which defaults to one year.
+	// +kubebuilder:validation:XValidation:rule=`duration(self) >= duration('1h')`,message="must be at least one hour"
+	// +optional
+	Lifetime *metav1.Duration `json:"lifetime,omitempty"`
+}
+
+// CertManagerSpec defines how cert-manager issues certificates for a cluster.
+type CertManagerSpec struct {
+
+	// The cert-manager Issuer or ClusterIssuer that signs every certificate.
+	// The issuer must write its certificate authority to "ca.crt" in each
+	// Secret it issues; ACME issuers and some Vault or Venafi setups do not.
+	// +kubebuilder:validation:Required
+	IssuerRef CertManagerIssuerReference `json:"issuerRef"`
+}
+
+// CertManagerIssuerReference identifies a cert-manager issuer.
+type CertManagerIssuerReference struct {
+
+	// Name of the issuer. An Issuer must be in the same namespace as the cluster.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Kind of the issuer.
+	// +kubebuilder:default=Issuer
+	// +kubebuilder:validation:Enum={Issuer,ClusterIssuer}
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// API group of the issuer. External issuers have their own group.
+	// +kubebuilder:default=cert-manager.io
+	// +optional
+	Group string `json:"group,omitempty"`
+}
+
 // PostgresClusterStatus defines the observed state of PostgresCluster
 type PostgresClusterStatus struct {
 
+	// When the certificates of the cluster expire.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Certificates []CertificateStatus `json:"certificates,omitempty"`
+
 	// Identifies the databases that have been installed into PostgreSQL.
 	DatabaseRevision string `json:"databaseRevision,omitempty"`
 
@@ -338,6 +454,10 @@ type PostgresClusterStatus struct {
 	// +optional
 	InstanceSets []PostgresInstanceSetStatus `json:"instances,omitempty"`
 
+	// Progress of the most recent change to the PostgreSQL image.
+	// +optional
+	ImageRollout *PostgresImageRolloutStatus `json:"imageRollout,omitempty"`
+
 	// +optional
 	Patroni PatroniStatus `json:"patroni,omitempty"`
 
@@ -360,6 +480,11 @@ type PostgresClusterStatus struct {
 	// +optional
 	Proxy PostgresProxyStatus `json:"proxy,omitempty"`
 
+	// Progress of this cluster through the most recent rotation of the root
+	// certificate authority in its namespace.
+	// +optional
+	RootCertificateRotation *RootCertificateRotationStatus `json:"rootCertificateRotation,omitempty"`
+
 	// The instance that should be started first when bootstrapping and/or starting a
 	// PostgresCluster.
 	// +optional
@@ -369,6 +494,10 @@ type PostgresClusterStatus struct {
 	// +optional
 	StartupInstanceSet string `json:"startupInstanceSet,omitempty"`
 
+	// Current state of the standby leader when this cluster is a standby.
+	// +optional
+	Standby *PostgresStandbyStatus `json:"standby,omitempty"`
+
 	// Current state of the PostgreSQL user interface.
 	// +optional
 	UserInterface *PostgresUserInterfaceStatus `json:"userInterface,omitempty"`
@@ -390,8 +519,9 @@ type PostgresClusterStatus struct {
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 
 	// conditions represent the observations of postgrescluster's current state.
-	// Known .status.conditions.type are: "PersistentVolumeResizing",
-	// "Progressing", "ProxyAvailable"
+	// Known .status.conditions.type are: "CertificatesExpiring",
+	// "CertificatesIssued", "PersistentVolumeResizing", "Progressing",
+	// "PrometheusMonitorsReady", "ProxyAvailable", "StandbyPromotionHeld"
 	// +optional
 	// +listType=map
 	// +listMapKey=type
@@ -401,12 +531,151 @@ type PostgresClusterStatus struct {
 
 // PostgresClusterStatus condition types.
 const (
+	CertificatesExpiring       = "CertificatesExpiring"
+	CertificatesIssued         = "CertificatesIssued"
 	PersistentVolumeResizing   = "PersistentVolumeResizing"
 	PostgresClusterProgressing = "Progressing"
+	PrometheusMonitorsReady    = "PrometheusMonitorsReady"
 	ProxyAvailable             = "ProxyAvailable"
 	Registered                 = "Registered"
+	StandbyPromotionHeld       = "StandbyPromotionHeld"
+)
+
+// CertificateStatus describes a certificate in use by a PostgresCluster.
+type CertificateStatus struct {
+
+	// What the certificate is used for, such as "server" or "replication".
+	// +required
+	Name string `json:"name"`
+
+	// The Secret that contains the certificate.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// When the certificate expires.
+	// +required
+	NotAfter metav1.Time `json:"notAfter"`
+
+	// When the certificate should be replaced. A certificate that is past
+	// this time was not renewed.
+	// +optional
+	RenewAfter *metav1.Time `json:"renewAfter,omitempty"`
+}
+
+// RootCertificateRotationStatus describes the replacement of the root
+// certificate authority that issues the certificates of a cluster. The root is
+// shared by every cluster in a namespace, so they rotate it together.
+type RootCertificateRotationStatus struct {
+
+	// The value of the annotation that requested this rotation.
+	// +required
+	ID string `json:"id"`
+
+	// The current step of the rotation:
+	//   - "Trusting" adds a new root to every trust bundle.
+	//   - "Reissuing" replaces every certificate with one from the new root.
+	//   - "Removing" removes the old root from every trust bundle.
+	//   - "Complete" means the old root is no longer used.
+	// The next step begins after every cluster in the namespace has loaded
+	// the certificates of this one.
+	// +kubebuilder:validation:Enum={Trusting,Reissuing,Removing,Complete}
+	// +required
+	Phase string `json:"phase"`
+
+	// Whether or not every running instance of this cluster has loaded the
+	// certificates of this phase.
+	// +optional
+	Loaded bool `json:"loaded,omitempty"`
+
+	// The value of the annotation on this cluster that last started a
+	// rotation. A rotation requested while another is underway starts after
+	// that one completes.
+	// +optional
+	Requested string `json:"requested,omitempty"`
+}
+
+// RootCertificateRotationStatus phases.
+const (
+	RootCertificateRotationTrusting  = "Trusting"
+	RootCertificateRotationReissuing = "Reissuing"
+	RootCertificateRotationRemoving  = "Removing"
+	RootCertificateRotationComplete  = "Complete"
+)
+
+// PostgresStandbyStatus describes the replay of WAL by a standby cluster.
+type PostgresStandbyStatus struct {
+
+	// Whether or not this cluster was a primary when it became a standby.
+	// +optional
+	Demoted bool `json:"demoted,omitempty"`
+
+	// The WAL location at which this cluster stopped writing when it was
+	// demoted to a standby. Another cluster can be promoted after it replays
+	// WAL up to this location.
+	// +optional
+	DemotedLSN string `json:"demotedLSN,omitempty"`
+
+	// The last WAL location replayed by the standby leader.
+	// +optional
+	ReplayLSN string `json:"replayLSN,omitempty"`
+
+	// The amount of WAL the standby leader has yet to replay from its
+	// source, in bytes. Only reported when streaming from a host.
+	// +optional
+	LagBytes *int64 `json:"lagBytes,omitempty"`
+}
+
+// PostgresImageRolloutStatus describes the redeployment of PostgreSQL
+// instances onto a new image, such as one with a new minor version.
+type PostgresImageRolloutStatus struct {
+
+	// The PostgreSQL image being rolled out.
+	// +required
+	Image string `json:"image"`
+
+	// The current step of the rollout:
+	//   - "Replicas" redeploys replicas and verifies their PostgreSQL version.
+	//   - "Switchover" redeploys the primary. This only labels the step; the
+	//     primary switches over to a replica as for any other change to it.
+	//   - "Complete" means every instance runs the same PostgreSQL version.
+	//   - "Halted" means a replica could not start on the image. The rollout
+	//     continues when that replica starts or the image changes.
+	// +kubebuilder:validation:Enum={Replicas,Switchover,Complete,Halted}
+	// +required
+	Phase string `json:"phase"`
+
+	// The PostgreSQL version observed on each instance.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Members []PostgresMemberVersionStatus `json:"members,omitempty"`
+}
+
+// PostgresImageRolloutStatus phases.
+const (
+	PostgresImageRolloutReplicas   = "Replicas"
+	PostgresImageRolloutSwitchover = "Switchover"
+	PostgresImageRolloutComplete   = "Complete"
+	PostgresImageRolloutHalted     = "Halted"
 )
 
+// PostgresMemberVersionStatus describes the PostgreSQL version of an instance.
+type PostgresMemberVersionStatus struct {
+
+	// The name of the instance.
+	// +required
+	Name string `json:"name"`
+
+	// The image of the PostgreSQL container of the instance.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// The "server_version_num" reported by PostgreSQL using that image.
+	// Zero until the version has been verified.
+	// +optional
+	ServerVersionNum int `json:"serverVersionNum,omitempty"`
+}
+
 type PostgresInstanceSetSpec struct {
 	// +optional
 	Metadata *Metadata `json:"metadata,omitempty"`
@@ -561,6 +830,15 @@ type PostgresProxySpec struct {
 
 	// Defines a PgBouncer proxy and connection pooler.
 	PGBouncer *PGBouncerPodSpec `json:"pgBouncer"`
+
+	// Additional PgBouncer connection poolers, each with its own Deployment
+	// and Service named "<cluster>-pgbouncer-<name>". Settings that a pooler
+	// does not specify come from pgBouncer above.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=8
+	// +optional
+	Poolers []PGBouncerPoolerSpec `json:"poolers,omitempty"`
 }
 
 // Default sets the defaults for any proxies that are set.
@@ -568,6 +846,9 @@ func (s *PostgresProxySpec) Default() {
 	if s.PGBouncer != nil {
 		s.PGBouncer.Default()
 	}
+	for i := range s.Poolers {
+		s.Poolers[i].Default()
+	}
 }
 
 type RegistrationRequirementStatus struct {
@@ -576,6 +857,12 @@ type RegistrationRequirementStatus struct {
 
 type PostgresProxyStatus struct {
 	PGBouncer PGBouncerPodStatus `json:"pgBouncer,omitempty"`
+
+	// Status of each additional PgBouncer pooler.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Poolers []PGBouncerPoolerStatus `json:"poolers,omitempty"`
 }
 
 // PostgresStandbySpec defines if/how the cluster should be a hot standby.
@@ -600,6 +887,35 @@ type PostgresStandbySpec struct {
 	// +optional
 	// +kubebuilder:validation:Minimum=1024
 	Port *int32 `json:"port,omitempty"`
+
+	// Hold the promotion of this cluster until it has replayed WAL up to the
+	// point where its source stopped writing. This applies when enabled
+	// changes to false while this cluster is a standby, such as during a
+	// switchover between data centers.
+	// +optional
+	PromoteAfter *PostgresStandbyPromotionSpec `json:"promoteAfter,omitempty"`
+}
+
+// PostgresStandbyPromotionSpec identifies the final WAL location of the
+// cluster that a standby replaces.
+// +kubebuilder:validation:XValidation:rule=`has(self.clusterName) != has(self.lsn)`,message="exactly one of clusterName or lsn is required"
+type PostgresStandbyPromotionSpec struct {
+	// The name of a PostgresCluster in this namespace that was demoted to a
+	// standby. Its status reports where it stopped writing.
+	// +optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// A WAL location, such as "0/3000060", reported by a demoted cluster that
+	// is not in this namespace.
+	// +kubebuilder:validation:Pattern=`^[0-9A-Fa-f]{1,8}/[0-9A-Fa-f]{1,8}$`
+	// +optional
+	LSN string `json:"lsn,omitempty"`
+
+	// Promote without waiting when the cluster named above has not reported
+	// where it stopped writing, such as when it was lost before it could be
+	// demoted. WAL it wrote that this cluster has not replayed is discarded.
+	// +optional
+	Force bool `json:"force,omitempty"`
 }
 
 // UserInterfaceSpec is a union of the supported PostgreSQL user interfaces.
@@ -678,6 +994,14 @@ func init() {
 type MonitoringSpec struct {
 	// +optional
 	PGMonitor *PGMonitorSpec `json:"pgmonitor,omitempty"`
+
+	// Prometheus Operator objects that scrape the exporter and alert on what
+	// it collects. These are written only when the Prometheus Operator CRDs
+	// are installed; the PrometheusMonitorsReady condition reports when they
+	// are not. Requires the exporter in pgmonitor.
+	// More info: https://prometheus-operator.dev/docs/getting-started/introduction/
+	// +optional
+	Prometheus *PrometheusSpec `json:"prometheus,omitempty"`
 }
 
 // MonitoringStatus is the current state of PostgreSQL cluster monitoring tool
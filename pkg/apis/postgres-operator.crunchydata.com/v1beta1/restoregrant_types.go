@@ -0,0 +1,83 @@
+// Copyright 2021 - 2024 Crunchy Data Solutions, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RestoreGrantSpec defines the desired state of RestoreGrant
+type RestoreGrantSpec struct {
+
+	// The names of PostgresClusters in this namespace whose pgBackRest
+	// repositories can be restored into other namespaces. When empty, every
+	// PostgresCluster in this namespace can be restored.
+	// +listType=set
+	// +optional
+	ClusterNames []string `json:"clusterNames,omitempty"`
+
+	// The namespaces that can restore these PostgresClusters, either to create
+	// a new PostgresCluster or to restore one in-place. Restoring copies the
+	// pgBackRest configuration and credentials of a PostgresCluster into the
+	// namespace of the PostgresCluster being restored.
+	// +listType=set
+	// +kubebuilder:validation:MinItems=1
+	// +required
+	TargetNamespaces []string `json:"targetNamespaces"`
+}
+
+// Allows returns whether or not spec allows the PostgresCluster named cluster
+// to be restored into namespace.
+func (spec *RestoreGrantSpec) Allows(cluster, namespace string) bool {
+	var clusterMatches bool
+	for _, name := range spec.ClusterNames {
+		clusterMatches = clusterMatches || name == cluster
+	}
+	if len(spec.ClusterNames) > 0 && !clusterMatches {
+		return false
+	}
+
+	for _, name := range spec.TargetNamespaces {
+		if name == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+//+kubebuilder:object:root=true
+
+// RestoreGrant allows PostgresClusters in other namespaces to restore from the
+// pgBackRest repositories of PostgresClusters in its namespace. Without one,
+// a PostgresCluster can only restore from PostgresClusters in its namespace.
+type RestoreGrant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RestoreGrantSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RestoreGrantList contains a list of RestoreGrant
+type RestoreGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RestoreGrant `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RestoreGrant{}, &RestoreGrantList{})
+}
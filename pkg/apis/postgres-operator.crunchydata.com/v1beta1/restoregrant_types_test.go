@@ -0,0 +1,48 @@
+/*
+ Copyright 2024 Crunchy Data Solutions, Inc.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRestoreGrantSpecAllows(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Empty", func(t *testing.T) {
+		var spec RestoreGrantSpec
+		assert.Assert(t, !spec.Allows("hippo", "target"))
+	})
+
+	t.Run("AllClusters", func(t *testing.T) {
+		spec := RestoreGrantSpec{TargetNamespaces: []string{"one", "two"}}
+		assert.Assert(t, spec.Allows("hippo", "one"))
+		assert.Assert(t, spec.Allows("rhino", "two"))
+		assert.Assert(t, !spec.Allows("hippo", "three"))
+	})
+
+	t.Run("SomeClusters", func(t *testing.T) {
+		spec := RestoreGrantSpec{
+			ClusterNames:     []string{"hippo"},
+			TargetNamespaces: []string{"one"},
+		}
+		assert.Assert(t, spec.Allows("hippo", "one"))
+		assert.Assert(t, !spec.Allows("rhino", "one"))
+		assert.Assert(t, !spec.Allows("hippo", "two"))
+	})
+}
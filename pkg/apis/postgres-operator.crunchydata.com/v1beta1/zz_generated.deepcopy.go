@@ -99,6 +99,77 @@ func (in *Backups) DeepCopy() *Backups {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertManagerIssuerReference) DeepCopyInto(out *CertManagerIssuerReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerIssuerReference.
+func (in *CertManagerIssuerReference) DeepCopy() *CertManagerIssuerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(CertManagerIssuerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertManagerSpec) DeepCopyInto(out *CertManagerSpec) {
+	*out = *in
+	out.IssuerRef = in.IssuerRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerSpec.
+func (in *CertManagerSpec) DeepCopy() *CertManagerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CertManagerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateStatus) DeepCopyInto(out *CertificateStatus) {
+	*out = *in
+	in.NotAfter.DeepCopyInto(&out.NotAfter)
+	if in.RenewAfter != nil {
+		in, out := &in.RenewAfter, &out.RenewAfter
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateStatus.
+func (in *CertificateStatus) DeepCopy() *CertificateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificatesSpec) DeepCopyInto(out *CertificatesSpec) {
+	*out = *in
+	if in.Lifetime != nil {
+		in, out := &in.Lifetime, &out.Lifetime
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificatesSpec.
+func (in *CertificatesSpec) DeepCopy() *CertificatesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificatesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterUpgrade) DeepCopyInto(out *ClusterUpgrade) {
 	*out = *in
@@ -293,6 +364,11 @@ func (in *DataSource) DeepCopyInto(out *DataSource) {
 		*out = new(PostgresClusterDataSource)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Streaming != nil {
+		in, out := &in.Streaming, &out.Streaming
+		*out = new(StreamingDataSource)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Volumes != nil {
 		in, out := &in.Volumes, &out.Volumes
 		*out = new(DataSourceVolumes)
@@ -370,6 +446,46 @@ func (in *DatabaseInitSQL) DeepCopy() *DatabaseInitSQL {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterQueriesSpec) DeepCopyInto(out *ExporterQueriesSpec) {
+	*out = *in
+	if in.ExcludeFiles != nil {
+		in, out := &in.ExcludeFiles, &out.ExcludeFiles
+		*out = make([]ExporterQueryFile, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeQueries != nil {
+		in, out := &in.ExcludeQueries, &out.ExcludeQueries
+		*out = make([]ExporterQueryName, len(*in))
+		copy(*out, *in)
+	}
+	if in.PGBackRestInfoThrottleMinutes != nil {
+		in, out := &in.PGBackRestInfoThrottleMinutes, &out.PGBackRestInfoThrottleMinutes
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PGStatStatementsLimit != nil {
+		in, out := &in.PGStatStatementsLimit, &out.PGStatStatementsLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PGStatStatementsThrottleMinutes != nil {
+		in, out := &in.PGStatStatementsThrottleMinutes, &out.PGStatStatementsThrottleMinutes
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterQueriesSpec.
+func (in *ExporterQueriesSpec) DeepCopy() *ExporterQueriesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterQueriesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExporterSpec) DeepCopyInto(out *ExporterSpec) {
 	*out = *in
@@ -385,6 +501,11 @@ func (in *ExporterSpec) DeepCopyInto(out *ExporterSpec) {
 		*out = new(corev1.SecretProjection)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Queries != nil {
+		in, out := &in.Queries, &out.Queries
+		*out = new(ExporterQueriesSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Resources.DeepCopyInto(&out.Resources)
 }
 
@@ -455,6 +576,11 @@ func (in *MonitoringSpec) DeepCopyInto(out *MonitoringSpec) {
 		*out = new(PGMonitorSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Prometheus != nil {
+		in, out := &in.Prometheus, &out.Prometheus
+		*out = new(PrometheusSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringSpec.
@@ -1144,6 +1270,63 @@ func (in *PGBackRestStatus) DeepCopy() *PGBackRestStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBouncerAuthenticationSpec) DeepCopyInto(out *PGBouncerAuthenticationSpec) {
+	*out = *in
+	if in.ConsoleUsers != nil {
+		in, out := &in.ConsoleUsers, &out.ConsoleUsers
+		*out = make([]PGBouncerConsoleUser, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]PostgresHBARule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerAuthenticationSpec.
+func (in *PGBouncerAuthenticationSpec) DeepCopy() *PGBouncerAuthenticationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBouncerAuthenticationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBouncerAutoscalingSpec) DeepCopyInto(out *PGBouncerAutoscalingSpec) {
+	*out = *in
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetCPUUtilizationPercentage != nil {
+		in, out := &in.TargetCPUUtilizationPercentage, &out.TargetCPUUtilizationPercentage
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetClientConnections != nil {
+		in, out := &in.TargetClientConnections, &out.TargetClientConnections
+		*out = new(PGBouncerConnectionsTarget)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerAutoscalingSpec.
+func (in *PGBouncerAutoscalingSpec) DeepCopy() *PGBouncerAutoscalingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBouncerAutoscalingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PGBouncerConfiguration) DeepCopyInto(out *PGBouncerConfiguration) {
 	*out = *in
@@ -1175,6 +1358,11 @@ func (in *PGBouncerConfiguration) DeepCopyInto(out *PGBouncerConfiguration) {
 			(*out)[key] = val
 		}
 	}
+	if in.Pools != nil {
+		in, out := &in.Pools, &out.Pools
+		*out = new(PGBouncerPoolSettings)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerConfiguration.
@@ -1187,6 +1375,71 @@ func (in *PGBouncerConfiguration) DeepCopy() *PGBouncerConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBouncerConnectionsTarget) DeepCopyInto(out *PGBouncerConnectionsTarget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerConnectionsTarget.
+func (in *PGBouncerConnectionsTarget) DeepCopy() *PGBouncerConnectionsTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBouncerConnectionsTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBouncerConsoleUser) DeepCopyInto(out *PGBouncerConsoleUser) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerConsoleUser.
+func (in *PGBouncerConsoleUser) DeepCopy() *PGBouncerConsoleUser {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBouncerConsoleUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBouncerDatabasePoolSettings) DeepCopyInto(out *PGBouncerDatabasePoolSettings) {
+	*out = *in
+	if in.PoolSize != nil {
+		in, out := &in.PoolSize, &out.PoolSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinPoolSize != nil {
+		in, out := &in.MinPoolSize, &out.MinPoolSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ReservePoolSize != nil {
+		in, out := &in.ReservePoolSize, &out.ReservePoolSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxDBConnections != nil {
+		in, out := &in.MaxDBConnections, &out.MaxDBConnections
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerDatabasePoolSettings.
+func (in *PGBouncerDatabasePoolSettings) DeepCopy() *PGBouncerDatabasePoolSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBouncerDatabasePoolSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PGBouncerPodSpec) DeepCopyInto(out *PGBouncerPodSpec) {
 	*out = *in
@@ -1195,6 +1448,16 @@ func (in *PGBouncerPodSpec) DeepCopyInto(out *PGBouncerPodSpec) {
 		*out = new(Metadata)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Authentication != nil {
+		in, out := &in.Authentication, &out.Authentication
+		*out = new(PGBouncerAuthenticationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(PGBouncerAutoscalingSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Affinity != nil {
 		in, out := &in.Affinity, &out.Affinity
 		*out = new(corev1.Affinity)
@@ -1223,6 +1486,11 @@ func (in *PGBouncerPodSpec) DeepCopyInto(out *PGBouncerPodSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.ReadOnly != nil {
+		in, out := &in.ReadOnly, &out.ReadOnly
+		*out = new(PGBouncerReadOnlySpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Replicas != nil {
 		in, out := &in.Replicas, &out.Replicas
 		*out = new(int32)
@@ -1244,6 +1512,16 @@ func (in *PGBouncerPodSpec) DeepCopyInto(out *PGBouncerPodSpec) {
 		*out = new(PGBouncerSidecars)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Switchover != nil {
+		in, out := &in.Switchover, &out.Switchover
+		*out = new(PGBouncerSwitchoverSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
 	if in.Tolerations != nil {
 		in, out := &in.Tolerations, &out.Tolerations
 		*out = make([]corev1.Toleration, len(*in))
@@ -1286,72 +1564,322 @@ func (in *PGBouncerPodStatus) DeepCopy() *PGBouncerPodStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PGBouncerSidecars) DeepCopyInto(out *PGBouncerSidecars) {
+func (in *PGBouncerPoolSettings) DeepCopyInto(out *PGBouncerPoolSettings) {
 	*out = *in
-	if in.PGBouncerConfig != nil {
-		in, out := &in.PGBouncerConfig, &out.PGBouncerConfig
-		*out = new(Sidecar)
-		(*in).DeepCopyInto(*out)
+	if in.DefaultPoolSize != nil {
+		in, out := &in.DefaultPoolSize, &out.DefaultPoolSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinPoolSize != nil {
+		in, out := &in.MinPoolSize, &out.MinPoolSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ReservePoolSize != nil {
+		in, out := &in.ReservePoolSize, &out.ReservePoolSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxClientConnections != nil {
+		in, out := &in.MaxClientConnections, &out.MaxClientConnections
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ServerIdleTimeoutSeconds != nil {
+		in, out := &in.ServerIdleTimeoutSeconds, &out.ServerIdleTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxPreparedStatements != nil {
+		in, out := &in.MaxPreparedStatements, &out.MaxPreparedStatements
+		*out = new(int32)
+		**out = **in
+	}
+	if in.QueryWaitTimeoutSeconds != nil {
+		in, out := &in.QueryWaitTimeoutSeconds, &out.QueryWaitTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]PGBouncerDatabasePoolSettings, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]PGBouncerUserPoolSettings, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerSidecars.
-func (in *PGBouncerSidecars) DeepCopy() *PGBouncerSidecars {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerPoolSettings.
+func (in *PGBouncerPoolSettings) DeepCopy() *PGBouncerPoolSettings {
 	if in == nil {
 		return nil
 	}
-	out := new(PGBouncerSidecars)
+	out := new(PGBouncerPoolSettings)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PGMonitorSpec) DeepCopyInto(out *PGMonitorSpec) {
+func (in *PGBouncerPoolerSpec) DeepCopyInto(out *PGBouncerPoolerSpec) {
 	*out = *in
-	if in.Exporter != nil {
-		in, out := &in.Exporter, &out.Exporter
-		*out = new(ExporterSpec)
+	in.Config.DeepCopyInto(&out.Config)
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinAvailable != nil {
+		in, out := &in.MinAvailable, &out.MinAvailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(ServiceSpec)
 		(*in).DeepCopyInto(*out)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGMonitorSpec.
-func (in *PGMonitorSpec) DeepCopy() *PGMonitorSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerPoolerSpec.
+func (in *PGBouncerPoolerSpec) DeepCopy() *PGBouncerPoolerSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PGMonitorSpec)
+	out := new(PGBouncerPoolerSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PGUpgrade) DeepCopyInto(out *PGUpgrade) {
+func (in *PGBouncerPoolerStatus) DeepCopyInto(out *PGBouncerPoolerStatus) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGUpgrade.
-func (in *PGUpgrade) DeepCopy() *PGUpgrade {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerPoolerStatus.
+func (in *PGBouncerPoolerStatus) DeepCopy() *PGBouncerPoolerStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PGUpgrade)
+	out := new(PGBouncerPoolerStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PGUpgrade) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBouncerReadOnlySpec) DeepCopyInto(out *PGBouncerReadOnlySpec) {
+	*out = *in
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]PostgresIdentifier, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerReadOnlySpec.
+func (in *PGBouncerReadOnlySpec) DeepCopy() *PGBouncerReadOnlySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBouncerReadOnlySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBouncerSidecars) DeepCopyInto(out *PGBouncerSidecars) {
+	*out = *in
+	if in.PGBouncerConfig != nil {
+		in, out := &in.PGBouncerConfig, &out.PGBouncerConfig
+		*out = new(Sidecar)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerSidecars.
+func (in *PGBouncerSidecars) DeepCopy() *PGBouncerSidecars {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBouncerSidecars)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBouncerSwitchoverSpec) DeepCopyInto(out *PGBouncerSwitchoverSpec) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerSwitchoverSpec.
+func (in *PGBouncerSwitchoverSpec) DeepCopy() *PGBouncerSwitchoverSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBouncerSwitchoverSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGBouncerUserPoolSettings) DeepCopyInto(out *PGBouncerUserPoolSettings) {
+	*out = *in
+	if in.MaxUserConnections != nil {
+		in, out := &in.MaxUserConnections, &out.MaxUserConnections
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGBouncerUserPoolSettings.
+func (in *PGBouncerUserPoolSettings) DeepCopy() *PGBouncerUserPoolSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(PGBouncerUserPoolSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGMonitorSpec) DeepCopyInto(out *PGMonitorSpec) {
+	*out = *in
+	if in.Exporter != nil {
+		in, out := &in.Exporter, &out.Exporter
+		*out = new(ExporterSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGMonitorSpec.
+func (in *PGMonitorSpec) DeepCopy() *PGMonitorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PGMonitorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGUpgrade) DeepCopyInto(out *PGUpgrade) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGUpgrade.
+func (in *PGUpgrade) DeepCopy() *PGUpgrade {
+	if in == nil {
+		return nil
+	}
+	out := new(PGUpgrade)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PGUpgrade) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
 		return c
 	}
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGUpgradeBackupSpec) DeepCopyInto(out *PGUpgradeBackupSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGUpgradeBackupSpec.
+func (in *PGUpgradeBackupSpec) DeepCopy() *PGUpgradeBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PGUpgradeBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGUpgradeBackupStatus) DeepCopyInto(out *PGUpgradeBackupStatus) {
+	*out = *in
+	if in.Manual != nil {
+		in, out := &in.Manual, &out.Manual
+		*out = new(PGBackRestManualBackup)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Restore != nil {
+		in, out := &in.Restore, &out.Restore
+		*out = new(PGBackRestRestore)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGUpgradeBackupStatus.
+func (in *PGUpgradeBackupStatus) DeepCopy() *PGUpgradeBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PGUpgradeBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGUpgradeCheckStatus) DeepCopyInto(out *PGUpgradeCheckStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGUpgradeCheckStatus.
+func (in *PGUpgradeCheckStatus) DeepCopy() *PGUpgradeCheckStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PGUpgradeCheckStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGUpgradeConnectGrant) DeepCopyInto(out *PGUpgradeConnectGrant) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGUpgradeConnectGrant.
+func (in *PGUpgradeConnectGrant) DeepCopy() *PGUpgradeConnectGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(PGUpgradeConnectGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PGUpgradeList) DeepCopyInto(out *PGUpgradeList) {
 	*out = *in
@@ -1384,6 +1912,75 @@ func (in *PGUpgradeList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGUpgradeLogicalSpec) DeepCopyInto(out *PGUpgradeLogicalSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGUpgradeLogicalSpec.
+func (in *PGUpgradeLogicalSpec) DeepCopy() *PGUpgradeLogicalSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PGUpgradeLogicalSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGUpgradeLogicalStatus) DeepCopyInto(out *PGUpgradeLogicalStatus) {
+	*out = *in
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReplicationLagBytes != nil {
+		in, out := &in.ReplicationLagBytes, &out.ReplicationLagBytes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.RevokedConnect != nil {
+		in, out := &in.RevokedConnect, &out.RevokedConnect
+		*out = new(PGUpgradeRevokedConnect)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CutoverTime != nil {
+		in, out := &in.CutoverTime, &out.CutoverTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGUpgradeLogicalStatus.
+func (in *PGUpgradeLogicalStatus) DeepCopy() *PGUpgradeLogicalStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PGUpgradeLogicalStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGUpgradeRevokedConnect) DeepCopyInto(out *PGUpgradeRevokedConnect) {
+	*out = *in
+	if in.Grants != nil {
+		in, out := &in.Grants, &out.Grants
+		*out = make([]PGUpgradeConnectGrant, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGUpgradeRevokedConnect.
+func (in *PGUpgradeRevokedConnect) DeepCopy() *PGUpgradeRevokedConnect {
+	if in == nil {
+		return nil
+	}
+	out := new(PGUpgradeRevokedConnect)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PGUpgradeSpec) DeepCopyInto(out *PGUpgradeSpec) {
 	*out = *in
@@ -1402,6 +1999,21 @@ func (in *PGUpgradeSpec) DeepCopyInto(out *PGUpgradeSpec) {
 		*out = make([]corev1.LocalObjectReference, len(*in))
 		copy(*out, *in)
 	}
+	if in.Jobs != nil {
+		in, out := &in.Jobs, &out.Jobs
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Logical != nil {
+		in, out := &in.Logical, &out.Logical
+		*out = new(PGUpgradeLogicalSpec)
+		**out = **in
+	}
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(PGUpgradeBackupSpec)
+		**out = **in
+	}
 	in.Resources.DeepCopyInto(&out.Resources)
 	if in.Affinity != nil {
 		in, out := &in.Affinity, &out.Affinity
@@ -1442,6 +2054,28 @@ func (in *PGUpgradeStatus) DeepCopyInto(out *PGUpgradeStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(PGUpgradeBackupStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Check != nil {
+		in, out := &in.Check, &out.Check
+		*out = new(PGUpgradeCheckStatus)
+		**out = **in
+	}
+	if in.Logical != nil {
+		in, out := &in.Logical, &out.Logical
+		*out = new(PGUpgradeLogicalStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]PGUpgradeStepStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGUpgradeStatus.
@@ -1454,6 +2088,29 @@ func (in *PGUpgradeStatus) DeepCopy() *PGUpgradeStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PGUpgradeStepStatus) DeepCopyInto(out *PGUpgradeStepStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PGUpgradeStepStatus.
+func (in *PGUpgradeStepStatus) DeepCopy() *PGUpgradeStepStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PGUpgradeStepStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PatroniSpec) DeepCopyInto(out *PatroniSpec) {
 	*out = *in
@@ -1557,6 +2214,33 @@ func (in *PostgresAdditionalConfig) DeepCopy() *PostgresAdditionalConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresAuthenticationSpec) DeepCopyInto(out *PostgresAuthenticationSpec) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]PostgresHBARule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.IdentMaps != nil {
+		in, out := &in.IdentMaps, &out.IdentMaps
+		*out = make([]PostgresIdentMap, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresAuthenticationSpec.
+func (in *PostgresAuthenticationSpec) DeepCopy() *PostgresAuthenticationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresAuthenticationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PostgresCluster) DeepCopyInto(out *PostgresCluster) {
 	*out = *in
@@ -1662,12 +2346,27 @@ func (in *PostgresClusterSpec) DeepCopyInto(out *PostgresClusterSpec) {
 		*out = new(Metadata)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Authentication != nil {
+		in, out := &in.Authentication, &out.Authentication
+		*out = new(PostgresAuthenticationSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.DataSource != nil {
 		in, out := &in.DataSource, &out.DataSource
 		*out = new(DataSource)
 		(*in).DeepCopyInto(*out)
 	}
 	in.Backups.DeepCopyInto(&out.Backups)
+	if in.CertManager != nil {
+		in, out := &in.CertManager, &out.CertManager
+		*out = new(CertManagerSpec)
+		**out = **in
+	}
+	if in.Certificates != nil {
+		in, out := &in.Certificates, &out.Certificates
+		*out = new(CertificatesSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.CustomTLSSecret != nil {
 		in, out := &in.CustomTLSSecret, &out.CustomTLSSecret
 		*out = new(corev1.SecretProjection)
@@ -1783,11 +2482,23 @@ func (in *PostgresClusterSpec) DeepCopy() *PostgresClusterSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PostgresClusterStatus) DeepCopyInto(out *PostgresClusterStatus) {
 	*out = *in
+	if in.Certificates != nil {
+		in, out := &in.Certificates, &out.Certificates
+		*out = make([]CertificateStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.InstanceSets != nil {
 		in, out := &in.InstanceSets, &out.InstanceSets
 		*out = make([]PostgresInstanceSetStatus, len(*in))
 		copy(*out, *in)
 	}
+	if in.ImageRollout != nil {
+		in, out := &in.ImageRollout, &out.ImageRollout
+		*out = new(PostgresImageRolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Patroni.DeepCopyInto(&out.Patroni)
 	if in.PGBackRest != nil {
 		in, out := &in.PGBackRest, &out.PGBackRest
@@ -1799,7 +2510,17 @@ func (in *PostgresClusterStatus) DeepCopyInto(out *PostgresClusterStatus) {
 		*out = new(RegistrationRequirementStatus)
 		**out = **in
 	}
-	out.Proxy = in.Proxy
+	in.Proxy.DeepCopyInto(&out.Proxy)
+	if in.RootCertificateRotation != nil {
+		in, out := &in.RootCertificateRotation, &out.RootCertificateRotation
+		*out = new(RootCertificateRotationStatus)
+		**out = **in
+	}
+	if in.Standby != nil {
+		in, out := &in.Standby, &out.Standby
+		*out = new(PostgresStandbyStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.UserInterface != nil {
 		in, out := &in.UserInterface, &out.UserInterface
 		*out = new(PostgresUserInterfaceStatus)
@@ -1830,6 +2551,73 @@ func (in *PostgresClusterStatus) DeepCopy() *PostgresClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresHBARule) DeepCopyInto(out *PostgresHBARule) {
+	*out = *in
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]PostgresIdentifier, len(*in))
+		copy(*out, *in)
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]PostgresIdentifier, len(*in))
+		copy(*out, *in)
+	}
+	if in.Options != nil {
+		in, out := &in.Options, &out.Options
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresHBARule.
+func (in *PostgresHBARule) DeepCopy() *PostgresHBARule {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresHBARule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresIdentMap) DeepCopyInto(out *PostgresIdentMap) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresIdentMap.
+func (in *PostgresIdentMap) DeepCopy() *PostgresIdentMap {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresIdentMap)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresImageRolloutStatus) DeepCopyInto(out *PostgresImageRolloutStatus) {
+	*out = *in
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]PostgresMemberVersionStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresImageRolloutStatus.
+func (in *PostgresImageRolloutStatus) DeepCopy() *PostgresImageRolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresImageRolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PostgresInstanceSetSpec) DeepCopyInto(out *PostgresInstanceSetSpec) {
 	*out = *in
@@ -1925,6 +2713,21 @@ func (in *PostgresInstanceSetStatus) DeepCopy() *PostgresInstanceSetStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresMemberVersionStatus) DeepCopyInto(out *PostgresMemberVersionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresMemberVersionStatus.
+func (in *PostgresMemberVersionStatus) DeepCopy() *PostgresMemberVersionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresMemberVersionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PostgresPasswordSpec) DeepCopyInto(out *PostgresPasswordSpec) {
 	*out = *in
@@ -1948,6 +2751,13 @@ func (in *PostgresProxySpec) DeepCopyInto(out *PostgresProxySpec) {
 		*out = new(PGBouncerPodSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Poolers != nil {
+		in, out := &in.Poolers, &out.Poolers
+		*out = make([]PGBouncerPoolerSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresProxySpec.
@@ -1964,6 +2774,11 @@ func (in *PostgresProxySpec) DeepCopy() *PostgresProxySpec {
 func (in *PostgresProxyStatus) DeepCopyInto(out *PostgresProxyStatus) {
 	*out = *in
 	out.PGBouncer = in.PGBouncer
+	if in.Poolers != nil {
+		in, out := &in.Poolers, &out.Poolers
+		*out = make([]PGBouncerPoolerStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresProxyStatus.
@@ -1976,6 +2791,21 @@ func (in *PostgresProxyStatus) DeepCopy() *PostgresProxyStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresStandbyPromotionSpec) DeepCopyInto(out *PostgresStandbyPromotionSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresStandbyPromotionSpec.
+func (in *PostgresStandbyPromotionSpec) DeepCopy() *PostgresStandbyPromotionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresStandbyPromotionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PostgresStandbySpec) DeepCopyInto(out *PostgresStandbySpec) {
 	*out = *in
@@ -1984,6 +2814,11 @@ func (in *PostgresStandbySpec) DeepCopyInto(out *PostgresStandbySpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.PromoteAfter != nil {
+		in, out := &in.PromoteAfter, &out.PromoteAfter
+		*out = new(PostgresStandbyPromotionSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresStandbySpec.
@@ -1996,6 +2831,26 @@ func (in *PostgresStandbySpec) DeepCopy() *PostgresStandbySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresStandbyStatus) DeepCopyInto(out *PostgresStandbyStatus) {
+	*out = *in
+	if in.LagBytes != nil {
+		in, out := &in.LagBytes, &out.LagBytes
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresStandbyStatus.
+func (in *PostgresStandbyStatus) DeepCopy() *PostgresStandbyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresStandbyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PostgresUserInterfaceStatus) DeepCopyInto(out *PostgresUserInterfaceStatus) {
 	*out = *in
@@ -2025,6 +2880,11 @@ func (in *PostgresUserSpec) DeepCopyInto(out *PostgresUserSpec) {
 		*out = new(PostgresPasswordSpec)
 		**out = **in
 	}
+	if in.ClientCertificate != nil {
+		in, out := &in.ClientCertificate, &out.ClientCertificate
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresUserSpec.
@@ -2037,6 +2897,71 @@ func (in *PostgresUserSpec) DeepCopy() *PostgresUserSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusAlertsSpec) DeepCopyInto(out *PrometheusAlertsSpec) {
+	*out = *in
+	if in.ArchiveFailure != nil {
+		in, out := &in.ArchiveFailure, &out.ArchiveFailure
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.DiskUsagePercent != nil {
+		in, out := &in.DiskUsagePercent, &out.DiskUsagePercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.FullBackupAge != nil {
+		in, out := &in.FullBackupAge, &out.FullBackupAge
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ReplicationLag != nil {
+		in, out := &in.ReplicationLag, &out.ReplicationLag
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusAlertsSpec.
+func (in *PrometheusAlertsSpec) DeepCopy() *PrometheusAlertsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusAlertsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusSpec) DeepCopyInto(out *PrometheusSpec) {
+	*out = *in
+	if in.Alerts != nil {
+		in, out := &in.Alerts, &out.Alerts
+		*out = new(PrometheusAlertsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(Metadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScrapeInterval != nil {
+		in, out := &in.ScrapeInterval, &out.ScrapeInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusSpec.
+func (in *PrometheusSpec) DeepCopy() *PrometheusSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RegistrationRequirementStatus) DeepCopyInto(out *RegistrationRequirementStatus) {
 	*out = *in
@@ -2144,6 +3069,104 @@ func (in *RepoStatus) DeepCopy() *RepoStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreGrant) DeepCopyInto(out *RestoreGrant) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreGrant.
+func (in *RestoreGrant) DeepCopy() *RestoreGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RestoreGrant) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreGrantList) DeepCopyInto(out *RestoreGrantList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RestoreGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreGrantList.
+func (in *RestoreGrantList) DeepCopy() *RestoreGrantList {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreGrantList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RestoreGrantList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreGrantSpec) DeepCopyInto(out *RestoreGrantSpec) {
+	*out = *in
+	if in.ClusterNames != nil {
+		in, out := &in.ClusterNames, &out.ClusterNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TargetNamespaces != nil {
+		in, out := &in.TargetNamespaces, &out.TargetNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreGrantSpec.
+func (in *RestoreGrantSpec) DeepCopy() *RestoreGrantSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreGrantSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RootCertificateRotationStatus) DeepCopyInto(out *RootCertificateRotationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RootCertificateRotationStatus.
+func (in *RootCertificateRotationStatus) DeepCopy() *RootCertificateRotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RootCertificateRotationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in SchemalessObject) DeepCopyInto(out *SchemalessObject) {
 	{
@@ -2248,6 +3271,39 @@ func (in *StandalonePGAdminConfiguration) DeepCopy() *StandalonePGAdminConfigura
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StreamingDataSource) DeepCopyInto(out *StreamingDataSource) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PriorityClassName != nil {
+		in, out := &in.PriorityClassName, &out.PriorityClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StreamingDataSource.
+func (in *StreamingDataSource) DeepCopy() *StreamingDataSource {
+	if in == nil {
+		return nil
+	}
+	out := new(StreamingDataSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TablespaceVolume) DeepCopyInto(out *TablespaceVolume) {
 	*out = *in